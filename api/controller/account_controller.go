@@ -0,0 +1,13 @@
+package controller
+
+import "github.com/gin-gonic/gin"
+
+// IAccountController defines the interface for account controller
+type IAccountController interface {
+	Update(ctx *gin.Context)
+	ChangePassword(ctx *gin.Context)
+	ChangeEmail(ctx *gin.Context)
+	VerifyEmail(ctx *gin.Context)
+	ScheduleDeletion(ctx *gin.Context)
+	CancelDeletion(ctx *gin.Context)
+}
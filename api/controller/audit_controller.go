@@ -0,0 +1,10 @@
+package controller
+
+import "github.com/gin-gonic/gin"
+
+// IAuditController defines the interface for the audit log controller
+type IAuditController interface {
+	List(ctx *gin.Context)
+	Export(ctx *gin.Context)
+	Verify(ctx *gin.Context)
+}
@@ -0,0 +1,8 @@
+package controller
+
+import "github.com/gin-gonic/gin"
+
+// IAuthzController defines the interface for the access policy controller
+type IAuthzController interface {
+	Explain(ctx *gin.Context)
+}
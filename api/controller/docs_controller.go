@@ -0,0 +1,13 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/openapi"
+)
+
+// IDocsController defines the interface for API documentation controller
+type IDocsController interface {
+	SetDocument(document *openapi.Document)
+	OpenAPI(ctx *gin.Context)
+	Docs(ctx *gin.Context)
+}
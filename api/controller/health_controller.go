@@ -0,0 +1,10 @@
+package controller
+
+import "github.com/gin-gonic/gin"
+
+// IHealthController defines the interface for health and build info controller
+type IHealthController interface {
+	Healthz(ctx *gin.Context)
+	Readyz(ctx *gin.Context)
+	Version(ctx *gin.Context)
+}
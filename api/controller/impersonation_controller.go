@@ -0,0 +1,8 @@
+package controller
+
+import "github.com/gin-gonic/gin"
+
+// IImpersonationController defines the interface for the impersonation controller
+type IImpersonationController interface {
+	Impersonate(ctx *gin.Context)
+}
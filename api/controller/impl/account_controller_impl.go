@@ -0,0 +1,160 @@
+package impl
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/problem"
+	"github.com/userblog/management/api/validation"
+	"github.com/userblog/management/internal/service"
+)
+
+// AccountController implements the IAccountController interface
+type AccountController struct {
+	accountService service.IAccountService
+}
+
+// NewAccountController creates a new account controller
+func NewAccountController(accountService service.IAccountService) controller.IAccountController {
+	return &AccountController{
+		accountService: accountService,
+	}
+}
+
+// Update handles the update own name API endpoint
+func (c *AccountController) Update(ctx *gin.Context) {
+	var req dto.UpdateAccountRequest
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	updated, err := c.accountService.UpdateName(ctx.Request.Context(), user.ID, req.FirstName, req.LastName)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	updated.Password = ""
+	ctx.JSON(http.StatusOK, updated)
+}
+
+// ChangePassword handles the change own password API endpoint
+func (c *AccountController) ChangePassword(ctx *gin.Context) {
+	var req dto.ChangePasswordRequest
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	user, sessionID, err := currentSession(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	if err := c.accountService.ChangePassword(ctx.Request.Context(), user.ID, sessionID, req.CurrentPassword, req.NewPassword); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Password changed successfully; other sessions have been logged out"})
+}
+
+// ChangeEmail handles the change own email API endpoint
+func (c *AccountController) ChangeEmail(ctx *gin.Context) {
+	var req dto.ChangeEmailRequest
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	expiresAt, err := c.accountService.RequestEmailChange(ctx.Request.Context(), user.ID, req.CurrentPassword, req.Email)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, dto.PendingChangeResponse{
+		Message: "A verification token has been sent to the new email address",
+		At:      expiresAt,
+	})
+}
+
+// VerifyEmail handles the verify new email API endpoint
+func (c *AccountController) VerifyEmail(ctx *gin.Context) {
+	var req dto.VerifyEmailRequest
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	if err := c.accountService.VerifyEmailChange(ctx.Request.Context(), user.ID, req.Token); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Email changed successfully"})
+}
+
+// ScheduleDeletion handles the delete own account API endpoint
+func (c *AccountController) ScheduleDeletion(ctx *gin.Context) {
+	var req dto.DeleteAccountRequest
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	deleteAt, err := c.accountService.ScheduleDeletion(ctx.Request.Context(), user.ID, req.CurrentPassword)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, dto.PendingChangeResponse{
+		Message: "Your account will be deleted; sign in and cancel before then to keep it",
+		At:      deleteAt,
+	})
+}
+
+// CancelDeletion handles the cancel own account deletion API endpoint
+func (c *AccountController) CancelDeletion(ctx *gin.Context) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	if err := c.accountService.CancelDeletion(ctx.Request.Context(), user.ID); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Account deletion cancelled"})
+}
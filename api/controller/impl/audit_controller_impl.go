@@ -0,0 +1,122 @@
+package impl
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/problem"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/logger"
+)
+
+// AuditController implements the IAuditController interface
+type AuditController struct {
+	auditService service.IAuditService
+}
+
+// NewAuditController creates a new audit log controller
+func NewAuditController(auditService service.IAuditService) controller.IAuditController {
+	return &AuditController{
+		auditService: auditService,
+	}
+}
+
+// List handles the list audit events API endpoint
+func (c *AuditController) List(ctx *gin.Context) {
+	filter, err := auditFilter(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	perPage, err := strconv.Atoi(ctx.DefaultQuery("per_page", "10"))
+	if err != nil || perPage < 1 || perPage > 100 {
+		perPage = 10
+	}
+
+	events, count, err := c.auditService.List(ctx.Request.Context(), filter, page, perPage)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":       events,
+		"total":      count,
+		"page":       page,
+		"per_page":   perPage,
+		"total_page": (count + perPage - 1) / perPage,
+	})
+}
+
+// Export handles the export audit events API endpoint, streaming one JSON event per line
+func (c *AuditController) Export(ctx *gin.Context) {
+	filter, err := auditFilter(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.Header("Content-Type", "application/x-ndjson")
+	ctx.Header("Content-Disposition", `attachment; filename="audit-`+time.Now().UTC().Format("20060102T150405Z")+`.ndjson"`)
+	ctx.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(ctx.Writer)
+	err = c.auditService.Export(ctx.Request.Context(), filter, func(event *models.AuditEvent) error {
+		return encoder.Encode(event)
+	})
+	if err != nil {
+		// The status has been sent, so a failed export can only be cut short
+		logger.ErrorF(ctx.Request.Context(), "Audit export failed: %v", err)
+	}
+}
+
+// Verify handles the verify audit log API endpoint
+func (c *AuditController) Verify(ctx *gin.Context) {
+	result, err := c.auditService.Verify(ctx.Request.Context())
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// auditFilter reads the audit event filter from the query parameters
+func auditFilter(ctx *gin.Context) (repository.AuditFilter, error) {
+	filter := repository.AuditFilter{
+		Action:     ctx.Query("action"),
+		TargetType: ctx.Query("target_type"),
+		TargetID:   ctx.Query("target_id"),
+	}
+
+	if actorID := ctx.Query("actor_id"); actorID != "" {
+		id, err := strconv.ParseUint(actorID, 10, 64)
+		if err != nil {
+			return filter, service.NewValidationError("invalid actor ID")
+		}
+		filter.ActorID = uint(id)
+	}
+
+	for name, field := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := ctx.Query(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, service.NewValidationError(name + " must be an RFC 3339 time")
+			}
+			*field = parsed
+		}
+	}
+	return filter, nil
+}
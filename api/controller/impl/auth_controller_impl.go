@@ -1,9 +1,12 @@
 package impl
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/userblog/management/api/controller"
 	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/problem"
+	"github.com/userblog/management/api/validation"
 	"github.com/userblog/management/internal/models"
 	"github.com/userblog/management/internal/service"
 	"net/http"
@@ -24,8 +27,8 @@ func NewAuthController(authService service.IAuthService) controller.IAuthControl
 // Register handles the register API endpoint
 func (c *AuthController) Register(ctx *gin.Context) {
 	var req dto.RegisterRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
 		return
 	}
 
@@ -36,12 +39,11 @@ func (c *AuthController) Register(ctx *gin.Context) {
 		Password:  req.Password,
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
-		RoleID:    req.RoleID,
 	}
 
 	// Register the user
-	if err := c.authService.Register(&user); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.authService.Register(ctx.Request.Context(), &user, req.InvitationToken); err != nil {
+		problem.Error(ctx, err)
 		return
 	}
 
@@ -54,15 +56,15 @@ func (c *AuthController) Register(ctx *gin.Context) {
 // Login handles the login API endpoint
 func (c *AuthController) Login(ctx *gin.Context) {
 	var req dto.LoginRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
 		return
 	}
 
 	// Authenticate the user
-	token, err := c.authService.Login(req.Username, req.Password)
+	token, err := c.authService.Login(ctx.Request.Context(), req.Username, req.Password)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		problem.Error(ctx, err)
 		return
 	}
 
@@ -76,13 +78,13 @@ func (c *AuthController) GetMe(ctx *gin.Context) {
 	// Get user from context
 	userInterface, exists := ctx.Get("user")
 	if !exists {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		problem.Error(ctx, service.NewUnauthorizedError("user not found in context"))
 		return
 	}
 
 	user, ok := userInterface.(models.User)
 	if !ok {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cast user from context"})
+		problem.Error(ctx, errors.New("failed to cast user from context"))
 		return
 	}
 
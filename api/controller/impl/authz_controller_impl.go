@@ -0,0 +1,69 @@
+package impl
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/problem"
+	"github.com/userblog/management/api/validation"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/policy"
+)
+
+// AuthzController implements the IAuthzController interface
+type AuthzController struct {
+	authzService service.IAuthzService
+}
+
+// NewAuthzController creates a new access policy controller
+func NewAuthzController(authzService service.IAuthzService) controller.IAuthzController {
+	return &AuthzController{
+		authzService: authzService,
+	}
+}
+
+// Explain handles the explain access decision API endpoint
+func (c *AuthzController) Explain(ctx *gin.Context) {
+	var req dto.ExplainRequest
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	// Get user from context
+	userInterface, exists := ctx.Get("user")
+	if !exists {
+		problem.Error(ctx, service.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	user, ok := userInterface.(models.User)
+	if !ok {
+		problem.Error(ctx, errors.New("failed to cast user from context"))
+		return
+	}
+
+	// Explaining another user's decisions reveals what they can do, so it needs user:read
+	if req.UserID == 0 {
+		req.UserID = user.ID
+	}
+	if req.UserID != user.ID {
+		decision := c.authzService.Decide(ctx.Request.Context(), &user, "user:read", policy.Attributes{"id": req.UserID})
+		if !decision.Allowed {
+			problem.Error(ctx, service.NewForbiddenError(decision.Reason))
+			return
+		}
+	}
+
+	decision, err := c.authzService.Explain(ctx.Request.Context(), req.UserID, req.Action, req.Resource, req.Environment)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, decision)
+}
@@ -1,13 +1,17 @@
 package impl
 
 import (
+	"errors"
 	"github.com/userblog/management/api/dto"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/problem"
+	"github.com/userblog/management/api/validation"
 	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
 	"github.com/userblog/management/internal/service"
 )
 
@@ -26,21 +30,21 @@ func NewBlogController(blogService service.IBlogService) controller.IBlogControl
 // Create handles the create blog API endpoint
 func (c *BlogController) Create(ctx *gin.Context) {
 	var req dto.CreateBlogRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
 		return
 	}
 
 	// Get user from context
 	userInterface, exists := ctx.Get("user")
 	if !exists {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		problem.Error(ctx, service.NewUnauthorizedError("user not found in context"))
 		return
 	}
 
 	user, ok := userInterface.(models.User)
 	if !ok {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cast user from context"})
+		problem.Error(ctx, errors.New("failed to cast user from context"))
 		return
 	}
 
@@ -52,72 +56,85 @@ func (c *BlogController) Create(ctx *gin.Context) {
 	}
 
 	// Create the blog
-	if err := c.blogService.Create(&blog, user.ID); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := c.blogService.Create(ctx.Request.Context(), &blog, user.ID); err != nil {
+		problem.Error(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, blog)
+	ctx.JSON(http.StatusCreated, blogResponse(&blog))
 }
 
 // GetByID handles the get blog by ID API endpoint
 func (c *BlogController) GetByID(ctx *gin.Context) {
 	id, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid blog ID"})
+		problem.Error(ctx, service.NewValidationError("invalid blog ID"))
 		return
 	}
 
+	includes, err := dto.ParseIncludes(ctx, repository.BlogIncludes, []string{"user"})
+	if err != nil {
+		problem.Error(ctx, service.NewValidationError(err.Error()))
+		return
+	}
+	fields := dto.ParseFields(ctx)
+
 	// Get the blog
-	blog, err := c.blogService.GetByID(uint(id))
+	blog, err := c.blogService.GetByID(ctx.Request.Context(), uint(id), includes)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Blog not found"})
+		problem.Error(ctx, err)
 		return
 	}
 
-	// Check if the blog is published or if the user is the owner
+	// Check if the blog is published, or if the user is the owner or may manage every blog
+	visible := blog.Published
 	userInterface, exists := ctx.Get("user")
 	if exists {
 		user, ok := userInterface.(models.User)
-		if ok && (user.ID == blog.UserID || user.Role.Name == "admin") {
-			ctx.JSON(http.StatusOK, blog)
-			return
+		if ok && (user.ID == blog.UserID || user.Role.Grants("blog", models.PermissionWildcard)) {
+			visible = true
 		}
 	}
 
-	// If the blog is not published and the user is not the owner or admin
-	if !blog.Published {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": "Blog is not published"})
+	// If the blog is not published and the user is neither the owner nor a blog manager
+	if !visible {
+		problem.Error(ctx, service.NewForbiddenError("blog is not published"))
+		return
+	}
+
+	data, err := dto.Shape(blogResponse(blog), fields, includes, repository.BlogIncludes)
+	if err != nil {
+		problem.Error(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, blog)
+	ctx.JSON(http.StatusOK, data)
 }
 
 // Update handles the update blog API endpoint
 func (c *BlogController) Update(ctx *gin.Context) {
 	id, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid blog ID"})
+		problem.Error(ctx, service.NewValidationError("invalid blog ID"))
 		return
 	}
 
 	var req dto.UpdateBlogRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
 		return
 	}
 
 	// Get user from context
 	userInterface, exists := ctx.Get("user")
 	if !exists {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		problem.Error(ctx, service.NewUnauthorizedError("user not found in context"))
 		return
 	}
 
 	user, ok := userInterface.(models.User)
 	if !ok {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cast user from context"})
+		problem.Error(ctx, errors.New("failed to cast user from context"))
 		return
 	}
 
@@ -130,38 +147,38 @@ func (c *BlogController) Update(ctx *gin.Context) {
 	blog.ID = uint(id)
 
 	// Update the blog
-	if err := c.blogService.Update(&blog, user.ID); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := c.blogService.Update(ctx.Request.Context(), &blog, user.ID); err != nil {
+		problem.Error(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, blog)
+	ctx.JSON(http.StatusOK, blogResponse(&blog))
 }
 
 // Delete handles the delete blog API endpoint
 func (c *BlogController) Delete(ctx *gin.Context) {
 	id, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid blog ID"})
+		problem.Error(ctx, service.NewValidationError("invalid blog ID"))
 		return
 	}
 
 	// Get user from context
 	userInterface, exists := ctx.Get("user")
 	if !exists {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		problem.Error(ctx, service.NewUnauthorizedError("user not found in context"))
 		return
 	}
 
 	user, ok := userInterface.(models.User)
 	if !ok {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cast user from context"})
+		problem.Error(ctx, errors.New("failed to cast user from context"))
 		return
 	}
 
 	// Delete the blog
-	if err := c.blogService.Delete(uint(id), user.ID); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := c.blogService.Delete(ctx.Request.Context(), uint(id), user.ID); err != nil {
+		problem.Error(ctx, err)
 		return
 	}
 
@@ -189,15 +206,28 @@ func (c *BlogController) List(ctx *gin.Context) {
 		pubOnly = false
 	}
 
+	includes, err := dto.ParseIncludes(ctx, repository.BlogIncludes, []string{"user"})
+	if err != nil {
+		problem.Error(ctx, service.NewValidationError(err.Error()))
+		return
+	}
+	fields := dto.ParseFields(ctx)
+
 	// List blogs
-	blogs, count, err := c.blogService.List(page, perPage, pubOnly)
+	blogs, count, err := c.blogService.List(ctx.Request.Context(), page, perPage, pubOnly, includes)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	data, err := dto.ShapeList(blogResponses(blogs), fields, includes, repository.BlogIncludes)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		problem.Error(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"data":       blogs,
+		"data":       data,
 		"total":      count,
 		"page":       page,
 		"per_page":   perPage,
@@ -213,7 +243,7 @@ func (c *BlogController) ListByUser(ctx *gin.Context) {
 
 	userID, err := strconv.Atoi(userIDStr)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		problem.Error(ctx, service.NewValidationError("invalid user ID"))
 		return
 	}
 
@@ -227,18 +257,54 @@ func (c *BlogController) ListByUser(ctx *gin.Context) {
 		perPage = 10
 	}
 
+	includes, err := dto.ParseIncludes(ctx, repository.BlogIncludes, []string{"user"})
+	if err != nil {
+		problem.Error(ctx, service.NewValidationError(err.Error()))
+		return
+	}
+	fields := dto.ParseFields(ctx)
+
 	// List blogs by user
-	blogs, count, err := c.blogService.ListByUser(uint(userID), page, perPage)
+	blogs, count, err := c.blogService.ListByUser(ctx.Request.Context(), uint(userID), page, perPage, includes)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	data, err := dto.ShapeList(blogResponses(blogs), fields, includes, repository.BlogIncludes)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		problem.Error(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"data":       blogs,
+		"data":       data,
 		"total":      count,
 		"page":       page,
 		"per_page":   perPage,
 		"total_page": (count + perPage - 1) / perPage,
 	})
 }
+
+// blogResponse returns the blog as it is shown, with only the public details of its author
+func blogResponse(blog *models.Blog) dto.BlogResponse {
+	response := dto.BlogResponse{Blog: *blog}
+	if blog.User.ID != 0 {
+		response.User = &dto.BlogAuthor{
+			ID:          blog.User.ID,
+			Username:    blog.User.Username,
+			DisplayName: blog.User.DisplayName,
+			AvatarURL:   blog.User.AvatarURL,
+		}
+	}
+	return response
+}
+
+// blogResponses applies blogResponse to every blog
+func blogResponses(blogs []models.Blog) []dto.BlogResponse {
+	responses := make([]dto.BlogResponse, 0, len(blogs))
+	for i := range blogs {
+		responses = append(responses, blogResponse(&blogs[i]))
+	}
+	return responses
+}
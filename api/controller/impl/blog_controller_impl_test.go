@@ -0,0 +1,114 @@
+package impl
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/internal/service"
+)
+
+func TestBlogResponseHidesAuthorSecrets(t *testing.T) {
+	blog := models.Blog{Title: "Hello", UserID: 7}
+	blog.ID = 3
+	blog.User = models.User{
+		Username: "alice",
+		Email:    "alice@example.com",
+		Password: "$2a$10$hash",
+		Role:     models.Role{Name: "admin"},
+		Profile:  models.Profile{DisplayName: "Alice", Bio: "Writes things", Location: "Lisbon"},
+	}
+	blog.User.ID = 7
+
+	shaped, err := dto.Shape(blogResponse(&blog), nil, []string{"user"}, repository.BlogIncludes)
+	if err != nil {
+		t.Fatalf("Shape: %v", err)
+	}
+	data, err := json.Marshal(shaped)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	for _, secret := range []string{"alice@example.com", "$2a$10$hash", "password", "email", "role", "Lisbon"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("response %s contains %q", data, secret)
+		}
+	}
+	for _, public := range []string{`"username":"alice"`, `"display_name":"Alice"`} {
+		if !strings.Contains(string(data), public) {
+			t.Errorf("response %s is missing %s", data, public)
+		}
+	}
+}
+
+func TestBlogResponseWithoutAuthor(t *testing.T) {
+	blog := models.Blog{Title: "Hello", UserID: 7}
+
+	shaped, err := dto.Shape(blogResponse(&blog), nil, nil, repository.BlogIncludes)
+	if err != nil {
+		t.Fatalf("Shape: %v", err)
+	}
+	if _, ok := shaped.(map[string]interface{})["user"]; ok {
+		t.Errorf("response %v includes a user that wasn't loaded", shaped)
+	}
+}
+
+// draftBlogService returns an unpublished blog written by user 7 for any ID
+type draftBlogService struct {
+	service.IBlogService
+}
+
+func (draftBlogService) GetByID(ctx context.Context, id uint, includes []string) (*models.Blog, error) {
+	blog := &models.Blog{Title: "Draft", UserID: 7}
+	blog.ID = id
+	return blog, nil
+}
+
+func TestGetUnpublishedBlog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	controller := NewBlogController(draftBlogService{})
+
+	user := func(id uint, role models.Role) *models.User {
+		u := &models.User{Username: "someone", Role: role}
+		u.ID = id
+		return u
+	}
+	manageBlogs := models.Permission{Resource: "blog", Action: models.PermissionWildcard}
+	readBlog := models.Permission{Resource: "blog", Action: "read"}
+
+	tests := []struct {
+		name string
+		user *models.User
+		want int
+	}{
+		{name: "anonymous", want: http.StatusForbidden},
+		{name: "author", user: user(7, models.Role{Name: "user"}), want: http.StatusOK},
+		{name: "another user", user: user(8, models.Role{Name: "user", Permissions: []models.Permission{readBlog}}), want: http.StatusForbidden},
+		{name: "blog:* under another name", user: user(8, models.Role{Name: "editor", Permissions: []models.Permission{manageBlogs}}), want: http.StatusOK},
+		// The role's name grants nothing by itself
+		{name: "admin without blog:*", user: user(8, models.Role{Name: "admin", Permissions: []models.Permission{readBlog}}), want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := gin.New()
+			engine.GET("/blogs/:id", func(c *gin.Context) {
+				if tt.user != nil {
+					c.Set("user", *tt.user)
+				}
+			}, controller.GetByID)
+
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/blogs/3", nil))
+			if rec.Code != tt.want {
+				t.Errorf("status %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package impl
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/openapi"
+	"github.com/userblog/management/api/problem"
+)
+
+// DocsController implements the IDocsController interface
+type DocsController struct {
+	document []byte
+}
+
+// NewDocsController creates a new API documentation controller
+func NewDocsController() controller.IDocsController {
+	return &DocsController{}
+}
+
+// SetDocument sets the OpenAPI document served by the controller.
+// The document can only be built once every route is registered, so it is set after routing.
+func (c *DocsController) SetDocument(document *openapi.Document) {
+	data, err := json.Marshal(document)
+	if err != nil {
+		panic(err)
+	}
+	c.document = data
+}
+
+// OpenAPI handles the OpenAPI document API endpoint
+func (c *DocsController) OpenAPI(ctx *gin.Context) {
+	if c.document == nil {
+		problem.Write(ctx, problem.New(http.StatusServiceUnavailable, "API document is not available yet"))
+		return
+	}
+
+	ctx.Data(http.StatusOK, "application/json", c.document)
+}
+
+// Docs handles the interactive API documentation page
+func (c *DocsController) Docs(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", openapi.DocsPage)
+}
@@ -0,0 +1,44 @@
+package impl
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/pkg/buildinfo"
+	"github.com/userblog/management/pkg/health"
+)
+
+// HealthController implements the IHealthController interface
+type HealthController struct {
+	checker *health.Checker
+}
+
+// NewHealthController creates a new health controller backed by the given readiness checker
+func NewHealthController(checker *health.Checker) controller.IHealthController {
+	return &HealthController{
+		checker: checker,
+	}
+}
+
+// Healthz handles the liveness probe; it only reports that the process is serving requests
+func (c *HealthController) Healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// Readyz handles the readiness probe, running every registered check
+func (c *HealthController) Readyz(ctx *gin.Context) {
+	report := c.checker.Run(ctx.Request.Context())
+
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	ctx.JSON(status, report)
+}
+
+// Version handles the build info endpoint
+func (c *HealthController) Version(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, buildinfo.Get())
+}
@@ -0,0 +1,67 @@
+package impl
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/problem"
+	"github.com/userblog/management/api/validation"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/service"
+)
+
+// ImpersonationController implements the IImpersonationController interface
+type ImpersonationController struct {
+	impersonationService service.IImpersonationService
+}
+
+// NewImpersonationController creates a new impersonation controller
+func NewImpersonationController(impersonationService service.IImpersonationService) controller.IImpersonationController {
+	return &ImpersonationController{
+		impersonationService: impersonationService,
+	}
+}
+
+// Impersonate handles the start impersonation API endpoint
+func (c *ImpersonationController) Impersonate(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		problem.Error(ctx, service.NewValidationError("invalid user ID"))
+		return
+	}
+
+	var req dto.ImpersonateRequest
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	// Get user from context
+	userInterface, exists := ctx.Get("user")
+	if !exists {
+		problem.Error(ctx, service.NewUnauthorizedError("user not found in context"))
+		return
+	}
+
+	actor, ok := userInterface.(models.User)
+	if !ok {
+		problem.Error(ctx, errors.New("failed to cast user from context"))
+		return
+	}
+
+	token, session, err := c.impersonationService.Start(ctx.Request.Context(), &actor, uint(userID), req.Reason)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, dto.ImpersonateResponse{
+		Token:     token,
+		UserID:    session.UserID,
+		ExpiresAt: session.ExpiresAt,
+	})
+}
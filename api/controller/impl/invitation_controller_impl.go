@@ -0,0 +1,98 @@
+package impl
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/problem"
+	"github.com/userblog/management/api/validation"
+	"github.com/userblog/management/internal/service"
+)
+
+// InvitationController implements the IInvitationController interface
+type InvitationController struct {
+	invitationService service.IInvitationService
+}
+
+// NewInvitationController creates a new invitation to register controller
+func NewInvitationController(invitationService service.IInvitationService) controller.IInvitationController {
+	return &InvitationController{
+		invitationService: invitationService,
+	}
+}
+
+// List handles the list pending invitations API endpoint
+func (c *InvitationController) List(ctx *gin.Context) {
+	invitations, err := c.invitationService.List(ctx.Request.Context())
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, invitations)
+}
+
+// Invite handles the invite user to register API endpoint
+func (c *InvitationController) Invite(ctx *gin.Context) {
+	var req dto.InviteUserRequest
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	invitation, err := c.invitationService.Invite(ctx.Request.Context(), user, req.Email, req.RoleID)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, invitation)
+}
+
+// Revoke handles the revoke invitation API endpoint
+func (c *InvitationController) Revoke(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("invitation_id"))
+	if err != nil {
+		problem.Error(ctx, service.NewValidationError("invalid invitation ID"))
+		return
+	}
+
+	if err := c.invitationService.Revoke(ctx.Request.Context(), uint(id)); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Invitation revoked successfully"})
+}
+
+// Resend handles the resend invitation API endpoint
+func (c *InvitationController) Resend(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("invitation_id"))
+	if err != nil {
+		problem.Error(ctx, service.NewValidationError("invalid invitation ID"))
+		return
+	}
+
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	invitation, err := c.invitationService.Resend(ctx.Request.Context(), user, uint(id))
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, invitation)
+}
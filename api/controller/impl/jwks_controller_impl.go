@@ -0,0 +1,30 @@
+package impl
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/internal/service"
+)
+
+// jwksMaxAge lets verifiers cache the key set briefly; they should refetch on an unknown kid
+const jwksMaxAge = "public, max-age=300"
+
+// JWKSController implements the IJWKSController interface
+type JWKSController struct {
+	tokenService service.ITokenService
+}
+
+// NewJWKSController creates a new JWKS controller
+func NewJWKSController(tokenService service.ITokenService) controller.IJWKSController {
+	return &JWKSController{
+		tokenService: tokenService,
+	}
+}
+
+// JWKS handles the JSON Web Key Set of the keys that verify our tokens
+func (c *JWKSController) JWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", jwksMaxAge)
+	ctx.JSON(http.StatusOK, c.tokenService.JWKS(ctx.Request.Context()))
+}
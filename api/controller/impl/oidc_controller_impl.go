@@ -0,0 +1,62 @@
+package impl
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/problem"
+	"github.com/userblog/management/internal/service"
+)
+
+// OIDCController implements the IOIDCController interface
+type OIDCController struct {
+	oidcService service.IOIDCService
+}
+
+// NewOIDCController creates a new OpenID Connect login controller
+func NewOIDCController(oidcService service.IOIDCService) controller.IOIDCController {
+	return &OIDCController{
+		oidcService: oidcService,
+	}
+}
+
+// Login handles the start of a login, redirecting to the identity provider
+func (c *OIDCController) Login(ctx *gin.Context) {
+	authURL, err := c.oidcService.AuthorizationURL(ctx.Request.Context(), ctx.Param("provider"))
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// Callback handles the provider's redirect back, exchanging the code for one of our tokens
+func (c *OIDCController) Callback(ctx *gin.Context) {
+	if providerErr := ctx.Query("error"); providerErr != "" {
+		message := "login was cancelled or denied by the identity provider: " + providerErr
+		if description := ctx.Query("error_description"); description != "" {
+			message += " (" + description + ")"
+		}
+		problem.Error(ctx, service.NewUnauthorizedError(message))
+		return
+	}
+
+	code, state := ctx.Query("code"), ctx.Query("state")
+	if code == "" || state == "" {
+		problem.Error(ctx, service.NewValidationError("code and state are required"))
+		return
+	}
+
+	token, err := c.oidcService.Callback(ctx.Request.Context(), ctx.Param("provider"), code, state)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.LoginResponse{
+		Token: token,
+	})
+}
@@ -0,0 +1,306 @@
+package impl
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/problem"
+	"github.com/userblog/management/api/validation"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/service"
+)
+
+// OrganizationController implements the IOrganizationController interface
+type OrganizationController struct {
+	orgService service.IOrganizationService
+}
+
+// NewOrganizationController creates a new organization controller
+func NewOrganizationController(orgService service.IOrganizationService) controller.IOrganizationController {
+	return &OrganizationController{
+		orgService: orgService,
+	}
+}
+
+// ListMine handles the list current user's organizations API endpoint
+func (c *OrganizationController) ListMine(ctx *gin.Context) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	memberships, err := c.orgService.ListMemberships(ctx.Request.Context(), user.ID)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	response := make([]dto.MembershipResponse, 0, len(memberships))
+	for _, membership := range memberships {
+		response = append(response, membershipResponse(&membership))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// Create handles the create organization API endpoint
+func (c *OrganizationController) Create(ctx *gin.Context) {
+	var req dto.CreateOrganizationRequest
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	org := models.Organization{
+		Name:          req.Name,
+		Slug:          req.Slug,
+		Description:   req.Description,
+		DefaultRoleID: req.DefaultRoleID,
+	}
+	if err := c.orgService.Create(ctx.Request.Context(), &org, user.ID); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, organizationResponse(&org))
+}
+
+// AcceptInvitation handles the accept organization invitation API endpoint
+func (c *OrganizationController) AcceptInvitation(ctx *gin.Context) {
+	var req dto.AcceptInvitationRequest
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	membership, err := c.orgService.AcceptInvitation(ctx.Request.Context(), user, req.Token)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, membershipResponse(membership))
+}
+
+// Current handles the get current organization API endpoint
+func (c *OrganizationController) Current(ctx *gin.Context) {
+	org, err := c.orgService.Current(ctx.Request.Context())
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, organizationResponse(org))
+}
+
+// Update handles the update organization settings API endpoint
+func (c *OrganizationController) Update(ctx *gin.Context) {
+	var req dto.UpdateOrganizationRequest
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	org := models.Organization{
+		Name:          req.Name,
+		Description:   req.Description,
+		DefaultRoleID: req.DefaultRoleID,
+	}
+	if err := c.orgService.Update(ctx.Request.Context(), &org); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, organizationResponse(&org))
+}
+
+// ListMembers handles the list organization members API endpoint
+func (c *OrganizationController) ListMembers(ctx *gin.Context) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	perPage, err := strconv.Atoi(ctx.DefaultQuery("per_page", "10"))
+	if err != nil || perPage < 1 || perPage > 100 {
+		perPage = 10
+	}
+
+	memberships, count, err := c.orgService.ListMembers(ctx.Request.Context(), page, perPage)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	members := make([]dto.MemberResponse, 0, len(memberships))
+	for _, membership := range memberships {
+		members = append(members, dto.MemberResponse{
+			UserID:    membership.UserID,
+			Username:  membership.User.Username,
+			Email:     membership.User.Email,
+			FirstName: membership.User.FirstName,
+			LastName:  membership.User.LastName,
+			RoleID:    membership.RoleID,
+			Role:      membership.Role.Name,
+			JoinedAt:  membership.CreatedAt,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":       members,
+		"total":      count,
+		"page":       page,
+		"per_page":   perPage,
+		"total_page": (count + perPage - 1) / perPage,
+	})
+}
+
+// UpdateMember handles the change member role API endpoint
+func (c *OrganizationController) UpdateMember(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		problem.Error(ctx, service.NewValidationError("invalid user ID"))
+		return
+	}
+
+	var req dto.UpdateMemberRequest
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	if err := c.orgService.UpdateMember(ctx.Request.Context(), user, uint(userID), req.RoleID); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Member updated successfully"})
+}
+
+// RemoveMember handles the remove member API endpoint
+func (c *OrganizationController) RemoveMember(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		problem.Error(ctx, service.NewValidationError("invalid user ID"))
+		return
+	}
+
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	if err := c.orgService.RemoveMember(ctx.Request.Context(), user, uint(userID)); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+// ListInvitations handles the list pending invitations API endpoint
+func (c *OrganizationController) ListInvitations(ctx *gin.Context) {
+	invitations, err := c.orgService.ListInvitations(ctx.Request.Context())
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, invitations)
+}
+
+// Invite handles the invite member API endpoint
+func (c *OrganizationController) Invite(ctx *gin.Context) {
+	var req dto.InviteMemberRequest
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	invitation, err := c.orgService.Invite(ctx.Request.Context(), user, req.Email, req.RoleID)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, invitation)
+}
+
+// RevokeInvitation handles the revoke invitation API endpoint
+func (c *OrganizationController) RevokeInvitation(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		problem.Error(ctx, service.NewValidationError("invalid invitation ID"))
+		return
+	}
+
+	if err := c.orgService.RevokeInvitation(ctx.Request.Context(), uint(id)); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Invitation revoked successfully"})
+}
+
+// currentUser returns the authenticated user
+func currentUser(ctx *gin.Context) (*models.User, error) {
+	userInterface, exists := ctx.Get("user")
+	if !exists {
+		return nil, service.NewUnauthorizedError("user not found in context")
+	}
+	user, ok := userInterface.(models.User)
+	if !ok {
+		return nil, errors.New("failed to cast user from context")
+	}
+	return &user, nil
+}
+
+// organizationResponse converts an organization to its API representation
+func organizationResponse(org *models.Organization) dto.OrganizationResponse {
+	return dto.OrganizationResponse{
+		ID:            org.ID,
+		Name:          org.Name,
+		Slug:          org.Slug,
+		Description:   org.Description,
+		DefaultRoleID: org.DefaultRoleID,
+	}
+}
+
+// membershipResponse converts a membership of the current user to its API representation
+func membershipResponse(membership *models.Membership) dto.MembershipResponse {
+	return dto.MembershipResponse{
+		Organization: organizationResponse(&membership.Organization),
+		RoleID:       membership.RoleID,
+		Role:         membership.Role.Name,
+		JoinedAt:     membership.CreatedAt,
+	}
+}
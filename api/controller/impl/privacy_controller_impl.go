@@ -0,0 +1,227 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/problem"
+	"github.com/userblog/management/api/validation"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/internal/service"
+)
+
+// PrivacyController implements the IPrivacyController interface
+type PrivacyController struct {
+	privacyService service.IPrivacyService
+}
+
+// NewPrivacyController creates a new data subject request controller
+func NewPrivacyController(privacyService service.IPrivacyService) controller.IPrivacyController {
+	return &PrivacyController{
+		privacyService: privacyService,
+	}
+}
+
+// RequestExport handles the request export of own data API endpoint
+func (c *PrivacyController) RequestExport(ctx *gin.Context) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	request, err := c.privacyService.RequestExport(ctx.Request.Context(), user.ID)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, request)
+}
+
+// RequestErasure handles the request erasure of own data API endpoint
+func (c *PrivacyController) RequestErasure(ctx *gin.Context) {
+	var req dto.ErasureRequest
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	request, err := c.privacyService.RequestErasure(ctx.Request.Context(), user.ID, req.CurrentPassword, req.Reason)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, request)
+}
+
+// ListOwn handles the list own data requests API endpoint
+func (c *PrivacyController) ListOwn(ctx *gin.Context) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	requests, err := c.privacyService.ListOwn(ctx.Request.Context(), user.ID)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, requests)
+}
+
+// Download handles the download own data export API endpoint
+func (c *PrivacyController) Download(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		problem.Error(ctx, service.NewValidationError("invalid data request ID"))
+		return
+	}
+
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	data, err := c.privacyService.Download(ctx.Request.Context(), user.ID, uint(id))
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="data-export-%d.zip"`, id))
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Data(http.StatusOK, "application/zip", data)
+}
+
+// List handles the list data requests API endpoint
+func (c *PrivacyController) List(ctx *gin.Context) {
+	filter := repository.DataRequestFilter{
+		Kind:   ctx.Query("kind"),
+		Status: ctx.Query("status"),
+	}
+	if userID := ctx.Query("user_id"); userID != "" {
+		id, err := strconv.ParseUint(userID, 10, 64)
+		if err != nil {
+			problem.Error(ctx, service.NewValidationError("user_id must be a user ID"))
+			return
+		}
+		filter.UserID = uint(id)
+	}
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	perPage, err := strconv.Atoi(ctx.DefaultQuery("per_page", "10"))
+	if err != nil || perPage < 1 || perPage > 100 {
+		perPage = 10
+	}
+
+	requests, count, err := c.privacyService.List(ctx.Request.Context(), filter, page, perPage)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"data":       requests,
+		"total":      count,
+		"page":       page,
+		"per_page":   perPage,
+		"total_page": (count + perPage - 1) / perPage,
+	})
+}
+
+// Get handles the get data request API endpoint
+func (c *PrivacyController) Get(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		problem.Error(ctx, service.NewValidationError("invalid data request ID"))
+		return
+	}
+
+	request, err := c.privacyService.Get(ctx.Request.Context(), uint(id))
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, request)
+}
+
+// Approve handles the approve erasure request API endpoint
+func (c *PrivacyController) Approve(ctx *gin.Context) {
+	c.review(ctx, c.privacyService.Approve)
+}
+
+// Reject handles the reject erasure request API endpoint
+func (c *PrivacyController) Reject(ctx *gin.Context) {
+	c.review(ctx, c.privacyService.Reject)
+}
+
+// review decides an erasure request with the reviewer's optional note
+func (c *PrivacyController) review(ctx *gin.Context, decide func(ctx context.Context, reviewer *models.User, id uint, note string) (*models.DataRequest, error)) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		problem.Error(ctx, service.NewValidationError("invalid data request ID"))
+		return
+	}
+
+	// The note is optional, and so is the body
+	var req dto.ReviewDataRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := validation.BindJSON(ctx, &req); err != nil {
+			problem.Error(ctx, err)
+			return
+		}
+	}
+
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	request, err := decide(ctx.Request.Context(), user, uint(id), req.Note)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, request)
+}
+
+// Certificate handles the get erasure certificate API endpoint
+func (c *PrivacyController) Certificate(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		problem.Error(ctx, service.NewValidationError("invalid data request ID"))
+		return
+	}
+
+	certificate, err := c.privacyService.Certificate(ctx.Request.Context(), uint(id))
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, certificate)
+}
@@ -0,0 +1,150 @@
+package impl
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/problem"
+	"github.com/userblog/management/api/validation"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/config"
+)
+
+// multipartOverhead is allowed on top of the avatar size for the rest of an upload request
+const multipartOverhead = 64 << 10
+
+// ProfileController implements the IProfileController interface
+type ProfileController struct {
+	profileService service.IProfileService
+}
+
+// NewProfileController creates a new profile controller
+func NewProfileController(profileService service.IProfileService) controller.IProfileController {
+	return &ProfileController{
+		profileService: profileService,
+	}
+}
+
+// Get handles the get own profile API endpoint
+func (c *ProfileController) Get(ctx *gin.Context) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	profile, err := c.profileService.Get(ctx.Request.Context(), user.ID)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, profile)
+}
+
+// Update handles the update own profile API endpoint
+func (c *ProfileController) Update(ctx *gin.Context) {
+	var req dto.UpdateProfileRequest
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	profile := models.Profile{
+		DisplayName: req.DisplayName,
+		Bio:         req.Bio,
+		Website:     req.Website,
+		Location:    req.Location,
+		SocialLinks: req.SocialLinks,
+	}
+	if err := c.profileService.Update(ctx.Request.Context(), user.ID, &profile); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, profile)
+}
+
+// UploadAvatar handles the upload own avatar API endpoint
+func (c *ProfileController) UploadAvatar(ctx *gin.Context) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	maxBytes := config.Current().Avatars.MaxBytes
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, int64(maxBytes+multipartOverhead))
+
+	file, header, err := ctx.Request.FormFile("avatar")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) || (err == nil && header.Size > int64(maxBytes)) {
+		problem.Error(ctx, service.NewValidationError(fmt.Sprintf("avatar must be at most %d bytes", maxBytes)))
+		return
+	}
+	if err != nil {
+		problem.Error(ctx, service.NewValidationError("avatar must be uploaded as multipart/form-data"))
+		return
+	}
+	defer file.Close()
+
+	profile, err := c.profileService.SetAvatar(ctx.Request.Context(), user.ID, file)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, profile)
+}
+
+// RemoveAvatar handles the remove own avatar API endpoint
+func (c *ProfileController) RemoveAvatar(ctx *gin.Context) {
+	user, err := currentUser(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	if err := c.profileService.RemoveAvatar(ctx.Request.Context(), user.ID); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Avatar removed successfully"})
+}
+
+// Author handles the public author page API endpoint
+func (c *ProfileController) Author(ctx *gin.Context) {
+	author, err := c.profileService.Author(ctx.Request.Context(), ctx.Param("username"))
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, author)
+}
+
+// Avatar handles the avatar image API endpoint. Avatar names change with their content, so
+// they can be cached indefinitely.
+func (c *ProfileController) Avatar(ctx *gin.Context) {
+	data, contentType, err := c.profileService.Avatar(ctx.Request.Context(), ctx.Param("name"))
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "public, max-age=31536000, immutable")
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Data(http.StatusOK, contentType, data)
+}
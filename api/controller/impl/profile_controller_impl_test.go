@@ -0,0 +1,142 @@
+package impl
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/config"
+)
+
+// recordingProfileService keeps the avatars and profiles it is given
+type recordingProfileService struct {
+	service.IProfileService
+	avatars  [][]byte
+	profiles []models.Profile
+}
+
+func (s *recordingProfileService) SetAvatar(ctx context.Context, userID uint, image io.Reader) (*models.Profile, error) {
+	data, err := io.ReadAll(image)
+	if err != nil {
+		return nil, err
+	}
+	s.avatars = append(s.avatars, data)
+	return &models.Profile{AvatarURL: "/api/avatars/1-0123456789abcdef.png"}, nil
+}
+
+func (s *recordingProfileService) Update(ctx context.Context, userID uint, profile *models.Profile) error {
+	s.profiles = append(s.profiles, *profile)
+	return nil
+}
+
+// newProfileTestEngine serves the own profile endpoints to alice, with avatars of at most
+// maxBytes
+func newProfileTestEngine(t *testing.T, maxBytes int) (*gin.Engine, *recordingProfileService) {
+	t.Helper()
+
+	previous := config.Current()
+	cfg := config.Default()
+	cfg.Avatars.MaxBytes = maxBytes
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(previous) })
+
+	gin.SetMode(gin.TestMode)
+	profiles := &recordingProfileService{}
+	controller := NewProfileController(profiles)
+	engine := gin.New()
+	setUser := func(c *gin.Context) {
+		user := models.User{Username: "alice"}
+		user.ID = 2
+		c.Set("user", user)
+	}
+	engine.PUT("/me/avatar", setUser, controller.UploadAvatar)
+	engine.PUT("/me/profile", setUser, controller.Update)
+	return engine, profiles
+}
+
+// upload returns a request uploading data as the avatar part of a multipart form
+func upload(t *testing.T, data []byte) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("avatar", "avatar.png")
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	part.Write(data)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPut, "/me/avatar", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+func TestUploadAvatarEnforcesMaxBytes(t *testing.T) {
+	engine, profiles := newProfileTestEngine(t, 1024)
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{name: "at the limit", req: upload(t, bytes.Repeat([]byte("a"), 1024)), want: http.StatusOK},
+		{name: "over the limit", req: upload(t, bytes.Repeat([]byte("a"), 1025)), want: http.StatusBadRequest},
+		// Bodies far over the limit are cut off while reading rather than buffered
+		{name: "far over the limit", req: upload(t, bytes.Repeat([]byte("a"), 1<<20)), want: http.StatusBadRequest},
+		{name: "not multipart", req: httptest.NewRequest(http.MethodPut, "/me/avatar", strings.NewReader("raw")), want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, tt.req)
+			if rec.Code != tt.want {
+				t.Errorf("status %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+
+	if len(profiles.avatars) != 1 || len(profiles.avatars[0]) != 1024 {
+		t.Errorf("%d avatars reached the service, want only the one at the limit", len(profiles.avatars))
+	}
+}
+
+func TestUpdateProfileValidation(t *testing.T) {
+	engine, profiles := newProfileTestEngine(t, 1024)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "valid", body: `{"display_name":"Alice","website":"https://alice.example.com","social_links":{"github":"https://github.com/alice"}}`, want: http.StatusOK},
+		{name: "empty", body: `{}`, want: http.StatusOK},
+		{name: "website not a URL", body: `{"website":"javascript:alert(1)"}`, want: http.StatusBadRequest},
+		{name: "display name too long", body: `{"display_name":"` + strings.Repeat("a", 101) + `"}`, want: http.StatusBadRequest},
+		{name: "social network not a slug", body: `{"social_links":{"Git Hub":"https://github.com/alice"}}`, want: http.StatusBadRequest},
+		{name: "social link not a URL", body: `{"social_links":{"github":"alice"}}`, want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/me/profile", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			engine.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+
+	if len(profiles.profiles) != 2 || profiles.profiles[0].SocialLinks["github"] != "https://github.com/alice" {
+		t.Errorf("profiles = %+v, want the two valid updates", profiles.profiles)
+	}
+}
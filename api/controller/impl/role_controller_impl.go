@@ -0,0 +1,98 @@
+package impl
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/problem"
+	"github.com/userblog/management/api/validation"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/service"
+)
+
+// RoleController implements the IRoleController interface
+type RoleController struct {
+	roleService service.IRoleService
+}
+
+// NewRoleController creates a new role controller
+func NewRoleController(roleService service.IRoleService) controller.IRoleController {
+	return &RoleController{
+		roleService: roleService,
+	}
+}
+
+// List handles the list roles API endpoint
+func (c *RoleController) List(ctx *gin.Context) {
+	roles, err := c.roleService.List(ctx.Request.Context())
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, roles)
+}
+
+// Create handles the create role API endpoint
+func (c *RoleController) Create(ctx *gin.Context) {
+	var req dto.CreateRoleRequest
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	role := models.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		ParentID:    &req.ParentID,
+	}
+
+	if err := c.roleService.Create(ctx.Request.Context(), &role, req.Permissions); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, role)
+}
+
+// Update handles the update role API endpoint
+func (c *RoleController) Update(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		problem.Error(ctx, service.NewValidationError("invalid role ID"))
+		return
+	}
+
+	var req dto.UpdateRoleRequest
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	role := models.Role{
+		Description: req.Description,
+		ParentID:    &req.ParentID,
+	}
+	role.ID = uint(id)
+
+	if err := c.roleService.Update(ctx.Request.Context(), &role, req.Permissions); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, role)
+}
+
+// ListPermissions handles the list grantable permissions API endpoint
+func (c *RoleController) ListPermissions(ctx *gin.Context) {
+	permissions, err := c.roleService.ListPermissions(ctx.Request.Context())
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, permissions)
+}
@@ -0,0 +1,136 @@
+package impl
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/problem"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/service"
+)
+
+// SessionController implements the ISessionController interface
+type SessionController struct {
+	sessionService service.ISessionService
+}
+
+// NewSessionController creates a new session controller
+func NewSessionController(sessionService service.ISessionService) controller.ISessionController {
+	return &SessionController{
+		sessionService: sessionService,
+	}
+}
+
+// List handles the list sessions API endpoint
+func (c *SessionController) List(ctx *gin.Context) {
+	user, current, err := currentSession(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	sessions, err := c.sessionService.List(ctx.Request.Context(), user.ID)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	response := make([]dto.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, dto.SessionResponse{
+			ID:         session.ID,
+			Device:     session.Device,
+			IP:         session.IP,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == current,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// Revoke handles the terminate session API endpoint
+func (c *SessionController) Revoke(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		problem.Error(ctx, service.NewValidationError("invalid session ID"))
+		return
+	}
+
+	user, _, err := currentSession(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	if err := c.sessionService.Revoke(ctx.Request.Context(), user.ID, uint(id)); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Session terminated successfully"})
+}
+
+// RevokeOthers handles the log out everywhere else API endpoint
+func (c *SessionController) RevokeOthers(ctx *gin.Context) {
+	user, current, err := currentSession(ctx)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	revoked, err := c.sessionService.RevokeOthers(ctx.Request.Context(), user.ID, current)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.RevokeSessionsResponse{
+		Message: "Other sessions terminated successfully",
+		Revoked: revoked,
+	})
+}
+
+// currentSession returns the authenticated user and the ID of the session their token belongs to
+func currentSession(ctx *gin.Context) (*models.User, uint, error) {
+	userInterface, exists := ctx.Get("user")
+	if !exists {
+		return nil, 0, service.NewUnauthorizedError("user not found in context")
+	}
+	user, ok := userInterface.(models.User)
+	if !ok {
+		return nil, 0, errors.New("failed to cast user from context")
+	}
+
+	claimsInterface, exists := ctx.Get("claims")
+	if !exists {
+		return nil, 0, service.NewUnauthorizedError("token claims not found in context")
+	}
+	claims, ok := claimsInterface.(service.TokenClaims)
+	if !ok {
+		return nil, 0, errors.New("failed to cast token claims from context")
+	}
+	sessionID, err := strconv.ParseUint(claims.SessionID, 10, 64)
+	if err != nil {
+		return nil, 0, service.NewUnauthorizedError("invalid token session")
+	}
+
+	return &user, uint(sessionID), nil
+}
+
+// impersonating reports whether the request was made with an impersonation token
+func impersonating(ctx *gin.Context) bool {
+	claimsInterface, exists := ctx.Get("claims")
+	if !exists {
+		return false
+	}
+	claims, ok := claimsInterface.(service.TokenClaims)
+	return ok && claims.Impersonating()
+}
@@ -1,12 +1,17 @@
 package impl
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/userblog/management/api/controller"
 	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/problem"
+	"github.com/userblog/management/api/validation"
 	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
 	"github.com/userblog/management/internal/service"
 	"net/http"
+	"sort"
 	"strconv"
 )
 
@@ -25,8 +30,8 @@ func NewUserController(userService service.IUserService) controller.IUserControl
 // Create handles the create user API endpoint
 func (c *UserController) Create(ctx *gin.Context) {
 	var req dto.CreateUserRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
 		return
 	}
 
@@ -41,8 +46,8 @@ func (c *UserController) Create(ctx *gin.Context) {
 	}
 
 	// Create the user
-	if err := c.userService.Create(&user); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.userService.Create(ctx.Request.Context(), &user); err != nil {
+		problem.Error(ctx, err)
 		return
 	}
 
@@ -56,53 +61,72 @@ func (c *UserController) Create(ctx *gin.Context) {
 func (c *UserController) GetByID(ctx *gin.Context) {
 	id, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		problem.Error(ctx, service.NewValidationError("invalid user ID"))
 		return
 	}
 
+	includes, err := dto.ParseIncludes(ctx, repository.UserIncludes, []string{"role.permissions"})
+	if err != nil {
+		problem.Error(ctx, service.NewValidationError(err.Error()))
+		return
+	}
+	fields := dto.ParseFields(ctx)
+
 	// Get the user
-	user, err := c.userService.GetByID(uint(id))
+	user, err := c.userService.GetByID(ctx.Request.Context(), uint(id), includes)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		problem.Error(ctx, err)
 		return
 	}
 
 	// Remove sensitive fields
 	user.Password = ""
 
-	ctx.JSON(http.StatusOK, user)
+	data, err := dto.Shape(user, fields, includes, repository.UserIncludes)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, data)
 }
 
 // Update handles the update user API endpoint
 func (c *UserController) Update(ctx *gin.Context) {
 	id, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		problem.Error(ctx, service.NewValidationError("invalid user ID"))
 		return
 	}
 
 	var req dto.UpdateUserRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := validation.BindJSON(ctx, &req); err != nil {
+		problem.Error(ctx, err)
 		return
 	}
 
 	// Get user from context to check permissions
 	userInterface, exists := ctx.Get("user")
 	if !exists {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		problem.Error(ctx, service.NewUnauthorizedError("user not found in context"))
 		return
 	}
 
 	currentUser, ok := userInterface.(models.User)
 	if !ok {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cast user from context"})
+		problem.Error(ctx, errors.New("failed to cast user from context"))
 		return
 	}
 
 	// Only admin can update other users
 	if currentUser.Role.Name != "admin" && currentUser.ID != uint(id) {
-		ctx.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own user information"})
+		problem.Error(ctx, service.NewForbiddenError("you can only update your own user information"))
+		return
+	}
+
+	// Support staff acting as a user must not take over the account
+	if req.Password != "" && impersonating(ctx) {
+		problem.Error(ctx, service.NewForbiddenError("passwords cannot be changed while impersonating a user"))
 		return
 	}
 
@@ -122,8 +146,8 @@ func (c *UserController) Update(ctx *gin.Context) {
 	}
 
 	// Update the user
-	if err := c.userService.Update(&user); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := c.userService.Update(ctx.Request.Context(), &user); err != nil {
+		problem.Error(ctx, err)
 		return
 	}
 
@@ -137,13 +161,13 @@ func (c *UserController) Update(ctx *gin.Context) {
 func (c *UserController) Delete(ctx *gin.Context) {
 	id, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		problem.Error(ctx, service.NewValidationError("invalid user ID"))
 		return
 	}
 
 	// Delete the user
-	if err := c.userService.Delete(uint(id)); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := c.userService.Delete(ctx.Request.Context(), uint(id)); err != nil {
+		problem.Error(ctx, err)
 		return
 	}
 
@@ -165,10 +189,17 @@ func (c *UserController) List(ctx *gin.Context) {
 		perPage = 10
 	}
 
+	includes, err := dto.ParseIncludes(ctx, repository.UserIncludes, []string{"role"})
+	if err != nil {
+		problem.Error(ctx, service.NewValidationError(err.Error()))
+		return
+	}
+	fields := dto.ParseFields(ctx)
+
 	// List users
-	users, count, err := c.userService.List(page, perPage)
+	users, count, err := c.userService.List(ctx.Request.Context(), page, perPage, includes)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		problem.Error(ctx, err)
 		return
 	}
 
@@ -177,11 +208,60 @@ func (c *UserController) List(ctx *gin.Context) {
 		users[i].Password = ""
 	}
 
+	data, err := dto.ShapeList(users, fields, includes, repository.UserIncludes)
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{
-		"data":       users,
+		"data":       data,
 		"total":      count,
 		"page":       page,
 		"per_page":   perPage,
 		"total_page": (count + perPage - 1) / perPage,
 	})
 }
+
+// Unlock handles the unlock user API endpoint, clearing a login lockout
+func (c *UserController) Unlock(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		problem.Error(ctx, service.NewValidationError("invalid user ID"))
+		return
+	}
+
+	if err := c.userService.Unlock(ctx.Request.Context(), uint(id)); err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "User unlocked successfully"})
+}
+
+// Permissions handles the effective user permissions API endpoint
+func (c *UserController) Permissions(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		problem.Error(ctx, service.NewValidationError("invalid user ID"))
+		return
+	}
+
+	effective, err := c.userService.Permissions(ctx.Request.Context(), uint(id))
+	if err != nil {
+		problem.Error(ctx, err)
+		return
+	}
+
+	permissions := make([]string, 0, len(effective.Permissions))
+	for _, permission := range effective.Permissions {
+		permissions = append(permissions, permission.Key())
+	}
+	sort.Strings(permissions)
+
+	ctx.JSON(http.StatusOK, dto.EffectivePermissionsResponse{
+		UserID:      uint(id),
+		Roles:       effective.Roles,
+		Permissions: permissions,
+	})
+}
@@ -0,0 +1,11 @@
+package controller
+
+import "github.com/gin-gonic/gin"
+
+// IInvitationController defines the interface for invitation to register controller
+type IInvitationController interface {
+	List(ctx *gin.Context)
+	Invite(ctx *gin.Context)
+	Revoke(ctx *gin.Context)
+	Resend(ctx *gin.Context)
+}
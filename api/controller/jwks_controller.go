@@ -0,0 +1,8 @@
+package controller
+
+import "github.com/gin-gonic/gin"
+
+// IJWKSController defines the interface for the public signing keys controller
+type IJWKSController interface {
+	JWKS(ctx *gin.Context)
+}
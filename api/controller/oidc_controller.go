@@ -0,0 +1,9 @@
+package controller
+
+import "github.com/gin-gonic/gin"
+
+// IOIDCController defines the interface for OpenID Connect login controller
+type IOIDCController interface {
+	Login(ctx *gin.Context)
+	Callback(ctx *gin.Context)
+}
@@ -0,0 +1,18 @@
+package controller
+
+import "github.com/gin-gonic/gin"
+
+// IOrganizationController defines the interface for organization controller
+type IOrganizationController interface {
+	ListMine(ctx *gin.Context)
+	Create(ctx *gin.Context)
+	AcceptInvitation(ctx *gin.Context)
+	Current(ctx *gin.Context)
+	Update(ctx *gin.Context)
+	ListMembers(ctx *gin.Context)
+	UpdateMember(ctx *gin.Context)
+	RemoveMember(ctx *gin.Context)
+	ListInvitations(ctx *gin.Context)
+	Invite(ctx *gin.Context)
+	RevokeInvitation(ctx *gin.Context)
+}
@@ -0,0 +1,16 @@
+package controller
+
+import "github.com/gin-gonic/gin"
+
+// IPrivacyController defines the interface for data subject request controller
+type IPrivacyController interface {
+	RequestExport(ctx *gin.Context)
+	RequestErasure(ctx *gin.Context)
+	ListOwn(ctx *gin.Context)
+	Download(ctx *gin.Context)
+	List(ctx *gin.Context)
+	Get(ctx *gin.Context)
+	Approve(ctx *gin.Context)
+	Reject(ctx *gin.Context)
+	Certificate(ctx *gin.Context)
+}
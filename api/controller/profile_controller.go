@@ -0,0 +1,13 @@
+package controller
+
+import "github.com/gin-gonic/gin"
+
+// IProfileController defines the interface for profile controller
+type IProfileController interface {
+	Get(ctx *gin.Context)
+	Update(ctx *gin.Context)
+	UploadAvatar(ctx *gin.Context)
+	RemoveAvatar(ctx *gin.Context)
+	Author(ctx *gin.Context)
+	Avatar(ctx *gin.Context)
+}
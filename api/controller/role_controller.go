@@ -0,0 +1,11 @@
+package controller
+
+import "github.com/gin-gonic/gin"
+
+// IRoleController defines the interface for the role controller
+type IRoleController interface {
+	List(ctx *gin.Context)
+	Create(ctx *gin.Context)
+	Update(ctx *gin.Context)
+	ListPermissions(ctx *gin.Context)
+}
@@ -0,0 +1,10 @@
+package controller
+
+import "github.com/gin-gonic/gin"
+
+// ISessionController defines the interface for the current user's session controller
+type ISessionController interface {
+	List(ctx *gin.Context)
+	Revoke(ctx *gin.Context)
+	RevokeOthers(ctx *gin.Context)
+}
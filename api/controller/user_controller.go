@@ -9,4 +9,6 @@ type IUserController interface {
 	Update(ctx *gin.Context)
 	Delete(ctx *gin.Context)
 	List(ctx *gin.Context)
+	Unlock(ctx *gin.Context)
+	Permissions(ctx *gin.Context)
 }
@@ -0,0 +1,120 @@
+package dto
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ParseIncludes reads the include query parameter and validates it against the allowed relations.
+// When the parameter is absent the defaults are returned; an empty value means no relations.
+func ParseIncludes(ctx *gin.Context, allowed map[string]string, defaults []string) ([]string, error) {
+	raw, exists := ctx.GetQuery("include")
+	if !exists {
+		return defaults, nil
+	}
+
+	includes := splitList(raw)
+	for _, include := range includes {
+		if _, ok := allowed[include]; !ok {
+			return nil, fmt.Errorf("invalid include: %s", include)
+		}
+	}
+
+	return includes, nil
+}
+
+// ParseFields reads the fields query parameter, returning nil when every field is wanted
+func ParseFields(ctx *gin.Context) []string {
+	return splitList(ctx.Query("fields"))
+}
+
+// Shape serializes v and keeps only the requested fields and included relations.
+// The ID field and included relations are always kept, and relations that were
+// not included are removed so they don't show up as empty objects.
+func Shape(v interface{}, fields, includes []string, allowed map[string]string) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var shaped map[string]interface{}
+	if err := json.Unmarshal(data, &shaped); err != nil {
+		return nil, err
+	}
+
+	// Drop relations that were not asked for
+	for relation := range allowed {
+		if !isIncluded(relation, includes) {
+			deletePath(shaped, strings.Split(relation, "."))
+		}
+	}
+
+	if len(fields) == 0 {
+		return shaped, nil
+	}
+
+	keep := map[string]bool{"ID": true}
+	for _, field := range fields {
+		keep[field] = true
+	}
+	for _, include := range includes {
+		keep[strings.SplitN(include, ".", 2)[0]] = true
+	}
+
+	for key := range shaped {
+		if !keep[key] {
+			delete(shaped, key)
+		}
+	}
+
+	return shaped, nil
+}
+
+// ShapeList applies Shape to every element of a slice
+func ShapeList[T any](items []T, fields, includes []string, allowed map[string]string) ([]interface{}, error) {
+	result := make([]interface{}, 0, len(items))
+	for i := range items {
+		shaped, err := Shape(items[i], fields, includes, allowed)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, shaped)
+	}
+	return result, nil
+}
+
+// isIncluded reports whether relation was requested directly or as part of a nested include
+func isIncluded(relation string, includes []string) bool {
+	for _, include := range includes {
+		if include == relation || strings.HasPrefix(include, relation+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// deletePath removes the value at the given key path from a nested map
+func deletePath(m map[string]interface{}, path []string) {
+	if len(path) == 1 {
+		delete(m, path[0])
+		return
+	}
+
+	if child, ok := m[path[0]].(map[string]interface{}); ok {
+		deletePath(child, path[1:])
+	}
+}
+
+// splitList splits a comma separated query value, ignoring blanks
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
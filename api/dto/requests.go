@@ -1,20 +1,28 @@
 package dto
 
+import (
+	"time"
+
+	"github.com/userblog/management/internal/models"
+)
+
 // CreateUserRequest represents the create user request
 type CreateUserRequest struct {
-	Username  string `json:"username" binding:"required,min=3,max=30"`
+	Username  string `json:"username" binding:"required,min=3,max=30,username,notreserved"`
 	Email     string `json:"email" binding:"required,email"`
-	Password  string `json:"password" binding:"required,min=6"`
+	Password  string `json:"password" binding:"required,password"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
 	RoleID    uint   `json:"role_id"`
 }
 
-// UpdateUserRequest represents the update user request
+// UpdateUserRequest represents the update user request. Username has no notreserved tag: the tag
+// can't see the current username, so it would stop accounts such as the seeded admin from being
+// updated at all. UserService.Update refuses reserved usernames when the username changes.
 type UpdateUserRequest struct {
-	Username  string `json:"username" binding:"required,min=3,max=30"`
+	Username  string `json:"username" binding:"required,min=3,max=30,username"`
 	Email     string `json:"email" binding:"required,email"`
-	Password  string `json:"password"`
+	Password  string `json:"password" binding:"omitempty,password"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
 	RoleID    uint   `json:"role_id"`
@@ -22,12 +30,13 @@ type UpdateUserRequest struct {
 
 // RegisterRequest represents the register request
 type RegisterRequest struct {
-	Username  string `json:"username" binding:"required,min=3,max=30"`
+	Username  string `json:"username" binding:"required,min=3,max=30,username,notreserved"`
 	Email     string `json:"email" binding:"required,email"`
-	Password  string `json:"password" binding:"required,min=6"`
+	Password  string `json:"password" binding:"required,password"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
-	RoleID    uint   `json:"role_id"`
+	// InvitationToken is required when registration is invite-only
+	InvitationToken string `json:"invitation_token" binding:"max=128"`
 }
 
 // LoginRequest represents the login request
@@ -60,3 +69,210 @@ type UpdateBlogRequest struct {
 	Content   string `json:"content" binding:"required"`
 	Published bool   `json:"published"`
 }
+
+// BlogResponse represents a blog. Its author is described by BlogAuthor, never by the stored user,
+// which holds their email and password hash.
+type BlogResponse struct {
+	models.Blog
+	User *BlogAuthor `json:"user,omitempty"`
+}
+
+// BlogAuthor represents the public details of a blog's author, as shown on their author page
+type BlogAuthor struct {
+	ID          uint   `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+}
+
+// SessionResponse represents a login session of the current user
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	Device     string    `json:"device"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// RevokeSessionsResponse represents the response of logging out other sessions
+type RevokeSessionsResponse struct {
+	Message string `json:"message"`
+	Revoked int64  `json:"revoked"`
+}
+
+// ImpersonateRequest represents the start impersonation request
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"required,max=512"`
+}
+
+// ExplainRequest represents an access decision to explain. UserID defaults to the caller, and a
+// resource with an id is filled in from the stored resource.
+type ExplainRequest struct {
+	Action      string                 `json:"action" binding:"required,max=128"`
+	UserID      uint                   `json:"user_id"`
+	Resource    map[string]interface{} `json:"resource"`
+	Environment map[string]interface{} `json:"environment"`
+}
+
+// ImpersonateResponse represents the token for acting as another user
+type ImpersonateResponse struct {
+	Token     string    `json:"token"`
+	UserID    uint      `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateRoleRequest represents the create role request. Permissions are resource:action pairs
+// such as blog:read, blog:* or *:*.
+type CreateRoleRequest struct {
+	Name        string   `json:"name" binding:"required,max=255"`
+	Description string   `json:"description" binding:"max=255"`
+	ParentID    uint     `json:"parent_id"`
+	Permissions []string `json:"permissions"`
+}
+
+// UpdateRoleRequest represents the update role request, which replaces the role's description,
+// parent and permissions. A parent_id of 0 removes the parent.
+type UpdateRoleRequest struct {
+	Description string   `json:"description" binding:"max=255"`
+	ParentID    uint     `json:"parent_id"`
+	Permissions []string `json:"permissions"`
+}
+
+// EffectivePermissionsResponse represents a user's permissions after role inheritance
+type EffectivePermissionsResponse struct {
+	UserID      uint     `json:"user_id"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+// CreateOrganizationRequest represents the create organization request
+type CreateOrganizationRequest struct {
+	Name          string `json:"name" binding:"required,max=255"`
+	Slug          string `json:"slug" binding:"required,min=2,max=63,slug"`
+	Description   string `json:"description" binding:"max=1024"`
+	DefaultRoleID uint   `json:"default_role_id"`
+}
+
+// UpdateOrganizationRequest represents the update organization settings request
+type UpdateOrganizationRequest struct {
+	Name          string `json:"name" binding:"required,max=255"`
+	Description   string `json:"description" binding:"max=1024"`
+	DefaultRoleID uint   `json:"default_role_id"`
+}
+
+// UpdateMemberRequest represents the change member role request
+type UpdateMemberRequest struct {
+	RoleID uint `json:"role_id" binding:"required"`
+}
+
+// InviteUserRequest represents the invite user to register request
+type InviteUserRequest struct {
+	Email  string `json:"email" binding:"required,email,max=255"`
+	RoleID uint   `json:"role_id"`
+}
+
+// InviteMemberRequest represents the invite member request; the organization's default role is
+// used when no role is given
+type InviteMemberRequest struct {
+	Email  string `json:"email" binding:"required,email,max=255"`
+	RoleID uint   `json:"role_id"`
+}
+
+// AcceptInvitationRequest represents the accept invitation request
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required,max=128"`
+}
+
+// UpdateProfileRequest represents the update own profile request; it replaces every field
+type UpdateProfileRequest struct {
+	DisplayName string            `json:"display_name" binding:"max=100"`
+	Bio         string            `json:"bio" binding:"max=2000"`
+	Website     string            `json:"website" binding:"omitempty,http_url,max=255"`
+	Location    string            `json:"location" binding:"max=100"`
+	SocialLinks map[string]string `json:"social_links" binding:"max=10,dive,keys,slug,max=30,endkeys,http_url,max=255"`
+}
+
+// AvatarUploadRequest documents the multipart avatar upload, whose avatar part holds a JPEG,
+// PNG or GIF image
+type AvatarUploadRequest struct {
+	Avatar []byte `json:"avatar" binding:"required"`
+}
+
+// UpdateAccountRequest represents the update own name request
+type UpdateAccountRequest struct {
+	FirstName string `json:"first_name" binding:"max=255"`
+	LastName  string `json:"last_name" binding:"max=255"`
+}
+
+// ChangePasswordRequest represents the change own password request
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,password"`
+}
+
+// ChangeEmailRequest represents the change own email request; the new address must be verified
+// with the token sent to it
+type ChangeEmailRequest struct {
+	Email           string `json:"email" binding:"required,email,max=255"`
+	CurrentPassword string `json:"current_password" binding:"required"`
+}
+
+// VerifyEmailRequest represents the verify new email request
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required,max=128"`
+}
+
+// DeleteAccountRequest represents the delete own account request
+type DeleteAccountRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+}
+
+// PendingChangeResponse represents a change that takes effect later, such as an email change
+// waiting for verification or a scheduled deletion
+type PendingChangeResponse struct {
+	Message string    `json:"message"`
+	At      time.Time `json:"at"`
+}
+
+// ErasureRequest represents the request erasure of own data request
+type ErasureRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	Reason          string `json:"reason" binding:"max=1000"`
+}
+
+// ReviewDataRequest represents the approve or reject erasure request, with an optional note
+type ReviewDataRequest struct {
+	Note string `json:"note" binding:"max=1000"`
+}
+
+// MembershipResponse represents an organization the current user is a member of
+type MembershipResponse struct {
+	Organization OrganizationResponse `json:"organization"`
+	RoleID       uint                 `json:"role_id"`
+	Role         string               `json:"role"`
+	JoinedAt     time.Time            `json:"joined_at"`
+}
+
+// OrganizationResponse represents an organization and its settings
+type OrganizationResponse struct {
+	ID            uint   `json:"id"`
+	Name          string `json:"name"`
+	Slug          string `json:"slug"`
+	Description   string `json:"description"`
+	DefaultRoleID uint   `json:"default_role_id"`
+}
+
+// MemberResponse represents a member of an organization
+type MemberResponse struct {
+	UserID    uint      `json:"user_id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	FirstName string    `json:"first_name,omitempty"`
+	LastName  string    `json:"last_name,omitempty"`
+	RoleID    uint      `json:"role_id"`
+	Role      string    `json:"role"`
+	JoinedAt  time.Time `json:"joined_at"`
+}
@@ -1,28 +1,33 @@
 package middleware
 
 import (
-	"fmt"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/problem"
 	"github.com/userblog/management/internal/models"
 	"github.com/userblog/management/internal/service"
-	"net/http"
+	"github.com/userblog/management/pkg/logger"
+	"github.com/userblog/management/pkg/tenant"
 )
 
 // IAuthMiddleware defines the interface for authentication middleware
 type IAuthMiddleware interface {
 	JWTAuth() gin.HandlerFunc
-	RequirePermission(resource, action string) gin.HandlerFunc
+	DenyImpersonation() gin.HandlerFunc
 }
 
 // AuthMiddleware implements the IAuthMiddleware interface
 type AuthMiddleware struct {
-	authService service.IAuthService
+	authService          service.IAuthService
+	impersonationService service.IImpersonationService
 }
 
 // NewAuthMiddleware creates a new auth middleware
-func NewAuthMiddleware(authService service.IAuthService) IAuthMiddleware {
+func NewAuthMiddleware(authService service.IAuthService, impersonationService service.IImpersonationService) IAuthMiddleware {
 	return &AuthMiddleware{
-		authService: authService,
+		authService:          authService,
+		impersonationService: impersonationService,
 	}
 }
 
@@ -35,48 +40,64 @@ func (m *AuthMiddleware) JWTAuth() gin.HandlerFunc {
 		// Extract token from header
 		tokenString, err := m.authService.ExtractTokenFromHeader(authHeader)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-			c.Abort()
+			problem.Error(c, err)
 			return
 		}
 
 		// Validate token and get user
-		user, err := m.authService.ValidateToken(tokenString)
+		user, claims, err := m.authService.ValidateToken(c.Request.Context(), tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-			c.Abort()
+			problem.Error(c, err)
+			return
+		}
+
+		// Only members act in an organization
+		ctx := tenant.WithTokenOrganization(c.Request.Context(), claims.Organization)
+		if err := checkMembership(ctx, user); err != nil {
+			problem.Error(c, err)
 			return
 		}
 
-		// Set user in the context
+		// Set user and token claims in the context, the actor for attributing changes, and the
+		// organization the token is for unless the request named one
 		c.Set("user", *user)
+		c.Set("claims", *claims)
+		actor := service.Actor{UserID: user.ID, Username: user.Username}
+		if claims.Impersonating() {
+			actorID, _ := strconv.ParseUint(claims.Actor.Subject, 10, 64)
+			actor.ImpersonatorID = uint(actorID)
+		}
+		c.Request = c.Request.WithContext(service.WithActor(ctx, actor))
 		c.Next()
+
+		// Every request made while impersonating is audited, whatever its outcome
+		if claims.Impersonating() {
+			sessionID, _ := strconv.ParseUint(claims.SessionID, 10, 64)
+			err := m.impersonationService.Record(c.Request.Context(), &models.ImpersonationEvent{
+				ActorID:   actor.ImpersonatorID,
+				UserID:    user.ID,
+				SessionID: uint(sessionID),
+				Action:    models.ImpersonationRequest,
+				Method:    c.Request.Method,
+				Path:      c.Request.URL.Path,
+				Status:    c.Writer.Status(),
+			})
+			if err != nil {
+				logger.ErrorF(c.Request.Context(), "Failed to record impersonated request: %v", err)
+			}
+		}
 	}
 }
 
-// RequirePermission middleware to check if the user has the required permission
-func (m *AuthMiddleware) RequirePermission(resource, action string) gin.HandlerFunc {
+// DenyImpersonation middleware rejects sensitive requests, such as managing credentials or
+// sessions, made with an impersonation token
+func (m *AuthMiddleware) DenyImpersonation() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get user from context
-		userInterface, exists := c.Get("user")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
-			c.Abort()
-			return
-		}
-
-		user, ok := userInterface.(models.User)
-		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cast user from context"})
-			c.Abort()
-			return
-		}
-
-		// Check if user has the required permission
-		if !m.authService.ValidatePermission(&user, resource, action) {
-			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Permission denied: %s:%s", resource, action)})
-			c.Abort()
-			return
+		if claims, ok := c.Get("claims"); ok {
+			if tokenClaims, ok := claims.(service.TokenClaims); ok && tokenClaims.Impersonating() {
+				problem.Error(c, service.NewForbiddenError("not allowed while impersonating a user"))
+				return
+			}
 		}
 
 		c.Next()
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/service"
+)
+
+// fakeAuthService accepts "normal" and "impersonation" as tokens for alice, user 2, the latter
+// issued to user 1 acting as her
+type fakeAuthService struct {
+	service.IAuthService
+}
+
+func (fakeAuthService) ExtractTokenFromHeader(header string) (string, error) {
+	return strings.TrimPrefix(header, "Bearer "), nil
+}
+
+func (fakeAuthService) ValidateToken(ctx context.Context, token string) (*models.User, *service.TokenClaims, error) {
+	user := &models.User{Username: "alice"}
+	user.ID = 2
+	claims := &service.TokenClaims{SessionID: "5"}
+	switch token {
+	case "normal":
+	case "impersonation":
+		claims.Actor = &service.TokenActor{Subject: "1"}
+	default:
+		return nil, nil, service.NewUnauthorizedError("invalid token")
+	}
+	return user, claims, nil
+}
+
+// recordingImpersonationService keeps the events it is asked to record
+type recordingImpersonationService struct {
+	service.IImpersonationService
+	events []models.ImpersonationEvent
+}
+
+func (s *recordingImpersonationService) Record(ctx context.Context, event *models.ImpersonationEvent) error {
+	s.events = append(s.events, *event)
+	return nil
+}
+
+// newImpersonationTestAuth returns auth middleware accepting the tokens of fakeAuthService, and
+// the impersonation events it records
+func newImpersonationTestAuth() (IAuthMiddleware, *recordingImpersonationService) {
+	impersonation := &recordingImpersonationService{}
+	return NewAuthMiddleware(fakeAuthService{}, impersonation), impersonation
+}
+
+func TestJWTAuthRecordsImpersonatedRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth, impersonation := newImpersonationTestAuth()
+
+	var actor service.Actor
+	engine := gin.New()
+	engine.GET("/blogs/:id", auth.JWTAuth(), func(c *gin.Context) {
+		actor, _ = service.ActorFromContext(c.Request.Context())
+		c.Status(http.StatusNoContent)
+	})
+
+	for _, token := range []string{"normal", "impersonation", "impersonation"} {
+		req := httptest.NewRequest(http.MethodGet, "/blogs/7", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if actor.UserID != 2 || actor.ImpersonatorID != 1 {
+		t.Errorf("actor = %+v, want alice impersonated by user 1", actor)
+	}
+	if len(impersonation.events) != 2 {
+		t.Fatalf("recorded %d events, want one per impersonated request", len(impersonation.events))
+	}
+	event := impersonation.events[0]
+	if event.ActorID != 1 || event.UserID != 2 || event.SessionID != 5 || event.Action != models.ImpersonationRequest ||
+		event.Method != http.MethodGet || event.Path != "/blogs/7" || event.Status != http.StatusNoContent {
+		t.Errorf("event = %+v", event)
+	}
+}
+
+func TestDenyImpersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth, impersonation := newImpersonationTestAuth()
+
+	engine := gin.New()
+	engine.PUT("/me/password", auth.JWTAuth(), auth.DenyImpersonation(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		token string
+		want  int
+	}{
+		{token: "normal", want: http.StatusNoContent},
+		{token: "impersonation", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPut, "/me/password", nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s token: status %d, want %d", tt.token, rec.Code, tt.want)
+		}
+	}
+
+	// The refused request is still recorded
+	if len(impersonation.events) != 1 || impersonation.events[0].Status != http.StatusForbidden {
+		t.Errorf("events = %+v, want the refused request recorded", impersonation.events)
+	}
+}
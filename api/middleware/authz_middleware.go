@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/problem"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/policy"
+)
+
+// IAuthzMiddleware defines the interface for access policy middleware
+type IAuthzMiddleware interface {
+	Authorize(resource, action string) gin.HandlerFunc
+}
+
+// AuthzMiddleware implements the IAuthzMiddleware interface
+type AuthzMiddleware struct {
+	authzService service.IAuthzService
+}
+
+// NewAuthzMiddleware creates a new access policy middleware
+func NewAuthzMiddleware(authzService service.IAuthzService) IAuthzMiddleware {
+	return &AuthzMiddleware{
+		authzService: authzService,
+	}
+}
+
+// Authorize middleware checks the access policy for the action on the route's resource. When
+// the route has an :id parameter the stored resource is loaded, so rules on its attributes such
+// as user_id or published apply here as well as in the service. Place it after JWTAuth.
+func (m *AuthzMiddleware) Authorize(resource, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get user from context
+		userInterface, exists := c.Get("user")
+		if !exists {
+			problem.Error(c, service.NewUnauthorizedError("user not found in context"))
+			return
+		}
+
+		user, ok := userInterface.(models.User)
+		if !ok {
+			problem.Error(c, errors.New("failed to cast user from context"))
+			return
+		}
+
+		attributes := policy.Attributes{"type": resource}
+		if id, err := strconv.ParseUint(c.Param("id"), 10, 64); err == nil {
+			attributes["id"] = uint(id)
+		}
+
+		attributes, err := m.authzService.LoadResource(c.Request.Context(), resource, attributes)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+
+		decision := m.authzService.Decide(c.Request.Context(), &user, resource+":"+action, attributes)
+		if !decision.Allowed {
+			problem.Error(c, service.NewForbiddenError(decision.Reason))
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -4,27 +4,59 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/userblog/management/pkg/logger"
+	"github.com/userblog/management/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// Logger middleware adds request logging and trace ID to context
+// Logger middleware starts the request span, continuing any incoming W3C traceparent,
+// adds the trace ID, client IP and user agent to the request context and logs the response
 func Logger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		start := time.Now()
-		ctx := c.Request.Context()
 		clientIP := c.ClientIP()
-		traceID := uuid.New().String()
 		userAgent := c.Request.UserAgent()
 
-		c.Request = c.Request.WithContext(ctx)
-		c.Header("X-Trace-ID", traceID)
-
 		method := c.Request.Method
 		path := c.Request.URL.Path
 		raw := c.Request.URL.RawQuery
 
+		// Name the span after the route template so it groups well, falling back to the raw path
+		route := c.FullPath()
+		if route == "" {
+			route = path
+		}
+
+		// Continue the caller's trace when a traceparent header is present
+		propagator := otel.GetTextMapPropagator()
+		ctx := propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := tracing.Tracer().Start(ctx, method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(path),
+				semconv.ClientAddress(clientIP),
+				semconv.UserAgentOriginal(userAgent),
+			),
+		)
+		defer span.End()
+
+		traceID := tracing.TraceID(ctx)
+		ctx = logger.AddToContext(ctx, logger.TraceIDKey, traceID)
+		ctx = logger.AddToContext(ctx, logger.ClientIpKey, clientIP)
+		ctx = logger.AddToContext(ctx, logger.UserAgentKey, userAgent)
+		c.Request = c.Request.WithContext(ctx)
+
+		// Emit traceparent so callers can correlate with our spans
+		propagator.Inject(ctx, propagation.HeaderCarrier(c.Writer.Header()))
+		c.Header("X-Trace-ID", traceID)
+
 		// Add query string if present
 		fullPath := path
 		if raw != "" {
@@ -41,6 +73,11 @@ func Logger() gin.HandlerFunc {
 		statusCode := c.Writer.Status()
 		responseSize := c.Writer.Size()
 
+		span.SetAttributes(semconv.HTTPResponseStatusCode(statusCode))
+		if statusCode >= 500 {
+			span.SetStatus(codes.Error, "")
+		}
+
 		logger.InfoF(ctx, "API RESPONSE "+
 			"Method: %s | "+
 			"Path: %s | "+
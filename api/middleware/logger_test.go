@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider keeping every ended span until the test ends
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func TestLoggerStartsOneSpanPerRequest(t *testing.T) {
+	recorder := recordSpans(t)
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(Logger())
+	engine.GET("/api/blogs/:id", func(c *gin.Context) {
+		_, span := tracing.Start(c.Request.Context(), "BlogService.GetByID")
+		span.End()
+		c.Status(http.StatusOK)
+	})
+
+	traceIDs := map[string]bool{}
+	for _, path := range []string{"/api/blogs/1", "/api/blogs/2"} {
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		traceIDs[rec.Header().Get("X-Trace-ID")] = true
+	}
+	if len(traceIDs) != 2 {
+		t.Errorf("trace IDs %v, want a new one per request", traceIDs)
+	}
+
+	var servers []sdktrace.ReadOnlySpan
+	children := 0
+	for _, span := range recorder.Ended() {
+		switch span.SpanKind() {
+		case trace.SpanKindServer:
+			servers = append(servers, span)
+		default:
+			children++
+			if !span.Parent().IsValid() {
+				t.Errorf("span %s has no parent", span.Name())
+			}
+		}
+	}
+	if len(servers) != 2 || children != 2 {
+		t.Fatalf("%d server and %d child spans, want 2 of each", len(servers), children)
+	}
+	for _, span := range servers {
+		if span.Name() != "GET /api/blogs/:id" {
+			t.Errorf("span named %q, want the route template", span.Name())
+		}
+		if !traceIDs[span.SpanContext().TraceID().String()] {
+			t.Errorf("span trace ID %s wasn't returned in X-Trace-ID", span.SpanContext().TraceID())
+		}
+		attributes := map[string]string{}
+		for _, attribute := range span.Attributes() {
+			attributes[string(attribute.Key)] = attribute.Value.Emit()
+		}
+		if attributes[string(semconv.HTTPRouteKey)] != "/api/blogs/:id" || attributes["http.response.status_code"] != "200" {
+			t.Errorf("span attributes %v, want the route template and status", attributes)
+		}
+	}
+}
+
+func TestLoggerContinuesIncomingTrace(t *testing.T) {
+	recorder := recordSpans(t)
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(Logger())
+	engine.GET("/api/blogs", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/api/blogs", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("X-Trace-ID"); got != traceID {
+		t.Errorf("X-Trace-ID %q, want the caller's %s", got, traceID)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("%d spans, want one child of the caller's span", len(spans))
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/userblog/management/pkg/metrics"
+)
+
+// requestRoutes returns the route label of every http_requests_total series
+func requestRoutes(t *testing.T) []string {
+	t.Helper()
+
+	ch := make(chan prometheus.Metric, 100)
+	metrics.HTTPRequests.Collect(ch)
+	close(ch)
+
+	var routes []string
+	for metric := range ch {
+		var m dto.Metric
+		if err := metric.Write(&m); err != nil {
+			t.Fatalf("reading metric: %v", err)
+		}
+		for _, label := range m.GetLabel() {
+			if label.GetName() == "route" {
+				routes = append(routes, label.GetValue())
+			}
+		}
+	}
+	return routes
+}
+
+func TestMetricsLabelsRouteTemplates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(Metrics())
+	engine.GET("/api/blogs/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	ok := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/api/blogs/:id", "200")
+	unmatched := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "unmatched", "404")
+	beforeOK, beforeUnmatched := testutil.ToFloat64(ok), testutil.ToFloat64(unmatched)
+
+	for _, path := range []string{"/api/blogs/1", "/api/blogs/2", "/api/blogs/3", "/api/nope/1", "/api/nope/2"} {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := testutil.ToFloat64(ok) - beforeOK; got != 3 {
+		t.Errorf("/api/blogs/:id counted %v requests, want 3", got)
+	}
+	if got := testutil.ToFloat64(unmatched) - beforeUnmatched; got != 2 {
+		t.Errorf("unmatched counted %v requests, want 2", got)
+	}
+	// Raw paths would give every ID its own series
+	for _, route := range requestRoutes(t) {
+		if strings.Contains(route, "/1") || strings.Contains(route, "/2") || strings.Contains(route, "/3") {
+			t.Errorf("series labelled with the raw path %s", route)
+		}
+	}
+	if got := testutil.ToFloat64(metrics.HTTPRequestsInFlight); got != 0 {
+		t.Errorf("%v requests still in flight", got)
+	}
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/problem"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/tenant"
+)
+
+// ITenantMiddleware defines the interface for the middleware selecting a request's organization
+type ITenantMiddleware interface {
+	Resolve() gin.HandlerFunc
+}
+
+// TenantMiddleware implements the ITenantMiddleware interface
+type TenantMiddleware struct {
+	orgService service.IOrganizationService
+}
+
+// NewTenantMiddleware creates a new organization selecting middleware
+func NewTenantMiddleware(orgService service.IOrganizationService) ITenantMiddleware {
+	return &TenantMiddleware{
+		orgService: orgService,
+	}
+}
+
+// Resolve middleware sets the organization the request acts in: the one named by the
+// X-Organization header, else by the subdomain of organizations.domain, else the default one.
+// JWTAuth replaces the default with the organization of the token's org claim.
+func (m *TenantMiddleware) Resolve() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := config.Current().Orgs
+
+		slug, source := strings.ToLower(strings.TrimSpace(c.GetHeader(tenant.Header))), tenant.SourceHeader
+		if slug == "" {
+			slug, source = subdomain(c.Request.Host, cfg.Domain), tenant.SourceSubdomain
+		}
+		if slug == "" {
+			slug, source = cfg.Default, tenant.SourceDefault
+		}
+
+		org, err := m.orgService.Resolve(c.Request.Context(), slug)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+
+		c.Request = c.Request.WithContext(tenant.WithOrganization(c.Request.Context(), tenant.Organization{
+			ID:     org.ID,
+			Slug:   org.Slug,
+			Source: source,
+		}))
+		c.Next()
+	}
+}
+
+// checkMembership allows a user to act in the organization the request or their token named
+// only when they are a member of it, or their platform role administers every organization
+func checkMembership(ctx context.Context, user *models.User) error {
+	orgID := tenant.NamedID(ctx)
+	if orgID == 0 || user.Membership != nil && user.Membership.OrganizationID == orgID {
+		return nil
+	}
+	for _, permission := range user.Role.Permissions {
+		if permission.Grants("organization", models.PermissionWildcard) {
+			return nil
+		}
+	}
+	return service.NewForbiddenError("you are not a member of this organization")
+}
+
+// subdomain returns the label of host directly below domain, or "" when host isn't a subdomain
+// of it
+func subdomain(host, domain string) string {
+	if domain == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	label := strings.TrimSuffix(strings.ToLower(host), "."+strings.ToLower(domain))
+	if label == host || strings.Contains(label, ".") {
+		return ""
+	}
+	return label
+}
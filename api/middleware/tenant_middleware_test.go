@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/tenant"
+)
+
+func TestCheckMembership(t *testing.T) {
+	acme := func(source tenant.Source) context.Context {
+		return tenant.WithOrganization(context.Background(), tenant.Organization{ID: 2, Slug: "acme", Source: source})
+	}
+	member := models.User{Membership: &models.Membership{OrganizationID: 2}}
+	outsider := models.User{Role: models.Role{Permissions: []models.Permission{{Resource: "blog", Action: "*"}, {Resource: "organization", Action: "read"}}}}
+	platformAdmin := models.User{Role: models.Role{Permissions: []models.Permission{{Resource: "*", Action: "*"}}}}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		user    models.User
+		wantErr bool
+	}{
+		{name: "member by header", ctx: acme(tenant.SourceHeader), user: member},
+		{name: "member by token", ctx: acme(tenant.SourceToken), user: member},
+		{name: "outsider by header", ctx: acme(tenant.SourceHeader), user: outsider, wantErr: true},
+		{name: "outsider by subdomain", ctx: acme(tenant.SourceSubdomain), user: outsider, wantErr: true},
+		{name: "outsider by token", ctx: acme(tenant.SourceToken), user: outsider, wantErr: true},
+		{name: "outsider in default", ctx: acme(tenant.SourceDefault), user: outsider},
+		{name: "platform administrator", ctx: acme(tenant.SourceHeader), user: platformAdmin},
+		{name: "no organization", ctx: context.Background(), user: outsider},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkMembership(tt.ctx, &tt.user)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("checkMembership: %v", err)
+				}
+				return
+			}
+			if _, ok := err.(*service.ForbiddenError); !ok {
+				t.Errorf("error = %v, want a ForbiddenError", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package openapi
+
+import _ "embed"
+
+// DocsPage is a self-contained HTML page that renders the document served next to it
+//
+//go:embed docs.html
+var DocsPage []byte
@@ -0,0 +1,269 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/problem"
+)
+
+// Version is the OpenAPI specification version the document conforms to
+const Version = "3.1.0"
+
+// pathParamPattern matches gin path parameters such as :id
+var pathParamPattern = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+
+// Param describes a path or query parameter
+type Param struct {
+	Name        string
+	In          string // "path" or "query"
+	Type        string // JSON schema type, defaults to string
+	Description string
+	Required    bool
+}
+
+// Operation describes a single route for the OpenAPI document.
+// Path uses gin syntax and is relative to the base path passed to Build.
+type Operation struct {
+	Method      string
+	Path        string
+	Summary     string
+	Tags        []string
+	Auth        bool        // requires a bearer token
+	Permission  string      // resource:action checked by the access policy, if any
+	RateLimit   string      // rate limit policy applied to the route, if any
+	Params      []Param     // path params not listed here are documented as strings
+	Request     interface{} // request body DTO, nil when there is no body
+	RequestType string      // request body content type, defaults to application/json
+	Status      int         // success status code, defaults to 200
+	Response    interface{} // success response body, nil when there is no body
+	ContentType string      // success response content type, defaults to application/json
+}
+
+// Describer is implemented by routes that document the operations they register
+type Describer interface {
+	Operations() []Operation
+}
+
+// Document is an OpenAPI 3.1 document
+type Document struct {
+	OpenAPI    string                            `json:"openapi"`
+	Info       Info                              `json:"info"`
+	Servers    []Server                          `json:"servers,omitempty"`
+	Paths      map[string]map[string]interface{} `json:"paths"`
+	Components Components                        `json:"components"`
+}
+
+// Info holds the document metadata
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Server describes the base URL of the API
+type Server struct {
+	URL string `json:"url"`
+}
+
+// Components holds reusable schemas and security schemes
+type Components struct {
+	Schemas         map[string]*Schema                `json:"schemas"`
+	SecuritySchemes map[string]map[string]interface{} `json:"securitySchemes"`
+}
+
+// Build generates the document from the registered gin routes and the operations the routes describe.
+// It returns an error listing every route under basePath that has no operation, and every operation
+// that does not match a registered route, so undocumented endpoints are caught at startup.
+func Build(info Info, routes gin.RoutesInfo, basePath string, describers ...Describer) (*Document, error) {
+	registered := make(map[string]bool)
+	for _, route := range routes {
+		if strings.HasPrefix(route.Path, basePath) {
+			registered[route.Method+" "+route.Path] = true
+		}
+	}
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Servers: []Server{{URL: basePath}},
+		Paths:   make(map[string]map[string]interface{}),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+			SecuritySchemes: map[string]map[string]interface{}{
+				"bearerAuth": {"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+	schemas := newSchemaBuilder(doc.Components.Schemas)
+
+	var problems []string
+	documented := make(map[string]bool)
+	for _, describer := range describers {
+		for _, op := range describer.Operations() {
+			key := op.Method + " " + basePath + op.Path
+			if !registered[key] {
+				problems = append(problems, "documented but not registered: "+key)
+				continue
+			}
+			documented[key] = true
+
+			path := pathParamPattern.ReplaceAllString(op.Path, "{$1}")
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = make(map[string]interface{})
+			}
+			doc.Paths[path][strings.ToLower(op.Method)] = buildOperation(op, schemas)
+		}
+	}
+
+	for key := range registered {
+		if !documented[key] {
+			problems = append(problems, "registered but not documented: "+key)
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return doc, fmt.Errorf("openapi: %s", strings.Join(problems, "; "))
+	}
+
+	return doc, nil
+}
+
+// buildOperation renders a single operation object
+func buildOperation(op Operation, schemas *schemaBuilder) map[string]interface{} {
+	operation := map[string]interface{}{
+		"summary":     op.Summary,
+		"operationId": operationID(op),
+	}
+	if len(op.Tags) > 0 {
+		operation["tags"] = op.Tags
+	}
+
+	// Parameters: declared ones first, then interface{} undeclared path params
+	declared := make(map[string]bool)
+	var params []map[string]interface{}
+	for _, p := range op.Params {
+		declared[p.Name] = true
+		params = append(params, buildParam(p))
+	}
+	for _, match := range pathParamPattern.FindAllStringSubmatch(op.Path, -1) {
+		if !declared[match[1]] {
+			params = append(params, buildParam(Param{Name: match[1], In: "path"}))
+		}
+	}
+	if len(params) > 0 {
+		operation["parameters"] = params
+	}
+
+	if op.Request != nil {
+		requestType := op.RequestType
+		if requestType == "" {
+			requestType = "application/json"
+		}
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				requestType: map[string]interface{}{"schema": schemas.schemaFor(op.Request)},
+			},
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	if op.Response != nil {
+		contentType := op.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		success["content"] = map[string]interface{}{
+			contentType: map[string]interface{}{"schema": schemas.schemaFor(op.Response)},
+		}
+	}
+
+	responses := map[string]interface{}{fmt.Sprint(status): success}
+	errorResponse := func(code int) {
+		responses[fmt.Sprint(code)] = map[string]interface{}{
+			"description": http.StatusText(code),
+			"content": map[string]interface{}{
+				problem.ContentType: map[string]interface{}{"schema": schemas.schemaFor(problem.Problem{})},
+			},
+		}
+	}
+	if op.Request != nil || len(params) > 0 {
+		errorResponse(http.StatusBadRequest)
+	}
+	if op.Auth {
+		operation["security"] = []map[string][]string{{"bearerAuth": {}}}
+		errorResponse(http.StatusUnauthorized)
+	}
+	if op.Permission != "" {
+		operation["x-permission"] = op.Permission
+		operation["description"] = "Requires the `" + op.Permission + "` permission."
+		errorResponse(http.StatusForbidden)
+	}
+	if pathParamPattern.MatchString(op.Path) {
+		errorResponse(http.StatusNotFound)
+	}
+	if op.RateLimit != "" {
+		operation["x-rate-limit-policy"] = op.RateLimit
+		errorResponse(http.StatusTooManyRequests)
+	}
+	operation["responses"] = responses
+
+	return operation
+}
+
+// buildParam renders a parameter object
+func buildParam(p Param) map[string]interface{} {
+	paramType := p.Type
+	if paramType == "" {
+		paramType = "string"
+	}
+
+	param := map[string]interface{}{
+		"name":     p.Name,
+		"in":       p.In,
+		"required": p.Required || p.In == "path",
+		"schema":   map[string]interface{}{"type": paramType},
+	}
+	if p.Description != "" {
+		param["description"] = p.Description
+	}
+	return param
+}
+
+// operationID derives a stable identifier such as getBlogsById from the method and path
+func operationID(op Operation) string {
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(op.Method))
+	for _, segment := range strings.Split(op.Path, "/") {
+		if segment == "" {
+			continue
+		}
+		if strings.HasPrefix(segment, ":") {
+			sb.WriteString("By")
+			segment = segment[1:]
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '_' || r == '-' || r == '.' }) {
+			sb.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return sb.String()
+}
+
+// Page describes a paginated list response whose data items have the type of Item
+type Page struct {
+	Item interface{}
+}
+
+// Message describes a response holding only a message
+type Message struct {
+	Message string `json:"message"`
+}
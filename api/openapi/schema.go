@@ -0,0 +1,220 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/userblog/management/api/validation"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Schema is a JSON schema object as used by OpenAPI 3.1
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+}
+
+// schemaBuilder converts Go types to schemas, registering named structs as components
+type schemaBuilder struct {
+	components map[string]*Schema
+}
+
+func newSchemaBuilder(components map[string]*Schema) *schemaBuilder {
+	return &schemaBuilder{components: components}
+}
+
+// schemaFor returns the schema for a value, handling the Page wrapper
+func (b *schemaBuilder) schemaFor(v interface{}) *Schema {
+	if page, ok := v.(Page); ok {
+		return &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"data":       {Type: "array", Items: b.schemaForType(reflect.TypeOf(page.Item))},
+				"total":      {Type: "integer"},
+				"page":       {Type: "integer"},
+				"per_page":   {Type: "integer"},
+				"total_page": {Type: "integer"},
+			},
+			Required: []string{"data", "total", "page", "per_page", "total_page"},
+		}
+	}
+	return b.schemaForType(reflect.TypeOf(v))
+}
+
+// schemaForType returns the schema for a Go type
+func (b *schemaBuilder) schemaForType(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &Schema{Type: "integer", Minimum: &zero}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schemaForType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schemaForType(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		if _, exists := b.components[t.Name()]; !exists {
+			// Reserve the name first so recursive types terminate
+			b.components[t.Name()] = &Schema{}
+			*b.components[t.Name()] = *b.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	default:
+		return &Schema{}
+	}
+}
+
+// structSchema builds an object schema from the exported fields of a struct
+func (b *schemaBuilder) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	b.addFields(schema, t)
+	return schema
+}
+
+// addFields adds the fields of t to schema, flattening embedded structs such as gorm.Model
+func (b *schemaBuilder) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, skip := jsonName(field)
+		if skip {
+			continue
+		}
+
+		if field.Anonymous && field.Tag.Get("json") == "" && field.Type.Kind() == reflect.Struct {
+			b.addFields(schema, field.Type)
+			continue
+		}
+
+		property := b.schemaForType(field.Type)
+		if applyBinding(property, field.Tag.Get("binding")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+}
+
+// jsonName returns the serialized name of a field and whether it is skipped
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		name = field.Name
+	}
+	return name, false
+}
+
+// applyBinding maps gin binding tags onto schema constraints and reports whether the field is required
+func applyBinding(schema *Schema, binding string) bool {
+	required := false
+	if binding == "" {
+		return required
+	}
+
+	// $ref schemas can't carry sibling constraints, so only the required flag applies
+	constrain := schema.Ref == ""
+
+	for _, rule := range strings.Split(binding, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			// The remaining rules apply to the elements of a slice or map
+			return required
+		case "required":
+			required = true
+		case "email":
+			if constrain {
+				schema.Format = "email"
+			}
+		case "url", "http_url":
+			if constrain {
+				schema.Format = "uri"
+			}
+		case "username":
+			if constrain {
+				schema.Pattern = validation.UsernamePattern.String()
+			}
+		case "slug":
+			if constrain {
+				schema.Pattern = validation.SlugPattern.String()
+			}
+		case "password":
+			if constrain {
+				n := validation.PasswordMinLength
+				schema.MinLength = &n
+			}
+		case "oneof":
+			if constrain {
+				schema.Enum = strings.Fields(arg)
+			}
+		case "min", "max":
+			n, err := strconv.Atoi(arg)
+			if err != nil || !constrain {
+				continue
+			}
+			switch schema.Type {
+			case "string":
+				if name == "min" {
+					schema.MinLength = &n
+				} else {
+					schema.MaxLength = &n
+				}
+			case "integer", "number":
+				f := float64(n)
+				if name == "min" {
+					schema.Minimum = &f
+				} else {
+					schema.Maximum = &f
+				}
+			}
+		}
+	}
+
+	return required
+}
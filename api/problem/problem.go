@@ -0,0 +1,109 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/logger"
+)
+
+// ContentType is the media type of problem responses
+const ContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details response
+type Problem struct {
+	Type     string               `json:"type"`
+	Title    string               `json:"title"`
+	Status   int                  `json:"status"`
+	Code     string               `json:"code"`
+	Detail   string               `json:"detail,omitempty"`
+	Instance string               `json:"instance,omitempty"`
+	TraceID  string               `json:"traceId,omitempty"`
+	Errors   []service.FieldError `json:"errors,omitempty"`
+}
+
+// New creates a problem for the given status with the standard type, title and code
+func New(status int, detail string) Problem {
+	code := strings.ToUpper(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+	return Problem{
+		Type:   "/problems/" + strings.ToLower(strings.ReplaceAll(code, "_", "-")),
+		Title:  http.StatusText(status),
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+// FromError maps a service error to a problem. Unknown errors become a 500 without exposing their message.
+func FromError(err error) Problem {
+	var notFound *service.NotFoundError
+	var conflict *service.ConflictError
+	var forbidden *service.ForbiddenError
+	var unauthorized *service.UnauthorizedError
+	var validation *service.ValidationError
+	var locked *service.LockedError
+
+	switch {
+	case errors.As(err, &notFound):
+		return New(http.StatusNotFound, notFound.Error())
+	case errors.As(err, &conflict):
+		return New(http.StatusConflict, conflict.Error())
+	case errors.As(err, &forbidden):
+		return New(http.StatusForbidden, forbidden.Error())
+	case errors.As(err, &unauthorized):
+		return New(http.StatusUnauthorized, unauthorized.Error())
+	case errors.As(err, &locked):
+		return New(http.StatusLocked, locked.Error())
+	case errors.As(err, &validation):
+		p := New(http.StatusBadRequest, validation.Error())
+		p.Errors = validation.Fields
+		return p
+	default:
+		return New(http.StatusInternalServerError, "An unexpected error occurred")
+	}
+}
+
+// Error maps err to a problem, writes it and aborts the request
+func Error(ctx *gin.Context, err error) {
+	p := FromError(err)
+
+	var locked *service.LockedError
+	if errors.As(err, &locked) {
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+	}
+
+	if p.Status == http.StatusInternalServerError {
+		logger.ErrorF(ctx.Request.Context(), "Request Error: %v", err)
+	}
+	Write(ctx, p)
+}
+
+// Write completes the problem with request details, writes it and aborts the request
+func Write(ctx *gin.Context, p Problem) {
+	p.Instance = ctx.Request.URL.Path
+	p.TraceID = TraceID(ctx)
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		ctx.AbortWithStatus(p.Status)
+		return
+	}
+
+	ctx.Abort()
+	ctx.Data(p.Status, ContentType, body)
+}
+
+// TraceID returns the trace ID of the request, if any
+func TraceID(ctx *gin.Context) string {
+	if traceID := ctx.GetString(string(logger.TraceIDKey)); traceID != "" {
+		return traceID
+	}
+	traceID, _ := ctx.Request.Context().Value(logger.TraceIDKey).(string)
+	return traceID
+}
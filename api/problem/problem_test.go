@@ -0,0 +1,111 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/internal/service"
+)
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		typ    string
+		title  string
+		detail string
+	}{
+		{name: "not found", err: service.NewNotFoundError("blog"), status: http.StatusNotFound, typ: "/problems/not-found", title: "Not Found", detail: "blog not found"},
+		{name: "forbidden", err: service.NewForbiddenError("you can only update your own blogs"), status: http.StatusForbidden, typ: "/problems/forbidden", title: "Forbidden", detail: "you can only update your own blogs"},
+		{name: "unauthorized", err: service.NewUnauthorizedError("invalid token"), status: http.StatusUnauthorized, typ: "/problems/unauthorized", title: "Unauthorized", detail: "invalid token"},
+		{name: "validation", err: service.NewValidationError("invalid blog ID"), status: http.StatusBadRequest, typ: "/problems/bad-request", title: "Bad Request", detail: "invalid blog ID"},
+		{name: "conflict", err: service.NewConflictError("username already exists"), status: http.StatusConflict, typ: "/problems/conflict", title: "Conflict", detail: "username already exists"},
+		{name: "locked", err: service.NewLockedError("too many failed logins", time.Minute), status: http.StatusLocked, typ: "/problems/locked", title: "Locked", detail: "too many failed logins"},
+		{name: "wrapped", err: fmt.Errorf("updating: %w", service.NewConflictError("email already exists")), status: http.StatusConflict, typ: "/problems/conflict", title: "Conflict", detail: "email already exists"},
+		{name: "unknown", err: errors.New("pq: relation \"users\" does not exist"), status: http.StatusInternalServerError, typ: "/problems/internal-server-error", title: "Internal Server Error", detail: "An unexpected error occurred"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := FromError(tt.err)
+			if p.Status != tt.status || p.Type != tt.typ || p.Title != tt.title || p.Detail != tt.detail {
+				t.Errorf("FromError = %+v, want status %d, type %s, title %s and detail %q", p, tt.status, tt.typ, tt.title, tt.detail)
+			}
+		})
+	}
+}
+
+func TestFromErrorKeepsFieldErrors(t *testing.T) {
+	field := service.FieldError{Field: "username", Code: "notreserved", Message: "username is reserved and can't be used"}
+	p := FromError(service.NewValidationError("username is reserved", field))
+	if len(p.Errors) != 1 || p.Errors[0] != field {
+		t.Errorf("errors = %+v, want %+v", p.Errors, field)
+	}
+}
+
+// serve handles a GET request for path with handle and returns the response
+func serve(t *testing.T, path string, handle func(*gin.Context)) *httptest.ResponseRecorder {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET(path, handle)
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestErrorHidesInternalMessages(t *testing.T) {
+	rec := serve(t, "/api/blogs", func(c *gin.Context) {
+		Error(c, errors.New("dial tcp 10.0.0.5:5432: connection refused"))
+	})
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status %d, want 500", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("Content-Type %q, want %q", got, ContentType)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "10.0.0.5") || strings.Contains(body, "connection refused") {
+		t.Errorf("response %s exposes the internal error", body)
+	}
+
+	var p Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("decoding problem: %v", err)
+	}
+	if p.Code != "INTERNAL_SERVER_ERROR" || p.Instance != "/api/blogs" {
+		t.Errorf("problem = %+v, want code INTERNAL_SERVER_ERROR for /api/blogs", p)
+	}
+}
+
+func TestErrorSetsRetryAfterWhenLocked(t *testing.T) {
+	rec := serve(t, "/api/auth/login", func(c *gin.Context) {
+		Error(c, service.NewLockedError("too many failed logins", 1500*time.Millisecond))
+	})
+
+	if rec.Code != http.StatusLocked || rec.Header().Get("Retry-After") != "2" {
+		t.Errorf("status %d and Retry-After %q, want 423 and 2", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	rec := serve(t, "/api/auth/login", func(c *gin.Context) {
+		Write(c, New(http.StatusTooManyRequests, "rate limit exceeded, retry later"))
+	})
+
+	var p Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("decoding problem: %v", err)
+	}
+	if rec.Code != http.StatusTooManyRequests || p.Type != "/problems/too-many-requests" || p.Title != "Too Many Requests" || p.Code != "TOO_MANY_REQUESTS" {
+		t.Errorf("status %d and problem %+v, want a 429 too-many-requests problem", rec.Code, p)
+	}
+}
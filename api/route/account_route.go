@@ -0,0 +1,41 @@
+package route
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/middleware"
+	"github.com/userblog/management/api/openapi"
+	"github.com/userblog/management/internal/models"
+)
+
+type AccountRoute struct {
+	accountController controller.IAccountController
+	authMiddleware    middleware.IAuthMiddleware
+	rateLimiter       middleware.IRateLimitMiddleware
+	*operations
+}
+
+func NewAccountRoute(accountController controller.IAccountController, authMiddleware middleware.IAuthMiddleware, rateLimiter middleware.IRateLimitMiddleware) AccountRoute {
+	return AccountRoute{
+		accountController: accountController,
+		authMiddleware:    authMiddleware,
+		rateLimiter:       rateLimiter,
+		operations:        &operations{},
+	}
+}
+
+func (r AccountRoute) AccountRoute(rg *gin.RouterGroup) {
+	// The signed-in user's own account; no permission is needed, and impersonators can't change it
+	me := newRoutes(rg, r.operations, nil, "account").sub("/me")
+	me.auth(r.authMiddleware.JWTAuth()).limit(r.rateLimiter, "users").use(r.authMiddleware.DenyImpersonation())
+
+	me.put("", "", openapi.Operation{Summary: "Update the current user's name", Request: dto.UpdateAccountRequest{}, Response: models.User{}}, r.accountController.Update)
+	me.post("/password", "", openapi.Operation{Summary: "Change the current user's password, logging out their other sessions", Request: dto.ChangePasswordRequest{}, Response: openapi.Message{}}, r.accountController.ChangePassword)
+	me.post("/email", "", openapi.Operation{Summary: "Send a verification token to a new email address for the current user", Request: dto.ChangeEmailRequest{}, Response: dto.PendingChangeResponse{}, Status: http.StatusAccepted}, r.accountController.ChangeEmail)
+	me.post("/email/verify", "", openapi.Operation{Summary: "Verify and apply the current user's new email address", Request: dto.VerifyEmailRequest{}, Response: openapi.Message{}}, r.accountController.VerifyEmail)
+	me.post("/deletion", "", openapi.Operation{Summary: "Schedule deletion of the current user's account after a grace period", Request: dto.DeleteAccountRequest{}, Response: dto.PendingChangeResponse{}, Status: http.StatusAccepted}, r.accountController.ScheduleDeletion)
+	me.delete("/deletion", "", openapi.Operation{Summary: "Cancel the scheduled deletion of the current user's account", Response: openapi.Message{}}, r.accountController.CancelDeletion)
+}
@@ -0,0 +1,57 @@
+package route
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/middleware"
+	"github.com/userblog/management/api/openapi"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/service"
+)
+
+type AdminRoute struct {
+	impersonationController controller.IImpersonationController
+	auditController         controller.IAuditController
+	invitationController    controller.IInvitationController
+	authMiddleware          middleware.IAuthMiddleware
+	authzMiddleware         middleware.IAuthzMiddleware
+	rateLimiter             middleware.IRateLimitMiddleware
+	*operations
+}
+
+func NewAdminRoute(impersonationController controller.IImpersonationController, auditController controller.IAuditController, invitationController controller.IInvitationController, authMiddleware middleware.IAuthMiddleware, authzMiddleware middleware.IAuthzMiddleware, rateLimiter middleware.IRateLimitMiddleware) AdminRoute {
+	return AdminRoute{
+		impersonationController: impersonationController,
+		auditController:         auditController,
+		invitationController:    invitationController,
+		authMiddleware:          authMiddleware,
+		authzMiddleware:         authzMiddleware,
+		rateLimiter:             rateLimiter,
+		operations:              &operations{},
+	}
+}
+
+func (r AdminRoute) AdminRoute(rg *gin.RouterGroup) {
+	router := newRoutes(rg, r.operations, r.authzMiddleware, "admin").sub("/admin")
+	router.auth(r.authMiddleware.JWTAuth()).limit(r.rateLimiter, "users")
+	auditParams := []openapi.Param{
+		{Name: "actor_id", In: "query", Type: "integer", Description: "User who made the change"},
+		{Name: "action", In: "query", Description: "Action, e.g. user.update, or a prefix ending in *, e.g. auth.*"},
+		{Name: "target_type", In: "query", Description: "Type of the changed entity, e.g. user or blog"},
+		{Name: "target_id", In: "query", Description: "ID of the changed entity"},
+		{Name: "from", In: "query", Description: "Earliest time, RFC 3339"},
+		{Name: "to", In: "query", Description: "Time before which events are returned, RFC 3339"},
+	}
+
+	router.post("/impersonate/:user_id", "user:impersonate", openapi.Operation{Summary: "Obtain a short-lived token acting as a user", Params: idParam("user_id"), Request: dto.ImpersonateRequest{}, Status: http.StatusCreated, Response: dto.ImpersonateResponse{}}, r.authMiddleware.DenyImpersonation(), r.impersonationController.Impersonate)
+	router.get("/invitations", "user:invite", openapi.Operation{Summary: "List pending invitations to register", Response: []models.Invitation{}}, r.invitationController.List)
+	router.post("/invitations", "user:invite", openapi.Operation{Summary: "Invite someone to register by email", Request: dto.InviteUserRequest{}, Status: http.StatusCreated, Response: models.Invitation{}}, r.authMiddleware.DenyImpersonation(), r.invitationController.Invite)
+	router.delete("/invitations/:invitation_id", "user:invite", openapi.Operation{Summary: "Revoke an invitation to register", Params: idParam("invitation_id"), Response: openapi.Message{}}, r.authMiddleware.DenyImpersonation(), r.invitationController.Revoke)
+	router.post("/invitations/:invitation_id/resend", "user:invite", openapi.Operation{Summary: "Send an invitation again with a new token and expiry", Params: idParam("invitation_id"), Response: models.Invitation{}}, r.authMiddleware.DenyImpersonation(), r.invitationController.Resend)
+	router.get("/audit", "audit:read", openapi.Operation{Summary: "List audit events, newest first", Params: params(auditParams, pageParams), Response: openapi.Page{Item: models.AuditEvent{}}}, r.auditController.List)
+	router.get("/audit/export", "audit:read", openapi.Operation{Summary: "Export audit events as NDJSON, oldest first", Params: auditParams, Response: models.AuditEvent{}, ContentType: "application/x-ndjson"}, r.auditController.Export)
+	router.get("/audit/verify", "audit:read", openapi.Operation{Summary: "Verify the audit log hash chain", Response: service.AuditVerification{}}, r.auditController.Verify)
+}
@@ -1,27 +1,39 @@
 package route
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
 	"github.com/userblog/management/api/middleware"
+	"github.com/userblog/management/api/openapi"
+	"github.com/userblog/management/internal/models"
 )
 
 type AuthRoute struct {
 	authController controller.IAuthController
 	authMiddleware middleware.IAuthMiddleware
+	rateLimiter    middleware.IRateLimitMiddleware
+	*operations
 }
 
-func NewAuthRoute(authController controller.IAuthController, authMiddleware middleware.IAuthMiddleware) AuthRoute {
+func NewAuthRoute(authController controller.IAuthController, authMiddleware middleware.IAuthMiddleware, rateLimiter middleware.IRateLimitMiddleware) AuthRoute {
 	return AuthRoute{
 		authController: authController,
 		authMiddleware: authMiddleware,
+		rateLimiter:    rateLimiter,
+		operations:     &operations{},
 	}
 }
 
 func (r AuthRoute) AuthRoute(rg *gin.RouterGroup) {
-	router := rg.Group("/auth")
+	router := newRoutes(rg, r.operations, nil, "auth").sub("/auth")
+
+	public := router.sub("").limit(r.rateLimiter, "auth")
+	public.post("/register", "", openapi.Operation{Summary: "Register a new user", Request: dto.RegisterRequest{}, Status: http.StatusCreated, Response: dto.RegisterResponse{}}, r.authController.Register)
+	public.post("/login", "", openapi.Operation{Summary: "Log in and obtain a token", Request: dto.LoginRequest{}, Response: dto.LoginResponse{}}, r.authController.Login)
 
-	router.POST("/register", r.authController.Register)
-	router.POST("/login", r.authController.Login)
-	router.GET("/me", r.authMiddleware.JWTAuth(), r.authController.GetMe)
+	me := router.sub("").auth(r.authMiddleware.JWTAuth()).limit(r.rateLimiter, "users")
+	me.get("/me", "", openapi.Operation{Summary: "Get the current user", Response: models.User{}}, r.authController.GetMe)
 }
@@ -0,0 +1,33 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/middleware"
+	"github.com/userblog/management/api/openapi"
+	"github.com/userblog/management/pkg/policy"
+)
+
+type AuthzRoute struct {
+	authzController controller.IAuthzController
+	authMiddleware  middleware.IAuthMiddleware
+	rateLimiter     middleware.IRateLimitMiddleware
+	*operations
+}
+
+func NewAuthzRoute(authzController controller.IAuthzController, authMiddleware middleware.IAuthMiddleware, rateLimiter middleware.IRateLimitMiddleware) AuthzRoute {
+	return AuthzRoute{
+		authzController: authzController,
+		authMiddleware:  authMiddleware,
+		rateLimiter:     rateLimiter,
+		operations:      &operations{},
+	}
+}
+
+func (r AuthzRoute) AuthzRoute(rg *gin.RouterGroup) {
+	router := newRoutes(rg, r.operations, nil, "authz").sub("/authz")
+	router.auth(r.authMiddleware.JWTAuth()).limit(r.rateLimiter, "users")
+
+	router.post("/explain", "", openapi.Operation{Summary: "Explain which rule allows or denies an action", Request: dto.ExplainRequest{}, Response: policy.Decision{}}, r.authzController.Explain)
+}
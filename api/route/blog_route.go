@@ -1,36 +1,46 @@
 package route
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
 	"github.com/userblog/management/api/middleware"
+	"github.com/userblog/management/api/openapi"
 )
 
 type BlogRoute struct {
-	blogController controller.IBlogController
-	authMiddleware middleware.IAuthMiddleware
+	blogController  controller.IBlogController
+	authMiddleware  middleware.IAuthMiddleware
+	authzMiddleware middleware.IAuthzMiddleware
+	rateLimiter     middleware.IRateLimitMiddleware
+	*operations
 }
 
-func NewBlogRoute(blogController controller.IBlogController, authMiddleware middleware.IAuthMiddleware) BlogRoute {
+func NewBlogRoute(blogController controller.IBlogController, authMiddleware middleware.IAuthMiddleware, authzMiddleware middleware.IAuthzMiddleware, rateLimiter middleware.IRateLimitMiddleware) BlogRoute {
 	return BlogRoute{
-		blogController: blogController,
-		authMiddleware: authMiddleware,
+		blogController:  blogController,
+		authMiddleware:  authMiddleware,
+		authzMiddleware: authzMiddleware,
+		rateLimiter:     rateLimiter,
+		operations:      &operations{},
 	}
 }
 
 func (r BlogRoute) BlogRoute(rg *gin.RouterGroup) {
-	router := rg.Group("/blogs")
+	router := newRoutes(rg, r.operations, r.authzMiddleware, "blogs").sub("/blogs")
+	publishedOnly := []openapi.Param{{Name: "published_only", In: "query", Type: "boolean", Description: "Only return published blogs"}}
 
 	// Public routes
-	router.GET("", r.blogController.List)
-	router.GET("/:id", r.blogController.GetByID)
-	router.GET("/user/:user_id", r.blogController.ListByUser)
-
-	// Protected routes
-	authRouter := router.Group("")
-	authRouter.Use(r.authMiddleware.JWTAuth())
+	public := router.sub("").limit(r.rateLimiter, "blogs")
+	public.get("", "", openapi.Operation{Summary: "List blogs", Params: params(pageParams, publishedOnly, shapeParams), Response: openapi.Page{Item: dto.BlogResponse{}}}, r.blogController.List)
+	public.get("/:id", "", openapi.Operation{Summary: "Get a blog by ID", Params: params(idParam("id"), shapeParams), Response: dto.BlogResponse{}}, r.blogController.GetByID)
+	public.get("/user/:user_id", "", openapi.Operation{Summary: "List blogs by user", Params: params(idParam("user_id"), pageParams, shapeParams), Response: openapi.Page{Item: dto.BlogResponse{}}}, r.blogController.ListByUser)
 
-	authRouter.POST("", r.authMiddleware.RequirePermission("blog", "create"), r.blogController.Create)
-	authRouter.PUT("/:id", r.authMiddleware.RequirePermission("blog", "update"), r.blogController.Update)
-	authRouter.DELETE("/:id", r.authMiddleware.RequirePermission("blog", "delete"), r.blogController.Delete)
+	// Protected routes, limited after authentication so the user is known
+	authRouter := router.sub("").auth(r.authMiddleware.JWTAuth()).limit(r.rateLimiter, "blogs")
+	authRouter.post("", "blog:create", openapi.Operation{Summary: "Create a blog", Request: dto.CreateBlogRequest{}, Status: http.StatusCreated, Response: dto.BlogResponse{}}, r.blogController.Create)
+	authRouter.put("/:id", "blog:update", openapi.Operation{Summary: "Update a blog", Params: idParam("id"), Request: dto.UpdateBlogRequest{}, Response: dto.BlogResponse{}}, r.blogController.Update)
+	authRouter.delete("/:id", "blog:delete", openapi.Operation{Summary: "Delete a blog", Params: idParam("id"), Response: openapi.Message{}}, r.blogController.Delete)
 }
@@ -0,0 +1,26 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/openapi"
+)
+
+type DocsRoute struct {
+	docsController controller.IDocsController
+	*operations
+}
+
+func NewDocsRoute(docsController controller.IDocsController) DocsRoute {
+	return DocsRoute{
+		docsController: docsController,
+		operations:     &operations{},
+	}
+}
+
+func (r DocsRoute) DocsRoute(rg *gin.RouterGroup) {
+	router := newRoutes(rg, r.operations, nil, "docs")
+
+	router.get("/openapi.json", "", openapi.Operation{Summary: "Get the OpenAPI document", Response: map[string]interface{}{}}, r.docsController.OpenAPI)
+	router.get("/docs", "", openapi.Operation{Summary: "Interactive API documentation", Response: "", ContentType: "text/html"}, r.docsController.Docs)
+}
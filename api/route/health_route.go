@@ -0,0 +1,23 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+)
+
+type HealthRoute struct {
+	healthController controller.IHealthController
+}
+
+func NewHealthRoute(healthController controller.IHealthController) HealthRoute {
+	return HealthRoute{
+		healthController: healthController,
+	}
+}
+
+// HealthRoute registers the probe endpoints at the root, outside the documented /api
+func (r HealthRoute) HealthRoute(rg *gin.RouterGroup) {
+	rg.GET("/healthz", r.healthController.Healthz)
+	rg.GET("/readyz", r.healthController.Readyz)
+	rg.GET("/version", r.healthController.Version)
+}
@@ -0,0 +1,90 @@
+package route
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/userblog/management/api/middleware"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/config"
+)
+
+// impersonatingAuthService accepts any token as alice's, issued to user 1 acting as her
+type impersonatingAuthService struct {
+	service.IAuthService
+}
+
+func (impersonatingAuthService) ExtractTokenFromHeader(header string) (string, error) {
+	return header, nil
+}
+
+func (impersonatingAuthService) ValidateToken(ctx context.Context, token string) (*models.User, *service.TokenClaims, error) {
+	user := &models.User{Username: "alice"}
+	user.ID = 2
+	return user, &service.TokenClaims{SessionID: "5", Actor: &service.TokenActor{Subject: "1"}}, nil
+}
+
+// impersonationRecorder keeps the impersonation events recorded
+type impersonationRecorder struct {
+	service.IImpersonationService
+	events []models.ImpersonationEvent
+}
+
+func (r *impersonationRecorder) Record(ctx context.Context, event *models.ImpersonationEvent) error {
+	r.events = append(r.events, *event)
+	return nil
+}
+
+func TestSensitiveRoutesDenyImpersonation(t *testing.T) {
+	previous := config.Current()
+	cfg := config.Default()
+	cfg.RateLimit.Enabled = false
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(previous) })
+
+	recorder := &impersonationRecorder{}
+	engine, _ := registerAPIWithAuth(t, middleware.NewAuthMiddleware(impersonatingAuthService{}, recorder))
+
+	// Each is refused before its controller, which would panic without a service, is reached
+	routes := []struct{ method, path string }{
+		{http.MethodPut, "/api/me"},
+		{http.MethodPost, "/api/me/password"},
+		{http.MethodPost, "/api/me/email"},
+		{http.MethodPost, "/api/me/deletion"},
+		{http.MethodPut, "/api/me/profile"},
+		{http.MethodPut, "/api/me/avatar"},
+		{http.MethodPost, "/api/me/data-requests/export"},
+		{http.MethodDelete, "/api/auth/sessions"},
+		{http.MethodDelete, "/api/auth/sessions/3"},
+		{http.MethodPost, "/api/roles"},
+		{http.MethodPut, "/api/roles/3"},
+		{http.MethodPost, "/api/admin/invitations"},
+		{http.MethodDelete, "/api/admin/invitations/3"},
+		{http.MethodPost, "/api/org/invitations"},
+		{http.MethodDelete, "/api/org/invitations/3"},
+		{http.MethodPost, "/api/orgs/invitations/accept"},
+		{http.MethodPost, "/api/admin/impersonate/3"},
+	}
+	for _, route := range routes {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(route.method, route.path, nil)
+		req.Header.Set("Authorization", "impersonation")
+		engine.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s %s: status %d, want 403", route.method, route.path, rec.Code)
+		}
+	}
+
+	// Every request is recorded, refused or not
+	if len(recorder.events) != len(routes) {
+		t.Fatalf("recorded %d events for %d requests", len(recorder.events), len(routes))
+	}
+	for i, event := range recorder.events {
+		if event.Method != routes[i].method || event.Path != routes[i].path || event.Status != http.StatusForbidden || event.ActorID != 1 {
+			t.Errorf("event %d = %+v, want %s %s refused", i, event, routes[i].method, routes[i].path)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+)
+
+type JWKSRoute struct {
+	jwksController controller.IJWKSController
+}
+
+func NewJWKSRoute(jwksController controller.IJWKSController) JWKSRoute {
+	return JWKSRoute{
+		jwksController: jwksController,
+	}
+}
+
+// JWKSRoute registers the well-known key set at the root, where token verifiers expect it
+func (r JWKSRoute) JWKSRoute(rg *gin.RouterGroup) {
+	rg.GET("/.well-known/jwks.json", r.jwksController.JWKS)
+}
@@ -0,0 +1,38 @@
+package route
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/middleware"
+	"github.com/userblog/management/api/openapi"
+)
+
+type OIDCRoute struct {
+	oidcController controller.IOIDCController
+	rateLimiter    middleware.IRateLimitMiddleware
+	*operations
+}
+
+func NewOIDCRoute(oidcController controller.IOIDCController, rateLimiter middleware.IRateLimitMiddleware) OIDCRoute {
+	return OIDCRoute{
+		oidcController: oidcController,
+		rateLimiter:    rateLimiter,
+		operations:     &operations{},
+	}
+}
+
+func (r OIDCRoute) OIDCRoute(rg *gin.RouterGroup) {
+	router := newRoutes(rg, r.operations, nil, "auth").sub("/auth/oidc")
+	router.limit(r.rateLimiter, "auth")
+	provider := openapi.Param{Name: "provider", In: "path", Description: "Identity provider name from the oidc settings"}
+
+	router.get("/:provider/login", "", openapi.Operation{Summary: "Start a login at an identity provider", Params: []openapi.Param{provider}, Status: http.StatusFound}, r.oidcController.Login)
+	router.get("/:provider/callback", "", openapi.Operation{Summary: "Complete an identity provider login and obtain a token", Params: []openapi.Param{
+		provider,
+		{Name: "code", In: "query", Required: true, Description: "Authorization code from the provider"},
+		{Name: "state", In: "query", Required: true, Description: "State from the login redirect"},
+	}, Response: dto.LoginResponse{}}, r.oidcController.Callback)
+}
@@ -0,0 +1,133 @@
+package route
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	controllerImpl "github.com/userblog/management/api/controller/impl"
+	"github.com/userblog/management/api/middleware"
+	"github.com/userblog/management/api/openapi"
+)
+
+// registerAPI registers every API route as main does, with controllers and middleware that
+// are never called
+func registerAPI(t *testing.T) (*gin.Engine, []openapi.Describer) {
+	t.Helper()
+	return registerAPIWithAuth(t, middleware.NewAuthMiddleware(nil, nil))
+}
+
+// registerAPIWithAuth registers every API route behind the given authentication middleware
+func registerAPIWithAuth(t *testing.T, authMiddleware middleware.IAuthMiddleware) (*gin.Engine, []openapi.Describer) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	authzMiddleware := middleware.NewAuthzMiddleware(nil)
+	rateLimiter := middleware.NewRateLimitMiddleware(nil)
+
+	authRoute := NewAuthRoute(controllerImpl.NewAuthController(nil), authMiddleware, rateLimiter)
+	oidcRoute := NewOIDCRoute(controllerImpl.NewOIDCController(nil), rateLimiter)
+	sessionRoute := NewSessionRoute(controllerImpl.NewSessionController(nil), authMiddleware, rateLimiter)
+	authzRoute := NewAuthzRoute(controllerImpl.NewAuthzController(nil), authMiddleware, rateLimiter)
+	adminRoute := NewAdminRoute(controllerImpl.NewImpersonationController(nil), controllerImpl.NewAuditController(nil), controllerImpl.NewInvitationController(nil), authMiddleware, authzMiddleware, rateLimiter)
+	userRoute := NewUserRoute(controllerImpl.NewUserController(nil), authMiddleware, authzMiddleware, rateLimiter)
+	roleRoute := NewRoleRoute(controllerImpl.NewRoleController(nil), authMiddleware, authzMiddleware, rateLimiter)
+	orgRoute := NewOrganizationRoute(controllerImpl.NewOrganizationController(nil), authMiddleware, authzMiddleware, rateLimiter)
+	profileRoute := NewProfileRoute(controllerImpl.NewProfileController(nil), authMiddleware, rateLimiter)
+	accountRoute := NewAccountRoute(controllerImpl.NewAccountController(nil), authMiddleware, rateLimiter)
+	privacyRoute := NewPrivacyRoute(controllerImpl.NewPrivacyController(nil), authMiddleware, authzMiddleware, rateLimiter)
+	blogRoute := NewBlogRoute(controllerImpl.NewBlogController(nil), authMiddleware, authzMiddleware, rateLimiter)
+	docsRoute := NewDocsRoute(controllerImpl.NewDocsController())
+
+	engine := gin.New()
+	NewHealthRoute(controllerImpl.NewHealthController(nil)).HealthRoute(&engine.RouterGroup)
+	NewJWKSRoute(controllerImpl.NewJWKSController(nil)).JWKSRoute(&engine.RouterGroup)
+
+	api := engine.Group("/api")
+	authRoute.AuthRoute(api)
+	oidcRoute.OIDCRoute(api)
+	sessionRoute.SessionRoute(api)
+	authzRoute.AuthzRoute(api)
+	adminRoute.AdminRoute(api)
+	userRoute.UserRoute(api)
+	roleRoute.RoleRoute(api)
+	orgRoute.OrganizationRoute(api)
+	profileRoute.ProfileRoute(api)
+	accountRoute.AccountRoute(api)
+	privacyRoute.PrivacyRoute(api)
+	blogRoute.BlogRoute(api)
+	docsRoute.DocsRoute(api)
+
+	return engine, []openapi.Describer{authRoute, oidcRoute, sessionRoute, authzRoute, adminRoute, userRoute, roleRoute, orgRoute, profileRoute, accountRoute, privacyRoute, blogRoute, docsRoute}
+}
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	engine, describers := registerAPI(t)
+
+	documented := make(map[string]openapi.Operation)
+	for _, describer := range describers {
+		for _, op := range describer.Operations() {
+			documented[op.Method+" /api"+op.Path] = op
+		}
+	}
+
+	for _, route := range engine.Routes() {
+		if !strings.HasPrefix(route.Path, "/api/") {
+			continue
+		}
+		if _, ok := documented[route.Method+" "+route.Path]; !ok {
+			t.Errorf("%s %s is registered but not documented", route.Method, route.Path)
+		}
+	}
+
+	if _, err := openapi.Build(openapi.Info{Title: "test", Version: "test"}, engine.Routes(), "/api", describers...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOperationsFollowRegistration(t *testing.T) {
+	_, describers := registerAPI(t)
+
+	ops := make(map[string]openapi.Operation)
+	for _, describer := range describers {
+		for _, op := range describer.Operations() {
+			ops[op.Method+" "+op.Path] = op
+		}
+	}
+
+	tests := []struct {
+		key        string
+		permission string
+		auth       bool
+		rateLimit  string
+	}{
+		{key: "GET /blogs", rateLimit: "blogs"},
+		{key: "PUT /blogs/:id", permission: "blog:update", auth: true, rateLimit: "blogs"},
+		{key: "POST /auth/login", rateLimit: "auth"},
+		{key: "GET /auth/me", auth: true, rateLimit: "users"},
+		{key: "DELETE /admin/invitations/:invitation_id", permission: "user:invite", auth: true, rateLimit: "users"},
+		{key: "GET /openapi.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			op, ok := ops[tt.key]
+			if !ok {
+				t.Fatalf("%s is not documented", tt.key)
+			}
+			if op.Permission != tt.permission || op.Auth != tt.auth || op.RateLimit != tt.rateLimit {
+				t.Errorf("got permission %q, auth %v, rate limit %q; want %q, %v, %q", op.Permission, op.Auth, op.RateLimit, tt.permission, tt.auth, tt.rateLimit)
+			}
+		})
+	}
+}
+
+func TestUnregisteredRouteFailsBuild(t *testing.T) {
+	engine, describers := registerAPI(t)
+	engine.Handle(http.MethodGet, "/api/undocumented", func(*gin.Context) {})
+
+	if _, err := openapi.Build(openapi.Info{}, engine.Routes(), "/api", describers...); err == nil || !strings.Contains(err.Error(), "GET /api/undocumented") {
+		t.Errorf("Build error = %v, want the undocumented route reported", err)
+	}
+}
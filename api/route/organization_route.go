@@ -0,0 +1,51 @@
+package route
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/middleware"
+	"github.com/userblog/management/api/openapi"
+	"github.com/userblog/management/internal/models"
+)
+
+type OrganizationRoute struct {
+	orgController   controller.IOrganizationController
+	authMiddleware  middleware.IAuthMiddleware
+	authzMiddleware middleware.IAuthzMiddleware
+	rateLimiter     middleware.IRateLimitMiddleware
+	*operations
+}
+
+func NewOrganizationRoute(orgController controller.IOrganizationController, authMiddleware middleware.IAuthMiddleware, authzMiddleware middleware.IAuthzMiddleware, rateLimiter middleware.IRateLimitMiddleware) OrganizationRoute {
+	return OrganizationRoute{
+		orgController:   orgController,
+		authMiddleware:  authMiddleware,
+		authzMiddleware: authzMiddleware,
+		rateLimiter:     rateLimiter,
+		operations:      &operations{},
+	}
+}
+
+func (r OrganizationRoute) OrganizationRoute(rg *gin.RouterGroup) {
+	router := newRoutes(rg, r.operations, r.authzMiddleware, "organizations")
+
+	// Organizations of the signed-in user
+	orgs := router.sub("/orgs").auth(r.authMiddleware.JWTAuth()).limit(r.rateLimiter, "users")
+	orgs.get("", "", openapi.Operation{Summary: "List the organizations the current user belongs to", Response: []dto.MembershipResponse{}}, r.orgController.ListMine)
+	orgs.post("", "organization:create", openapi.Operation{Summary: "Create an organization owned by the current user", Request: dto.CreateOrganizationRequest{}, Status: http.StatusCreated, Response: dto.OrganizationResponse{}}, r.authMiddleware.DenyImpersonation(), r.orgController.Create)
+	orgs.post("/invitations/accept", "", openapi.Operation{Summary: "Join an organization with an invitation token", Request: dto.AcceptInvitationRequest{}, Response: dto.MembershipResponse{}}, r.authMiddleware.DenyImpersonation(), r.orgController.AcceptInvitation)
+
+	// The organization the request is made in
+	org := router.sub("/org").auth(r.authMiddleware.JWTAuth()).limit(r.rateLimiter, "users")
+	org.get("", "", openapi.Operation{Summary: "Get the current organization", Response: dto.OrganizationResponse{}}, r.orgController.Current)
+	org.put("", "organization:update", openapi.Operation{Summary: "Update the current organization's settings", Request: dto.UpdateOrganizationRequest{}, Response: dto.OrganizationResponse{}}, r.orgController.Update)
+	org.get("/members", "organization:read", openapi.Operation{Summary: "List members of the current organization", Params: pageParams, Response: openapi.Page{Item: dto.MemberResponse{}}}, r.orgController.ListMembers)
+	org.put("/members/:user_id", "organization:update", openapi.Operation{Summary: "Change a member's organization role", Params: idParam("user_id"), Request: dto.UpdateMemberRequest{}, Response: openapi.Message{}}, r.orgController.UpdateMember)
+	org.delete("/members/:user_id", "organization:update", openapi.Operation{Summary: "Remove a member from the current organization", Params: idParam("user_id"), Response: openapi.Message{}}, r.orgController.RemoveMember)
+	org.get("/invitations", "organization:invite", openapi.Operation{Summary: "List pending invitations to the current organization", Response: []models.Invitation{}}, r.orgController.ListInvitations)
+	org.post("/invitations", "organization:invite", openapi.Operation{Summary: "Invite someone to the current organization by email", Request: dto.InviteMemberRequest{}, Status: http.StatusCreated, Response: models.Invitation{}}, r.authMiddleware.DenyImpersonation(), r.orgController.Invite)
+	org.delete("/invitations/:id", "organization:invite", openapi.Operation{Summary: "Revoke a pending invitation", Params: idParam("id"), Response: openapi.Message{}}, r.authMiddleware.DenyImpersonation(), r.orgController.RevokeInvitation)
+}
@@ -0,0 +1,29 @@
+package route
+
+import "github.com/userblog/management/api/openapi"
+
+// Query parameters shared by the documented list and read endpoints
+var (
+	pageParams = []openapi.Param{
+		{Name: "page", In: "query", Type: "integer", Description: "Page number, starting at 1"},
+		{Name: "per_page", In: "query", Type: "integer", Description: "Items per page, at most 100"},
+	}
+	shapeParams = []openapi.Param{
+		{Name: "fields", In: "query", Description: "Comma separated attributes to return"},
+		{Name: "include", In: "query", Description: "Comma separated relations to load"},
+	}
+)
+
+// params concatenates parameter lists
+func params(lists ...[]openapi.Param) []openapi.Param {
+	var result []openapi.Param
+	for _, list := range lists {
+		result = append(result, list...)
+	}
+	return result
+}
+
+// idParam documents a numeric path parameter
+func idParam(name string) []openapi.Param {
+	return []openapi.Param{{Name: name, In: "path", Type: "integer"}}
+}
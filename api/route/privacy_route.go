@@ -0,0 +1,54 @@
+package route
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/middleware"
+	"github.com/userblog/management/api/openapi"
+	"github.com/userblog/management/internal/models"
+)
+
+type PrivacyRoute struct {
+	privacyController controller.IPrivacyController
+	authMiddleware    middleware.IAuthMiddleware
+	authzMiddleware   middleware.IAuthzMiddleware
+	rateLimiter       middleware.IRateLimitMiddleware
+	*operations
+}
+
+func NewPrivacyRoute(privacyController controller.IPrivacyController, authMiddleware middleware.IAuthMiddleware, authzMiddleware middleware.IAuthzMiddleware, rateLimiter middleware.IRateLimitMiddleware) PrivacyRoute {
+	return PrivacyRoute{
+		privacyController: privacyController,
+		authMiddleware:    authMiddleware,
+		authzMiddleware:   authzMiddleware,
+		rateLimiter:       rateLimiter,
+		operations:        &operations{},
+	}
+}
+
+func (r PrivacyRoute) PrivacyRoute(rg *gin.RouterGroup) {
+	router := newRoutes(rg, r.operations, r.authzMiddleware, "privacy")
+
+	// Data subjects' own requests; impersonators can't see or make them
+	me := router.sub("/me").auth(r.authMiddleware.JWTAuth()).limit(r.rateLimiter, "users").use(r.authMiddleware.DenyImpersonation())
+	me.get("/data-requests", "", openapi.Operation{Summary: "List the current user's data export and erasure requests", Response: []models.DataRequest{}}, r.privacyController.ListOwn)
+	me.post("/data-requests/export", "", openapi.Operation{Summary: "Request a ZIP export of everything stored about the current user", Status: http.StatusAccepted, Response: models.DataRequest{}}, r.privacyController.RequestExport)
+	me.post("/data-requests/erasure", "", openapi.Operation{Summary: "Request erasure of the current user's data, subject to approval", Request: dto.ErasureRequest{}, Status: http.StatusAccepted, Response: models.DataRequest{}}, r.privacyController.RequestErasure)
+	me.get("/data-requests/:id/download", "", openapi.Operation{Summary: "Download a completed data export", Params: idParam("id"), Response: []byte{}, ContentType: "application/zip"}, r.privacyController.Download)
+
+	// Review of every user's requests
+	listParams := []openapi.Param{
+		{Name: "user_id", In: "query", Type: "integer", Description: "User the requests are about"},
+		{Name: "kind", In: "query", Description: "export or erasure"},
+		{Name: "status", In: "query", Description: "pending, rejected, queued, processing, completed, failed or expired"},
+	}
+	admin := router.sub("/admin/data-requests").auth(r.authMiddleware.JWTAuth()).limit(r.rateLimiter, "users")
+	admin.get("", "data_request:read", openapi.Operation{Summary: "List data export and erasure requests, newest first", Params: params(listParams, pageParams), Response: openapi.Page{Item: models.DataRequest{}}}, r.privacyController.List)
+	admin.get("/:id", "data_request:read", openapi.Operation{Summary: "Get a data request and its status", Params: idParam("id"), Response: models.DataRequest{}}, r.privacyController.Get)
+	admin.get("/:id/certificate", "data_request:read", openapi.Operation{Summary: "Get the certificate of a completed erasure", Params: idParam("id"), Response: models.ErasureCertificate{}}, r.privacyController.Certificate)
+	admin.post("/:id/approve", "data_request:review", openapi.Operation{Summary: "Approve an erasure request, queueing it for processing", Params: idParam("id"), Request: dto.ReviewDataRequest{}, Response: models.DataRequest{}}, r.authMiddleware.DenyImpersonation(), r.privacyController.Approve)
+	admin.post("/:id/reject", "data_request:review", openapi.Operation{Summary: "Reject an erasure request", Params: idParam("id"), Request: dto.ReviewDataRequest{}, Response: models.DataRequest{}}, r.authMiddleware.DenyImpersonation(), r.privacyController.Reject)
+}
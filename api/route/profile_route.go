@@ -0,0 +1,43 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/middleware"
+	"github.com/userblog/management/api/openapi"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/service"
+)
+
+type ProfileRoute struct {
+	profileController controller.IProfileController
+	authMiddleware    middleware.IAuthMiddleware
+	rateLimiter       middleware.IRateLimitMiddleware
+	*operations
+}
+
+func NewProfileRoute(profileController controller.IProfileController, authMiddleware middleware.IAuthMiddleware, rateLimiter middleware.IRateLimitMiddleware) ProfileRoute {
+	return ProfileRoute{
+		profileController: profileController,
+		authMiddleware:    authMiddleware,
+		rateLimiter:       rateLimiter,
+		operations:        &operations{},
+	}
+}
+
+func (r ProfileRoute) ProfileRoute(rg *gin.RouterGroup) {
+	router := newRoutes(rg, r.operations, nil, "profiles")
+
+	// Public author pages and avatars
+	public := router.sub("").limit(r.rateLimiter, "blogs")
+	public.get("/authors/:username", "", openapi.Operation{Summary: "Get the public profile of an author with their published post count", Response: service.Author{}}, r.profileController.Author)
+	public.get("/avatars/:name", "", openapi.Operation{Summary: "Get an avatar image", Response: []byte{}, ContentType: "image/*"}, r.profileController.Avatar)
+
+	// The signed-in user's own profile
+	me := router.sub("/me").auth(r.authMiddleware.JWTAuth()).limit(r.rateLimiter, "users")
+	me.get("/profile", "", openapi.Operation{Summary: "Get the current user's profile", Response: models.Profile{}}, r.profileController.Get)
+	me.put("/profile", "", openapi.Operation{Summary: "Update the current user's profile", Request: dto.UpdateProfileRequest{}, Response: models.Profile{}}, r.authMiddleware.DenyImpersonation(), r.profileController.Update)
+	me.put("/avatar", "", openapi.Operation{Summary: "Upload an avatar, which is cropped square and scaled down", Request: dto.AvatarUploadRequest{}, RequestType: "multipart/form-data", Response: models.Profile{}}, r.authMiddleware.DenyImpersonation(), r.profileController.UploadAvatar)
+	me.delete("/avatar", "", openapi.Operation{Summary: "Remove the current user's avatar", Response: openapi.Message{}}, r.authMiddleware.DenyImpersonation(), r.profileController.RemoveAvatar)
+}
@@ -0,0 +1,40 @@
+package route
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/middleware"
+	"github.com/userblog/management/api/openapi"
+	"github.com/userblog/management/internal/models"
+)
+
+type RoleRoute struct {
+	roleController  controller.IRoleController
+	authMiddleware  middleware.IAuthMiddleware
+	authzMiddleware middleware.IAuthzMiddleware
+	rateLimiter     middleware.IRateLimitMiddleware
+	*operations
+}
+
+func NewRoleRoute(roleController controller.IRoleController, authMiddleware middleware.IAuthMiddleware, authzMiddleware middleware.IAuthzMiddleware, rateLimiter middleware.IRateLimitMiddleware) RoleRoute {
+	return RoleRoute{
+		roleController:  roleController,
+		authMiddleware:  authMiddleware,
+		authzMiddleware: authzMiddleware,
+		rateLimiter:     rateLimiter,
+		operations:      &operations{},
+	}
+}
+
+func (r RoleRoute) RoleRoute(rg *gin.RouterGroup) {
+	router := newRoutes(rg, r.operations, r.authzMiddleware, "roles").sub("/roles")
+	router.auth(r.authMiddleware.JWTAuth()).limit(r.rateLimiter, "users")
+
+	router.get("", "role:read", openapi.Operation{Summary: "List roles", Response: []models.Role{}}, r.roleController.List)
+	router.get("/permissions", "role:read", openapi.Operation{Summary: "List the permissions roles can be granted", Response: []models.Permission{}}, r.roleController.ListPermissions)
+	router.post("", "role:create", openapi.Operation{Summary: "Create a role", Request: dto.CreateRoleRequest{}, Status: http.StatusCreated, Response: models.Role{}}, r.authMiddleware.DenyImpersonation(), r.roleController.Create)
+	router.put("/:id", "role:update", openapi.Operation{Summary: "Update a role", Params: idParam("id"), Request: dto.UpdateRoleRequest{}, Response: models.Role{}}, r.authMiddleware.DenyImpersonation(), r.roleController.Update)
+}
@@ -0,0 +1,110 @@
+package route
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/middleware"
+	"github.com/userblog/management/api/openapi"
+)
+
+// operations collects the operations a route documents as it registers them. Routes embed it to
+// implement openapi.Describer.
+type operations struct {
+	list []openapi.Operation
+}
+
+// Operations returns the operations registered so far
+func (o *operations) Operations() []openapi.Operation {
+	return o.list
+}
+
+// routes registers handlers on a router group and documents each one from its registration, so
+// the method, path, permission, authentication and rate limit in the OpenAPI document can't
+// drift from what the router does
+type routes struct {
+	group *gin.RouterGroup
+	root  string // base path of the API, which documented paths are relative to
+	authz middleware.IAuthzMiddleware
+	docs  *operations
+	// defaults holds the tags, authentication and rate limit shared by the group's operations
+	defaults openapi.Operation
+}
+
+// newRoutes starts registering a route's handlers on the API group rg
+func newRoutes(rg *gin.RouterGroup, docs *operations, authz middleware.IAuthzMiddleware, tags ...string) *routes {
+	return &routes{
+		group:    rg,
+		root:     rg.BasePath(),
+		authz:    authz,
+		docs:     docs,
+		defaults: openapi.Operation{Tags: tags},
+	}
+}
+
+// sub returns routes registering on a subgroup, which starts with the group's middleware
+func (r *routes) sub(relativePath string) *routes {
+	sub := *r
+	sub.group = r.group.Group(relativePath)
+	return &sub
+}
+
+// auth requires a bearer token, checked by the authentication middleware, on later routes
+func (r *routes) auth(handler gin.HandlerFunc) *routes {
+	r.group.Use(handler)
+	r.defaults.Auth = true
+	return r
+}
+
+// limit applies the named rate limit policy to later routes
+func (r *routes) limit(limiter middleware.IRateLimitMiddleware, policy string) *routes {
+	r.group.Use(limiter.Limit(policy))
+	r.defaults.RateLimit = policy
+	return r
+}
+
+// use adds middleware to later routes that doesn't change how they are documented
+func (r *routes) use(handlers ...gin.HandlerFunc) *routes {
+	r.group.Use(handlers...)
+	return r
+}
+
+func (r *routes) get(relativePath, permission string, op openapi.Operation, handlers ...gin.HandlerFunc) {
+	r.handle(http.MethodGet, relativePath, permission, op, handlers...)
+}
+
+func (r *routes) post(relativePath, permission string, op openapi.Operation, handlers ...gin.HandlerFunc) {
+	r.handle(http.MethodPost, relativePath, permission, op, handlers...)
+}
+
+func (r *routes) put(relativePath, permission string, op openapi.Operation, handlers ...gin.HandlerFunc) {
+	r.handle(http.MethodPut, relativePath, permission, op, handlers...)
+}
+
+func (r *routes) delete(relativePath, permission string, op openapi.Operation, handlers ...gin.HandlerFunc) {
+	r.handle(http.MethodDelete, relativePath, permission, op, handlers...)
+}
+
+// handle registers the handlers, the last of which serves the request, and documents them with op.
+// A permission, given as resource:action, is checked by the access policy just before the last
+// handler; an empty one means any caller the group lets through may use the route.
+func (r *routes) handle(method, relativePath, permission string, op openapi.Operation, handlers ...gin.HandlerFunc) {
+	if permission != "" {
+		resource, action, _ := strings.Cut(permission, ":")
+		last := len(handlers) - 1
+		handlers = append(handlers[:last:last], r.authz.Authorize(resource, action), handlers[last])
+	}
+	r.group.Handle(method, relativePath, handlers...)
+
+	op.Method = method
+	op.Path = strings.TrimPrefix(path.Join(r.group.BasePath(), relativePath), r.root)
+	op.Permission = permission
+	op.Auth = r.defaults.Auth
+	op.RateLimit = r.defaults.RateLimit
+	if op.Tags == nil {
+		op.Tags = r.defaults.Tags
+	}
+	r.docs.list = append(r.docs.list, op)
+}
@@ -0,0 +1,34 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
+	"github.com/userblog/management/api/middleware"
+	"github.com/userblog/management/api/openapi"
+)
+
+type SessionRoute struct {
+	sessionController controller.ISessionController
+	authMiddleware    middleware.IAuthMiddleware
+	rateLimiter       middleware.IRateLimitMiddleware
+	*operations
+}
+
+func NewSessionRoute(sessionController controller.ISessionController, authMiddleware middleware.IAuthMiddleware, rateLimiter middleware.IRateLimitMiddleware) SessionRoute {
+	return SessionRoute{
+		sessionController: sessionController,
+		authMiddleware:    authMiddleware,
+		rateLimiter:       rateLimiter,
+		operations:        &operations{},
+	}
+}
+
+func (r SessionRoute) SessionRoute(rg *gin.RouterGroup) {
+	router := newRoutes(rg, r.operations, nil, "auth").sub("/auth/sessions")
+	router.auth(r.authMiddleware.JWTAuth()).limit(r.rateLimiter, "users")
+
+	router.get("", "", openapi.Operation{Summary: "List the current user's sessions", Response: []dto.SessionResponse{}}, r.sessionController.List)
+	router.delete("", "", openapi.Operation{Summary: "Log out every session except the current one", Response: dto.RevokeSessionsResponse{}}, r.authMiddleware.DenyImpersonation(), r.sessionController.RevokeOthers)
+	router.delete("/:id", "", openapi.Operation{Summary: "Log out a session", Params: idParam("id"), Response: openapi.Message{}}, r.authMiddleware.DenyImpersonation(), r.sessionController.Revoke)
+}
@@ -1,30 +1,43 @@
 package route
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 	"github.com/userblog/management/api/controller"
+	"github.com/userblog/management/api/dto"
 	"github.com/userblog/management/api/middleware"
+	"github.com/userblog/management/api/openapi"
+	"github.com/userblog/management/internal/models"
 )
 
 type UserRoute struct {
-	userController controller.IUserController
-	authMiddleware middleware.IAuthMiddleware
+	userController  controller.IUserController
+	authMiddleware  middleware.IAuthMiddleware
+	authzMiddleware middleware.IAuthzMiddleware
+	rateLimiter     middleware.IRateLimitMiddleware
+	*operations
 }
 
-func NewUserRoute(userController controller.IUserController, authMiddleware middleware.IAuthMiddleware) UserRoute {
+func NewUserRoute(userController controller.IUserController, authMiddleware middleware.IAuthMiddleware, authzMiddleware middleware.IAuthzMiddleware, rateLimiter middleware.IRateLimitMiddleware) UserRoute {
 	return UserRoute{
-		userController: userController,
-		authMiddleware: authMiddleware,
+		userController:  userController,
+		authMiddleware:  authMiddleware,
+		authzMiddleware: authzMiddleware,
+		rateLimiter:     rateLimiter,
+		operations:      &operations{},
 	}
 }
 
 func (r UserRoute) UserRoute(rg *gin.RouterGroup) {
-	router := rg.Group("/users")
-	router.Use(r.authMiddleware.JWTAuth())
+	router := newRoutes(rg, r.operations, r.authzMiddleware, "users").sub("/users")
+	router.auth(r.authMiddleware.JWTAuth()).limit(r.rateLimiter, "users")
 
-	router.GET("", r.authMiddleware.RequirePermission("user", "read"), r.userController.List)
-	router.GET("/:id", r.authMiddleware.RequirePermission("user", "read"), r.userController.GetByID)
-	router.POST("", r.authMiddleware.RequirePermission("user", "create"), r.userController.Create)
-	router.PUT("/:id", r.authMiddleware.RequirePermission("user", "update"), r.userController.Update)
-	router.DELETE("/:id", r.authMiddleware.RequirePermission("user", "delete"), r.userController.Delete)
+	router.get("", "user:read", openapi.Operation{Summary: "List users", Params: params(pageParams, shapeParams), Response: openapi.Page{Item: models.User{}}}, r.userController.List)
+	router.get("/:id", "user:read", openapi.Operation{Summary: "Get a user by ID", Params: params(idParam("id"), shapeParams), Response: models.User{}}, r.userController.GetByID)
+	router.post("", "user:create", openapi.Operation{Summary: "Create a user", Request: dto.CreateUserRequest{}, Status: http.StatusCreated, Response: models.User{}}, r.userController.Create)
+	router.put("/:id", "user:update", openapi.Operation{Summary: "Update a user", Params: idParam("id"), Request: dto.UpdateUserRequest{}, Response: models.User{}}, r.userController.Update)
+	router.delete("/:id", "user:delete", openapi.Operation{Summary: "Delete a user", Params: idParam("id"), Response: openapi.Message{}}, r.userController.Delete)
+	router.post("/:id/unlock", "user:update", openapi.Operation{Summary: "Clear a login lockout", Params: idParam("id"), Response: openapi.Message{}}, r.userController.Unlock)
+	router.get("/:id/permissions", "user:read", openapi.Operation{Summary: "Get a user's effective permissions", Params: idParam("id"), Response: dto.EffectivePermissionsResponse{}}, r.userController.Permissions)
 }
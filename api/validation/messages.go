@@ -0,0 +1,122 @@
+package validation
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is used when the request doesn't ask for a supported language
+const DefaultLanguage = "en"
+
+// messages holds the validation message templates per language.
+// {field} and {param} are replaced with the field name and the rule parameter.
+var messages = map[string]map[string]string{
+	"en": {
+		"summary":     "The request contains invalid fields",
+		"required":    "{field} is required",
+		"email":       "{field} must be a valid email address",
+		"url":         "{field} must be a valid URL",
+		"http_url":    "{field} must be an http or https URL",
+		"min":         "{field} must be at least {param}",
+		"max":         "{field} must be at most {param}",
+		"min.string":  "{field} must be at least {param} characters long",
+		"max.string":  "{field} must be at most {param} characters long",
+		"oneof":       "{field} must be one of: {param}",
+		"username":    "{field} may only contain letters, digits, '_', '.' and '-'",
+		"password":    "{field} must be at least 8 characters long and contain letters and digits",
+		"notreserved": "{field} is reserved and can't be used",
+		"slug":        "{field} may only contain lowercase letters, digits and '-', and can't start or end with '-'",
+		"type":        "{field} must be of type {param}",
+		"json":        "The request body is not valid JSON",
+		"default":     "{field} is invalid",
+	},
+	"es": {
+		"summary":     "La solicitud contiene campos no válidos",
+		"required":    "{field} es obligatorio",
+		"email":       "{field} debe ser un correo electrónico válido",
+		"url":         "{field} debe ser una URL válida",
+		"http_url":    "{field} debe ser una URL http o https",
+		"min":         "{field} debe ser al menos {param}",
+		"max":         "{field} debe ser como máximo {param}",
+		"min.string":  "{field} debe tener al menos {param} caracteres",
+		"max.string":  "{field} debe tener como máximo {param} caracteres",
+		"oneof":       "{field} debe ser uno de: {param}",
+		"username":    "{field} solo puede contener letras, dígitos, '_', '.' y '-'",
+		"password":    "{field} debe tener al menos 8 caracteres e incluir letras y dígitos",
+		"notreserved": "{field} está reservado y no se puede usar",
+		"slug":        "{field} solo puede contener minúsculas, dígitos y '-', y no puede empezar ni terminar con '-'",
+		"type":        "{field} debe ser de tipo {param}",
+		"json":        "El cuerpo de la solicitud no es JSON válido",
+		"default":     "{field} no es válido",
+	},
+	"fr": {
+		"summary":     "La requête contient des champs invalides",
+		"required":    "{field} est obligatoire",
+		"email":       "{field} doit être une adresse e-mail valide",
+		"url":         "{field} doit être une URL valide",
+		"http_url":    "{field} doit être une URL http ou https",
+		"min":         "{field} doit être au moins {param}",
+		"max":         "{field} doit être au plus {param}",
+		"min.string":  "{field} doit contenir au moins {param} caractères",
+		"max.string":  "{field} doit contenir au plus {param} caractères",
+		"oneof":       "{field} doit être l'une des valeurs : {param}",
+		"username":    "{field} ne peut contenir que des lettres, des chiffres, '_', '.' et '-'",
+		"password":    "{field} doit contenir au moins 8 caractères, dont des lettres et des chiffres",
+		"notreserved": "{field} est réservé et ne peut pas être utilisé",
+		"slug":        "{field} ne peut contenir que des minuscules, des chiffres et '-', sans commencer ni finir par '-'",
+		"type":        "{field} doit être de type {param}",
+		"json":        "Le corps de la requête n'est pas un JSON valide",
+		"default":     "{field} est invalide",
+	},
+}
+
+// Translate renders the message for key in lang, falling back to English and then to the default message
+func Translate(lang, key, field, param string) string {
+	template, ok := messages[lang][key]
+	if !ok {
+		template, ok = messages[DefaultLanguage][key]
+	}
+	if !ok {
+		template = messages[DefaultLanguage]["default"]
+	}
+
+	return strings.NewReplacer("{field}", field, "{param}", param).Replace(template)
+}
+
+// Language picks the best supported language from an Accept-Language header
+func Language(header string) string {
+	type candidate struct {
+		lang    string
+		quality float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+
+		quality := 1.0
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+
+		base := strings.ToLower(strings.SplitN(tag, "-", 2)[0])
+		candidates = append(candidates, candidate{lang: base, quality: quality})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	for _, c := range candidates {
+		if _, ok := messages[c.lang]; ok && c.quality > 0 {
+			return c.lang
+		}
+	}
+	return DefaultLanguage
+}
@@ -0,0 +1,159 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/config"
+)
+
+// UsernamePattern is the character set allowed in usernames
+var UsernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// SlugPattern is the form of organization slugs, which can be used as subdomains
+var SlugPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// PasswordMinLength is the minimum length enforced by the password validator
+const PasswordMinLength = 8
+
+// init registers JSON field names and the custom validators with gin's validator
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	// Report fields by their JSON name instead of the Go field name
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	_ = v.RegisterValidation("username", validateUsername)
+	_ = v.RegisterValidation("password", validatePassword)
+	_ = v.RegisterValidation("notreserved", validateNotReserved)
+	_ = v.RegisterValidation("slug", validateSlug)
+}
+
+// validateUsername checks that a username only uses letters, digits, '_', '.' and '-'
+func validateUsername(fl validator.FieldLevel) bool {
+	return UsernamePattern.MatchString(fl.Field().String())
+}
+
+// validateSlug checks that a slug only uses lowercase letters, digits and inner '-'
+func validateSlug(fl validator.FieldLevel) bool {
+	return SlugPattern.MatchString(fl.Field().String())
+}
+
+// validatePassword checks that a password is long enough and mixes letters and digits
+func validatePassword(fl validator.FieldLevel) bool {
+	password := fl.Field().String()
+	if len(password) < PasswordMinLength {
+		return false
+	}
+
+	hasLetter, hasDigit := false, false
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	return hasLetter && hasDigit
+}
+
+// validateNotReserved checks that a username is not on the configured reserved list
+func validateNotReserved(fl validator.FieldLevel) bool {
+	return !ReservedUsername(fl.Field().String())
+}
+
+// ReservedUsername reports whether a username is on the configured reserved list, ignoring case.
+// Services check it where a binding tag can't, such as usernames chosen for single sign-on.
+func ReservedUsername(username string) bool {
+	for _, reserved := range config.Current().Validation.ReservedUsernames {
+		if strings.EqualFold(username, strings.TrimSpace(reserved)) {
+			return true
+		}
+	}
+	return false
+}
+
+// BindJSON binds the request body into obj and converts binding failures into a
+// ValidationError with field details translated to the request's Accept-Language
+func BindJSON(ctx *gin.Context, obj interface{}) error {
+	err := ctx.ShouldBindJSON(obj)
+	if err == nil {
+		return nil
+	}
+
+	lang := Language(ctx.GetHeader("Accept-Language"))
+	return service.NewValidationError(Translate(lang, "summary", "", ""), FieldErrors(err, lang)...)
+}
+
+// FieldErrors converts a binding error into field errors with messages in lang
+func FieldErrors(err error, lang string) []service.FieldError {
+	var validationErrors validator.ValidationErrors
+	var typeError *json.UnmarshalTypeError
+	var syntaxError *json.SyntaxError
+
+	switch {
+	case errors.As(err, &validationErrors):
+		fields := make([]service.FieldError, 0, len(validationErrors))
+		for _, fe := range validationErrors {
+			field := fieldPath(fe.Namespace())
+			key := fe.Tag()
+			if (key == "min" || key == "max") && fe.Kind() == reflect.String {
+				key += ".string"
+			}
+			fields = append(fields, service.FieldError{
+				Field:   field,
+				Code:    fe.Tag(),
+				Message: Translate(lang, key, field, fe.Param()),
+			})
+		}
+		return fields
+	case errors.As(err, &typeError):
+		return []service.FieldError{{
+			Field:   typeError.Field,
+			Code:    "type",
+			Message: Translate(lang, "type", typeError.Field, typeError.Type.String()),
+		}}
+	case errors.As(err, &syntaxError), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return []service.FieldError{{
+			Field:   "",
+			Code:    "json",
+			Message: Translate(lang, "json", "", ""),
+		}}
+	default:
+		return []service.FieldError{{
+			Field:   "",
+			Code:    "invalid",
+			Message: err.Error(),
+		}}
+	}
+}
+
+// fieldPath strips the top-level struct name from a validator namespace such as CreateUserRequest.email
+func fieldPath(namespace string) string {
+	if i := strings.Index(namespace, "."); i != -1 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
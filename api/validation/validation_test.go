@@ -0,0 +1,40 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/userblog/management/pkg/config"
+)
+
+func TestReservedUsername(t *testing.T) {
+	previous := config.Current()
+	cfg := config.Default()
+	cfg.Validation.ReservedUsernames = []string{"admin", " Support "}
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(previous) })
+
+	tests := []struct {
+		username string
+		want     bool
+	}{
+		{username: "admin", want: true},
+		{username: "ADMIN", want: true},
+		{username: "support", want: true},
+		{username: "admin2", want: false},
+		{username: "alice", want: false},
+	}
+	for _, tt := range tests {
+		if got := ReservedUsername(tt.username); got != tt.want {
+			t.Errorf("ReservedUsername(%q) = %v, want %v", tt.username, got, tt.want)
+		}
+
+		// The notreserved tag agrees with the services
+		req := struct {
+			Username string `binding:"notreserved"`
+		}{Username: tt.username}
+		if err := binding.Validator.ValidateStruct(req); (err != nil) != tt.want {
+			t.Errorf("notreserved on %q: %v", tt.username, err)
+		}
+	}
+}
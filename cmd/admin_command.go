@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	_ "github.com/userblog/management/api/validation" // registers the username and password validators
+	"github.com/userblog/management/internal/models"
+	repoImpl "github.com/userblog/management/internal/repository/impl"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/db"
+)
+
+// adminAccount is the administrator created by "admin create", validated like a registration
+type adminAccount struct {
+	Username string `json:"username" binding:"required,min=3,max=30,username"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,password"`
+}
+
+// runAdminCommand implements "admin create -username name -email address", creating a user with
+// the admin role. The password is read from standard input so it stays out of the shell history
+// and the process list. Registration never grants the admin role, so this is how a new
+// installation gets its first administrator.
+func runAdminCommand(args []string) int {
+	if len(args) == 0 || args[0] != "create" {
+		fmt.Fprintln(os.Stderr, "usage: main admin create -username name -email address [-config path] [-set key=value] < password")
+		return 2
+	}
+
+	var opts options
+	fs := flag.NewFlagSet("admin create", flag.ContinueOnError)
+	opts.registerFlags(fs)
+	var account adminAccount
+	fs.StringVar(&account.Username, "username", "", "username of the administrator")
+	fs.StringVar(&account.Email, "email", "", "email address of the administrator")
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		fmt.Fprintln(os.Stderr, "failed to read the password from standard input:", err)
+		return 1
+	}
+	account.Password = strings.TrimRight(password, "\r\n")
+
+	if err := binding.Validator.ValidateStruct(&account); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	cfg, err := opts.load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	config.Set(cfg)
+
+	ctx := context.Background()
+	database := db.Connect(cfg.Database)
+	defer database.Close()
+
+	// The admin role is seeded with the schema
+	initializeDatabaseScript(ctx, database)
+
+	var role models.Role
+	if err := database.Where("name = ?", "admin").First(&role).Error; err != nil {
+		fmt.Fprintln(os.Stderr, "failed to find the admin role:", err)
+		return 1
+	}
+
+	userRepo := repoImpl.NewUserRepository(database)
+	if _, err := userRepo.FindByUsername(ctx, account.Username); err == nil {
+		fmt.Fprintf(os.Stderr, "username %q already exists\n", account.Username)
+		return 1
+	}
+	if _, err := userRepo.FindByEmail(ctx, account.Email); err == nil {
+		fmt.Fprintf(os.Stderr, "email %q already exists\n", account.Email)
+		return 1
+	}
+
+	user := &models.User{
+		Username: account.Username,
+		Email:    account.Email,
+		Password: account.Password,
+		RoleID:   role.ID,
+	}
+	if err := userRepo.Create(ctx, user); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to create the administrator:", err)
+		return 1
+	}
+
+	fmt.Printf("Created administrator %s (id %d)\n", user.Username, user.ID)
+	return 0
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/internal/service"
+	serviceImpl "github.com/userblog/management/internal/service/impl"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/logger"
+)
+
+// buildAuthenticators returns the password authenticators in auth.authenticators order and
+// starts the periodic profile sync of the directory, if one is used
+func buildAuthenticators(ctx context.Context, background *jobs, cfg *config.Config, userRepo repository.IUserRepository, roleRepo repository.IRoleRepository, identityRepo repository.IIdentityRepository, principals service.IPrincipalService, auditService service.IAuditService) []service.IAuthenticator {
+	var authenticators []service.IAuthenticator
+	for _, name := range cfg.Auth.Authenticators {
+		switch name {
+		case "database":
+			authenticators = append(authenticators, serviceImpl.NewDatabaseAuthenticator(userRepo))
+		case "ldap":
+			ldap := serviceImpl.NewLDAPAuthenticator(userRepo, roleRepo, identityRepo, principals, auditService, cfg.LDAP)
+			// A disabled sync returns at once, which would fail the scheduler readiness check
+			if cfg.LDAP.SyncInterval > 0 {
+				background.Go("directory sync", func(jobCtx context.Context) { ldap.RunSync(jobCtx, cfg.LDAP.SyncInterval) })
+			}
+			authenticators = append(authenticators, ldap)
+		}
+	}
+
+	logger.InfoF(ctx, "Password logins are checked by: %v", cfg.Auth.Authenticators)
+	return authenticators
+}
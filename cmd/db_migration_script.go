@@ -2,44 +2,43 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"github.com/jinzhu/gorm"
 	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/health"
 )
 
 // initializeDatabaseScript creates default roles and permissions
 func initializeDatabaseScript(ctx context.Context, db *gorm.DB) {
-	db.Debug().AutoMigrate(&models.User{}, &models.Role{}, &models.Permission{}, &models.Blog{})
+	db.Debug().AutoMigrate(models.All()...)
 
-	// Create admin role if it doesn't exist
-	var adminRole models.Role
-	if db.Debug().Where("name = ?", "admin").First(&adminRole).RecordNotFound() {
-		adminRole = models.Role{
-			Name:        "admin",
-			Description: "Administrator with all permissions",
-		}
-		db.Create(&adminRole)
-	}
-
-	// Create user role if it doesn't exist
-	var userRole models.Role
-	if db.Debug().Where("name = ?", "user").First(&userRole).RecordNotFound() {
-		userRole = models.Role{
-			Name:        "user",
-			Description: "Regular user with limited permissions",
-		}
-		db.Debug().Create(&userRole)
-	}
-
-	// Create permissions
+	// Create permissions. The wildcards grant every action on a resource, or everything.
 	permissions := []models.Permission{
+		{Name: "manage_all", Description: "Can do everything", Resource: models.PermissionWildcard, Action: models.PermissionWildcard},
+		{Name: "manage_blogs", Description: "Can do everything with blog posts", Resource: "blog", Action: models.PermissionWildcard},
 		{Name: "create_blog", Description: "Can create blog posts", Resource: "blog", Action: "create"},
 		{Name: "read_blog", Description: "Can read blog posts", Resource: "blog", Action: "read"},
 		{Name: "update_blog", Description: "Can update blog posts", Resource: "blog", Action: "update"},
 		{Name: "delete_blog", Description: "Can delete blog posts", Resource: "blog", Action: "delete"},
+		{Name: "manage_users", Description: "Can do everything with users", Resource: "user", Action: models.PermissionWildcard},
 		{Name: "create_user", Description: "Can create users", Resource: "user", Action: "create"},
 		{Name: "read_user", Description: "Can read user information", Resource: "user", Action: "read"},
 		{Name: "update_user", Description: "Can update user information", Resource: "user", Action: "update"},
 		{Name: "delete_user", Description: "Can delete users", Resource: "user", Action: "delete"},
+		{Name: "impersonate_user", Description: "Can act as another user for support", Resource: "user", Action: "impersonate"},
+		{Name: "invite_user", Description: "Can invite people to register", Resource: "user", Action: "invite"},
+		{Name: "create_role", Description: "Can create roles", Resource: "role", Action: "create"},
+		{Name: "read_role", Description: "Can read roles and their permissions", Resource: "role", Action: "read"},
+		{Name: "update_role", Description: "Can change the permissions and parent of roles", Resource: "role", Action: "update"},
+		{Name: "read_audit", Description: "Can read the audit log", Resource: "audit", Action: "read"},
+		{Name: "read_data_request", Description: "Can read data export and erasure requests", Resource: "data_request", Action: "read"},
+		{Name: "review_data_request", Description: "Can approve or reject erasure requests", Resource: "data_request", Action: "review"},
+		{Name: "manage_organization", Description: "Can do everything with an organization", Resource: "organization", Action: models.PermissionWildcard},
+		{Name: "create_organization", Description: "Can create organizations", Resource: "organization", Action: "create"},
+		{Name: "read_organization", Description: "Can read an organization's members", Resource: "organization", Action: "read"},
+		{Name: "update_organization", Description: "Can change an organization's settings and members", Resource: "organization", Action: "update"},
+		{Name: "invite_member", Description: "Can invite members to an organization", Resource: "organization", Action: "invite"},
 	}
 
 	// Create permissions if they don't exist
@@ -55,21 +54,68 @@ func initializeDatabaseScript(ctx context.Context, db *gorm.DB) {
 		}
 	}
 
-	// Assign all permissions to admin role
-	db.Debug().Exec("DELETE FROM role_permissions WHERE role_id = ?", adminRole.ID)
-	for _, p := range permissions {
+	// Default roles get their permissions when first created, so changes made to them through
+	// the API survive restarts
+	adminRole := ensureRole(db, "admin", "Administrator with all permissions", "manage_all")
+	ensureRole(db, "user", "Regular user with limited permissions", "read_blog", "read_user")
+	ensureRole(db, "org_admin", "Administrator of an organization", "manage_organization", "manage_blogs")
+	authorRole := ensureRole(db, "author", "Organization member who writes blog posts", "create_blog", "read_blog", "read_organization")
+
+	// The admin role always has every permission, including ones added by later versions
+	grantPermissions(db, adminRole, "manage_all")
+
+	// Content created before organizations existed belongs to the default organization
+	defaultOrg := ensureOrganization(db, config.Current().Orgs.Default, authorRole)
+	db.Debug().Model(&models.Blog{}).Where("organization_id IS NULL OR organization_id = 0").UpdateColumn("organization_id", defaultOrg.ID)
+}
+
+// ensureOrganization creates the organization with the slug if it doesn't exist, and returns it
+func ensureOrganization(db *gorm.DB, slug string, defaultRole models.Role) models.Organization {
+	var org models.Organization
+	if db.Debug().Where("slug = ?", slug).First(&org).RecordNotFound() {
+		org = models.Organization{
+			Name:          slug,
+			Slug:          slug,
+			DefaultRoleID: defaultRole.ID,
+		}
+		db.Debug().Create(&org)
+	}
+	return org
+}
+
+// ensureRole creates the role with the named permissions if it doesn't exist, and returns it
+func ensureRole(db *gorm.DB, name, description string, permissions ...string) models.Role {
+	var role models.Role
+	if db.Debug().Where("name = ?", name).First(&role).RecordNotFound() {
+		role = models.Role{
+			Name:        name,
+			Description: description,
+		}
+		db.Debug().Create(&role)
+		grantPermissions(db, role, permissions...)
+	}
+	return role
+}
+
+// grantPermissions grants the named permissions to the role, skipping those it already has
+func grantPermissions(db *gorm.DB, role models.Role, names ...string) {
+	for _, name := range names {
 		var permission models.Permission
-		db.Debug().Where("name = ?", p.Name).First(&permission)
-		db.Debug().Exec("INSERT INTO role_permissions (role_id, permission_id) VALUES (?, ?)", adminRole.ID, permission.ID)
+		if db.Debug().Where("name = ?", name).First(&permission).RecordNotFound() {
+			continue
+		}
+		db.Debug().Model(&role).Association("Permissions").Append(permission)
 	}
+}
 
-	// Assign only read permissions to user role
-	db.Debug().Exec("DELETE FROM role_permissions WHERE role_id = ?", userRole.ID)
-	for _, p := range permissions {
-		if p.Action == "read" {
-			var permission models.Permission
-			db.Debug().Where("name = ?", p.Name).First(&permission)
-			db.Debug().Exec("INSERT INTO role_permissions (role_id, permission_id) VALUES (?, ?)", userRole.ID, permission.ID)
+// checkMigrations reports a pending migration when any model's table is missing
+func checkMigrations(db *gorm.DB) health.CheckFunc {
+	return func(ctx context.Context) error {
+		for _, model := range models.All() {
+			if !db.HasTable(model) {
+				return fmt.Errorf("pending migration: table for %T is missing", model)
+			}
 		}
+		return nil
 	}
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/userblog/management/pkg/config"
+)
+
+// options holds the command line flags shared by the server and the config command
+type options struct {
+	configPath string
+	overrides  []string
+}
+
+// registerFlags adds the shared flags to fs
+func (o *options) registerFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.configPath, "config", "config.yml", "path to the yaml configuration file")
+	fs.Func("set", "override a setting by its yaml key, e.g. -set app.port=9000 (repeatable)", func(value string) error {
+		o.overrides = append(o.overrides, value)
+		return nil
+	})
+}
+
+// load reads and validates the configuration described by the flags
+func (o *options) load() (*config.Config, error) {
+	cfg, err := config.Load(o.configPath, o.overrides)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// runConfigCommand implements "config print [--redacted]", printing the effective configuration
+func runConfigCommand(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: main config print [--redacted] [-config path] [-set key=value]")
+		return 2
+	}
+
+	var opts options
+	fs := flag.NewFlagSet("config print", flag.ContinueOnError)
+	opts.registerFlags(fs)
+	redacted := fs.Bool("redacted", false, "mask secrets such as the JWT secret and passwords")
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	// Print even an invalid configuration so it can be inspected, but report the problems
+	cfg, err := config.Load(opts.configPath, opts.overrides)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *redacted {
+		cfg = cfg.Redacted()
+	}
+
+	out, err := cfg.YAML()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Print(string(out))
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/userblog/management/pkg/logger"
+)
+
+// jobs runs the background jobs started alongside the server, such as key rotation and the
+// config watcher, so that shutdown can stop them before the database is closed
+type jobs struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	stopped map[string]bool // jobs that returned or panicked before the jobs were stopped
+}
+
+// newJobs returns jobs whose context is derived from ctx
+func newJobs(ctx context.Context) *jobs {
+	ctx, cancel := context.WithCancel(ctx)
+	return &jobs{ctx: ctx, cancel: cancel, stopped: map[string]bool{}}
+}
+
+// Go runs the named job in its own goroutine until the jobs are stopped. A job that returns or
+// panics before then is logged and fails Check.
+func (j *jobs) Go(name string, job func(ctx context.Context)) {
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		defer func() {
+			r := recover()
+			if r != nil {
+				logger.ErrorF(j.ctx, "Background job %s panicked: %v", name, r)
+			}
+			if j.ctx.Err() != nil {
+				return
+			}
+			if r == nil {
+				logger.ErrorF(j.ctx, "Background job %s stopped", name)
+			}
+			j.mu.Lock()
+			j.stopped[name] = true
+			j.mu.Unlock()
+		}()
+		job(j.ctx)
+	}()
+}
+
+// Check is a readiness check failing once the jobs are stopped or any of them stopped early
+func (j *jobs) Check(ctx context.Context) error {
+	if j.ctx.Err() != nil {
+		return errors.New("background jobs are stopped")
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if len(j.stopped) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(j.stopped))
+	for name := range j.stopped {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("background jobs stopped: %s", strings.Join(names, ", "))
+}
+
+// Stop cancels the jobs and waits for them to return, or for ctx to be done
+func (j *jobs) Stop(ctx context.Context) error {
+	j.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		j.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds or a second has passed
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestJobsCheck(t *testing.T) {
+	background := newJobs(context.Background())
+	background.Go("key rotation", func(ctx context.Context) { <-ctx.Done() })
+
+	if err := background.Check(context.Background()); err != nil {
+		t.Fatalf("Check while running: %v", err)
+	}
+
+	background.Go("privacy jobs", func(ctx context.Context) {})
+	background.Go("account purge", func(ctx context.Context) { panic("database is gone") })
+	waitFor(t, func() bool {
+		err := background.Check(context.Background())
+		return err != nil && strings.Contains(err.Error(), "account purge, privacy jobs")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := background.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if err := background.Check(context.Background()); err == nil || !strings.Contains(err.Error(), "are stopped") {
+		t.Errorf("Check after Stop = %v, want the jobs reported stopped", err)
+	}
+}
+
+func TestJobsStoppedOnShutdownArentReported(t *testing.T) {
+	background := newJobs(context.Background())
+	background.Go("config watcher", func(ctx context.Context) { <-ctx.Done() })
+
+	if err := background.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	background.mu.Lock()
+	defer background.mu.Unlock()
+	if len(background.stopped) != 0 {
+		t.Errorf("stopped = %v, want jobs returning on shutdown not counted", background.stopped)
+	}
+}
@@ -3,27 +3,53 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
 	controllerImpl "github.com/userblog/management/api/controller/impl"
 	"github.com/userblog/management/api/middleware"
 	middlewareImpl "github.com/userblog/management/api/middleware"
+	"github.com/userblog/management/api/openapi"
 	"github.com/userblog/management/api/route"
 	repoImpl "github.com/userblog/management/internal/repository/impl"
 	serviceImpl "github.com/userblog/management/internal/service/impl"
+	"github.com/userblog/management/pkg/buildinfo"
+	"github.com/userblog/management/pkg/cache"
 	"github.com/userblog/management/pkg/config"
 	"github.com/userblog/management/pkg/db"
+	"github.com/userblog/management/pkg/event"
+	"github.com/userblog/management/pkg/health"
+	"github.com/userblog/management/pkg/lockout"
 	"github.com/userblog/management/pkg/logger"
+	"github.com/userblog/management/pkg/mail"
+	"github.com/userblog/management/pkg/metrics"
+	"github.com/userblog/management/pkg/oidc"
+	"github.com/userblog/management/pkg/ratelimit"
+	"github.com/userblog/management/pkg/storage"
+	"github.com/userblog/management/pkg/tracing"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 )
 
+// configReloadInterval is how often the config file is checked for changes
+const configReloadInterval = 5 * time.Second
+
+// keyRotationCheckInterval is how often token signing keys are reloaded and checked for rotation
+const keyRotationCheckInterval = time.Minute
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfigCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		os.Exit(runAdminCommand(os.Args[2:]))
+	}
 
 	startTime := time.Now()
 
@@ -31,8 +57,61 @@ func main() {
 	ctx := context.Background()
 	ctx = logger.AddToContext(ctx, logger.DebugIDKey, "startup")
 
+	// Load and validate the configuration, refusing to start with insecure settings
+	var opts options
+	opts.registerFlags(flag.CommandLine)
+	flag.Parse()
+
+	cfg, err := opts.load()
+	if err != nil {
+		logger.FatalF(ctx, "❌ %v", err)
+	}
+	config.Set(cfg)
+
+	if err := logger.Configure(cfg.IsProduction(), cfg.App.LogLevel); err != nil {
+		logger.FatalF(ctx, "❌ Failed to configure logger: %v", err)
+	}
+	if !cfg.App.Debug {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	// Background jobs run until shutdown, which waits for them before closing the database
+	background := newJobs(ctx)
+
+	// Apply safe settings such as the log level, rate limits and rotated secrets when they change
+	config.OnReload(func(cfg *config.Config) {
+		if err := logger.SetLevel(cfg.App.LogLevel); err != nil {
+			logger.ErrorF(ctx, "Failed to apply log level: %v", err)
+		}
+	})
+	background.Go("config watcher", func(jobCtx context.Context) {
+		config.Watch(jobCtx, opts.configPath, opts.overrides, configReloadInterval, cfg.Secrets.RefreshInterval, func(applied, ignored []string, err error) {
+			if err != nil {
+				logger.ErrorF(ctx, "Config reload rejected, keeping the current configuration: %v", err)
+				return
+			}
+			if len(applied) > 0 {
+				logger.InfoF(ctx, "Config reloaded: %s", strings.Join(applied, ", "))
+			}
+			if len(ignored) > 0 {
+				logger.WarnF(ctx, "Config changes that need a restart were ignored: %s", strings.Join(ignored, ", "))
+			}
+		})
+	})
+
+	// Initialize tracing before anything creates spans
+	shutdownTracing, err := tracing.Init(ctx, cfg)
+	if err != nil {
+		logger.FatalF(ctx, "❌ Failed to initialize tracing: %v", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.ErrorF(ctx, "Failed to flush traces: %v", err)
+		}
+	}()
+
 	// Initialize database
-	database := db.Connect()
+	database := db.Connect(cfg.Database)
 	defer func(database *gorm.DB) {
 		err := database.Close()
 		if err != nil {
@@ -42,56 +121,211 @@ func main() {
 
 	logger.Info(ctx, "Database connected")
 
+	// Time every query and expose connection pool stats
+	metrics.InstrumentDB(database, "main")
+	tracing.InstrumentDB(database, database.Dialect().GetName())
+
 	// Seed database with roles and permissions
 	initializeDatabaseScript(ctx, database)
 	logger.Info(ctx, "Database schema initialized and seeded with roles and permissions")
 
+	// Register readiness checks
+	checker := health.NewChecker(cfg.App.ReadinessTimeout)
+	checker.Register("database", func(ctx context.Context) error {
+		return database.DB().PingContext(ctx)
+	})
+	checker.Register("migrations", checkMigrations(database))
+	checker.Register("scheduler", background.Check)
+
 	// Initialize repositories with the database connection
 	var userRepo = repoImpl.NewUserRepository(database)
 	var blogRepo = repoImpl.NewBlogRepository(database)
+	var signingKeyRepo = repoImpl.NewSigningKeyRepository(database)
+	var roleRepo = repoImpl.NewRoleRepository(database)
+	var identityRepo = repoImpl.NewIdentityRepository(database)
+	var sessionRepo = repoImpl.NewSessionRepository(database)
+	var impersonationEventRepo = repoImpl.NewImpersonationEventRepository(database)
+	var auditEventRepo = repoImpl.NewAuditEventRepository(database)
+	var orgRepo = repoImpl.NewOrganizationRepository(database)
+	var invitationRepo = repoImpl.NewInvitationRepository(database)
+	var dataRequestRepo = repoImpl.NewDataRequestRepository(database)
+	var personalDataRepo = repoImpl.NewPersonalDataRepository(database)
+
+	// Initialize the event bus and the notifications sent on its events
+	var events = event.NewBus()
+	serviceImpl.RegisterNotifications(events, mail.NewMailer(cfg.Mail))
+
+	// Failed login counters, shared by login and the admin unlock endpoint
+	var loginAttempts = lockout.NewMemoryStore()
+
+	// Load the token signing keys, generating one on first start, and rotate them on schedule
+	var tokenService = serviceImpl.NewTokenService(signingKeyRepo)
+	if err := tokenService.Refresh(ctx); err != nil {
+		logger.FatalF(ctx, "❌ Failed to load token signing keys: %v", err)
+	}
+	background.Go("key rotation", func(jobCtx context.Context) { tokenService.RunRotation(jobCtx, keyRotationCheckInterval) })
+
+	// Security relevant and content changes are recorded in the audit log
+	var auditService = serviceImpl.NewAuditService(auditEventRepo)
+
+	// Uploaded files such as avatars
+	var files = storage.NewDiskStore(cfg.Storage.Dir)
+
+	// Authenticated users and role permission sets are cached, and dropped whenever they change
+	var cacheStore = cache.NewMemoryStore(cfg.Cache.MaxEntries)
+	var roleService = serviceImpl.NewRoleService(roleRepo, auditService, cache.New("roles", cacheStore))
+	var principalService = serviceImpl.NewPrincipalService(userRepo, orgRepo, roleService, cache.New("principals", cacheStore))
+
+	// Password logins try each configured authenticator in order
+	var authenticators = buildAuthenticators(ctx, background, cfg, userRepo, roleRepo, identityRepo, principalService, auditService)
 
 	// Initialize services
-	var authService = serviceImpl.NewAuthService(userRepo)
-	var userService = serviceImpl.NewUserService(userRepo)
-	var blogService = serviceImpl.NewBlogService(blogRepo)
+	var sessionService = serviceImpl.NewSessionService(sessionRepo, auditService)
+	var impersonationService = serviceImpl.NewImpersonationService(impersonationEventRepo, sessionService, tokenService, principalService, auditService)
+	var authService = serviceImpl.NewAuthService(userRepo, roleRepo, invitationRepo, authenticators, tokenService, sessionService, principalService, loginAttempts, events, auditService)
+	var userService = serviceImpl.NewUserService(userRepo, roleRepo, loginAttempts, roleService, principalService, auditService)
+	var authzService = serviceImpl.NewAuthzService(userRepo, blogRepo, principalService)
+	var blogService = serviceImpl.NewBlogService(blogRepo, authzService, principalService, auditService)
+	var invitationService = serviceImpl.NewInvitationService(invitationRepo, userRepo, roleRepo, roleService, events, auditService)
+	var profileService = serviceImpl.NewProfileService(userRepo, blogRepo, principalService, files, auditService)
+	var orgService = serviceImpl.NewOrganizationService(orgRepo, invitationRepo, roleRepo, roleService, principalService, events, auditService, cache.New("organizations", cacheStore))
+	var accountService = serviceImpl.NewAccountService(userRepo, sessionService, principalService, events, auditService)
+	var privacyService = serviceImpl.NewPrivacyService(dataRequestRepo, personalDataRepo, userRepo, principalService, files, events, auditService)
+	var oidcService = serviceImpl.NewOIDCService(userRepo, roleRepo, identityRepo, principalService, tokenService, sessionService, auditService, oidc.NewMemoryStateStore())
+
+	// Accounts whose owners asked to delete them are deleted once their grace period has passed
+	background.Go("account purge", func(jobCtx context.Context) { accountService.RunPurge(jobCtx, cfg.Account.PurgeInterval) })
+
+	// Data exports and approved erasures are carried out in the background
+	background.Go("privacy jobs", func(jobCtx context.Context) { privacyService.RunJobs(jobCtx, cfg.Privacy.JobInterval) })
 
 	// Initialize middleware
-	var authMiddleware middleware.IAuthMiddleware = middlewareImpl.NewAuthMiddleware(authService)
+	var authMiddleware middleware.IAuthMiddleware = middlewareImpl.NewAuthMiddleware(authService, impersonationService)
+	var authzMiddleware middleware.IAuthzMiddleware = middlewareImpl.NewAuthzMiddleware(authzService)
+	var tenantMiddleware middleware.ITenantMiddleware = middlewareImpl.NewTenantMiddleware(orgService)
+	var rateLimitMiddleware middleware.IRateLimitMiddleware = middlewareImpl.NewRateLimitMiddleware(ratelimit.NewMemoryStore())
 
 	// Initialize controllers
 	var authController = controllerImpl.NewAuthController(authService)
 	var userController = controllerImpl.NewUserController(userService)
 	var blogController = controllerImpl.NewBlogController(blogService)
+	var oidcController = controllerImpl.NewOIDCController(oidcService)
+	var sessionController = controllerImpl.NewSessionController(sessionService)
+	var impersonationController = controllerImpl.NewImpersonationController(impersonationService)
+	var auditController = controllerImpl.NewAuditController(auditService)
+	var invitationController = controllerImpl.NewInvitationController(invitationService)
+	var authzController = controllerImpl.NewAuthzController(authzService)
+	var roleController = controllerImpl.NewRoleController(roleService)
+	var orgController = controllerImpl.NewOrganizationController(orgService)
+	var profileController = controllerImpl.NewProfileController(profileService)
+	var accountController = controllerImpl.NewAccountController(accountService)
+	var privacyController = controllerImpl.NewPrivacyController(privacyService)
+	var docsController = controllerImpl.NewDocsController()
+	var healthController = controllerImpl.NewHealthController(checker)
+	var jwksController = controllerImpl.NewJWKSController(tokenService)
 
 	// Initialize routes
-	authRoute := route.NewAuthRoute(authController, authMiddleware)
-	userRoute := route.NewUserRoute(userController, authMiddleware)
-	blogRoute := route.NewBlogRoute(blogController, authMiddleware)
+	authRoute := route.NewAuthRoute(authController, authMiddleware, rateLimitMiddleware)
+	userRoute := route.NewUserRoute(userController, authMiddleware, authzMiddleware, rateLimitMiddleware)
+	blogRoute := route.NewBlogRoute(blogController, authMiddleware, authzMiddleware, rateLimitMiddleware)
+	oidcRoute := route.NewOIDCRoute(oidcController, rateLimitMiddleware)
+	sessionRoute := route.NewSessionRoute(sessionController, authMiddleware, rateLimitMiddleware)
+	roleRoute := route.NewRoleRoute(roleController, authMiddleware, authzMiddleware, rateLimitMiddleware)
+	authzRoute := route.NewAuthzRoute(authzController, authMiddleware, rateLimitMiddleware)
+	profileRoute := route.NewProfileRoute(profileController, authMiddleware, rateLimitMiddleware)
+	accountRoute := route.NewAccountRoute(accountController, authMiddleware, rateLimitMiddleware)
+	privacyRoute := route.NewPrivacyRoute(privacyController, authMiddleware, authzMiddleware, rateLimitMiddleware)
+	orgRoute := route.NewOrganizationRoute(orgController, authMiddleware, authzMiddleware, rateLimitMiddleware)
+	adminRoute := route.NewAdminRoute(impersonationController, auditController, invitationController, authMiddleware, authzMiddleware, rateLimitMiddleware)
+	docsRoute := route.NewDocsRoute(docsController)
+	healthRoute := route.NewHealthRoute(healthController)
+	jwksRoute := route.NewJWKSRoute(jwksController)
 
 	// Initialize router
 	router := gin.Default()
 
+	// Only trust X-Forwarded-For from known proxies so clients can't spoof their IP past rate limits
+	if err := router.SetTrustedProxies(cfg.App.TrustedProxies); err != nil {
+		logger.FatalF(ctx, "❌ Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// Apply middlewares
 	router.Use(middleware.GlobalExceptionHandler())
 	router.Use(middleware.Logger())
+	router.Use(middleware.Metrics())
 	router.Use(middleware.CORS())
 
+	// Probes and the key set live at the root so they stay outside the API and its documentation
+	healthRoute.HealthRoute(&router.RouterGroup)
+	jwksRoute.JWKSRoute(&router.RouterGroup)
+
 	// Create API router group
 	api := router.Group("/api")
+	api.Use(tenantMiddleware.Resolve())
 
 	// Register routes
 	authRoute.AuthRoute(api)
+	oidcRoute.OIDCRoute(api)
+	sessionRoute.SessionRoute(api)
+	authzRoute.AuthzRoute(api)
+	adminRoute.AdminRoute(api)
 	userRoute.UserRoute(api)
+	roleRoute.RoleRoute(api)
+	orgRoute.OrganizationRoute(api)
+	profileRoute.ProfileRoute(api)
+	accountRoute.AccountRoute(api)
+	privacyRoute.PrivacyRoute(api)
 	blogRoute.BlogRoute(api)
+	docsRoute.DocsRoute(api)
+
+	// Build the OpenAPI document, refusing to start if any route is undocumented
+	document, err := openapi.Build(openapi.Info{
+		Title:   cfg.App.Name,
+		Version: buildinfo.Version,
+	}, router.Routes(), "/api", authRoute, oidcRoute, sessionRoute, authzRoute, adminRoute, userRoute, roleRoute, orgRoute, profileRoute, accountRoute, privacyRoute, blogRoute, docsRoute)
+	if err != nil {
+		logger.FatalF(ctx, "❌ %v", err)
+	}
+	docsController.SetDocument(document)
+
+	// Start the metrics server on its own admin port
+	metricsServer := startMetricsServer(ctx, cfg.Metrics)
 
 	// Start server
-	startServerWithGracefulShutdown(ctx, router, startTime)
+	startServerWithGracefulShutdown(ctx, cfg.App, router, startTime, metricsServer, checker, background)
+}
+
+// startMetricsServer serves Prometheus metrics on the metrics port, keeping them off the public API port.
+// Returns nil when the port is set to an empty value.
+func startMetricsServer(ctx context.Context, cfg config.MetricsConfig) *http.Server {
+	port := cfg.Port
+	if port == "" {
+		logger.Warn(ctx, "METRICS_PORT is empty, metrics endpoint disabled")
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler(cfg.Token))
+
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%s", port),
+		Handler: mux,
+	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.ErrorF(ctx, "❌ Metrics server failed: %v", err)
+		}
+	}()
+
+	logger.InfoF(ctx, "Metrics available on port %s at /metrics", port)
+	return srv
 }
 
-func startServerWithGracefulShutdown(ctx context.Context, router *gin.Engine, startTime time.Time) {
-	port := config.GetOrDefaultString("PORT", "8080")
+func startServerWithGracefulShutdown(ctx context.Context, cfg config.AppConfig, router *gin.Engine, startTime time.Time, metricsServer *http.Server, checker *health.Checker, background *jobs) {
+	port := cfg.Port
 
-	logger.InfoF(ctx, "Server starting on port: %s", port)
+	logger.InfoF(ctx, "Server starting on port: %s (version %s, commit %s)", port, buildinfo.Version, buildinfo.Commit)
 
 	addr := fmt.Sprintf(":%s", port)
 	srv := &http.Server{
@@ -120,7 +354,15 @@ func startServerWithGracefulShutdown(ctx context.Context, router *gin.Engine, st
 	// Wait for the interrupt signal
 	<-quit
 	logger.InfoF(ctx, "⚠️ Shutdown signal received, shutting down server gracefully...")
+
+	// Fail readiness immediately and keep serving for the drain delay, so load balancers stop
+	// routing here before the server stops accepting connections
+	checker.SetShuttingDown()
 	shutdownStart := time.Now()
+	if cfg.ShutdownDelay > 0 {
+		logger.InfoF(ctx, "Draining for %s before shutting down", cfg.ShutdownDelay)
+		time.Sleep(cfg.ShutdownDelay)
+	}
 
 	// Create context with timeout for graceful shutdown
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
@@ -130,6 +372,17 @@ func startServerWithGracefulShutdown(ctx context.Context, router *gin.Engine, st
 		logger.FatalF(ctx, "❌ Server forced to shutdown: %v", err)
 	}
 
+	if metricsServer != nil {
+		if err = metricsServer.Shutdown(ctx); err != nil {
+			logger.ErrorF(ctx, "Metrics server forced to shutdown: %v", err)
+		}
+	}
+
+	// Stop the background jobs before the database they use is closed
+	if err = background.Stop(ctx); err != nil {
+		logger.ErrorF(ctx, "Background jobs didn't stop in time: %v", err)
+	}
+
 	shutdownDuration := time.Since(shutdownStart).Seconds()
 	logger.InfoF(ctx, "✅ Server exited gracefully in %.2f seconds", shutdownDuration)
 }
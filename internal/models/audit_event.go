@@ -0,0 +1,113 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// AuditEventVersion is the hash format of new audit entries. Version 1 entries hash a SHA-256
+// digest of each personal field instead of its value, so a field can be erased by keeping its
+// digest in ErasedDigests; version 0 entries hash the values and can't be erased.
+const AuditEventVersion = 1
+
+// AuditPersonalFields are the fields of an entry that can identify a person
+var AuditPersonalFields = []string{"actor_name", "changes", "ip"}
+
+// AuditEvent is an entry of the append-only audit log. Each entry's hash covers its content and
+// the previous entry's hash, so altering or removing an entry breaks the chain after it.
+// OrganizationID is the organization the request named, if any.
+type AuditEvent struct {
+	ID             uint      `gorm:"primary_key" json:"id"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+	ActorID        uint      `gorm:"index" json:"actor_id"`
+	ActorName      string    `gorm:"size:255" json:"actor_name"`
+	ImpersonatorID uint      `json:"impersonator_id,omitempty"`
+	Action         string    `gorm:"size:64;not null;index" json:"action"`
+	TargetType     string    `gorm:"size:64;index:idx_audit_target" json:"target_type"`
+	TargetID       string    `gorm:"size:64;index:idx_audit_target" json:"target_id"`
+	Changes        JSONText  `gorm:"type:text" json:"changes,omitempty"`
+	IP             string    `gorm:"size:64" json:"ip"`
+	TraceID        string    `gorm:"size:64" json:"trace_id"`
+	OrganizationID uint      `gorm:"index" json:"organization_id,omitempty"`
+	PrevHash       string    `gorm:"size:64;unique_index" json:"prev_hash"`
+	Hash           string    `gorm:"size:64;not null" json:"hash"`
+	Version        int       `gorm:"not null;default:0" json:"version"`
+	ErasedDigests  JSONText  `gorm:"type:text" json:"erased_digests,omitempty"`
+}
+
+// ComputeHash returns the SHA-256 chain hash of the entry
+func (e *AuditEvent) ComputeHash() string {
+	// Times are hashed as Unix seconds, the precision every supported database keeps
+	fields := []interface{}{
+		e.PrevHash, e.CreatedAt.Unix(), e.ActorID, e.ActorName, e.ImpersonatorID,
+		e.Action, e.TargetType, e.TargetID, string(e.Changes), e.IP, e.TraceID,
+	}
+	if e.Version >= 1 {
+		digests := e.personalDigests()
+		fields[3], fields[8], fields[9] = digests["actor_name"], digests["changes"], digests["ip"]
+	}
+	// The organization is only hashed when set, so entries from before organizations still verify
+	if e.OrganizationID != 0 {
+		fields = append(fields, e.OrganizationID)
+	}
+	payload, _ := json.Marshal(fields)
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// Erase clears the named personal fields of a version 1 entry, keeping the digests they were
+// hashed as so the chain still verifies. It reports whether the entry could be erased.
+func (e *AuditEvent) Erase(fields ...string) bool {
+	if e.Version < 1 {
+		return false
+	}
+	digests := e.personalDigests()
+	erased := map[string]string{}
+	_ = json.Unmarshal([]byte(e.ErasedDigests), &erased)
+	for _, field := range fields {
+		erased[field] = digests[field]
+		switch field {
+		case "actor_name":
+			e.ActorName = ""
+		case "changes":
+			e.Changes = ""
+		case "ip":
+			e.IP = ""
+		}
+	}
+	data, _ := json.Marshal(erased)
+	e.ErasedDigests = JSONText(data)
+	return true
+}
+
+// personalDigests returns the digest of each personal field: the one kept when it was erased,
+// or that of its value. A value written to an erased field is hashed, breaking the chain.
+func (e *AuditEvent) personalDigests() map[string]string {
+	erased := map[string]string{}
+	_ = json.Unmarshal([]byte(e.ErasedDigests), &erased)
+
+	values := map[string]string{"actor_name": e.ActorName, "changes": string(e.Changes), "ip": e.IP}
+	digests := make(map[string]string, len(values))
+	for field, value := range values {
+		if digest, ok := erased[field]; ok && value == "" {
+			digests[field] = digest
+			continue
+		}
+		sum := sha256.Sum256([]byte(value))
+		digests[field] = hex.EncodeToString(sum[:])
+	}
+	return digests
+}
+
+// JSONText is a text column holding a JSON document, which is embedded as is in API responses
+type JSONText string
+
+// MarshalJSON returns the document itself, or null when it is empty
+func (t JSONText) MarshalJSON() ([]byte, error) {
+	if t == "" {
+		return []byte("null"), nil
+	}
+	return []byte(t), nil
+}
@@ -10,4 +10,6 @@ type Blog struct {
 	Published bool   `gorm:"default:false" json:"published"`
 	UserID    uint   `gorm:"not null;" json:"user_id"`
 	User      User   `gorm:"foreignKey:UserID" json:"user,omitempty"`
+
+	OrganizationID uint `gorm:"index" json:"organization_id"`
 }
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// DataRequest is a data subject's request for a copy of their personal data or for its
+// erasure. Erasures wait for an administrator's approval; exports are processed right away.
+type DataRequest struct {
+	gorm.Model
+	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	Kind         string     `gorm:"size:16;not null;index" json:"kind"`
+	Status       string     `gorm:"size:16;not null;index" json:"status"`
+	Reason       string     `gorm:"size:1000" json:"reason,omitempty"`
+	ReviewedByID uint       `json:"reviewed_by_id,omitempty"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty"`
+	ReviewNote   string     `gorm:"size:1000" json:"review_note,omitempty"`
+	// FileKey is where the export archive is stored until ExpiresAt
+	FileKey     string     `gorm:"size:255" json:"-"`
+	FileSize    int        `json:"file_size,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Error       string     `gorm:"size:1000" json:"error,omitempty"`
+}
+
+// Data request kinds
+const (
+	DataExport  = "export"
+	DataErasure = "erasure"
+)
+
+// Data request statuses. Requests move from pending (erasures only) to queued once approved,
+// then through processing to completed or failed. Completed exports become expired once their
+// archive is removed.
+const (
+	DataRequestPending    = "pending"
+	DataRequestRejected   = "rejected"
+	DataRequestQueued     = "queued"
+	DataRequestProcessing = "processing"
+	DataRequestCompleted  = "completed"
+	DataRequestFailed     = "failed"
+	DataRequestExpired    = "expired"
+)
+
+// ErasureCertificate records the completion of an erasure: who approved it and what was done to
+// each table. Its digest covers its content, so later alterations can be detected.
+type ErasureCertificate struct {
+	ID           uint      `gorm:"primary_key" json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	RequestID    uint      `gorm:"not null;unique_index" json:"request_id"`
+	UserID       uint      `gorm:"not null;index" json:"user_id"`
+	ApprovedByID uint      `json:"approved_by_id"`
+	Tables       JSONText  `gorm:"type:text" json:"tables"`
+	Digest       string    `gorm:"size:64;not null" json:"digest"`
+}
+
+// ErasureResult is what an erasure did to one table
+type ErasureResult struct {
+	Action string `json:"action"`
+	Rows   int64  `json:"rows"`
+	Kept   int64  `json:"kept,omitempty"` // rows the action couldn't apply to, left as they were
+}
+
+// ComputeDigest returns the SHA-256 digest of the certificate
+func (c *ErasureCertificate) ComputeDigest() string {
+	// Times are hashed as Unix seconds, the precision every supported database keeps
+	payload, _ := json.Marshal([]interface{}{
+		c.RequestID, c.UserID, c.ApprovedByID, c.CreatedAt.Unix(), string(c.Tables),
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,12 @@
+package models
+
+import "github.com/jinzhu/gorm"
+
+// Identity links a user to an account at an external OpenID Connect provider
+type Identity struct {
+	gorm.Model
+	UserID   uint   `gorm:"not null;index" json:"user_id"`
+	Provider string `gorm:"size:64;not null;unique_index:idx_identity_provider_subject" json:"provider"`
+	Subject  string `gorm:"size:255;not null;unique_index:idx_identity_provider_subject" json:"subject"`
+	Email    string `gorm:"size:255" json:"email"`
+}
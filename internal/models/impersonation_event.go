@@ -0,0 +1,24 @@
+package models
+
+import "github.com/jinzhu/gorm"
+
+// ImpersonationEvent records the start of an impersonation or a request made during one
+type ImpersonationEvent struct {
+	gorm.Model
+	ActorID   uint   `gorm:"not null;index" json:"actor_id"`
+	UserID    uint   `gorm:"not null;index" json:"user_id"`
+	SessionID uint   `gorm:"not null;index" json:"session_id"`
+	Action    string `gorm:"size:16;not null" json:"action"`
+	Reason    string `gorm:"size:512" json:"reason,omitempty"`
+	Method    string `gorm:"size:16" json:"method,omitempty"`
+	Path      string `gorm:"size:1024" json:"path,omitempty"`
+	Status    int    `json:"status,omitempty"`
+	IP        string `gorm:"size:64" json:"ip"`
+	TraceID   string `gorm:"size:64" json:"trace_id"`
+}
+
+// Impersonation event actions
+const (
+	ImpersonationStarted = "start"
+	ImpersonationRequest = "request"
+)
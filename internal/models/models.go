@@ -0,0 +1,6 @@
+package models
+
+// All returns every model managed by the schema migration, in migration order
+func All() []interface{} {
+	return []interface{}{&User{}, &Role{}, &Permission{}, &Blog{}, &SigningKey{}, &Identity{}, &Session{}, &ImpersonationEvent{}, &AuditEvent{}, &Organization{}, &Membership{}, &Invitation{}, &DataRequest{}, &ErasureCertificate{}}
+}
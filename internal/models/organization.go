@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Organization is a tenant, such as a team, whose blogs and members are kept apart from
+// other organizations'
+type Organization struct {
+	gorm.Model
+	Name          string `gorm:"size:255;not null" json:"name"`
+	Slug          string `gorm:"size:64;not null;unique" json:"slug"`
+	Description   string `gorm:"size:1024" json:"description"`
+	DefaultRoleID uint   `json:"default_role_id"`
+}
+
+// Membership gives a user a role within an organization, on top of their platform role
+type Membership struct {
+	gorm.Model
+	OrganizationID uint         `gorm:"not null;unique_index:idx_membership_organization_user" json:"organization_id"`
+	Organization   Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+	UserID         uint         `gorm:"not null;unique_index:idx_membership_organization_user;index" json:"user_id"`
+	User           User         `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	RoleID         uint         `gorm:"not null" json:"role_id"`
+	Role           Role         `gorm:"foreignKey:RoleID" json:"role,omitempty"`
+}
+
+// Invitation asks the owner of an email address to join an organization with a role or, when
+// OrganizationID is 0, to register with a role. Only a hash of its single-use token is stored.
+type Invitation struct {
+	gorm.Model
+	OrganizationID uint       `gorm:"index" json:"organization_id"`
+	Email          string     `gorm:"size:255;not null;index" json:"email"`
+	RoleID         uint       `gorm:"not null" json:"role_id"`
+	TokenHash      string     `gorm:"size:64;not null;unique" json:"-"`
+	InvitedByID    uint       `json:"invited_by_id"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty"`
+	AcceptedByID   uint       `json:"accepted_by_id,omitempty"`
+}
+
+// OrganizationResources are the resources an organization's membership roles can grant
+// permissions on; other resources, such as users and roles, belong to the platform
+var OrganizationResources = []string{"blog", "organization"}
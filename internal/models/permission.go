@@ -10,3 +10,18 @@ type Permission struct {
 	Resource    string `gorm:"size:255;not null;" json:"resource"`
 	Action      string `gorm:"size:255;not null;" json:"action"`
 }
+
+// PermissionWildcard matches any resource or action in a permission
+const PermissionWildcard = "*"
+
+// Key returns the permission as resource:action
+func (p Permission) Key() string {
+	return p.Resource + ":" + p.Action
+}
+
+// Grants reports whether the permission covers the action on the resource, treating * in the
+// permission as any resource or action
+func (p Permission) Grants(resource, action string) bool {
+	return (p.Resource == PermissionWildcard || p.Resource == resource) &&
+		(p.Action == PermissionWildcard || p.Action == action)
+}
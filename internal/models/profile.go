@@ -0,0 +1,51 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Profile is the public part of a user, shown on their author page
+type Profile struct {
+	DisplayName string      `gorm:"size:100" json:"display_name,omitempty"`
+	Bio         string      `gorm:"size:2000" json:"bio,omitempty"`
+	AvatarURL   string      `gorm:"size:255" json:"avatar_url,omitempty"`
+	Website     string      `gorm:"size:255" json:"website,omitempty"`
+	Location    string      `gorm:"size:100" json:"location,omitempty"`
+	SocialLinks SocialLinks `gorm:"type:text" json:"social_links,omitempty"`
+}
+
+// SocialLinks maps network names, such as github or mastodon, to profile URLs. It is stored as
+// a JSON object.
+type SocialLinks map[string]string
+
+// Value encodes the links for the database
+func (l SocialLinks) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(l)
+	return string(data), err
+}
+
+// Scan decodes links read from the database
+func (l *SocialLinks) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("unsupported social links value %T", value)
+	}
+	if len(data) == 0 {
+		*l = nil
+		return nil
+	}
+	return json.Unmarshal(data, l)
+}
@@ -2,10 +2,25 @@ package models
 
 import "github.com/jinzhu/gorm"
 
-// Role represents the role model
+// Role represents the role model. A role inherits every permission of its parent, transitively.
+// A role belonging to an organization can only be given by its memberships; one with an
+// OrganizationID of 0 is shared by every organization.
 type Role struct {
 	gorm.Model
-	Name        string       `gorm:"size:255;not null;unique" json:"name"`
-	Description string       `gorm:"size:255;" json:"description"`
-	Permissions []Permission `gorm:"many2many:role_permissions;" json:"permissions,omitempty"`
+	Name           string       `gorm:"size:255;not null;unique" json:"name"`
+	Description    string       `gorm:"size:255;" json:"description"`
+	OrganizationID uint         `gorm:"index" json:"organization_id,omitempty"`
+	ParentID       *uint        `gorm:"index" json:"parent_id,omitempty"`
+	Permissions    []Permission `gorm:"many2many:role_permissions;" json:"permissions,omitempty"`
+}
+
+// Grants reports whether any of the role's permissions covers the action on the resource. Only
+// the permissions loaded on the role count, so load it with its inherited ones first.
+func (r Role) Grants(resource, action string) bool {
+	for _, permission := range r.Permissions {
+		if permission.Grants(resource, action) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Session is a login of a user on a device. Every access token names its session, and
+// deleting the session terminates the token. ActorID is set when an administrator started the
+// session to act as the user. OrganizationID is the organization the login named, if any.
+type Session struct {
+	gorm.Model
+	UserID         uint      `gorm:"not null;index" json:"user_id"`
+	ActorID        uint      `gorm:"index" json:"actor_id,omitempty"`
+	OrganizationID uint      `gorm:"index" json:"organization_id,omitempty"`
+	IP             string    `gorm:"size:64" json:"ip"`
+	UserAgent      string    `gorm:"size:512" json:"user_agent"`
+	Device         string    `gorm:"size:128" json:"device"`
+	LastSeenAt     time.Time `json:"last_seen_at"`
+	ExpiresAt      time.Time `gorm:"index" json:"expires_at"`
+}
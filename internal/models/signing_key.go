@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// SigningKey is a generated token signing key. It signs new tokens until it is rotated out and
+// verifies them until ExpiresAt, after which it is deleted. PrivateKey is encrypted with
+// jwt.key_encryption_key when one is configured; without it the table holds secret material.
+type SigningKey struct {
+	gorm.Model
+	KID        string    `gorm:"size:64;not null;unique_index" json:"kid"`
+	Algorithm  string    `gorm:"size:16;not null" json:"algorithm"`
+	PrivateKey string    `gorm:"type:text;not null" json:"-"`
+	ExpiresAt  time.Time `gorm:"not null;index" json:"expires_at"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/jinzhu/gorm"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -15,11 +17,32 @@ type User struct {
 	LastName  string `gorm:"size:255;" json:"last_name,omitempty"`
 	RoleID    uint   `gorm:"not null;" json:"role_id"`
 	Role      Role   `gorm:"foreignKey:RoleID" json:"role,omitempty"`
+	Profile
+
+	// PendingEmail is an address the user asked to change to, applied once they verify it with
+	// the token whose hash is kept
+	PendingEmail        string     `gorm:"size:255" json:"pending_email,omitempty"`
+	EmailTokenHash      string     `gorm:"size:64" json:"-"`
+	EmailTokenExpiresAt *time.Time `json:"-"`
+	// DeletionScheduledAt is when an account the user asked to delete will be deleted
+	DeletionScheduledAt *time.Time `gorm:"index" json:"deletion_scheduled_at,omitempty"`
+	// DisabledAt is when the account was disabled, as directory users are once they leave the
+	// directory. A disabled user can't log in or use their tokens.
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+
+	// Membership is the user's membership of the organization a request acts in, if any
+	Membership *Membership `gorm:"-" json:"membership,omitempty"`
+}
+
+// BeforeCreate is a hook that hashes the password of a new user
+func (u *User) BeforeCreate() error {
+	return u.SetPassword(u.Password)
 }
 
-// BeforeSave is a hook that runs before saving the user
-func (u *User) BeforeSave() error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+// SetPassword replaces the password with the hash of password. Updates must hash a changed
+// password this way, since only creating a user does it automatically.
+func (u *User) SetPassword(password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/userblog/management/internal/models"
+)
+
+// AuditFilter selects audit events; zero fields match everything, and an action ending in *
+// matches by prefix
+type AuditFilter struct {
+	ActorID    uint
+	Action     string
+	TargetType string
+	TargetID   string
+	From       time.Time
+	To         time.Time
+}
+
+// IAuditEventRepository defines the interface for the append-only audit log. There are no
+// update or delete operations.
+type IAuditEventRepository interface {
+	Create(ctx context.Context, event *models.AuditEvent) error
+	Last(ctx context.Context) (*models.AuditEvent, error)
+	List(ctx context.Context, filter AuditFilter, offset, limit int) ([]models.AuditEvent, int, error)
+	ListAfter(ctx context.Context, filter AuditFilter, afterID uint, limit int) ([]models.AuditEvent, error)
+}
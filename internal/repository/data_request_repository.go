@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/userblog/management/internal/models"
+)
+
+// DataRequestFilter selects data requests; zero fields match everything
+type DataRequestFilter struct {
+	UserID uint
+	Kind   string
+	Status string
+}
+
+// IDataRequestRepository defines the interface for data subject request database operations
+type IDataRequestRepository interface {
+	Create(ctx context.Context, request *models.DataRequest) error
+	FindByID(ctx context.Context, id uint) (*models.DataRequest, error)
+	List(ctx context.Context, filter DataRequestFilter, offset, limit int) ([]models.DataRequest, int, error)
+	ListByStatus(ctx context.Context, status string, limit int) ([]models.DataRequest, error)
+	ListExpired(ctx context.Context, now time.Time) ([]models.DataRequest, error)
+	CountOpen(ctx context.Context, userID uint, kind string) (int, error)
+	Transition(ctx context.Context, id uint, from string, columns map[string]interface{}) (int64, error)
+	CreateCertificate(ctx context.Context, certificate *models.ErasureCertificate) error
+	FindCertificate(ctx context.Context, requestID uint) (*models.ErasureCertificate, error)
+}
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/userblog/management/internal/models"
+)
+
+// IIdentityRepository defines the interface for external identity database operations
+type IIdentityRepository interface {
+	Create(ctx context.Context, identity *models.Identity) error
+	FindByProviderSubject(ctx context.Context, provider, subject string) (*models.Identity, error)
+	ListByProvider(ctx context.Context, provider string) ([]models.Identity, error)
+}
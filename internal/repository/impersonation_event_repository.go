@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/userblog/management/internal/models"
+)
+
+// IImpersonationEventRepository defines the interface for impersonation audit database operations
+type IImpersonationEventRepository interface {
+	Create(ctx context.Context, event *models.ImpersonationEvent) error
+}
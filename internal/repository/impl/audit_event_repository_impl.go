@@ -0,0 +1,87 @@
+package impl
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// AuditEventRepository implements the IAuditEventRepository interface
+type AuditEventRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditEventRepository creates a new audit log repository with the given database connection
+func NewAuditEventRepository(database *gorm.DB) repository.IAuditEventRepository {
+	return &AuditEventRepository{
+		db: database,
+	}
+}
+
+// conn returns the database handle carrying ctx for query tracing
+func (r *AuditEventRepository) conn(ctx context.Context) *gorm.DB {
+	return tracing.DB(ctx, r.db)
+}
+
+// Create appends an event; the unique previous hash rejects a second event chained to the same entry
+func (r *AuditEventRepository) Create(ctx context.Context, event *models.AuditEvent) error {
+	return r.conn(ctx).Create(event).Error
+}
+
+// Last returns the newest event of the whole log, which every organization's events are chained in
+func (r *AuditEventRepository) Last(ctx context.Context) (*models.AuditEvent, error) {
+	var event models.AuditEvent
+	err := r.conn(ctx).Order("id DESC").First(&event).Error
+	return &event, err
+}
+
+// List returns a page of the matching events, newest first, and the number of matches
+func (r *AuditEventRepository) List(ctx context.Context, filter repository.AuditFilter, offset, limit int) ([]models.AuditEvent, int, error) {
+	var events []models.AuditEvent
+	var count int
+
+	query := r.filtered(ctx, filter)
+	if err := query.Model(&models.AuditEvent{}).Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&events).Error
+	return events, count, err
+}
+
+// ListAfter returns up to limit matching events with IDs above afterID, oldest first
+func (r *AuditEventRepository) ListAfter(ctx context.Context, filter repository.AuditFilter, afterID uint, limit int) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	err := r.filtered(ctx, filter).Where("id > ?", afterID).Order("id").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// filtered applies the filter's conditions to the events of the organization the request named
+func (r *AuditEventRepository) filtered(ctx context.Context, filter repository.AuditFilter) *gorm.DB {
+	query := scopedNamed(ctx, r.conn(ctx))
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if prefix, ok := strings.CutSuffix(filter.Action, "*"); ok {
+		query = query.Where("action LIKE ?", prefix+"%")
+	} else if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+	return query
+}
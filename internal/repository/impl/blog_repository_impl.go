@@ -1,9 +1,12 @@
 package impl
 
 import (
+	"context"
 	"github.com/jinzhu/gorm"
 	"github.com/userblog/management/internal/models"
 	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/pkg/tenant"
+	"github.com/userblog/management/pkg/tracing"
 )
 
 // BlogRepository implements the IBlogRepository interface
@@ -18,34 +21,48 @@ func NewBlogRepository(database *gorm.DB) repository.IBlogRepository {
 	}
 }
 
-// Create creates a new blog
-func (r *BlogRepository) Create(blog *models.Blog) error {
-	return r.db.Create(blog).Error
+// conn returns the database handle carrying ctx for query tracing, restricted to the blogs of
+// the organization ctx acts in
+func (r *BlogRepository) conn(ctx context.Context) *gorm.DB {
+	return scoped(ctx, tracing.DB(ctx, r.db))
+}
+
+// Create creates a new blog in the organization ctx acts in
+func (r *BlogRepository) Create(ctx context.Context, blog *models.Blog) error {
+	if blog.OrganizationID == 0 {
+		blog.OrganizationID = tenant.ID(ctx)
+	}
+	return r.conn(ctx).Create(blog).Error
 }
 
 // FindByID finds a blog by ID
-func (r *BlogRepository) FindByID(id uint) (*models.Blog, error) {
+func (r *BlogRepository) FindByID(ctx context.Context, id uint) (*models.Blog, error) {
+	return r.FindByIDWithIncludes(ctx, id, []string{"user"})
+}
+
+// FindByIDWithIncludes finds a blog by ID, preloading only the requested relations
+func (r *BlogRepository) FindByIDWithIncludes(ctx context.Context, id uint, includes []string) (*models.Blog, error) {
 	var blog models.Blog
-	err := r.db.Preload("User").First(&blog, id).Error
+	err := repository.Preload(r.conn(ctx), includes, repository.BlogIncludes).First(&blog, id).Error
 	return &blog, err
 }
 
 // Update updates a blog
-func (r *BlogRepository) Update(blog *models.Blog) error {
-	return r.db.Save(blog).Error
+func (r *BlogRepository) Update(ctx context.Context, blog *models.Blog) error {
+	return r.conn(ctx).Save(blog).Error
 }
 
 // Delete deletes a blog
-func (r *BlogRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Blog{}, id).Error
+func (r *BlogRepository) Delete(ctx context.Context, id uint) error {
+	return r.conn(ctx).Delete(&models.Blog{}, id).Error
 }
 
 // List returns a list of blogs with pagination
-func (r *BlogRepository) List(offset, limit int, published bool) ([]models.Blog, int, error) {
+func (r *BlogRepository) List(ctx context.Context, offset, limit int, published bool, includes []string) ([]models.Blog, int, error) {
 	var blogs []models.Blog
 	var count int
 
-	query := r.db.Model(&models.Blog{})
+	query := r.conn(ctx).Model(&models.Blog{})
 	if published {
 		query = query.Where("published = ?", true)
 	}
@@ -56,21 +73,28 @@ func (r *BlogRepository) List(offset, limit int, published bool) ([]models.Blog,
 	}
 
 	// Get the blogs with pagination
-	err := query.Preload("User").Offset(offset).Limit(limit).Find(&blogs).Error
+	err := repository.Preload(query, includes, repository.BlogIncludes).Offset(offset).Limit(limit).Find(&blogs).Error
 	return blogs, count, err
 }
 
 // ListByUser returns a list of blogs by user with pagination
-func (r *BlogRepository) ListByUser(userID uint, offset, limit int) ([]models.Blog, int, error) {
+func (r *BlogRepository) ListByUser(ctx context.Context, userID uint, offset, limit int, includes []string) ([]models.Blog, int, error) {
 	var blogs []models.Blog
 	var count int
 
 	// Get the total count
-	if err := r.db.Model(&models.Blog{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+	if err := r.conn(ctx).Model(&models.Blog{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// Get the blogs with pagination
-	err := r.db.Preload("User").Where("user_id = ?", userID).Offset(offset).Limit(limit).Find(&blogs).Error
+	err := repository.Preload(r.conn(ctx), includes, repository.BlogIncludes).Where("user_id = ?", userID).Offset(offset).Limit(limit).Find(&blogs).Error
 	return blogs, count, err
 }
+
+// CountPublishedByUser returns how many published blogs a user has written
+func (r *BlogRepository) CountPublishedByUser(ctx context.Context, userID uint) (int, error) {
+	var count int
+	err := r.conn(ctx).Model(&models.Blog{}).Where("user_id = ? AND published = ?", userID, true).Count(&count).Error
+	return count, err
+}
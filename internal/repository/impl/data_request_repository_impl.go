@@ -0,0 +1,107 @@
+package impl
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// DataRequestRepository implements the IDataRequestRepository interface
+type DataRequestRepository struct {
+	db *gorm.DB
+}
+
+// NewDataRequestRepository creates a new data request repository with the given database connection
+func NewDataRequestRepository(database *gorm.DB) repository.IDataRequestRepository {
+	return &DataRequestRepository{
+		db: database,
+	}
+}
+
+// conn returns the database handle carrying ctx for query tracing
+func (r *DataRequestRepository) conn(ctx context.Context) *gorm.DB {
+	return tracing.DB(ctx, r.db)
+}
+
+// Create stores a new data request
+func (r *DataRequestRepository) Create(ctx context.Context, request *models.DataRequest) error {
+	return r.conn(ctx).Create(request).Error
+}
+
+// FindByID finds a data request by ID
+func (r *DataRequestRepository) FindByID(ctx context.Context, id uint) (*models.DataRequest, error) {
+	var request models.DataRequest
+	err := r.conn(ctx).First(&request, id).Error
+	return &request, err
+}
+
+// List returns a page of the matching requests, newest first, and the number of matches
+func (r *DataRequestRepository) List(ctx context.Context, filter repository.DataRequestFilter, offset, limit int) ([]models.DataRequest, int, error) {
+	var requests []models.DataRequest
+	var count int
+
+	query := r.conn(ctx).Model(&models.DataRequest{})
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&requests).Error
+	return requests, count, err
+}
+
+// ListByStatus returns up to limit requests with the status, oldest first
+func (r *DataRequestRepository) ListByStatus(ctx context.Context, status string, limit int) ([]models.DataRequest, error) {
+	var requests []models.DataRequest
+	err := r.conn(ctx).Where("status = ?", status).Order("id").Limit(limit).Find(&requests).Error
+	return requests, err
+}
+
+// ListExpired returns the completed exports whose archive has expired
+func (r *DataRequestRepository) ListExpired(ctx context.Context, now time.Time) ([]models.DataRequest, error) {
+	var requests []models.DataRequest
+	err := r.conn(ctx).Where("kind = ? AND status = ? AND expires_at <= ?", models.DataExport, models.DataRequestCompleted, now).
+		Find(&requests).Error
+	return requests, err
+}
+
+// CountOpen counts a user's requests of the kind that haven't finished
+func (r *DataRequestRepository) CountOpen(ctx context.Context, userID uint, kind string) (int, error) {
+	var count int
+	err := r.conn(ctx).Model(&models.DataRequest{}).
+		Where("user_id = ? AND kind = ? AND status IN (?)", userID, kind,
+			[]string{models.DataRequestPending, models.DataRequestQueued, models.DataRequestProcessing}).
+		Count(&count).Error
+	return count, err
+}
+
+// Transition updates a request still in the from status, so only one instance or reviewer acts
+// on it, and returns how many requests were updated
+func (r *DataRequestRepository) Transition(ctx context.Context, id uint, from string, columns map[string]interface{}) (int64, error) {
+	result := r.conn(ctx).Model(&models.DataRequest{}).Where("id = ? AND status = ?", id, from).Updates(columns)
+	return result.RowsAffected, result.Error
+}
+
+// CreateCertificate stores the certificate of a completed erasure
+func (r *DataRequestRepository) CreateCertificate(ctx context.Context, certificate *models.ErasureCertificate) error {
+	return r.conn(ctx).Create(certificate).Error
+}
+
+// FindCertificate finds the certificate of an erasure request
+func (r *DataRequestRepository) FindCertificate(ctx context.Context, requestID uint) (*models.ErasureCertificate, error) {
+	var certificate models.ErasureCertificate
+	err := r.conn(ctx).Where("request_id = ?", requestID).First(&certificate).Error
+	return &certificate, err
+}
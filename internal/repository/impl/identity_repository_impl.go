@@ -0,0 +1,46 @@
+package impl
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// IdentityRepository implements the IIdentityRepository interface
+type IdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewIdentityRepository creates a new identity repository with the given database connection
+func NewIdentityRepository(database *gorm.DB) repository.IIdentityRepository {
+	return &IdentityRepository{
+		db: database,
+	}
+}
+
+// conn returns the database handle carrying ctx for query tracing
+func (r *IdentityRepository) conn(ctx context.Context) *gorm.DB {
+	return tracing.DB(ctx, r.db)
+}
+
+// Create links a new external identity
+func (r *IdentityRepository) Create(ctx context.Context, identity *models.Identity) error {
+	return r.conn(ctx).Create(identity).Error
+}
+
+// FindByProviderSubject finds the identity a provider knows by subject
+func (r *IdentityRepository) FindByProviderSubject(ctx context.Context, provider, subject string) (*models.Identity, error) {
+	var identity models.Identity
+	err := r.conn(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	return &identity, err
+}
+
+// ListByProvider returns every identity linked at a provider
+func (r *IdentityRepository) ListByProvider(ctx context.Context, provider string) ([]models.Identity, error) {
+	var identities []models.Identity
+	err := r.conn(ctx).Where("provider = ?", provider).Order("id").Find(&identities).Error
+	return identities, err
+}
@@ -0,0 +1,32 @@
+package impl
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// ImpersonationEventRepository implements the IImpersonationEventRepository interface
+type ImpersonationEventRepository struct {
+	db *gorm.DB
+}
+
+// NewImpersonationEventRepository creates a new impersonation audit repository with the given database connection
+func NewImpersonationEventRepository(database *gorm.DB) repository.IImpersonationEventRepository {
+	return &ImpersonationEventRepository{
+		db: database,
+	}
+}
+
+// conn returns the database handle carrying ctx for query tracing
+func (r *ImpersonationEventRepository) conn(ctx context.Context) *gorm.DB {
+	return tracing.DB(ctx, r.db)
+}
+
+// Create appends an impersonation event
+func (r *ImpersonationEventRepository) Create(ctx context.Context, event *models.ImpersonationEvent) error {
+	return r.conn(ctx).Create(event).Error
+}
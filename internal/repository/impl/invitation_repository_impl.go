@@ -0,0 +1,77 @@
+package impl
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// InvitationRepository implements the IInvitationRepository interface
+type InvitationRepository struct {
+	db *gorm.DB
+}
+
+// NewInvitationRepository creates a new invitation repository with the given database connection
+func NewInvitationRepository(database *gorm.DB) repository.IInvitationRepository {
+	return &InvitationRepository{
+		db: database,
+	}
+}
+
+// conn returns the database handle carrying ctx for query tracing
+func (r *InvitationRepository) conn(ctx context.Context) *gorm.DB {
+	return tracing.DB(ctx, r.db)
+}
+
+// Create stores a new invitation
+func (r *InvitationRepository) Create(ctx context.Context, invitation *models.Invitation) error {
+	return r.conn(ctx).Create(invitation).Error
+}
+
+// FindByID finds one of an organization's invitations
+func (r *InvitationRepository) FindByID(ctx context.Context, orgID, id uint) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.conn(ctx).Where("organization_id = ?", orgID).First(&invitation, id).Error
+	return &invitation, err
+}
+
+// FindByTokenHash finds an invitation that hasn't been revoked by the hash of its token. The token
+// alone identifies it, since invitees aren't members of the organization they are invited to yet.
+func (r *InvitationRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.conn(ctx).Where("token_hash = ?", tokenHash).First(&invitation).Error
+	return &invitation, err
+}
+
+// ListPending returns an organization's unaccepted, unexpired invitations, newest first
+func (r *InvitationRepository) ListPending(ctx context.Context, orgID uint, now time.Time) ([]models.Invitation, error) {
+	var invitations []models.Invitation
+	err := r.conn(ctx).Where("organization_id = ? AND accepted_at IS NULL AND expires_at > ?", orgID, now).Order("id DESC").Find(&invitations).Error
+	return invitations, err
+}
+
+// MarkAccepted records that a user accepted an invitation, unless it already was, and returns
+// how many invitations were updated
+func (r *InvitationRepository) MarkAccepted(ctx context.Context, id, userID uint, at time.Time) (int64, error) {
+	result := r.conn(ctx).Model(&models.Invitation{}).Where("id = ? AND accepted_at IS NULL", id).
+		Updates(map[string]interface{}{"accepted_at": at, "accepted_by_id": userID})
+	return result.RowsAffected, result.Error
+}
+
+// Renew replaces the token and expiry of an unaccepted invitation and returns how many
+// invitations were updated
+func (r *InvitationRepository) Renew(ctx context.Context, orgID, id uint, tokenHash string, expiresAt time.Time) (int64, error) {
+	result := r.conn(ctx).Model(&models.Invitation{}).Where("organization_id = ? AND id = ? AND accepted_at IS NULL", orgID, id).
+		Updates(map[string]interface{}{"token_hash": tokenHash, "expires_at": expiresAt})
+	return result.RowsAffected, result.Error
+}
+
+// Delete revokes one of an organization's invitations and returns how many were deleted
+func (r *InvitationRepository) Delete(ctx context.Context, orgID, id uint) (int64, error) {
+	result := r.conn(ctx).Where("organization_id = ? AND id = ?", orgID, id).Delete(&models.Invitation{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,106 @@
+package impl
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// OrganizationRepository implements the IOrganizationRepository interface
+type OrganizationRepository struct {
+	db *gorm.DB
+}
+
+// NewOrganizationRepository creates a new organization repository with the given database connection
+func NewOrganizationRepository(database *gorm.DB) repository.IOrganizationRepository {
+	return &OrganizationRepository{
+		db: database,
+	}
+}
+
+// conn returns the database handle carrying ctx for query tracing
+func (r *OrganizationRepository) conn(ctx context.Context) *gorm.DB {
+	return tracing.DB(ctx, r.db)
+}
+
+// Create stores a new organization
+func (r *OrganizationRepository) Create(ctx context.Context, org *models.Organization) error {
+	return r.conn(ctx).Create(org).Error
+}
+
+// FindByID finds an organization by ID
+func (r *OrganizationRepository) FindByID(ctx context.Context, id uint) (*models.Organization, error) {
+	var org models.Organization
+	err := r.conn(ctx).First(&org, id).Error
+	return &org, err
+}
+
+// FindBySlug finds an organization by slug
+func (r *OrganizationRepository) FindBySlug(ctx context.Context, slug string) (*models.Organization, error) {
+	var org models.Organization
+	err := r.conn(ctx).Where("slug = ?", slug).First(&org).Error
+	return &org, err
+}
+
+// Update saves an organization's name, description and default role
+func (r *OrganizationRepository) Update(ctx context.Context, org *models.Organization) error {
+	return r.conn(ctx).Model(org).Updates(map[string]interface{}{
+		"name":            org.Name,
+		"description":     org.Description,
+		"default_role_id": org.DefaultRoleID,
+	}).Error
+}
+
+// CreateMembership adds a user to an organization
+func (r *OrganizationRepository) CreateMembership(ctx context.Context, membership *models.Membership) error {
+	return r.conn(ctx).Set("gorm:association_autoupdate", false).Create(membership).Error
+}
+
+// FindMembership finds a user's membership of an organization
+func (r *OrganizationRepository) FindMembership(ctx context.Context, orgID, userID uint) (*models.Membership, error) {
+	var membership models.Membership
+	err := r.conn(ctx).Preload("Role").Where("organization_id = ? AND user_id = ?", orgID, userID).First(&membership).Error
+	return &membership, err
+}
+
+// UpdateMembershipRole changes a member's role within an organization
+func (r *OrganizationRepository) UpdateMembershipRole(ctx context.Context, orgID, userID, roleID uint) error {
+	return r.conn(ctx).Model(&models.Membership{}).Where("organization_id = ? AND user_id = ?", orgID, userID).Update("role_id", roleID).Error
+}
+
+// DeleteMembership removes a user from an organization and returns how many memberships were deleted
+func (r *OrganizationRepository) DeleteMembership(ctx context.Context, orgID, userID uint) (int64, error) {
+	result := r.conn(ctx).Unscoped().Where("organization_id = ? AND user_id = ?", orgID, userID).Delete(&models.Membership{})
+	return result.RowsAffected, result.Error
+}
+
+// CountMembersWithRole returns how many members of an organization have the role
+func (r *OrganizationRepository) CountMembersWithRole(ctx context.Context, orgID, roleID uint) (int, error) {
+	var count int
+	err := r.conn(ctx).Model(&models.Membership{}).Where("organization_id = ? AND role_id = ?", orgID, roleID).Count(&count).Error
+	return count, err
+}
+
+// ListMembers returns an organization's memberships with their users and roles, with pagination
+func (r *OrganizationRepository) ListMembers(ctx context.Context, orgID uint, offset, limit int) ([]models.Membership, int, error) {
+	var memberships []models.Membership
+	var count int
+
+	query := r.conn(ctx).Model(&models.Membership{}).Where("organization_id = ?", orgID)
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("User").Preload("Role").Order("id").Offset(offset).Limit(limit).Find(&memberships).Error
+	return memberships, count, err
+}
+
+// ListMemberships returns every membership of a user, with its organization and role
+func (r *OrganizationRepository) ListMemberships(ctx context.Context, userID uint) ([]models.Membership, error) {
+	var memberships []models.Membership
+	err := r.conn(ctx).Preload("Organization").Preload("Role").Where("user_id = ?", userID).Order("organization_id").Find(&memberships).Error
+	return memberships, err
+}
@@ -0,0 +1,197 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// PersonalDataRepository implements the IPersonalDataRepository interface. It is the one place
+// that knows every table holding personal data, so new tables need adding here.
+type PersonalDataRepository struct {
+	db *gorm.DB
+}
+
+// NewPersonalDataRepository creates a new personal data repository with the given database connection
+func NewPersonalDataRepository(database *gorm.DB) repository.IPersonalDataRepository {
+	return &PersonalDataRepository{
+		db: database,
+	}
+}
+
+// conn returns the database handle carrying ctx for query tracing
+func (r *PersonalDataRepository) conn(ctx context.Context) *gorm.DB {
+	return tracing.DB(ctx, r.db)
+}
+
+// Collect reads everything stored about the user in every organization, including soft-deleted
+// rows, which are still stored
+func (r *PersonalDataRepository) Collect(ctx context.Context, userID uint) (*repository.PersonalData, error) {
+	db := r.conn(ctx).Unscoped()
+	data := &repository.PersonalData{}
+
+	if err := db.Preload("Role").First(&data.User, userID).Error; err != nil {
+		return nil, err
+	}
+	queries := []struct {
+		dest  interface{}
+		query string
+		args  []interface{}
+	}{
+		{&data.Blogs, "user_id = ?", []interface{}{userID}},
+		{&data.Memberships, "user_id = ?", []interface{}{userID}},
+		{&data.Sessions, "user_id = ?", []interface{}{userID}},
+		{&data.Identities, "user_id = ?", []interface{}{userID}},
+		{&data.Invitations, "email = ? OR accepted_by_id = ?", []interface{}{data.User.Email, userID}},
+		{&data.ImpersonationEvents, "user_id = ?", []interface{}{userID}},
+		{&data.AuditEvents, "actor_id = ? OR (target_type = 'user' AND target_id = ?)", []interface{}{userID, strconv.FormatUint(uint64(userID), 10)}},
+		{&data.DataRequests, "user_id = ?", []interface{}{userID}},
+	}
+	for _, q := range queries {
+		if err := db.Where(q.query, q.args...).Order("id").Find(q.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// Erase removes or anonymizes the user's data in one transaction, taking the action given for
+// each configurable table, and returns what was done to every table. Sessions and identities
+// are always deleted; the data requests are kept.
+func (r *PersonalDataRepository) Erase(ctx context.Context, user *models.User, actions map[string]string) (map[string]models.ErasureResult, error) {
+	results := map[string]models.ErasureResult{}
+	err := r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		tx = tx.Unscoped()
+		run := func(table, action string, query *gorm.DB) error {
+			if query.Error != nil {
+				return fmt.Errorf("failed to erase %s: %w", table, query.Error)
+			}
+			results[table] = models.ErasureResult{Action: action, Rows: query.RowsAffected}
+			return nil
+		}
+		count := func(table string, model interface{}, where string, args ...interface{}) error {
+			var rows int64
+			if err := tx.Model(model).Where(where, args...).Count(&rows).Error; err != nil {
+				return fmt.Errorf("failed to count %s: %w", table, err)
+			}
+			results[table] = models.ErasureResult{Action: config.ErasureKeep, Rows: rows}
+			return nil
+		}
+
+		if err := run("sessions", config.ErasureDelete, tx.Where("user_id = ?", user.ID).Delete(&models.Session{})); err != nil {
+			return err
+		}
+		if err := run("identities", config.ErasureDelete, tx.Where("user_id = ?", user.ID).Delete(&models.Identity{})); err != nil {
+			return err
+		}
+
+		steps := []struct {
+			table  string
+			model  interface{}
+			where  string
+			args   []interface{}
+			fields map[string]interface{} // anonymized values
+		}{
+			{"blogs", &models.Blog{}, "user_id = ?", []interface{}{user.ID}, nil},
+			{"memberships", &models.Membership{}, "user_id = ?", []interface{}{user.ID}, nil},
+			{"invitations", &models.Invitation{}, "email = ? OR accepted_by_id = ?", []interface{}{user.Email, user.ID}, nil},
+			{"impersonation_events", &models.ImpersonationEvent{}, "user_id = ?", []interface{}{user.ID},
+				map[string]interface{}{"reason": "", "path": ""}},
+		}
+		for _, step := range steps {
+			var err error
+			switch actions[step.table] {
+			case config.ErasureDelete:
+				err = run(step.table, config.ErasureDelete, tx.Where(step.where, step.args...).Delete(step.model))
+			case config.ErasureAnonymize:
+				err = run(step.table, config.ErasureAnonymize, tx.Model(step.model).Where(step.where, step.args...).UpdateColumns(step.fields))
+			default:
+				err = count(step.table, step.model, step.where, step.args...)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		auditWhere, auditArgs := "actor_id = ? OR (target_type = 'user' AND target_id = ?)", []interface{}{user.ID, strconv.FormatUint(uint64(user.ID), 10)}
+		if actions["audit_events"] == config.ErasureAnonymize {
+			result, err := eraseAuditEvents(tx, user.ID, auditWhere, auditArgs...)
+			if err != nil {
+				return fmt.Errorf("failed to erase audit_events: %w", err)
+			}
+			results["audit_events"] = result
+		} else if err := count("audit_events", &models.AuditEvent{}, auditWhere, auditArgs...); err != nil {
+			return err
+		}
+
+		// The user goes last, once nothing refers to them unless configured to be kept
+		if actions["users"] == config.ErasureDelete {
+			return run("users", config.ErasureDelete, tx.Where("id = ?", user.ID).Delete(&models.User{}))
+		}
+		return run("users", config.ErasureAnonymize, tx.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumns(anonymizedUser(user.ID)))
+	})
+	return results, err
+}
+
+// eraseAuditEvents clears the personal fields of the matching audit entries, keeping their
+// digests so the hash chain still verifies: the name, IP and changes of entries the user made,
+// and the changes of entries about them. Entries recorded before fields could be erased are kept.
+func eraseAuditEvents(tx *gorm.DB, userID uint, where string, args ...interface{}) (models.ErasureResult, error) {
+	result := models.ErasureResult{Action: config.ErasureAnonymize}
+	var events []models.AuditEvent
+	if err := tx.Where(where, args...).Order("id").Find(&events).Error; err != nil {
+		return result, err
+	}
+	for i := range events {
+		event := &events[i]
+		fields := []string{"changes"}
+		if event.ActorID == userID {
+			fields = models.AuditPersonalFields
+		}
+		if !event.Erase(fields...) {
+			result.Kept++
+			continue
+		}
+		err := tx.Model(event).UpdateColumns(map[string]interface{}{
+			"actor_name":     event.ActorName,
+			"changes":        event.Changes,
+			"ip":             event.IP,
+			"erased_digests": event.ErasedDigests,
+		}).Error
+		if err != nil {
+			return result, err
+		}
+		result.Rows++
+	}
+	return result, nil
+}
+
+// anonymizedUser returns the columns of an erased user that is kept so rows referring to it stay
+// valid. The account is deleted and can't be logged into, and its name no longer identifies anyone.
+func anonymizedUser(id uint) map[string]interface{} {
+	return map[string]interface{}{
+		"username":               fmt.Sprintf("erased-%d", id),
+		"email":                  fmt.Sprintf("erased-%d@erased.invalid", id),
+		"password":               "",
+		"first_name":             "",
+		"last_name":              "",
+		"display_name":           "",
+		"bio":                    "",
+		"avatar_url":             "",
+		"website":                "",
+		"location":               "",
+		"social_links":           models.SocialLinks(nil),
+		"pending_email":          "",
+		"email_token_hash":       "",
+		"email_token_expires_at": nil,
+		"deletion_scheduled_at":  nil,
+		"deleted_at":             gorm.Expr("COALESCE(deleted_at, ?)", time.Now()),
+	}
+}
@@ -0,0 +1,76 @@
+package impl
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// RoleRepository implements the IRoleRepository interface
+type RoleRepository struct {
+	db *gorm.DB
+}
+
+// NewRoleRepository creates a new role repository with the given database connection
+func NewRoleRepository(database *gorm.DB) repository.IRoleRepository {
+	return &RoleRepository{
+		db: database,
+	}
+}
+
+// conn returns the database handle carrying ctx for query tracing
+func (r *RoleRepository) conn(ctx context.Context) *gorm.DB {
+	return tracing.DB(ctx, r.db)
+}
+
+// Create creates a new role granted its permissions, which are left unchanged
+func (r *RoleRepository) Create(ctx context.Context, role *models.Role) error {
+	return r.conn(ctx).Set("gorm:association_autoupdate", false).Create(role).Error
+}
+
+// FindByID finds a role shared by every organization or belonging to the one the request named
+// by ID, with its own permissions
+func (r *RoleRepository) FindByID(ctx context.Context, id uint) (*models.Role, error) {
+	var role models.Role
+	err := scopedShared(ctx, r.conn(ctx)).Preload("Permissions").First(&role, id).Error
+	return &role, err
+}
+
+// FindByName finds a role by name in any organization, since names are unique and configuration
+// refers to roles by name
+func (r *RoleRepository) FindByName(ctx context.Context, name string) (*models.Role, error) {
+	var role models.Role
+	err := r.conn(ctx).Where("name = ?", name).First(&role).Error
+	return &role, err
+}
+
+// List returns the roles shared by every organization and those of the organization the request
+// named, with their own permissions
+func (r *RoleRepository) List(ctx context.Context) ([]models.Role, error) {
+	var roles []models.Role
+	err := scopedShared(ctx, r.conn(ctx)).Preload("Permissions").Order("id").Find(&roles).Error
+	return roles, err
+}
+
+// Update saves the role's description and parent
+func (r *RoleRepository) Update(ctx context.Context, role *models.Role) error {
+	return r.conn(ctx).Model(role).Updates(map[string]interface{}{
+		"description": role.Description,
+		"parent_id":   role.ParentID,
+	}).Error
+}
+
+// ReplacePermissions sets the permissions granted directly to the role
+func (r *RoleRepository) ReplacePermissions(ctx context.Context, role *models.Role, permissions []models.Permission) error {
+	return r.conn(ctx).Model(role).Association("Permissions").Replace(permissions).Error
+}
+
+// ListPermissions returns every permission that can be granted
+func (r *RoleRepository) ListPermissions(ctx context.Context) ([]models.Permission, error) {
+	var permissions []models.Permission
+	err := r.conn(ctx).Order("resource, action").Find(&permissions).Error
+	return permissions, err
+}
@@ -0,0 +1,73 @@
+package impl
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// SessionRepository implements the ISessionRepository interface
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository creates a new session repository with the given database connection
+func NewSessionRepository(database *gorm.DB) repository.ISessionRepository {
+	return &SessionRepository{
+		db: database,
+	}
+}
+
+// conn returns the database handle carrying ctx for query tracing
+func (r *SessionRepository) conn(ctx context.Context) *gorm.DB {
+	return tracing.DB(ctx, r.db)
+}
+
+// Create stores a new session
+func (r *SessionRepository) Create(ctx context.Context, session *models.Session) error {
+	return r.conn(ctx).Create(session).Error
+}
+
+// FindByID finds a session that hasn't been terminated, in any organization, so tokens can be
+// checked whichever organization a request names
+func (r *SessionRepository) FindByID(ctx context.Context, id uint) (*models.Session, error) {
+	var session models.Session
+	err := r.conn(ctx).First(&session, id).Error
+	return &session, err
+}
+
+// ListActiveByUser returns a user's unexpired sessions in the organization the request named,
+// most recently used first
+func (r *SessionRepository) ListActiveByUser(ctx context.Context, userID uint, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := scopedNamed(ctx, r.conn(ctx)).Where("user_id = ? AND expires_at > ?", userID, now).Order("last_seen_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// Touch records when a session was last used
+func (r *SessionRepository) Touch(ctx context.Context, id uint, lastSeen time.Time) error {
+	return r.conn(ctx).Model(&models.Session{}).Where("id = ?", id).UpdateColumn("last_seen_at", lastSeen).Error
+}
+
+// Delete terminates one of a user's sessions in the organization the request named and returns
+// how many were deleted
+func (r *SessionRepository) Delete(ctx context.Context, userID, id uint) (int64, error) {
+	result := scopedNamed(ctx, r.conn(ctx)).Where("user_id = ? AND id = ?", userID, id).Delete(&models.Session{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteOthers terminates all of a user's sessions except keepID, in every organization, and
+// returns how many were deleted
+func (r *SessionRepository) DeleteOthers(ctx context.Context, userID, keepID uint) (int64, error) {
+	result := r.conn(ctx).Where("user_id = ? AND id <> ?", userID, keepID).Delete(&models.Session{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteExpired permanently removes a user's expired sessions, including terminated ones
+func (r *SessionRepository) DeleteExpired(ctx context.Context, userID uint, now time.Time) error {
+	return r.conn(ctx).Unscoped().Where("user_id = ? AND expires_at <= ?", userID, now).Delete(&models.Session{}).Error
+}
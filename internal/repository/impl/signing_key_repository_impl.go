@@ -0,0 +1,45 @@
+package impl
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// SigningKeyRepository implements the ISigningKeyRepository interface
+type SigningKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewSigningKeyRepository creates a new signing key repository with the given database connection
+func NewSigningKeyRepository(database *gorm.DB) repository.ISigningKeyRepository {
+	return &SigningKeyRepository{
+		db: database,
+	}
+}
+
+// conn returns the database handle carrying ctx for query tracing
+func (r *SigningKeyRepository) conn(ctx context.Context) *gorm.DB {
+	return tracing.DB(ctx, r.db)
+}
+
+// Create stores a new signing key
+func (r *SigningKeyRepository) Create(ctx context.Context, key *models.SigningKey) error {
+	return r.conn(ctx).Create(key).Error
+}
+
+// ListUnexpired returns the keys that can still verify tokens, newest first
+func (r *SigningKeyRepository) ListUnexpired(ctx context.Context, now time.Time) ([]models.SigningKey, error) {
+	var keys []models.SigningKey
+	err := r.conn(ctx).Where("expires_at > ?", now).Order("created_at desc").Find(&keys).Error
+	return keys, err
+}
+
+// DeleteExpired permanently removes expired keys so their private keys don't linger
+func (r *SigningKeyRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	return r.conn(ctx).Unscoped().Where("expires_at <= ?", now).Delete(&models.SigningKey{}).Error
+}
@@ -0,0 +1,48 @@
+package impl
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/pkg/tenant"
+)
+
+// scoped restricts queries on a table with an organization_id column to the organization ctx
+// acts in. Outside of any, such as in background jobs, queries aren't restricted.
+func scoped(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if id := tenant.ID(ctx); id != 0 {
+		return db.Where("organization_id = ?", id)
+	}
+	return db
+}
+
+// scopedNamed restricts queries on a table with an organization_id column to the organization
+// the request named. The default organization is the platform's view, where nothing is hidden.
+func scopedNamed(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if id := tenant.NamedID(ctx); id != 0 {
+		return db.Where("organization_id = ?", id)
+	}
+	return db
+}
+
+// scopedShared restricts queries on a table with an organization_id column to the rows shared
+// by every organization, whose organization_id is 0, and those of the organization the request
+// named
+func scopedShared(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if id := tenant.NamedID(ctx); id != 0 {
+		return db.Where("organization_id IN (?)", []uint{0, id})
+	}
+	return db
+}
+
+// scopedMembers restricts queries on users to the members of the organization the request named
+func scopedMembers(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if id := tenant.NamedID(ctx); id != 0 {
+		// The subquery brings its own parentheses; another pair would make it a scalar, matching
+		// only the first member
+		members := db.New().Model(&models.Membership{}).Select("user_id").Where("organization_id = ?", id)
+		return db.Where("users.id IN ?", members.SubQuery())
+	}
+	return db
+}
@@ -0,0 +1,212 @@
+package impl
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/pkg/tenant"
+)
+
+// tenantTest holds two organizations, acme with alice and carol as members and globex with bob,
+// and the contexts of requests naming each and of a request in the default organization
+type tenantTest struct {
+	db                *gorm.DB
+	acme, globex      context.Context
+	platform          context.Context
+	alice, bob, admin models.User
+	carol             models.User
+	acmeID, globexID  uint
+}
+
+func newTenantTest(t *testing.T) *tenantTest {
+	t.Helper()
+
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	// Every connection to :memory: is a new database, so keep to one
+	db.DB().SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	if err := db.AutoMigrate(models.All()...).Error; err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+
+	tt := &tenantTest{db: db}
+	defaultOrg := models.Organization{Name: "Default", Slug: "default"}
+	acme := models.Organization{Name: "Acme", Slug: "acme"}
+	globex := models.Organization{Name: "Globex", Slug: "globex"}
+	tt.alice = models.User{Username: "alice", Email: "alice@example.com"}
+	tt.bob = models.User{Username: "bob", Email: "bob@example.com"}
+	tt.admin = models.User{Username: "admin", Email: "admin@example.com"}
+	tt.carol = models.User{Username: "carol", Email: "carol@example.com"}
+	for _, record := range []interface{}{&defaultOrg, &acme, &globex, &tt.alice, &tt.bob, &tt.admin, &tt.carol} {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("creating %T: %v", record, err)
+		}
+	}
+	for _, m := range []models.Membership{{OrganizationID: acme.ID, UserID: tt.alice.ID}, {OrganizationID: globex.ID, UserID: tt.bob.ID}, {OrganizationID: acme.ID, UserID: tt.carol.ID}} {
+		if err := db.Create(&m).Error; err != nil {
+			t.Fatalf("creating membership: %v", err)
+		}
+	}
+
+	tt.acmeID, tt.globexID = acme.ID, globex.ID
+	tt.acme = tenant.WithOrganization(context.Background(), tenant.Organization{ID: acme.ID, Slug: acme.Slug, Source: tenant.SourceHeader})
+	tt.globex = tenant.WithOrganization(context.Background(), tenant.Organization{ID: globex.ID, Slug: globex.Slug, Source: tenant.SourceToken})
+	tt.platform = tenant.WithOrganization(context.Background(), tenant.Organization{ID: defaultOrg.ID, Slug: defaultOrg.Slug, Source: tenant.SourceDefault})
+	return tt
+}
+
+func TestUsersScopedToMembers(t *testing.T) {
+	tt := newTenantTest(t)
+	users := NewUserRepository(tt.db)
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want []string
+	}{
+		{name: "acme", ctx: tt.acme, want: []string{"alice", "carol"}},
+		{name: "globex", ctx: tt.globex, want: []string{"bob"}},
+		{name: "default", ctx: tt.platform, want: []string{"admin", "alice", "bob", "carol"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			list, total, err := users.List(test.ctx, 0, 10, nil)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			var got []string
+			for _, user := range list {
+				got = append(got, user.Username)
+			}
+			sort.Strings(got)
+			if total != len(test.want) || !equal(got, test.want) {
+				t.Errorf("listed %v of %d, want %v", got, total, test.want)
+			}
+		})
+	}
+
+	// Every member is found, not only the first to join
+	if _, err := users.FindByIDWithIncludes(tt.acme, tt.carol.ID, nil); err != nil {
+		t.Errorf("finding an acme member in acme: %v", err)
+	}
+	if _, err := users.FindByIDWithIncludes(tt.acme, tt.bob.ID, nil); !gorm.IsRecordNotFoundError(err) {
+		t.Errorf("finding a globex member in acme: error = %v, want not found", err)
+	}
+	if err := users.Delete(tt.acme, tt.bob.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	// Accounts are looked up by ID for authentication whatever the organization
+	if _, err := users.FindByID(tt.acme, tt.bob.ID); err != nil {
+		t.Errorf("bob was deleted by a request in acme: %v", err)
+	}
+}
+
+func TestRolesScopedToOrganization(t *testing.T) {
+	tt := newTenantTest(t)
+	roles := NewRoleRepository(tt.db)
+
+	for _, role := range []models.Role{{Name: "user"}, {Name: "acme_editor", OrganizationID: tt.acmeID}, {Name: "globex_editor", OrganizationID: tt.globexID}} {
+		if err := roles.Create(context.Background(), &role); err != nil {
+			t.Fatalf("creating role %s: %v", role.Name, err)
+		}
+	}
+
+	list, err := roles.List(tt.acme)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var got []string
+	for _, role := range list {
+		got = append(got, role.Name)
+	}
+	sort.Strings(got)
+	if want := []string{"acme_editor", "user"}; !equal(got, want) {
+		t.Errorf("acme lists roles %v, want %v", got, want)
+	}
+
+	globexEditor, err := roles.FindByName(context.Background(), "globex_editor")
+	if err != nil {
+		t.Fatalf("FindByName: %v", err)
+	}
+	if _, err := roles.FindByID(tt.acme, globexEditor.ID); !gorm.IsRecordNotFoundError(err) {
+		t.Errorf("finding a globex role in acme: error = %v, want not found", err)
+	}
+	if list, _ := roles.List(tt.platform); len(list) != 3 {
+		t.Errorf("the default organization lists %d roles, want all 3", len(list))
+	}
+}
+
+func TestSessionsAndAuditEventsScopedToOrganization(t *testing.T) {
+	tt := newTenantTest(t)
+	sessions := NewSessionRepository(tt.db)
+	events := NewAuditEventRepository(tt.db)
+	expires := time.Now().Add(time.Hour)
+
+	for i, orgID := range []uint{0, tt.acmeID, tt.globexID} {
+		if err := sessions.Create(context.Background(), &models.Session{UserID: tt.alice.ID, OrganizationID: orgID, ExpiresAt: expires}); err != nil {
+			t.Fatalf("creating session: %v", err)
+		}
+		if err := events.Create(context.Background(), &models.AuditEvent{Action: "user.update", OrganizationID: orgID, PrevHash: strconv.Itoa(i), Hash: strconv.Itoa(i + 1)}); err != nil {
+			t.Fatalf("creating audit event: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want int
+	}{
+		{name: "acme", ctx: tt.acme, want: 1},
+		{name: "globex", ctx: tt.globex, want: 1},
+		{name: "default", ctx: tt.platform, want: 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			listed, err := sessions.ListActiveByUser(test.ctx, tt.alice.ID, time.Now())
+			if err != nil {
+				t.Fatalf("ListActiveByUser: %v", err)
+			}
+			if len(listed) != test.want {
+				t.Errorf("listed %d sessions, want %d", len(listed), test.want)
+			}
+			_, total, err := events.List(test.ctx, repository.AuditFilter{}, 0, 10)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if total != test.want {
+				t.Errorf("listed %d audit events, want %d", total, test.want)
+			}
+		})
+	}
+
+	// The hash chain runs through every organization's events
+	last, err := events.Last(tt.acme)
+	if err != nil {
+		t.Fatalf("Last: %v", err)
+	}
+	if last.OrganizationID != tt.globexID {
+		t.Errorf("last event is of organization %d, want the latest of all, %d", last.OrganizationID, tt.globexID)
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
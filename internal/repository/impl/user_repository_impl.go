@@ -1,9 +1,14 @@
 package impl
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/jinzhu/gorm"
 	"github.com/userblog/management/internal/models"
 	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/pkg/tracing"
 )
 
 // UserRepository implements the IUserRepository interface
@@ -18,53 +23,123 @@ func NewUserRepository(database *gorm.DB) repository.IUserRepository {
 	}
 }
 
+// conn returns the database handle carrying ctx for query tracing
+func (r *UserRepository) conn(ctx context.Context) *gorm.DB {
+	return tracing.DB(ctx, r.db)
+}
+
 // Create creates a new user
-func (r *UserRepository) Create(user *models.User) error {
-	return r.db.Create(user).Error
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	return r.conn(ctx).Create(user).Error
+}
+
+// errInvitationAccepted rolls back creating a user whose invitation was accepted meanwhile
+var errInvitationAccepted = errors.New("invitation has already been accepted")
+
+// CreateAcceptingInvitation creates a user and marks the invitation accepted by them in one
+// transaction, and returns how many invitations were accepted. When the invitation already
+// was, nothing is stored and 0 is returned.
+func (r *UserRepository) CreateAcceptingInvitation(ctx context.Context, user *models.User, invitationID uint, at time.Time) (int64, error) {
+	var accepted int64
+	err := r.conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&models.Invitation{}).Where("id = ? AND accepted_at IS NULL", invitationID).
+			Updates(map[string]interface{}{"accepted_at": at, "accepted_by_id": user.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if accepted = result.RowsAffected; accepted == 0 {
+			return errInvitationAccepted
+		}
+		return nil
+	})
+	if errors.Is(err, errInvitationAccepted) {
+		return 0, nil
+	}
+	return accepted, err
+}
+
+// FindByID finds a user by ID in any organization, since accounts are shared by the platform;
+// it is for authenticating users and acting on their own account
+func (r *UserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	err := r.conn(ctx).Preload("Role.Permissions").First(&user, id).Error
+	return &user, err
 }
 
-// FindByID finds a user by ID
-func (r *UserRepository) FindByID(id uint) (*models.User, error) {
+// FindByIDWithIncludes finds a user by ID among the members of the organization the request
+// named, preloading only the requested relations
+func (r *UserRepository) FindByIDWithIncludes(ctx context.Context, id uint, includes []string) (*models.User, error) {
 	var user models.User
-	err := r.db.Preload("Role.Permissions").First(&user, id).Error
+	err := repository.Preload(scopedMembers(ctx, r.conn(ctx)), includes, repository.UserIncludes).First(&user, id).Error
 	return &user, err
 }
 
-// FindByUsername finds a user by username
-func (r *UserRepository) FindByUsername(username string) (*models.User, error) {
+// FindByUsername finds a user by username in any organization
+func (r *UserRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	err := r.db.Preload("Role.Permissions").Where("username = ?", username).First(&user).Error
+	err := r.conn(ctx).Preload("Role.Permissions").Where("username = ?", username).First(&user).Error
 	return &user, err
 }
 
-// FindByEmail finds a user by email
-func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
+// FindByEmail finds a user by email in any organization
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	err := r.db.Preload("Role.Permissions").Where("email = ?", email).First(&user).Error
+	err := r.conn(ctx).Preload("Role.Permissions").Where("email = ?", email).First(&user).Error
 	return &user, err
 }
 
 // Update updates a user
-func (r *UserRepository) Update(user *models.User) error {
-	return r.db.Save(user).Error
+func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
+	// The preloaded role isn't saved with the user, or its ID would overwrite a changed role_id
+	return r.conn(ctx).Set("gorm:association_autoupdate", false).Set("gorm:association_save_reference", false).Save(user).Error
+}
+
+// UpdateRole changes a user's role without running the save hooks
+func (r *UserRepository) UpdateRole(ctx context.Context, id, roleID uint) error {
+	return r.conn(ctx).Model(&models.User{}).Where("id = ?", id).UpdateColumn("role_id", roleID).Error
+}
+
+// UpdateProfile changes a user's email and name without running the save hooks
+func (r *UserRepository) UpdateProfile(ctx context.Context, id uint, email, firstName, lastName string) error {
+	return r.conn(ctx).Model(&models.User{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"email":      email,
+		"first_name": firstName,
+		"last_name":  lastName,
+	}).Error
 }
 
-// Delete deletes a user
-func (r *UserRepository) Delete(id uint) error {
-	return r.db.Delete(&models.User{}, id).Error
+// UpdateColumns changes the named columns of a user without running the save hooks
+func (r *UserRepository) UpdateColumns(ctx context.Context, id uint, columns map[string]interface{}) error {
+	return r.conn(ctx).Model(&models.User{}).Where("id = ?", id).UpdateColumns(columns).Error
 }
 
-// List returns a list of users with pagination
-func (r *UserRepository) List(offset, limit int) ([]models.User, int, error) {
+// Delete deletes a user who is a member of the organization the request named
+func (r *UserRepository) Delete(ctx context.Context, id uint) error {
+	return scopedMembers(ctx, r.conn(ctx)).Delete(&models.User{}, id).Error
+}
+
+// List returns a list of the members of the organization the request named, or of every user
+// in the default organization, with pagination
+func (r *UserRepository) List(ctx context.Context, offset, limit int, includes []string) ([]models.User, int, error) {
 	var users []models.User
 	var count int
 
 	// Get the total count
-	if err := r.db.Model(&models.User{}).Count(&count).Error; err != nil {
+	if err := scopedMembers(ctx, r.conn(ctx)).Model(&models.User{}).Count(&count).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// Get the users with pagination
-	err := r.db.Preload("Role").Offset(offset).Limit(limit).Find(&users).Error
+	err := repository.Preload(scopedMembers(ctx, r.conn(ctx)), includes, repository.UserIncludes).Offset(offset).Limit(limit).Find(&users).Error
 	return users, count, err
 }
+
+// ListDeletionDue returns the users whose scheduled deletion is due
+func (r *UserRepository) ListDeletionDue(ctx context.Context, now time.Time) ([]models.User, error) {
+	var users []models.User
+	err := r.conn(ctx).Where("deletion_scheduled_at <= ?", now).Find(&users).Error
+	return users, err
+}
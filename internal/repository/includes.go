@@ -0,0 +1,26 @@
+package repository
+
+import "github.com/jinzhu/gorm"
+
+// BlogIncludes maps the relation names accepted by the include query parameter
+// on blog endpoints to the GORM preload paths that load them
+var BlogIncludes = map[string]string{
+	"user": "User",
+}
+
+// UserIncludes maps the relation names accepted by the include query parameter
+// on user endpoints to the GORM preload paths that load them
+var UserIncludes = map[string]string{
+	"role":             "Role",
+	"role.permissions": "Role.Permissions",
+}
+
+// Preload adds a GORM preload for every requested include found in allowed
+func Preload(db *gorm.DB, includes []string, allowed map[string]string) *gorm.DB {
+	for _, include := range includes {
+		if path, ok := allowed[include]; ok {
+			db = db.Preload(path)
+		}
+	}
+	return db
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/userblog/management/internal/models"
+)
+
+// IInvitationRepository defines the interface for invitation database operations
+type IInvitationRepository interface {
+	Create(ctx context.Context, invitation *models.Invitation) error
+	FindByID(ctx context.Context, orgID, id uint) (*models.Invitation, error)
+	FindByTokenHash(ctx context.Context, tokenHash string) (*models.Invitation, error)
+	ListPending(ctx context.Context, orgID uint, now time.Time) ([]models.Invitation, error)
+	MarkAccepted(ctx context.Context, id, userID uint, at time.Time) (int64, error)
+	Renew(ctx context.Context, orgID, id uint, tokenHash string, expiresAt time.Time) (int64, error)
+	Delete(ctx context.Context, orgID, id uint) (int64, error)
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/userblog/management/internal/models"
+)
+
+// IOrganizationRepository defines the interface for organization and membership database operations
+type IOrganizationRepository interface {
+	Create(ctx context.Context, org *models.Organization) error
+	FindByID(ctx context.Context, id uint) (*models.Organization, error)
+	FindBySlug(ctx context.Context, slug string) (*models.Organization, error)
+	Update(ctx context.Context, org *models.Organization) error
+	CreateMembership(ctx context.Context, membership *models.Membership) error
+	FindMembership(ctx context.Context, orgID, userID uint) (*models.Membership, error)
+	UpdateMembershipRole(ctx context.Context, orgID, userID, roleID uint) error
+	DeleteMembership(ctx context.Context, orgID, userID uint) (int64, error)
+	CountMembersWithRole(ctx context.Context, orgID, roleID uint) (int, error)
+	ListMembers(ctx context.Context, orgID uint, offset, limit int) ([]models.Membership, int, error)
+	ListMemberships(ctx context.Context, userID uint) ([]models.Membership, error)
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/userblog/management/internal/models"
+)
+
+// PersonalData is everything stored about a user, as included in their data export
+type PersonalData struct {
+	User                models.User
+	Blogs               []models.Blog
+	Memberships         []models.Membership
+	Sessions            []models.Session
+	Identities          []models.Identity
+	Invitations         []models.Invitation
+	ImpersonationEvents []models.ImpersonationEvent
+	AuditEvents         []models.AuditEvent
+	DataRequests        []models.DataRequest
+}
+
+// IPersonalDataRepository defines the interface for reading and erasing a user's data across
+// every table holding it
+type IPersonalDataRepository interface {
+	Collect(ctx context.Context, userID uint) (*PersonalData, error)
+	Erase(ctx context.Context, user *models.User, actions map[string]string) (map[string]models.ErasureResult, error)
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/userblog/management/internal/models"
+)
+
+// IRoleRepository defines the interface for role database operations
+type IRoleRepository interface {
+	Create(ctx context.Context, role *models.Role) error
+	FindByID(ctx context.Context, id uint) (*models.Role, error)
+	FindByName(ctx context.Context, name string) (*models.Role, error)
+	List(ctx context.Context) ([]models.Role, error)
+	Update(ctx context.Context, role *models.Role) error
+	ReplacePermissions(ctx context.Context, role *models.Role, permissions []models.Permission) error
+	ListPermissions(ctx context.Context) ([]models.Permission, error)
+}
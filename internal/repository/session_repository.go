@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/userblog/management/internal/models"
+)
+
+// ISessionRepository defines the interface for login session database operations
+type ISessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	FindByID(ctx context.Context, id uint) (*models.Session, error)
+	ListActiveByUser(ctx context.Context, userID uint, now time.Time) ([]models.Session, error)
+	Touch(ctx context.Context, id uint, lastSeen time.Time) error
+	Delete(ctx context.Context, userID, id uint) (int64, error)
+	DeleteOthers(ctx context.Context, userID, keepID uint) (int64, error)
+	DeleteExpired(ctx context.Context, userID uint, now time.Time) error
+}
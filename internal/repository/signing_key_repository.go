@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/userblog/management/internal/models"
+)
+
+// ISigningKeyRepository defines the interface for token signing key database operations
+type ISigningKeyRepository interface {
+	Create(ctx context.Context, key *models.SigningKey) error
+	ListUnexpired(ctx context.Context, now time.Time) ([]models.SigningKey, error)
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
@@ -1,19 +1,29 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"github.com/jinzhu/gorm"
 	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/pkg/tracing"
 )
 
 // IUserRepository defines the interface for user database operations
 type IUserRepository interface {
-	Create(user *models.User) error
-	FindByID(id uint) (*models.User, error)
-	FindByUsername(username string) (*models.User, error)
-	FindByEmail(email string) (*models.User, error)
-	Update(user *models.User) error
-	Delete(id uint) error
-	List(offset, limit int) ([]models.User, int, error)
+	Create(ctx context.Context, user *models.User) error
+	CreateAcceptingInvitation(ctx context.Context, user *models.User, invitationID uint, at time.Time) (int64, error)
+	FindByID(ctx context.Context, id uint) (*models.User, error)
+	FindByIDWithIncludes(ctx context.Context, id uint, includes []string) (*models.User, error)
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	UpdateRole(ctx context.Context, id, roleID uint) error
+	UpdateProfile(ctx context.Context, id uint, email, firstName, lastName string) error
+	UpdateColumns(ctx context.Context, id uint, columns map[string]interface{}) error
+	Delete(ctx context.Context, id uint) error
+	List(ctx context.Context, offset, limit int, includes []string) ([]models.User, int, error)
+	ListDeletionDue(ctx context.Context, now time.Time) ([]models.User, error)
 }
 
 // UserRepository handles all database operations for users
@@ -28,53 +38,78 @@ func NewUserRepository(database *gorm.DB) *UserRepository {
 	}
 }
 
+// conn returns the database handle carrying ctx for query tracing
+func (r *UserRepository) conn(ctx context.Context) *gorm.DB {
+	return tracing.DB(ctx, r.db)
+}
+
 // Create creates a new user
-func (r *UserRepository) Create(user *models.User) error {
-	return r.db.Create(user).Error
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	return r.conn(ctx).Create(user).Error
 }
 
 // FindByID finds a user by ID
-func (r *UserRepository) FindByID(id uint) (*models.User, error) {
+func (r *UserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	return r.FindByIDWithIncludes(ctx, id, []string{"role.permissions"})
+}
+
+// FindByIDWithIncludes finds a user by ID, preloading only the requested relations
+func (r *UserRepository) FindByIDWithIncludes(ctx context.Context, id uint, includes []string) (*models.User, error) {
 	var user models.User
-	err := r.db.Preload("Role.Permissions").First(&user, id).Error
+	err := Preload(r.conn(ctx), includes, UserIncludes).First(&user, id).Error
 	return &user, err
 }
 
 // FindByUsername finds a user by username
-func (r *UserRepository) FindByUsername(username string) (*models.User, error) {
+func (r *UserRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	err := r.db.Preload("Role.Permissions").Where("username = ?", username).First(&user).Error
+	err := r.conn(ctx).Preload("Role.Permissions").Where("username = ?", username).First(&user).Error
 	return &user, err
 }
 
 // FindByEmail finds a user by email
-func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	err := r.db.Preload("Role.Permissions").Where("email = ?", email).First(&user).Error
+	err := r.conn(ctx).Preload("Role.Permissions").Where("email = ?", email).First(&user).Error
 	return &user, err
 }
 
 // Update updates a user
-func (r *UserRepository) Update(user *models.User) error {
-	return r.db.Save(user).Error
+func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
+	// The preloaded role isn't saved with the user, or its ID would overwrite a changed role_id
+	return r.conn(ctx).Set("gorm:association_autoupdate", false).Set("gorm:association_save_reference", false).Save(user).Error
+}
+
+// UpdateRole changes a user's role without running the save hooks
+func (r *UserRepository) UpdateRole(ctx context.Context, id, roleID uint) error {
+	return r.conn(ctx).Model(&models.User{}).Where("id = ?", id).UpdateColumn("role_id", roleID).Error
+}
+
+// UpdateProfile changes a user's email and name without running the save hooks
+func (r *UserRepository) UpdateProfile(ctx context.Context, id uint, email, firstName, lastName string) error {
+	return r.conn(ctx).Model(&models.User{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"email":      email,
+		"first_name": firstName,
+		"last_name":  lastName,
+	}).Error
 }
 
 // Delete deletes a user
-func (r *UserRepository) Delete(id uint) error {
-	return r.db.Delete(&models.User{}, id).Error
+func (r *UserRepository) Delete(ctx context.Context, id uint) error {
+	return r.conn(ctx).Delete(&models.User{}, id).Error
 }
 
 // List returns a list of users with pagination
-func (r *UserRepository) List(offset, limit int) ([]models.User, int, error) {
+func (r *UserRepository) List(ctx context.Context, offset, limit int, includes []string) ([]models.User, int, error) {
 	var users []models.User
 	var count int
 
 	// Get the total count
-	if err := r.db.Model(&models.User{}).Count(&count).Error; err != nil {
+	if err := r.conn(ctx).Model(&models.User{}).Count(&count).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// Get the users with pagination
-	err := r.db.Preload("Role").Offset(offset).Limit(limit).Find(&users).Error
+	err := Preload(r.conn(ctx), includes, UserIncludes).Offset(offset).Limit(limit).Find(&users).Error
 	return users, count, err
 }
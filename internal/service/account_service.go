@@ -0,0 +1,21 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/userblog/management/internal/models"
+)
+
+// IAccountService defines the interface for users managing their own account. Password, email
+// and deletion requests are confirmed with the current password.
+type IAccountService interface {
+	UpdateName(ctx context.Context, userID uint, firstName, lastName string) (*models.User, error)
+	ChangePassword(ctx context.Context, userID, sessionID uint, currentPassword, newPassword string) error
+	RequestEmailChange(ctx context.Context, userID uint, currentPassword, email string) (time.Time, error)
+	VerifyEmailChange(ctx context.Context, userID uint, token string) error
+	ScheduleDeletion(ctx context.Context, userID uint, currentPassword string) (time.Time, error)
+	CancelDeletion(ctx context.Context, userID uint) error
+	PurgeDeleted(ctx context.Context) (int, error)
+	RunPurge(ctx context.Context, interval time.Duration)
+}
@@ -0,0 +1,25 @@
+package service
+
+import "context"
+
+// actorKey is the context key of the authenticated actor
+type actorKey struct{}
+
+// Actor is the user a request is made by. ImpersonatorID is set when an administrator is
+// acting as the user.
+type Actor struct {
+	UserID         uint
+	Username       string
+	ImpersonatorID uint
+}
+
+// WithActor returns a context carrying the actor, for attributing changes
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor of the request, if it is authenticated
+func ActorFromContext(ctx context.Context) (Actor, bool) {
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+	return actor, ok
+}
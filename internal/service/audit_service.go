@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/pkg/audit"
+)
+
+// Audited actions
+const (
+	AuditUserRegister         = "user.register"
+	AuditUserCreate           = "user.create"
+	AuditUserUpdate           = "user.update"
+	AuditUserDelete           = "user.delete"
+	AuditUserUnlock           = "user.unlock"
+	AuditUserDisable          = "user.disable"
+	AuditUserEnable           = "user.enable"
+	AuditUserRoleChange       = "user.role_change"
+	AuditUserImpersonate      = "user.impersonate"
+	AuditUserPasswordChange   = "user.password_change"
+	AuditUserEmailChange      = "user.email_change"
+	AuditUserDeletionSchedule = "user.deletion_schedule"
+	AuditUserDeletionCancel   = "user.deletion_cancel"
+	AuditUserInvite           = "user.invite"
+	AuditUserRevokeInvite     = "user.revoke_invite"
+	AuditUserResendInvite     = "user.resend_invite"
+	AuditRoleCreate           = "role.create"
+	AuditRoleUpdate           = "role.update"
+	AuditOrgCreate            = "organization.create"
+	AuditOrgUpdate            = "organization.update"
+	AuditOrgInvite            = "organization.invite"
+	AuditOrgRevokeInvite      = "organization.revoke_invite"
+	AuditOrgJoin              = "organization.join"
+	AuditOrgMemberUpdate      = "organization.member_update"
+	AuditOrgMemberRemove      = "organization.member_remove"
+	AuditPrivacyExportRequest = "privacy.export_request"
+	AuditPrivacyEraseRequest  = "privacy.erasure_request"
+	AuditPrivacyApprove       = "privacy.approve"
+	AuditPrivacyReject        = "privacy.reject"
+	AuditPrivacyExport        = "privacy.export"
+	AuditPrivacyErase         = "privacy.erase"
+	AuditBlogCreate           = "blog.create"
+	AuditBlogUpdate           = "blog.update"
+	AuditBlogDelete           = "blog.delete"
+	AuditLogin                = "auth.login"
+	AuditLoginFailed          = "auth.login_failed"
+	AuditLogout               = "auth.logout"
+	AuditLogoutOthers         = "auth.logout_others"
+)
+
+// AuditVerification is the result of checking the audit log's hash chain
+type AuditVerification struct {
+	Valid     bool   `json:"valid"`
+	Checked   int    `json:"checked"`
+	HeadHash  string `json:"head_hash"`
+	InvalidID uint   `json:"invalid_id,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// IAuditService defines the interface for the audit log
+type IAuditService interface {
+	Record(ctx context.Context, action, targetType string, targetID interface{}, changes map[string]audit.Change)
+	List(ctx context.Context, filter repository.AuditFilter, page, perPage int) ([]models.AuditEvent, int, error)
+	Export(ctx context.Context, filter repository.AuditFilter, fn func(*models.AuditEvent) error) error
+	Verify(ctx context.Context) (*AuditVerification, error)
+}
@@ -1,13 +1,16 @@
 package service
 
-import "github.com/userblog/management/internal/models"
+import (
+	"context"
+
+	"github.com/userblog/management/internal/models"
+)
 
 // IAuthService defines the interface for authentication operations
 type IAuthService interface {
-	Register(user *models.User) error
-	Login(username, password string) (string, error)
-	GetUserByID(id uint) (*models.User, error)
-	ValidateToken(tokenString string) (*models.User, error)
-	ValidatePermission(user *models.User, resource, action string) bool
+	Register(ctx context.Context, user *models.User, invitationToken string) error
+	Login(ctx context.Context, username, password string) (string, error)
+	GetUserByID(ctx context.Context, id uint) (*models.User, error)
+	ValidateToken(ctx context.Context, tokenString string) (*models.User, *TokenClaims, error)
 	ExtractTokenFromHeader(authHeader string) (string, error)
 }
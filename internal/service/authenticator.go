@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/userblog/management/internal/models"
+)
+
+// ErrInvalidCredentials is returned by an authenticator that doesn't accept a username and
+// password, so the next authenticator in the chain is tried
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// IAuthenticator verifies a username and password against one identity store and returns
+// the matching local user, creating or updating it if the store is external
+type IAuthenticator interface {
+	Name() string
+	Authenticate(ctx context.Context, username, password string) (*models.User, error)
+}
+
+// IDirectoryAuthenticator is an authenticator backed by a directory whose users' profiles and
+// roles are kept in sync with it
+type IDirectoryAuthenticator interface {
+	IAuthenticator
+	Sync(ctx context.Context) error
+	RunSync(ctx context.Context, interval time.Duration)
+}
@@ -0,0 +1,17 @@
+package service
+
+import (
+	"context"
+
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/pkg/policy"
+)
+
+// IAuthzService defines the interface for access decisions, which combine the permissions of
+// the user's role with the policy rules in the configuration
+type IAuthzService interface {
+	Decide(ctx context.Context, user *models.User, action string, resource policy.Attributes) *policy.Decision
+	Authorize(ctx context.Context, userID uint, action string, resource policy.Attributes) error
+	LoadResource(ctx context.Context, resourceType string, given policy.Attributes) (policy.Attributes, error)
+	Explain(ctx context.Context, userID uint, action string, resource, environment policy.Attributes) (*policy.Decision, error)
+}
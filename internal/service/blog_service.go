@@ -1,13 +1,17 @@
 package service
 
-import "github.com/userblog/management/internal/models"
+import (
+	"context"
+
+	"github.com/userblog/management/internal/models"
+)
 
 // IBlogService defines the interface for blog operations
 type IBlogService interface {
-	Create(blog *models.Blog, userID uint) error
-	GetByID(id uint) (*models.Blog, error)
-	Update(blog *models.Blog, userID uint) error
-	Delete(id uint, userID uint) error
-	List(page, perPage int, publishedOnly bool) ([]models.Blog, int, error)
-	ListByUser(userID uint, page, perPage int) ([]models.Blog, int, error)
+	Create(ctx context.Context, blog *models.Blog, userID uint) error
+	GetByID(ctx context.Context, id uint, includes []string) (*models.Blog, error)
+	Update(ctx context.Context, blog *models.Blog, userID uint) error
+	Delete(ctx context.Context, id uint, userID uint) error
+	List(ctx context.Context, page, perPage int, publishedOnly bool, includes []string) ([]models.Blog, int, error)
+	ListByUser(ctx context.Context, userID uint, page, perPage int, includes []string) ([]models.Blog, int, error)
 }
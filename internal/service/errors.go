@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// NotFoundError is returned when a requested entity does not exist
+type NotFoundError struct {
+	Resource string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s not found", e.Resource)
+}
+
+// ConflictError is returned when an operation clashes with existing data, such as a duplicate username
+type ConflictError struct {
+	Message string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+// ForbiddenError is returned when the caller is authenticated but not allowed to perform the operation
+type ForbiddenError struct {
+	Message string
+}
+
+func (e *ForbiddenError) Error() string {
+	return e.Message
+}
+
+// UnauthorizedError is returned when credentials or tokens are missing or invalid
+type UnauthorizedError struct {
+	Message string
+}
+
+func (e *UnauthorizedError) Error() string {
+	return e.Message
+}
+
+// LockedError is returned when too many failed attempts have temporarily locked an account or client
+type LockedError struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return e.Message
+}
+
+// FieldError describes why a single input field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when input is rejected, optionally with per-field details
+type ValidationError struct {
+	Message string
+	Fields  []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// NewNotFoundError creates a NotFoundError for the given resource
+func NewNotFoundError(resource string) error {
+	return &NotFoundError{Resource: resource}
+}
+
+// NewConflictError creates a ConflictError with the given message
+func NewConflictError(message string) error {
+	return &ConflictError{Message: message}
+}
+
+// NewForbiddenError creates a ForbiddenError with the given message
+func NewForbiddenError(message string) error {
+	return &ForbiddenError{Message: message}
+}
+
+// NewUnauthorizedError creates an UnauthorizedError with the given message
+func NewUnauthorizedError(message string) error {
+	return &UnauthorizedError{Message: message}
+}
+
+// NewValidationError creates a ValidationError with the given message and field details
+func NewValidationError(message string, fields ...FieldError) error {
+	return &ValidationError{Message: message, Fields: fields}
+}
+
+// NewLockedError creates a LockedError that clears after retryAfter
+func NewLockedError(message string, retryAfter time.Duration) error {
+	return &LockedError{Message: message, RetryAfter: retryAfter}
+}
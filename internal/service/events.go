@@ -0,0 +1,36 @@
+package service
+
+// Names of the events published by the services
+const (
+	// EventAccountLocked is published when failed logins lock an account.
+	// Data: user_id, username, email, ip, locked_until
+	EventAccountLocked = "account.locked"
+
+	// EventPasswordChanged is published when users change their own password.
+	// Data: user_id, username, email
+	EventPasswordChanged = "account.password_changed"
+
+	// EventEmailChangeRequested is published when users ask to change their email address.
+	// Data: user_id, username, email (the current address), new_email, token, expires_at
+	EventEmailChangeRequested = "account.email_change_requested"
+
+	// EventDeletionScheduled is published when users ask to delete their account.
+	// Data: user_id, username, email, delete_at
+	EventDeletionScheduled = "account.deletion_scheduled"
+
+	// EventDataExportReady is published when a user's data export can be downloaded.
+	// Data: user_id, username, email, request_id, expires_at
+	EventDataExportReady = "privacy.export_ready"
+
+	// EventDataErased is published when a user's data has been erased, with the address it had.
+	// Data: user_id, username, email, request_id, certificate_id
+	EventDataErased = "privacy.erased"
+
+	// EventUserInvited is published when someone is invited to register, or their invitation is resent.
+	// Data: email, inviter, token, expires_at
+	EventUserInvited = "user.invited"
+
+	// EventMemberInvited is published when someone is invited to join an organization.
+	// Data: email, organization, inviter, token, expires_at
+	EventMemberInvited = "organization.invited"
+)
@@ -0,0 +1,13 @@
+package service
+
+import (
+	"context"
+
+	"github.com/userblog/management/internal/models"
+)
+
+// IImpersonationService defines the interface for administrators acting as other users
+type IImpersonationService interface {
+	Start(ctx context.Context, actor *models.User, userID uint, reason string) (string, *models.Session, error)
+	Record(ctx context.Context, event *models.ImpersonationEvent) error
+}
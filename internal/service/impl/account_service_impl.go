@@ -0,0 +1,284 @@
+package impl
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+	"time"
+
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/audit"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/event"
+	"github.com/userblog/management/pkg/logger"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// systemActor is who changes made by background jobs, such as purging deleted accounts, are
+// attributed to
+var systemActor = service.Actor{Username: "system"}
+
+// AccountService implements the IAccountService interface
+type AccountService struct {
+	userRepo   repository.IUserRepository
+	sessions   service.ISessionService
+	principals service.IPrincipalService
+	events     event.IBus
+	audit      service.IAuditService
+}
+
+// NewAccountService creates a new service for users managing their own account
+func NewAccountService(userRepo repository.IUserRepository, sessions service.ISessionService, principals service.IPrincipalService, events event.IBus, auditService service.IAuditService) service.IAccountService {
+	return &AccountService{
+		userRepo:   userRepo,
+		sessions:   sessions,
+		principals: principals,
+		events:     events,
+		audit:      auditService,
+	}
+}
+
+// UpdateName changes the user's first and last name
+func (s *AccountService) UpdateName(ctx context.Context, userID uint, firstName, lastName string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "AccountService.UpdateName")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, notFound(err, "user")
+	}
+	before := *user
+
+	if err := s.userRepo.UpdateColumns(ctx, userID, map[string]interface{}{"first_name": firstName, "last_name": lastName}); err != nil {
+		return nil, err
+	}
+	user.FirstName, user.LastName = firstName, lastName
+	s.principals.Invalidate(ctx, userID)
+
+	s.audit.Record(ctx, service.AuditUserUpdate, "user", userID, audit.Diff(&before, user))
+	return user, nil
+}
+
+// ChangePassword replaces the user's password and signs out every other session
+func (s *AccountService) ChangePassword(ctx context.Context, userID, sessionID uint, currentPassword, newPassword string) error {
+	ctx, span := tracing.Start(ctx, "AccountService.ChangePassword")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return notFound(err, "user")
+	}
+	if err := user.ValidatePassword(currentPassword); err != nil {
+		return service.NewValidationError("current password is incorrect")
+	}
+	if err := user.SetPassword(newPassword); err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdateColumns(ctx, userID, map[string]interface{}{"password": user.Password}); err != nil {
+		return err
+	}
+	s.principals.Invalidate(ctx, userID)
+	if _, err := s.sessions.RevokeOthers(ctx, userID, sessionID); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, service.AuditUserPasswordChange, "user", userID, nil)
+	s.events.Publish(ctx, service.EventPasswordChanged, map[string]interface{}{
+		"user_id":  user.ID,
+		"username": user.Username,
+		"email":    user.Email,
+	})
+	return nil
+}
+
+// RequestEmailChange sends a token to the new address, which becomes the user's once the token
+// is verified. It returns when the token expires.
+func (s *AccountService) RequestEmailChange(ctx context.Context, userID uint, currentPassword, email string) (time.Time, error) {
+	ctx, span := tracing.Start(ctx, "AccountService.RequestEmailChange")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, notFound(err, "user")
+	}
+	if err := user.ValidatePassword(currentPassword); err != nil {
+		return time.Time{}, service.NewValidationError("current password is incorrect")
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if strings.EqualFold(email, user.Email) {
+		return time.Time{}, service.NewValidationError("this is already your email address")
+	}
+	if existing, err := s.userRepo.FindByEmail(ctx, email); err == nil && existing.ID != 0 {
+		return time.Time{}, service.NewConflictError("a user with this email already exists")
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return time.Time{}, err
+	}
+	expiresAt := time.Now().Add(config.Current().Account.EmailTokenTTL)
+	err = s.userRepo.UpdateColumns(ctx, userID, map[string]interface{}{
+		"pending_email":          email,
+		"email_token_hash":       hashToken(token),
+		"email_token_expires_at": expiresAt,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	s.events.Publish(ctx, service.EventEmailChangeRequested, map[string]interface{}{
+		"user_id":    user.ID,
+		"username":   user.Username,
+		"email":      user.Email,
+		"new_email":  email,
+		"token":      token,
+		"expires_at": expiresAt,
+	})
+	return expiresAt, nil
+}
+
+// VerifyEmailChange makes the pending email address the user's when the token sent to it is valid
+func (s *AccountService) VerifyEmailChange(ctx context.Context, userID uint, token string) error {
+	ctx, span := tracing.Start(ctx, "AccountService.VerifyEmailChange")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return notFound(err, "user")
+	}
+	if user.PendingEmail == "" || user.EmailTokenExpiresAt == nil || time.Now().After(*user.EmailTokenExpiresAt) ||
+		subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(user.EmailTokenHash)) != 1 {
+		return service.NewValidationError("invalid or expired email verification token")
+	}
+	// The address may have been taken since the change was requested
+	if existing, err := s.userRepo.FindByEmail(ctx, user.PendingEmail); err == nil && existing.ID != 0 {
+		return service.NewConflictError("a user with this email already exists")
+	}
+
+	err = s.userRepo.UpdateColumns(ctx, userID, map[string]interface{}{
+		"email":                  user.PendingEmail,
+		"pending_email":          "",
+		"email_token_hash":       "",
+		"email_token_expires_at": nil,
+	})
+	if err != nil {
+		return err
+	}
+	s.principals.Invalidate(ctx, userID)
+
+	s.audit.Record(ctx, service.AuditUserEmailChange, "user", userID, map[string]audit.Change{
+		"email": {Before: user.Email, After: user.PendingEmail},
+	})
+	return nil
+}
+
+// ScheduleDeletion deletes the user's account once the configured grace period has passed and
+// signs out all of its sessions. Signing in again and cancelling keeps the account. It returns
+// when the account will be deleted.
+func (s *AccountService) ScheduleDeletion(ctx context.Context, userID uint, currentPassword string) (time.Time, error) {
+	ctx, span := tracing.Start(ctx, "AccountService.ScheduleDeletion")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, notFound(err, "user")
+	}
+	if err := user.ValidatePassword(currentPassword); err != nil {
+		return time.Time{}, service.NewValidationError("current password is incorrect")
+	}
+	if user.DeletionScheduledAt != nil {
+		return *user.DeletionScheduledAt, nil
+	}
+
+	deleteAt := time.Now().Add(config.Current().Account.DeletionGrace)
+	if err := s.userRepo.UpdateColumns(ctx, userID, map[string]interface{}{"deletion_scheduled_at": deleteAt}); err != nil {
+		return time.Time{}, err
+	}
+	s.principals.Invalidate(ctx, userID)
+	if _, err := s.sessions.RevokeOthers(ctx, userID, 0); err != nil {
+		return time.Time{}, err
+	}
+
+	s.audit.Record(ctx, service.AuditUserDeletionSchedule, "user", userID, map[string]audit.Change{
+		"deletion_scheduled_at": {After: deleteAt},
+	})
+	s.events.Publish(ctx, service.EventDeletionScheduled, map[string]interface{}{
+		"user_id":   user.ID,
+		"username":  user.Username,
+		"email":     user.Email,
+		"delete_at": deleteAt,
+	})
+	return deleteAt, nil
+}
+
+// CancelDeletion keeps an account scheduled for deletion
+func (s *AccountService) CancelDeletion(ctx context.Context, userID uint) error {
+	ctx, span := tracing.Start(ctx, "AccountService.CancelDeletion")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return notFound(err, "user")
+	}
+	if user.DeletionScheduledAt == nil {
+		return service.NewNotFoundError("scheduled deletion")
+	}
+
+	if err := s.userRepo.UpdateColumns(ctx, userID, map[string]interface{}{"deletion_scheduled_at": nil}); err != nil {
+		return err
+	}
+	s.principals.Invalidate(ctx, userID)
+
+	s.audit.Record(ctx, service.AuditUserDeletionCancel, "user", userID, map[string]audit.Change{
+		"deletion_scheduled_at": {Before: *user.DeletionScheduledAt},
+	})
+	return nil
+}
+
+// PurgeDeleted deletes the accounts whose deletion grace period has passed and returns how many
+func (s *AccountService) PurgeDeleted(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "AccountService.PurgeDeleted")
+	defer span.End()
+
+	users, err := s.userRepo.ListDeletionDue(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	ctx = service.WithActor(ctx, systemActor)
+	for i := range users {
+		user := &users[i]
+		if err := s.userRepo.Delete(ctx, user.ID); err != nil {
+			return i, err
+		}
+		if _, err := s.sessions.RevokeOthers(ctx, user.ID, 0); err != nil {
+			logger.WarnF(ctx, "Failed to end the sessions of deleted user %d: %v", user.ID, err)
+		}
+		s.principals.Invalidate(ctx, user.ID)
+		s.audit.Record(ctx, service.AuditUserDelete, "user", user.ID, audit.Diff(user, nil))
+	}
+	return len(users), nil
+}
+
+// RunPurge deletes the accounts due for deletion every interval until ctx is done
+func (s *AccountService) RunPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeDeleted(ctx)
+			if err != nil {
+				logger.ErrorF(ctx, "Failed to delete accounts due for deletion: %v", err)
+			}
+			if purged > 0 {
+				logger.InfoF(ctx, "Deleted %d accounts at the end of their grace period", purged)
+			}
+		}
+	}
+}
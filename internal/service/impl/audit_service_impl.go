@@ -0,0 +1,175 @@
+package impl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/audit"
+	"github.com/userblog/management/pkg/logger"
+	"github.com/userblog/management/pkg/metrics"
+	"github.com/userblog/management/pkg/tenant"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+const (
+	// auditAppendAttempts bounds the retries when another instance appends to the chain first
+	auditAppendAttempts = 5
+	// auditBatchSize is how many events export and verification read at a time
+	auditBatchSize = 500
+)
+
+// AuditService implements the IAuditService interface
+type AuditService struct {
+	auditRepo repository.IAuditEventRepository
+
+	// mu serializes appends within this instance, so they don't race for the chain head
+	mu sync.Mutex
+}
+
+// NewAuditService creates a new audit log service
+func NewAuditService(auditRepo repository.IAuditEventRepository) service.IAuditService {
+	return &AuditService{
+		auditRepo: auditRepo,
+	}
+}
+
+// Record appends an event attributed to the request's actor. Failures are logged and counted
+// but don't fail the audited operation, which has already happened.
+func (s *AuditService) Record(ctx context.Context, action, targetType string, targetID interface{}, changes map[string]audit.Change) {
+	ctx, span := tracing.Start(ctx, "AuditService.Record")
+	defer span.End()
+
+	event := &models.AuditEvent{
+		Action:     action,
+		TargetType: targetType,
+		Version:    models.AuditEventVersion,
+	}
+	if targetID != nil {
+		event.TargetID = fmt.Sprint(targetID)
+	}
+	if actor, ok := service.ActorFromContext(ctx); ok {
+		event.ActorID = actor.UserID
+		event.ActorName = actor.Username
+		event.ImpersonatorID = actor.ImpersonatorID
+	}
+	event.IP, _ = ctx.Value(logger.ClientIpKey).(string)
+	event.TraceID, _ = ctx.Value(logger.TraceIDKey).(string)
+	event.OrganizationID = tenant.NamedID(ctx)
+	if len(changes) > 0 {
+		data, err := json.Marshal(changes)
+		if err != nil {
+			logger.ErrorF(ctx, "Failed to encode audit changes for %s: %v", action, err)
+		}
+		event.Changes = models.JSONText(data)
+	}
+
+	if err := s.append(ctx, event); err != nil {
+		metrics.AuditWriteFailures.Inc()
+		logger.ErrorF(ctx, "Failed to record audit event %s on %s %s: %v", action, targetType, event.TargetID, err)
+	}
+}
+
+// append chains the event to the newest one and stores it, retrying when another instance
+// appended in between and took the same predecessor
+func (s *AuditService) append(ctx context.Context, event *models.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < auditAppendAttempts; attempt++ {
+		last, lastErr := s.auditRepo.Last(ctx)
+		switch {
+		case gorm.IsRecordNotFoundError(lastErr):
+			event.PrevHash = ""
+		case lastErr != nil:
+			return lastErr
+		default:
+			event.PrevHash = last.Hash
+		}
+
+		event.ID = 0
+		event.CreatedAt = time.Now().UTC().Truncate(time.Second)
+		event.Hash = event.ComputeHash()
+		if err = s.auditRepo.Create(ctx, event); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// List returns a page of matching events, newest first, and the number of matches
+func (s *AuditService) List(ctx context.Context, filter repository.AuditFilter, page, perPage int) ([]models.AuditEvent, int, error) {
+	ctx, span := tracing.Start(ctx, "AuditService.List")
+	defer span.End()
+
+	offset := (page - 1) * perPage
+	return s.auditRepo.List(ctx, filter, offset, perPage)
+}
+
+// Export calls fn with every matching event, oldest first, reading them in batches
+func (s *AuditService) Export(ctx context.Context, filter repository.AuditFilter, fn func(*models.AuditEvent) error) error {
+	ctx, span := tracing.Start(ctx, "AuditService.Export")
+	defer span.End()
+
+	var afterID uint
+	for {
+		events, err := s.auditRepo.ListAfter(ctx, filter, afterID, auditBatchSize)
+		if err != nil {
+			return err
+		}
+		for i := range events {
+			if err := fn(&events[i]); err != nil {
+				return err
+			}
+		}
+		if len(events) < auditBatchSize {
+			return nil
+		}
+		afterID = events[len(events)-1].ID
+	}
+}
+
+// Verify walks the whole log, checking that every entry's hash matches its content and names
+// the previous entry's hash. The chain runs through every organization's events, so they are all
+// checked whichever organization the request names.
+func (s *AuditService) Verify(ctx context.Context) (*service.AuditVerification, error) {
+	ctx, span := tracing.Start(ctx, "AuditService.Verify")
+	defer span.End()
+	ctx = tenant.WithoutOrganization(ctx)
+
+	result := &service.AuditVerification{Valid: true}
+	err := s.Export(ctx, repository.AuditFilter{}, func(event *models.AuditEvent) error {
+		switch {
+		case event.PrevHash != result.HeadHash:
+			result.Reason = "entry does not follow the previous entry"
+		case event.Hash != event.ComputeHash():
+			result.Reason = "entry does not match its hash"
+		default:
+			result.Checked++
+			result.HeadHash = event.Hash
+			return nil
+		}
+		result.Valid = false
+		result.InvalidID = event.ID
+		return errAuditChainBroken
+	})
+	if err != nil && err != errAuditChainBroken {
+		return nil, err
+	}
+
+	if !result.Valid {
+		logger.ErrorF(ctx, "Audit log verification failed at entry %d: %s", result.InvalidID, result.Reason)
+	}
+	return result, nil
+}
+
+// errAuditChainBroken stops verification at the first invalid entry
+var errAuditChainBroken = errors.New("audit chain broken")
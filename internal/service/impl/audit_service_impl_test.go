@@ -0,0 +1,137 @@
+package impl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	repoImpl "github.com/userblog/management/internal/repository/impl"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/audit"
+	"github.com/userblog/management/pkg/tenant"
+)
+
+// newAuditLog records five events, some impersonated or in an organization, and returns the
+// database and the service that wrote them
+func newAuditLog(t *testing.T) (*gorm.DB, service.IAuditService) {
+	t.Helper()
+
+	db := newTestDB(t)
+	audits := NewAuditService(repoImpl.NewAuditEventRepository(db))
+
+	ctx := service.WithActor(context.Background(), service.Actor{UserID: 1, Username: "admin"})
+	impersonated := service.WithActor(context.Background(), service.Actor{UserID: 2, Username: "alice", ImpersonatorID: 1})
+	acme := tenant.WithOrganization(ctx, tenant.Organization{ID: 7, Slug: "acme", Source: tenant.SourceHeader})
+
+	audits.Record(ctx, service.AuditUserCreate, "user", 2, audit.Diff(nil, &models.User{Username: "alice", Password: "hash"}))
+	audits.Record(impersonated, service.AuditUserUpdate, "user", 2, map[string]audit.Change{"first_name": {Before: "", After: "Alice"}})
+	audits.Record(acme, service.AuditUserUpdate, "user", 2, nil)
+	audits.Record(ctx, service.AuditUserDisable, "user", 2, nil)
+	audits.Record(ctx, service.AuditUserEnable, "user", 2, nil)
+	return db, audits
+}
+
+func TestAuditChainVerifies(t *testing.T) {
+	db, audits := newAuditLog(t)
+
+	var events []models.AuditEvent
+	db.Order("id").Find(&events)
+	if len(events) != 5 {
+		t.Fatalf("recorded %d events, want 5", len(events))
+	}
+	for i, event := range events {
+		previous := ""
+		if i > 0 {
+			previous = events[i-1].Hash
+		}
+		if event.PrevHash != previous || event.Hash != event.ComputeHash() {
+			t.Errorf("event %d isn't chained to its predecessor", event.ID)
+		}
+	}
+	if events[1].ImpersonatorID != 1 || events[2].OrganizationID != 7 {
+		t.Errorf("events = %+v, want the impersonator and organization recorded", events)
+	}
+
+	// Verification covers every organization's events, whichever the request names
+	acme := tenant.WithOrganization(context.Background(), tenant.Organization{ID: 7, Slug: "acme", Source: tenant.SourceHeader})
+	result, err := audits.Verify(acme)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !result.Valid || result.Checked != 5 || result.HeadHash != events[4].Hash {
+		t.Errorf("Verify = %+v, want all 5 entries valid", result)
+	}
+}
+
+func TestAuditChainDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(db *gorm.DB)
+		wantID uint
+		reason string
+	}{
+		{
+			name: "changed entry",
+			tamper: func(db *gorm.DB) {
+				db.Model(&models.AuditEvent{}).Where("id = ?", 2).UpdateColumn("changes", `{"first_name":{"before":"","after":"Mallory"}}`)
+			},
+			wantID: 2,
+			reason: "entry does not match its hash",
+		},
+		{
+			name: "changed organization",
+			tamper: func(db *gorm.DB) {
+				db.Model(&models.AuditEvent{}).Where("id = ?", 3).UpdateColumn("organization_id", 0)
+			},
+			wantID: 3,
+			reason: "entry does not match its hash",
+		},
+		{
+			name:   "removed entry",
+			tamper: func(db *gorm.DB) { db.Delete(&models.AuditEvent{}, 3) },
+			wantID: 4,
+			reason: "entry does not follow the previous entry",
+		},
+		{
+			name: "rehashed entry",
+			tamper: func(db *gorm.DB) {
+				var event models.AuditEvent
+				db.First(&event, 4)
+				event.ActorName = "someone-else"
+				db.Model(&event).UpdateColumns(map[string]interface{}{"actor_name": event.ActorName, "hash": event.ComputeHash()})
+			},
+			wantID: 5,
+			reason: "entry does not follow the previous entry",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, audits := newAuditLog(t)
+			tt.tamper(db)
+
+			result, err := audits.Verify(context.Background())
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if result.Valid || result.InvalidID != tt.wantID || result.Reason != tt.reason {
+				t.Errorf("Verify = %+v, want entry %d invalid: %s", result, tt.wantID, tt.reason)
+			}
+		})
+	}
+}
+
+func TestAuditDiffRedactsPasswords(t *testing.T) {
+	changes := audit.Diff(&models.User{Username: "alice", Password: "old-hash"}, &models.User{Username: "alice", Password: "new-hash", FirstName: "Alice"})
+
+	if len(changes) != 2 {
+		t.Errorf("changes = %v, want the password and first name", changes)
+	}
+	if password := changes["password"]; password.Before != audit.Redacted || password.After != audit.Redacted {
+		t.Errorf("password change = %+v, want both sides redacted", password)
+	}
+	if name := changes["first_name"]; name.Before != nil || name.After != "Alice" {
+		t.Errorf("first name change = %+v", name)
+	}
+}
@@ -1,207 +1,392 @@
 package impl
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/jinzhu/gorm"
-	"github.com/joho/godotenv"
 	"github.com/userblog/management/internal/models"
 	"github.com/userblog/management/internal/repository"
 	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/audit"
 	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/event"
+	"github.com/userblog/management/pkg/lockout"
+	"github.com/userblog/management/pkg/logger"
+	"github.com/userblog/management/pkg/metrics"
+	"github.com/userblog/management/pkg/tenant"
+	"github.com/userblog/management/pkg/tracing"
 )
 
 // AuthService implements the IAuthService interface
 type AuthService struct {
-	userRepo repository.IUserRepository
+	userRepo       repository.IUserRepository
+	roleRepo       repository.IRoleRepository
+	invitationRepo repository.IInvitationRepository
+	authenticators []service.IAuthenticator
+	tokens         service.ITokenService
+	sessions       service.ISessionService
+	principals     service.IPrincipalService
+	attempts       lockout.Store
+	events         event.IBus
+	audit          service.IAuditService
 }
 
-// NewAuthService creates a new authentication service
-func NewAuthService(userRepo repository.IUserRepository) service.IAuthService {
+// NewAuthService creates a new authentication service that checks passwords with the
+// authenticators in order
+func NewAuthService(userRepo repository.IUserRepository, roleRepo repository.IRoleRepository, invitationRepo repository.IInvitationRepository, authenticators []service.IAuthenticator, tokens service.ITokenService, sessions service.ISessionService, principals service.IPrincipalService, attempts lockout.Store, events event.IBus, auditService service.IAuditService) service.IAuthService {
 	return &AuthService{
-		userRepo: userRepo,
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+		invitationRepo: invitationRepo,
+		authenticators: authenticators,
+		tokens:         tokens,
+		sessions:       sessions,
+		principals:     principals,
+		attempts:       attempts,
+		events:         events,
+		audit:          auditService,
 	}
 }
 
-// Register registers a new user
-func (s *AuthService) Register(user *models.User) error {
+// Register registers a new user, as the registration mode allows. The user gets the role of
+// their invitation, if it names one, or the default role; a role set by the caller is ignored.
+func (s *AuthService) Register(ctx context.Context, user *models.User, invitationToken string) error {
+	ctx, span := tracing.Start(ctx, "AuthService.Register")
+	defer span.End()
+
+	cfg := config.Current().Auth
+	if cfg.Registration == config.RegistrationClosed {
+		return service.NewForbiddenError("registration is closed")
+	}
+	if cfg.Registration == config.RegistrationInvite && invitationToken == "" {
+		return service.NewForbiddenError("registration requires an invitation")
+	}
+
+	var invitation *models.Invitation
+	var err error
+	if invitationToken != "" {
+		if invitation, err = s.findInvitation(ctx, invitationToken, user.Email); err != nil {
+			return err
+		}
+	}
+
 	// Check if username already exists
-	existingUser, err := s.userRepo.FindByUsername(user.Username)
+	existingUser, err := s.userRepo.FindByUsername(ctx, user.Username)
 	if err == nil && existingUser.ID != 0 {
-		return errors.New("username already exists")
+		return service.NewConflictError("username already exists")
 	}
 
 	// Check if email already exists
-	existingUser, err = s.userRepo.FindByEmail(user.Email)
+	existingUser, err = s.userRepo.FindByEmail(ctx, user.Email)
 	if err == nil && existingUser.ID != 0 {
-		return errors.New("email already exists")
+		return service.NewConflictError("email already exists")
 	}
 
-	// Set default role if not provided
-	if user.RoleID == 0 {
-		user.RoleID = 2 // Default to 'user' role (ID=2)
+	if invitation != nil && invitation.RoleID != 0 {
+		user.RoleID = invitation.RoleID
+	} else {
+		role, err := s.roleRepo.FindByName(ctx, cfg.DefaultRole)
+		if err != nil {
+			return fmt.Errorf("role %q for registration: %w", cfg.DefaultRole, err)
+		}
+		user.RoleID = role.ID
 	}
 
 	// Create the user
-	err = s.userRepo.Create(user)
+	if invitation == nil {
+		err = s.userRepo.Create(ctx, user)
+	} else {
+		// Of two registrations with one invitation at once, only the first to accept it creates its user
+		var accepted int64
+		accepted, err = s.userRepo.CreateAcceptingInvitation(ctx, user, invitation.ID, time.Now())
+		if err == nil && accepted == 0 {
+			err = service.NewConflictError("invitation has already been used")
+		}
+	}
 	if err != nil {
 		return err
 	}
 
+	ctx = service.WithActor(ctx, service.Actor{UserID: user.ID, Username: user.Username})
+	s.audit.Record(ctx, service.AuditUserRegister, "user", user.ID, audit.Diff(nil, user))
 	return nil
 }
 
-// Login authenticates a user and returns a JWT token
-func (s *AuthService) Login(username, password string) (string, error) {
-	// Find user by username
-	user, err := s.userRepo.FindByUsername(username)
+// findInvitation returns the unused, unexpired invitation to register with the token, which must
+// have been sent to the email address
+func (s *AuthService) findInvitation(ctx context.Context, token, email string) (*models.Invitation, error) {
+	invitation, err := s.invitationRepo.FindByTokenHash(ctx, hashToken(token))
+	if err == nil && invitation.OrganizationID != platformInvitations {
+		err = gorm.ErrRecordNotFound
+	}
 	if err != nil {
-		if gorm.IsRecordNotFoundError(err) {
-			return "", errors.New("invalid username or password")
+		return nil, notFound(err, "invitation")
+	}
+	if invitation.AcceptedAt != nil {
+		return nil, service.NewConflictError("invitation has already been used")
+	}
+	if !time.Now().Before(invitation.ExpiresAt) {
+		return nil, service.NewValidationError("invitation has expired")
+	}
+	if !strings.EqualFold(invitation.Email, email) {
+		return nil, service.NewForbiddenError("invitation was sent to another email address")
+	}
+	return invitation, nil
+}
+
+// Login authenticates a user and returns a JWT token
+func (s *AuthService) Login(ctx context.Context, username, password string) (string, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Login")
+	defer span.End()
+
+	// Attempts are counted by username, known or not, so lockouts don't reveal which accounts exist
+	ip, _ := ctx.Value(logger.ClientIpKey).(string)
+	accountKey := accountLockoutKey(username)
+	ipKey := "ip:" + ip
+
+	if err := s.checkLockout(ctx, accountKey, ipKey); err != nil {
+		return "", err
+	}
+
+	user, err := s.authenticate(ctx, username, password)
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		// The account, if there is one, is only needed to notify its owner of a lockout
+		known, findErr := s.userRepo.FindByUsername(ctx, username)
+		if findErr != nil {
+			known = nil
 		}
+		s.recordLoginFailure(ctx, known, username)
+		return "", s.loginFailed(ctx, known, accountKey, ipKey)
+	}
+	if err != nil {
 		return "", err
 	}
+	if user.DisabledAt != nil {
+		return "", service.NewForbiddenError("account is disabled")
+	}
 
-	// Validate password
-	if err := user.ValidatePassword(password); err != nil {
-		return "", errors.New("invalid username or password")
+	if err := s.attempts.Reset(ctx, accountKey); err != nil {
+		return "", err
 	}
 
-	// Generate JWT token
-	token, err := generateToken(user)
+	// Every login is a session of its own, so it can be terminated separately
+	session, err := s.sessions.Start(ctx, user)
+	if err != nil {
+		return "", err
+	}
+	token, err := s.tokens.Issue(ctx, user, session)
 	if err != nil {
 		return "", err
 	}
 
+	ctx = service.WithActor(ctx, service.Actor{UserID: user.ID, Username: user.Username})
+	s.audit.Record(ctx, service.AuditLogin, "session", session.ID, nil)
+	metrics.LoginAttempts.WithLabelValues("success").Inc()
 	return token, nil
 }
 
 // GetUserByID returns a user by ID
-func (s *AuthService) GetUserByID(id uint) (*models.User, error) {
-	return s.userRepo.FindByID(id)
+func (s *AuthService) GetUserByID(ctx context.Context, id uint) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.GetUserByID")
+	defer span.End()
+
+	user, err := s.principals.Load(ctx, id)
+	if err != nil {
+		return nil, notFound(err, "user")
+	}
+	return user, nil
 }
 
-// ValidateToken validates a JWT token and returns the user
-func (s *AuthService) ValidateToken(tokenString string) (*models.User, error) {
-	// Load environment variables
-	_ = godotenv.Load()
-	jwtSecret := config.GetOrDefaultString("JWT_SECRET", "")
+// ValidateToken validates a JWT token and its session and returns the user with the token's claims
+func (s *AuthService) ValidateToken(ctx context.Context, tokenString string) (*models.User, *service.TokenClaims, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.ValidateToken")
+	defer span.End()
 
 	// Check if the token is empty
 	if tokenString == "" {
-		return nil, errors.New("token is required")
+		metrics.TokenValidationFailures.WithLabelValues("missing").Inc()
+		return nil, nil, service.NewUnauthorizedError("token is required")
 	}
 
-	// Parse the token
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Validate the algorithm
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(jwtSecret), nil
-	})
-
+	// Verify the signature and the standard claims
+	claims, err := s.tokens.Verify(ctx, tokenString)
 	if err != nil {
-		return nil, fmt.Errorf("invalid token: %v", err)
-	}
-
-	// Check if the token is valid
-	if !token.Valid {
-		return nil, errors.New("invalid token")
-	}
-
-	// Extract claims
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return nil, errors.New("invalid token claims")
+		reason := "invalid"
+		if validationErr, ok := err.(*jwt.ValidationError); ok && validationErr.Errors&jwt.ValidationErrorExpired != 0 {
+			reason = "expired"
+		}
+		metrics.TokenValidationFailures.WithLabelValues(reason).Inc()
+		return nil, nil, service.NewUnauthorizedError(fmt.Sprintf("invalid token: %v", err))
 	}
 
-	// Check if the token is expired
-	expires := time.Unix(int64(claims["exp"].(float64)), 0)
-	if time.Now().After(expires) {
-		return nil, errors.New("token has expired")
+	// Get user ID from the subject
+	userID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil {
+		metrics.TokenValidationFailures.WithLabelValues("invalid").Inc()
+		return nil, nil, service.NewUnauthorizedError("invalid token subject")
 	}
 
-	// Get user ID from claims
-	userID := uint(claims["user_id"].(float64))
-
-	// Load user from database
-	user, err := s.userRepo.FindByID(userID)
+	// Load user with their effective permissions in the organization the token is for, unless
+	// the request names another
+	ctx = tenant.WithTokenOrganization(ctx, claims.Organization)
+	user, err := s.principals.Load(ctx, uint(userID))
 	if err != nil {
 		if gorm.IsRecordNotFoundError(err) {
-			return nil, errors.New("user not found")
+			metrics.TokenValidationFailures.WithLabelValues("user_not_found").Inc()
+			return nil, nil, service.NewUnauthorizedError("user not found")
 		}
-		return nil, err
+		return nil, nil, err
 	}
-
-	return user, nil
-}
-
-// ValidatePermission checks if a user has the required permission
-func (s *AuthService) ValidatePermission(user *models.User, resource, action string) bool {
-	if user == nil || user.Role.Permissions == nil {
-		return false
+	if user.DisabledAt != nil {
+		metrics.TokenValidationFailures.WithLabelValues("user_disabled").Inc()
+		return nil, nil, service.NewUnauthorizedError("account is disabled")
 	}
 
-	// Check if user has the required permission
-	for _, permission := range user.Role.Permissions {
-		if permission.Resource == resource && permission.Action == action {
-			return true
-		}
+	// A terminated session logs out every token issued for it
+	session, err := s.sessions.Check(ctx, user.ID, claims.SessionID)
+	if err != nil {
+		metrics.TokenValidationFailures.WithLabelValues("session_terminated").Inc()
+		return nil, nil, err
+	}
+	if err := s.checkActor(ctx, claims, session); err != nil {
+		metrics.TokenValidationFailures.WithLabelValues("impersonation_ended").Inc()
+		return nil, nil, err
 	}
 
-	return false
+	return user, claims, nil
 }
 
 // ExtractTokenFromHeader extracts the JWT token from the Authorization header
 func (s *AuthService) ExtractTokenFromHeader(authHeader string) (string, error) {
 	if authHeader == "" {
-		return "", errors.New("authorization header is required")
+		return "", service.NewUnauthorizedError("authorization header is required")
 	}
 
 	// Check if the header has the Bearer prefix
 	parts := strings.Split(authHeader, " ")
 	if len(parts) != 2 || parts[0] != "Bearer" {
-		return "", errors.New("authorization header format must be Bearer {token}")
+		return "", service.NewUnauthorizedError("authorization header format must be Bearer {token}")
 	}
 
 	return parts[1], nil
 }
 
-// generateToken generates a JWT token for a user
-func generateToken(user *models.User) (string, error) {
-	// Load environment variables
-	_ = godotenv.Load()
-	jwtSecret := config.GetOrDefaultString("JWT_SECRET", "your-secret-key")
-	tokenExpiry := config.GetOrDefaultString("TOKEN_EXPIRY", "24")
+// checkActor ends an impersonation as soon as the administrator behind it loses the permission
+// to impersonate or is deleted
+func (s *AuthService) checkActor(ctx context.Context, claims *service.TokenClaims, session *models.Session) error {
+	if !claims.Impersonating() && session.ActorID == 0 {
+		return nil
+	}
+	if !claims.Impersonating() || claims.Actor.Subject != strconv.FormatUint(uint64(session.ActorID), 10) {
+		return service.NewUnauthorizedError("token does not match its session")
+	}
 
-	// Parse token expiry duration
-	expiryHours := 24 // Default to 24 hours
-	_, err := fmt.Sscanf(tokenExpiry, "%d", &expiryHours)
-	if err != nil {
-		expiryHours = 24
+	actor, err := s.principals.Load(ctx, session.ActorID)
+	if gorm.IsRecordNotFoundError(err) || (err == nil && !hasPermission(actor, "user", "impersonate")) {
+		return service.NewUnauthorizedError("impersonation has ended")
+	}
+	return err
+}
+
+// authenticate tries each authenticator in order until one accepts the credentials. An
+// authenticator that fails, such as an unreachable directory, is skipped; its error is only
+// returned when no authenticator could check the credentials at all.
+func (s *AuthService) authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	var failure error
+	for _, authenticator := range s.authenticators {
+		user, err := authenticator.Authenticate(ctx, username, password)
+		switch {
+		case err == nil:
+			return user, nil
+		case errors.Is(err, service.ErrInvalidCredentials):
+			failure = service.ErrInvalidCredentials
+		default:
+			logger.ErrorF(ctx, "The %s authenticator failed: %v", authenticator.Name(), err)
+			if failure == nil {
+				failure = err
+			}
+		}
+	}
+	if failure == nil {
+		return nil, service.ErrInvalidCredentials
+	}
+	return nil, failure
+}
+
+// recordLoginFailure audits a rejected login by the unauthenticated username that was tried,
+// targeting the account with that name if there is one
+func (s *AuthService) recordLoginFailure(ctx context.Context, known *models.User, username string) {
+	var target interface{}
+	if known != nil {
+		target = known.ID
 	}
+	s.audit.Record(service.WithActor(ctx, service.Actor{Username: username}), service.AuditLoginFailed, "user", target, nil)
+}
 
-	// Create token claims
-	claims := jwt.MapClaims{
-		"user_id":  user.ID,
-		"username": user.Username,
-		"role_id":  user.RoleID,
-		"exp":      time.Now().Add(time.Hour * time.Duration(expiryHours)).Unix(),
+// checkLockout returns a LockedError when the account or the client IP is locked
+func (s *AuthService) checkLockout(ctx context.Context, keys ...string) error {
+	now := time.Now()
+	for _, key := range keys {
+		status, err := s.attempts.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if status.Locked(now) {
+			metrics.LoginAttempts.WithLabelValues("locked").Inc()
+			return service.NewLockedError("too many failed login attempts, try again later", status.LockedUntil.Sub(now))
+		}
 	}
+	return nil
+}
 
-	// Create token with claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+// loginFailed records a failed attempt, publishes a lockout event when the account becomes locked
+// and applies the progressive delay before returning the generic credentials error
+func (s *AuthService) loginFailed(ctx context.Context, user *models.User, accountKey, ipKey string) error {
+	metrics.LoginAttempts.WithLabelValues("failure").Inc()
 
-	// Sign token with secret key
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	accountPolicy := lockout.AccountPolicy()
+	account, err := s.attempts.Fail(ctx, accountKey, accountPolicy)
 	if err != nil {
-		return "", err
+		return err
+	}
+	client, err := s.attempts.Fail(ctx, ipKey, lockout.IPPolicy())
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if account.Locked(now) && user != nil {
+		logger.WarnF(ctx, "Account %s locked until %s after %d failed logins", user.Username, account.LockedUntil.Format(time.RFC3339), account.Failures)
+		s.events.Publish(ctx, service.EventAccountLocked, map[string]interface{}{
+			"user_id":      user.ID,
+			"username":     user.Username,
+			"email":        user.Email,
+			"ip":           strings.TrimPrefix(ipKey, "ip:"),
+			"locked_until": account.LockedUntil,
+		})
+	}
+	if client.Locked(now) {
+		logger.WarnF(ctx, "Client %s locked until %s after %d failed logins", strings.TrimPrefix(ipKey, "ip:"), client.LockedUntil.Format(time.RFC3339), client.Failures)
 	}
 
-	return tokenString, nil
+	// Slow down repeated guessing; the delay depends only on the failure count, not on whether the user exists
+	if delay := accountPolicy.Delay(account.Failures); delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+	}
+
+	return service.NewUnauthorizedError("invalid username or password")
+}
+
+// accountLockoutKey returns the failed attempt key for a username
+func accountLockoutKey(username string) string {
+	return "account:" + strings.ToLower(username)
 }
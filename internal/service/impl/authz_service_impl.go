@@ -0,0 +1,268 @@
+package impl
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/logger"
+	"github.com/userblog/management/pkg/policy"
+	"github.com/userblog/management/pkg/tenant"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// AuthzService implements the IAuthzService interface
+type AuthzService struct {
+	userRepo   repository.IUserRepository
+	blogRepo   repository.IBlogRepository
+	principals service.IPrincipalService
+
+	// compiled caches the engine for the active configuration, which changes on reload
+	compiled atomic.Pointer[compiledPolicy]
+}
+
+// compiledPolicy is the policy engine built from one configuration
+type compiledPolicy struct {
+	cfg    *config.Config
+	engine *policy.Engine
+}
+
+// NewAuthzService creates a new access decision service
+func NewAuthzService(userRepo repository.IUserRepository, blogRepo repository.IBlogRepository, principals service.IPrincipalService) service.IAuthzService {
+	return &AuthzService{
+		userRepo:   userRepo,
+		blogRepo:   blogRepo,
+		principals: principals,
+	}
+}
+
+// Decide evaluates whether the user may perform the action, given as resource:action, on the
+// resource described by its attributes
+func (s *AuthzService) Decide(ctx context.Context, user *models.User, action string, resource policy.Attributes) *policy.Decision {
+	ctx, span := tracing.Start(ctx, "AuthzService.Decide")
+	defer span.End()
+
+	return s.decide(ctx, user, action, resource, nil)
+}
+
+// Authorize returns a forbidden error unless the user may perform the action on the resource
+func (s *AuthzService) Authorize(ctx context.Context, userID uint, action string, resource policy.Attributes) error {
+	ctx, span := tracing.Start(ctx, "AuthzService.Authorize")
+	defer span.End()
+
+	user, err := s.principals.Load(ctx, userID)
+	if err != nil {
+		return notFound(err, "user")
+	}
+
+	decision := s.decide(ctx, user, action, resource, nil)
+	if !decision.Allowed {
+		return service.NewForbiddenError(decision.Reason)
+	}
+	return nil
+}
+
+// LoadResource adds the attributes of the stored resource named by type and id, such as a
+// blog's user_id and published, to those given, which take precedence
+func (s *AuthzService) LoadResource(ctx context.Context, resourceType string, given policy.Attributes) (policy.Attributes, error) {
+	ctx, span := tracing.Start(ctx, "AuthzService.LoadResource")
+	defer span.End()
+
+	return s.loadResource(ctx, resourceType, given)
+}
+
+// Explain decides as Decide does for the given user, with every rule that applied. Attributes
+// of a stored resource named by type and id are filled in, and the environment can be overridden
+// to ask, for example, what would happen at another hour.
+func (s *AuthzService) Explain(ctx context.Context, userID uint, action string, resource, environment policy.Attributes) (*policy.Decision, error) {
+	ctx, span := tracing.Start(ctx, "AuthzService.Explain")
+	defer span.End()
+
+	if !strings.Contains(action, ":") {
+		return nil, service.NewValidationError("action must be resource:action, such as blog:update")
+	}
+
+	user, err := s.principals.Load(ctx, userID)
+	if err != nil {
+		return nil, notFound(err, "user")
+	}
+
+	resource, err = s.loadResource(ctx, actionResource(action), resource)
+	if err != nil {
+		return nil, err
+	}
+	return s.decide(ctx, user, action, resource, environment), nil
+}
+
+// decide builds the request for the policy engine and evaluates it
+func (s *AuthzService) decide(ctx context.Context, user *models.User, action string, resource, environment policy.Attributes) *policy.Decision {
+	req := policy.Request{
+		Action:      action,
+		Subject:     subjectAttributes(user),
+		Resource:    policy.Attributes{"type": actionResource(action)},
+		Environment: environmentAttributes(ctx),
+	}
+	for name, value := range resource {
+		req.Resource[name] = value
+	}
+	for name, value := range environment {
+		req.Environment[name] = value
+	}
+
+	resourceName, verb, _ := strings.Cut(action, ":")
+	if hasPermission(user, resourceName, verb) {
+		req.Role = user.Role.Name
+	}
+
+	engine := s.engine(ctx)
+	if engine == nil {
+		return &policy.Decision{Action: action, Reason: "the access policy is invalid", Rules: []policy.RuleResult{}}
+	}
+	return engine.Evaluate(req)
+}
+
+// engine returns the policy engine for the active configuration, compiling it after a reload
+func (s *AuthzService) engine(ctx context.Context) *policy.Engine {
+	cfg := config.Current()
+	if compiled := s.compiled.Load(); compiled != nil && compiled.cfg == cfg {
+		return compiled.engine
+	}
+
+	// Validation compiles the rules too, so this only fails if the configuration was set unchecked
+	engine, err := policy.Compile(cfg.Authz.PolicyRules())
+	if err != nil {
+		logger.ErrorF(ctx, "Invalid access policy, denying every request: %v", err)
+	}
+	s.compiled.Store(&compiledPolicy{cfg: cfg, engine: engine})
+	return engine
+}
+
+// loadResource adds the attributes of the stored resource named by its id to those given,
+// which take precedence
+func (s *AuthzService) loadResource(ctx context.Context, resourceType string, given policy.Attributes) (policy.Attributes, error) {
+	if t, ok := given["type"].(string); ok {
+		resourceType = t
+	}
+	id, ok := attributeID(given["id"])
+	if !ok {
+		return given, nil
+	}
+
+	var stored policy.Attributes
+	switch resourceType {
+	case "blog":
+		blog, err := s.blogRepo.FindByID(ctx, id)
+		if err != nil {
+			return nil, notFound(err, "blog")
+		}
+		stored = blogAttributes(blog)
+	case "user":
+		user, err := s.userRepo.FindByID(ctx, id)
+		if err != nil {
+			return nil, notFound(err, "user")
+		}
+		stored = userAttributes(user)
+	default:
+		return given, nil
+	}
+
+	for name, value := range given {
+		stored[name] = value
+	}
+	return stored, nil
+}
+
+// actionResource returns the resource part of a resource:action pair
+func actionResource(action string) string {
+	resource, _, _ := strings.Cut(action, ":")
+	return resource
+}
+
+// attributeID reads a resource ID given as a number or a string
+func attributeID(value interface{}) (uint, bool) {
+	switch v := value.(type) {
+	case uint:
+		return v, true
+	case int:
+		return uint(v), v > 0
+	case float64:
+		return uint(v), v > 0
+	case string:
+		id, err := strconv.ParseUint(v, 10, 64)
+		return uint(id), err == nil
+	}
+	return 0, false
+}
+
+// subjectAttributes describes the user making a request to the policy engine
+func subjectAttributes(user *models.User) policy.Attributes {
+	permissions := make([]string, 0, len(user.Role.Permissions))
+	for _, permission := range user.Role.Permissions {
+		permissions = append(permissions, permission.Resource+":"+permission.Action)
+	}
+	subject := policy.Attributes{
+		"id":          user.ID,
+		"username":    user.Username,
+		"email":       user.Email,
+		"role":        user.Role.Name,
+		"permissions": permissions,
+	}
+	if user.Membership != nil {
+		subject["org_role"] = user.Membership.Role.Name
+	}
+	return subject
+}
+
+// userAttributes describes a user acted on to the policy engine
+func userAttributes(user *models.User) policy.Attributes {
+	return policy.Attributes{
+		"type":     "user",
+		"id":       user.ID,
+		"username": user.Username,
+		"email":    user.Email,
+		"role":     user.Role.Name,
+	}
+}
+
+// blogAttributes describes a blog to the policy engine
+func blogAttributes(blog *models.Blog) policy.Attributes {
+	return policy.Attributes{
+		"type":       "blog",
+		"id":         blog.ID,
+		"user_id":    blog.UserID,
+		"title":      blog.Title,
+		"published":  blog.Published,
+		"org_id":     blog.OrganizationID,
+		"created_at": blog.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// environmentAttributes describes when and from where a request is made. Times are in UTC.
+func environmentAttributes(ctx context.Context) policy.Attributes {
+	now := time.Now().UTC()
+	env := policy.Attributes{
+		"time":          now.Format(time.RFC3339),
+		"hour":          now.Hour(),
+		"weekday":       strings.ToLower(now.Weekday().String()),
+		"impersonating": false,
+	}
+	if ip, ok := ctx.Value(logger.ClientIpKey).(string); ok && ip != "" {
+		env["ip"] = ip
+	}
+	if actor, ok := service.ActorFromContext(ctx); ok {
+		env["impersonating"] = actor.ImpersonatorID != 0
+	}
+	if org, ok := tenant.FromContext(ctx); ok {
+		env["org_id"] = org.ID
+		if org.Slug != "" {
+			env["org"] = org.Slug
+		}
+	}
+	return env
+}
@@ -0,0 +1,57 @@
+package impl
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/tracing"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyUser is checked against when the username is unknown, so both failure cases cost one bcrypt comparison
+var dummyUser = func() models.User {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+	return models.User{Password: string(hash)}
+}()
+
+// DatabaseAuthenticator implements the IAuthenticator interface with the bcrypt password hashes
+// in the users table
+type DatabaseAuthenticator struct {
+	userRepo repository.IUserRepository
+}
+
+// NewDatabaseAuthenticator creates a new authenticator for local passwords
+func NewDatabaseAuthenticator(userRepo repository.IUserRepository) service.IAuthenticator {
+	return &DatabaseAuthenticator{
+		userRepo: userRepo,
+	}
+}
+
+// Name returns the name used in auth.authenticators
+func (a *DatabaseAuthenticator) Name() string {
+	return "database"
+}
+
+// Authenticate checks the password against the user's stored hash
+func (a *DatabaseAuthenticator) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "DatabaseAuthenticator.Authenticate")
+	defer span.End()
+
+	user, err := a.userRepo.FindByUsername(ctx, username)
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+
+	// Validate password, against a dummy hash for unknown users to keep timing uniform
+	if err != nil {
+		_ = dummyUser.ValidatePassword(password)
+		return nil, service.ErrInvalidCredentials
+	}
+	if err := user.ValidatePassword(password); err != nil {
+		return nil, service.ErrInvalidCredentials
+	}
+	return user, nil
+}
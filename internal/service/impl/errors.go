@@ -0,0 +1,14 @@
+package impl
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/service"
+)
+
+// notFound converts a GORM record-not-found error into a NotFoundError for resource
+func notFound(err error, resource string) error {
+	if gorm.IsRecordNotFoundError(err) {
+		return service.NewNotFoundError(resource)
+	}
+	return err
+}
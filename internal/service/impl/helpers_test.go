@@ -0,0 +1,133 @@
+package impl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/userblog/management/internal/models"
+	repoImpl "github.com/userblog/management/internal/repository/impl"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/cache"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/event"
+	"github.com/userblog/management/pkg/lockout"
+	"github.com/userblog/management/pkg/token"
+)
+
+// newTestDB opens an in-memory SQLite database with every table and the admin and user roles
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	// Every connection to :memory: is a new database, so keep to one
+	db.DB().SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	if err := db.AutoMigrate(models.All()...).Error; err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+	for _, name := range []string{"admin", "user"} {
+		if err := db.Create(&models.Role{Name: name}).Error; err != nil {
+			t.Fatalf("creating role %s: %v", name, err)
+		}
+	}
+	return db
+}
+
+// setConfig makes cfg the active configuration until the test ends
+func setConfig(t *testing.T, cfg *config.Config) {
+	t.Helper()
+
+	previous := config.Current()
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(previous) })
+}
+
+// authFixture holds the services that issue and validate tokens, on a database with the admin
+// (user 1, every permission) and alice (user 2, none)
+type authFixture struct {
+	db            *gorm.DB
+	auth          service.IAuthService
+	tokens        service.ITokenService
+	sessions      service.ISessionService
+	principals    service.IPrincipalService
+	impersonation service.IImpersonationService
+	admin, alice  *models.User
+}
+
+// newAuthFixture signs tokens with HS256 so no keys need generating
+func newAuthFixture(t *testing.T) *authFixture {
+	t.Helper()
+
+	db := newTestDB(t)
+	cfg := config.Default()
+	cfg.JWT.Algorithm = token.HS256
+	cfg.JWT.Secret = "a-test-secret-long-enough-for-hs256"
+	setConfig(t, cfg)
+
+	all := &models.Permission{Name: "all", Resource: models.PermissionWildcard, Action: models.PermissionWildcard}
+	if err := db.Create(all).Error; err != nil {
+		t.Fatalf("creating permission: %v", err)
+	}
+	if err := db.Model(&models.Role{Model: gorm.Model{ID: 1}}).Association("Permissions").Append(all).Error; err != nil {
+		t.Fatalf("granting the admin role every permission: %v", err)
+	}
+
+	userRepo := repoImpl.NewUserRepository(db)
+	roleRepo := repoImpl.NewRoleRepository(db)
+	auditService := NewAuditService(repoImpl.NewAuditEventRepository(db))
+	cacheStore := cache.NewMemoryStore(100)
+	roleService := NewRoleService(roleRepo, auditService, cache.New("roles", cacheStore))
+	principals := NewPrincipalService(userRepo, repoImpl.NewOrganizationRepository(db), roleService, cache.New("principals", cacheStore))
+	sessions := NewSessionService(repoImpl.NewSessionRepository(db), auditService)
+	tokens := NewTokenService(repoImpl.NewSigningKeyRepository(db))
+
+	f := &authFixture{
+		db:            db,
+		auth:          NewAuthService(userRepo, roleRepo, repoImpl.NewInvitationRepository(db), nil, tokens, sessions, principals, lockout.NewMemoryStore(), event.NewBus(), auditService),
+		tokens:        tokens,
+		sessions:      sessions,
+		principals:    principals,
+		impersonation: NewImpersonationService(repoImpl.NewImpersonationEventRepository(db), sessions, tokens, principals, auditService),
+		admin:         &models.User{Username: "admin", Email: "admin@example.com", RoleID: 1},
+		alice:         &models.User{Username: "alice", Email: "alice@example.com", RoleID: 2},
+	}
+	for _, user := range []*models.User{f.admin, f.alice} {
+		if err := userRepo.Create(context.Background(), user); err != nil {
+			t.Fatalf("creating %s: %v", user.Username, err)
+		}
+	}
+	return f
+}
+
+// login starts a session for the user and returns a token for it
+func (f *authFixture) login(t *testing.T, user *models.User) (string, *models.Session) {
+	t.Helper()
+
+	ctx := context.Background()
+	session, err := f.sessions.Start(ctx, user)
+	if err != nil {
+		t.Fatalf("starting a session: %v", err)
+	}
+	signed, err := f.tokens.Issue(ctx, user, session)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	return signed, session
+}
+
+// principal loads the user with their permissions, as the authentication middleware does
+func (f *authFixture) principal(t *testing.T, id uint) *models.User {
+	t.Helper()
+
+	user, err := f.principals.Load(context.Background(), id)
+	if err != nil {
+		t.Fatalf("loading user %d: %v", id, err)
+	}
+	return user
+}
@@ -0,0 +1,88 @@
+package impl
+
+import (
+	"context"
+	"time"
+
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/logger"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// ImpersonationService implements the IImpersonationService interface
+type ImpersonationService struct {
+	eventRepo  repository.IImpersonationEventRepository
+	sessions   service.ISessionService
+	tokens     service.ITokenService
+	principals service.IPrincipalService
+	audit      service.IAuditService
+}
+
+// NewImpersonationService creates a new impersonation service
+func NewImpersonationService(eventRepo repository.IImpersonationEventRepository, sessions service.ISessionService, tokens service.ITokenService, principals service.IPrincipalService, auditService service.IAuditService) service.IImpersonationService {
+	return &ImpersonationService{
+		eventRepo:  eventRepo,
+		sessions:   sessions,
+		tokens:     tokens,
+		principals: principals,
+		audit:      auditService,
+	}
+}
+
+// Start issues a short-lived token with which the actor acts as the user, and records why
+func (s *ImpersonationService) Start(ctx context.Context, actor *models.User, userID uint, reason string) (string, *models.Session, error) {
+	ctx, span := tracing.Start(ctx, "ImpersonationService.Start")
+	defer span.End()
+
+	if actor.ID == userID {
+		return "", nil, service.NewValidationError("you cannot impersonate yourself")
+	}
+
+	user, err := s.principals.Load(ctx, userID)
+	if err != nil {
+		return "", nil, notFound(err, "user")
+	}
+
+	// Impersonating must not give the actor permissions they don't already have
+	for _, permission := range user.Role.Permissions {
+		if !hasPermission(actor, permission.Resource, permission.Action) {
+			return "", nil, service.NewForbiddenError("you cannot impersonate a user with permissions you don't have")
+		}
+	}
+
+	session, err := s.sessions.StartImpersonation(ctx, actor, user)
+	if err != nil {
+		return "", nil, err
+	}
+	token, err := s.tokens.Issue(ctx, user, session)
+	if err != nil {
+		return "", nil, err
+	}
+
+	err = s.Record(ctx, &models.ImpersonationEvent{
+		ActorID:   actor.ID,
+		UserID:    user.ID,
+		SessionID: session.ID,
+		Action:    models.ImpersonationStarted,
+		Reason:    reason,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	s.audit.Record(ctx, service.AuditUserImpersonate, "user", user.ID, nil)
+	logger.WarnF(ctx, "User %s started impersonating user %s until %s: %s", actor.Username, user.Username, session.ExpiresAt.Format(time.RFC3339), reason)
+
+	return token, session, nil
+}
+
+// Record appends an impersonation event, adding the client IP and trace ID of the request
+func (s *ImpersonationService) Record(ctx context.Context, event *models.ImpersonationEvent) error {
+	ctx, span := tracing.Start(ctx, "ImpersonationService.Record")
+	defer span.End()
+
+	event.IP, _ = ctx.Value(logger.ClientIpKey).(string)
+	event.TraceID, _ = ctx.Value(logger.TraceIDKey).(string)
+	return s.eventRepo.Create(ctx, event)
+}
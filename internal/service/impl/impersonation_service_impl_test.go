@@ -0,0 +1,113 @@
+package impl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/config"
+)
+
+func TestImpersonationTokenNamesTheActor(t *testing.T) {
+	f := newAuthFixture(t)
+	ctx := context.Background()
+
+	signed, session, err := f.impersonation.Start(ctx, f.principal(t, f.admin.ID), f.alice.ID, "ticket 42")
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if session.UserID != f.alice.ID || session.ActorID != f.admin.ID {
+		t.Errorf("session = %+v, want alice's, started by the admin", session)
+	}
+
+	user, claims, err := f.auth.ValidateToken(ctx, signed)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if user.ID != f.alice.ID || !claims.Impersonating() || claims.Actor.Subject != "1" {
+		t.Errorf("token for user %d with act %+v, want alice acted on by user 1", user.ID, claims.Actor)
+	}
+
+	// The token expires with the short impersonation session, not after the usual expiry
+	cfg := config.Current().JWT
+	expires := time.Unix(claims.ExpiresAt, 0)
+	if until := time.Until(expires); until > cfg.ImpersonationExpiry || until < cfg.ImpersonationExpiry-time.Minute {
+		t.Errorf("token expires in %s, want %s", until, cfg.ImpersonationExpiry)
+	}
+	if _, login := f.login(t, f.alice); !login.ExpiresAt.After(expires) {
+		t.Error("an impersonation token outlives a normal login")
+	}
+
+	var events []models.ImpersonationEvent
+	f.db.Find(&events)
+	if len(events) != 1 || events[0].Action != models.ImpersonationStarted || events[0].Reason != "ticket 42" || events[0].SessionID != session.ID {
+		t.Errorf("events = %+v, want the start recorded with its reason", events)
+	}
+}
+
+func TestImpersonationIsRefused(t *testing.T) {
+	f := newAuthFixture(t)
+	ctx := context.Background()
+
+	_, _, err := f.impersonation.Start(ctx, f.principal(t, f.admin.ID), f.admin.ID, "testing")
+	assertErrorType[*service.ValidationError](t, err)
+
+	// Alice lacks the admin's permissions
+	_, _, err = f.impersonation.Start(ctx, f.principal(t, f.alice.ID), f.admin.ID, "testing")
+	assertErrorType[*service.ForbiddenError](t, err)
+
+	var count int
+	f.db.Model(&models.ImpersonationEvent{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d events recorded for refused impersonations", count)
+	}
+}
+
+func TestImpersonationEndsWithTheActorsPermission(t *testing.T) {
+	tests := []struct {
+		name string
+		end  func(f *authFixture)
+	}{
+		{
+			name: "demoted",
+			end: func(f *authFixture) {
+				f.db.Model(&models.User{}).Where("id = ?", f.admin.ID).UpdateColumn("role_id", 2)
+			},
+		},
+		{
+			name: "deleted",
+			end: func(f *authFixture) {
+				f.db.Delete(&models.User{}, f.admin.ID)
+			},
+		},
+		{
+			name: "session revoked",
+			end: func(f *authFixture) {
+				f.db.Delete(&models.Session{}, "user_id = ?", f.alice.ID)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAuthFixture(t)
+			ctx := context.Background()
+
+			signed, _, err := f.impersonation.Start(ctx, f.principal(t, f.admin.ID), f.alice.ID, "ticket 42")
+			if err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			if _, _, err := f.auth.ValidateToken(ctx, signed); err != nil {
+				t.Fatalf("ValidateToken: %v", err)
+			}
+
+			tt.end(f)
+			f.principals.Invalidate(ctx, f.admin.ID)
+
+			_, _, err = f.auth.ValidateToken(ctx, signed)
+			assertErrorType[*service.UnauthorizedError](t, err)
+		})
+	}
+}
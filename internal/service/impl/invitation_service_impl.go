@@ -0,0 +1,160 @@
+package impl
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/audit"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/event"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// platformInvitations is the organization ID of invitations to register rather than to join
+// an organization
+const platformInvitations = 0
+
+// InvitationService implements the IInvitationService interface
+type InvitationService struct {
+	invitationRepo repository.IInvitationRepository
+	userRepo       repository.IUserRepository
+	roleRepo       repository.IRoleRepository
+	roles          service.IRoleService
+	events         event.IBus
+	audit          service.IAuditService
+}
+
+// NewInvitationService creates a new service for invitations to register
+func NewInvitationService(invitationRepo repository.IInvitationRepository, userRepo repository.IUserRepository, roleRepo repository.IRoleRepository, roles service.IRoleService, events event.IBus, auditService service.IAuditService) service.IInvitationService {
+	return &InvitationService{
+		invitationRepo: invitationRepo,
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+		roles:          roles,
+		events:         events,
+		audit:          auditService,
+	}
+}
+
+// Invite invites the owner of the email address to register with the role, or the default role
+// when roleID is 0. The single-use token is only sent to the invitee.
+func (s *InvitationService) Invite(ctx context.Context, actor *models.User, email string, roleID uint) (*models.Invitation, error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.Invite")
+	defer span.End()
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	if existing, err := s.userRepo.FindByEmail(ctx, email); err == nil && existing.ID != 0 {
+		return nil, service.NewConflictError("a user with this email already exists")
+	}
+	if roleID != 0 {
+		if err := s.checkGrantable(ctx, actor, roleID); err != nil {
+			return nil, err
+		}
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	invitation := &models.Invitation{
+		OrganizationID: platformInvitations,
+		Email:          email,
+		RoleID:         roleID,
+		TokenHash:      hashToken(token),
+		InvitedByID:    actor.ID,
+		ExpiresAt:      time.Now().Add(config.Current().Auth.InvitationTTL),
+	}
+	if err := s.invitationRepo.Create(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, service.AuditUserInvite, "invitation", invitation.ID, map[string]audit.Change{
+		"email":   {After: invitation.Email},
+		"role_id": {After: roleID},
+	})
+	s.send(ctx, actor, invitation, token)
+	return invitation, nil
+}
+
+// List returns the pending invitations to register
+func (s *InvitationService) List(ctx context.Context) ([]models.Invitation, error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.List")
+	defer span.End()
+
+	return s.invitationRepo.ListPending(ctx, platformInvitations, time.Now())
+}
+
+// Revoke revokes an invitation to register
+func (s *InvitationService) Revoke(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "InvitationService.Revoke")
+	defer span.End()
+
+	deleted, err := s.invitationRepo.Delete(ctx, platformInvitations, id)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return service.NewNotFoundError("invitation")
+	}
+
+	s.audit.Record(ctx, service.AuditUserRevokeInvite, "invitation", id, nil)
+	return nil
+}
+
+// Resend sends an unaccepted invitation again with a new token, which replaces the old one, and
+// a new expiry
+func (s *InvitationService) Resend(ctx context.Context, actor *models.User, id uint) (*models.Invitation, error) {
+	ctx, span := tracing.Start(ctx, "InvitationService.Resend")
+	defer span.End()
+
+	invitation, err := s.invitationRepo.FindByID(ctx, platformInvitations, id)
+	if err != nil {
+		return nil, notFound(err, "invitation")
+	}
+	if invitation.AcceptedAt != nil {
+		return nil, service.NewConflictError("invitation has already been used")
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(config.Current().Auth.InvitationTTL)
+	renewed, err := s.invitationRepo.Renew(ctx, platformInvitations, id, hashToken(token), expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if renewed == 0 {
+		return nil, service.NewConflictError("invitation has already been used")
+	}
+
+	s.audit.Record(ctx, service.AuditUserResendInvite, "invitation", id, map[string]audit.Change{
+		"expires_at": {Before: invitation.ExpiresAt, After: expiresAt},
+	})
+	invitation.ExpiresAt = expiresAt
+	s.send(ctx, actor, invitation, token)
+	return invitation, nil
+}
+
+// send publishes the invitation with its token for the invitee to be notified
+func (s *InvitationService) send(ctx context.Context, actor *models.User, invitation *models.Invitation, token string) {
+	s.events.Publish(ctx, service.EventUserInvited, map[string]interface{}{
+		"email":      invitation.Email,
+		"inviter":    actor.Username,
+		"token":      token,
+		"expires_at": invitation.ExpiresAt,
+	})
+}
+
+// checkGrantable verifies that the role can be given to users directly and doesn't grant
+// permissions the actor lacks
+func (s *InvitationService) checkGrantable(ctx context.Context, actor *models.User, roleID uint) error {
+	if err := checkPlatformRole(ctx, s.roleRepo, roleID); err != nil {
+		return err
+	}
+	return checkGrantable(ctx, s.roles, actor, roleID, nil)
+}
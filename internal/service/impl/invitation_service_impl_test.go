@@ -0,0 +1,146 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/userblog/management/internal/models"
+	repoImpl "github.com/userblog/management/internal/repository/impl"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/cache"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/event"
+)
+
+// setRegistration switches the registration mode of the active configuration
+func setRegistration(t *testing.T, mode string) {
+	t.Helper()
+
+	cfg := *config.Current()
+	cfg.Auth.Registration = mode
+	setConfig(t, &cfg)
+}
+
+// invite stores an invitation to register for email with the role, expiring after ttl, and
+// returns its token
+func invite(t *testing.T, f *authFixture, email string, roleID uint, ttl time.Duration) string {
+	t.Helper()
+
+	token, err := randomToken()
+	if err != nil {
+		t.Fatalf("randomToken: %v", err)
+	}
+	invitation := &models.Invitation{Email: email, RoleID: roleID, TokenHash: hashToken(token), InvitedByID: f.admin.ID, ExpiresAt: time.Now().Add(ttl)}
+	if err := f.db.Create(invitation).Error; err != nil {
+		t.Fatalf("creating invitation: %v", err)
+	}
+	return token
+}
+
+func TestRegistrationModes(t *testing.T) {
+	tests := []struct {
+		mode      string
+		invited   bool
+		forbidden bool
+	}{
+		{mode: config.RegistrationOpen},
+		{mode: config.RegistrationOpen, invited: true},
+		{mode: config.RegistrationInvite, forbidden: true},
+		{mode: config.RegistrationInvite, invited: true},
+		{mode: config.RegistrationClosed, forbidden: true},
+		{mode: config.RegistrationClosed, invited: true, forbidden: true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s invited=%v", tt.mode, tt.invited), func(t *testing.T) {
+			f := newAuthFixture(t)
+			setRegistration(t, tt.mode)
+
+			token := ""
+			if tt.invited {
+				token = invite(t, f, "carol@example.com", 1, time.Hour)
+			}
+			user := &models.User{Username: "carol", Email: "carol@example.com", Password: "carol-password", RoleID: 1}
+			err := f.auth.Register(context.Background(), user, token)
+			if tt.forbidden {
+				assertErrorType[*service.ForbiddenError](t, err)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Register: %v", err)
+			}
+
+			// The invitation's role is given; otherwise the default role, whatever was asked for
+			wantRole := uint(2)
+			if tt.invited {
+				wantRole = 1
+			}
+			var created models.User
+			f.db.Where("username = ?", "carol").First(&created)
+			if created.RoleID != wantRole {
+				t.Errorf("role = %d, want %d", created.RoleID, wantRole)
+			}
+		})
+	}
+}
+
+func TestInvitationTokens(t *testing.T) {
+	f := newAuthFixture(t)
+	setRegistration(t, config.RegistrationInvite)
+	ctx := context.Background()
+
+	token := invite(t, f, "carol@example.com", 0, time.Hour)
+	if err := f.auth.Register(ctx, &models.User{Username: "carol", Email: "carol@example.com", Password: "carol-password"}, token); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	var invitation models.Invitation
+	f.db.Where("token_hash = ?", hashToken(token)).First(&invitation)
+	if invitation.AcceptedAt == nil || invitation.AcceptedByID == 0 {
+		t.Errorf("invitation = %+v, want accepted", invitation)
+	}
+
+	// A token is used once, even for another account
+	err := f.auth.Register(ctx, &models.User{Username: "carol2", Email: "carol@example.com", Password: "carol-password"}, token)
+	assertErrorType[*service.ConflictError](t, err)
+
+	expired := invite(t, f, "dave@example.com", 0, -time.Minute)
+	err = f.auth.Register(ctx, &models.User{Username: "dave", Email: "dave@example.com", Password: "dave-password"}, expired)
+	assertErrorType[*service.ValidationError](t, err)
+
+	other := invite(t, f, "erin@example.com", 0, time.Hour)
+	err = f.auth.Register(ctx, &models.User{Username: "frank", Email: "frank@example.com", Password: "frank-password"}, other)
+	assertErrorType[*service.ForbiddenError](t, err)
+
+	err = f.auth.Register(ctx, &models.User{Username: "gina", Email: "gina@example.com", Password: "gina-password"}, "unknown-token")
+	assertErrorType[*service.NotFoundError](t, err)
+}
+
+func TestInviteRefusesRolesGrantingMore(t *testing.T) {
+	f := newAuthFixture(t)
+	ctx := context.Background()
+	auditService := NewAuditService(repoImpl.NewAuditEventRepository(f.db))
+	roleRepo := repoImpl.NewRoleRepository(f.db)
+	roleService := NewRoleService(roleRepo, auditService, cache.New("roles", cache.NewMemoryStore(100)))
+	invitations := NewInvitationService(repoImpl.NewInvitationRepository(f.db), repoImpl.NewUserRepository(f.db), roleRepo, roleService, event.NewBus(), auditService)
+
+	// alice holds no permissions, so can't invite with the admin role, which holds them all
+	_, err := invitations.Invite(ctx, f.principal(t, f.alice.ID), "carol@example.com", 1)
+	assertErrorType[*service.ForbiddenError](t, err)
+
+	admin := f.principal(t, f.admin.ID)
+	if _, err := invitations.Invite(ctx, admin, "carol@example.com", 1); err != nil {
+		t.Errorf("inviting with a role the actor holds: %v", err)
+	}
+
+	// Roles belonging to an organization are only given by its memberships
+	orgRole := &models.Role{Name: "editor", OrganizationID: 7}
+	if err := f.db.Create(orgRole).Error; err != nil {
+		t.Fatalf("creating role: %v", err)
+	}
+	_, err = invitations.Invite(ctx, admin, "dave@example.com", orgRole.ID)
+	assertErrorType[*service.ValidationError](t, err)
+
+	_, err = invitations.Invite(ctx, admin, "alice@example.com", 0)
+	assertErrorType[*service.ConflictError](t, err)
+}
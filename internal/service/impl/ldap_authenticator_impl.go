@@ -0,0 +1,289 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/audit"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/directory"
+	"github.com/userblog/management/pkg/logger"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// ldapProvider is the identity provider name of directory accounts
+const ldapProvider = "ldap"
+
+// LDAPAuthenticator implements the IDirectoryAuthenticator interface by binding to an LDAP or
+// Active Directory server. Directory users get a local account on first login, linked through
+// an identity, whose profile and role follow the directory.
+type LDAPAuthenticator struct {
+	userRepo     repository.IUserRepository
+	roleRepo     repository.IRoleRepository
+	identityRepo repository.IIdentityRepository
+	principals   service.IPrincipalService
+	audit        service.IAuditService
+	directory    *directory.LDAP
+	cfg          config.LDAPConfig
+}
+
+// NewLDAPAuthenticator creates a new directory authenticator
+func NewLDAPAuthenticator(userRepo repository.IUserRepository, roleRepo repository.IRoleRepository, identityRepo repository.IIdentityRepository, principals service.IPrincipalService, auditService service.IAuditService, cfg config.LDAPConfig) service.IDirectoryAuthenticator {
+	return &LDAPAuthenticator{
+		userRepo:     userRepo,
+		roleRepo:     roleRepo,
+		identityRepo: identityRepo,
+		principals:   principals,
+		audit:        auditService,
+		directory:    directory.NewLDAP(cfg),
+		cfg:          cfg,
+	}
+}
+
+// Name returns the name used in auth.authenticators
+func (a *LDAPAuthenticator) Name() string {
+	return ldapProvider
+}
+
+// Authenticate binds as the user's directory entry and returns the linked local user
+func (a *LDAPAuthenticator) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "LDAPAuthenticator.Authenticate")
+	defer span.End()
+
+	entry, err := a.directory.Authenticate(username, password)
+	if errors.Is(err, directory.ErrInvalidCredentials) || errors.Is(err, directory.ErrNotFound) {
+		return nil, service.ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.linkedUser(ctx, entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.apply(ctx, user, entry); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Sync refreshes the profile and role of every directory user from their current entry, and
+// disables the accounts of users who are no longer in the directory
+func (a *LDAPAuthenticator) Sync(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "LDAPAuthenticator.Sync")
+	defer span.End()
+
+	identities, err := a.identityRepo.ListByProvider(ctx, ldapProvider)
+	if err != nil {
+		return err
+	}
+
+	for _, identity := range identities {
+		user, err := a.userRepo.FindByID(ctx, identity.UserID)
+		if gorm.IsRecordNotFoundError(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		entry, err := a.directory.Lookup(identity.Subject)
+		if errors.Is(err, directory.ErrNotFound) {
+			if err := a.disable(ctx, user); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := a.apply(ctx, user, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunSync syncs directory users every interval until ctx is done
+func (a *LDAPAuthenticator) RunSync(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.Sync(ctx); err != nil {
+				logger.ErrorF(ctx, "Directory sync failed: %v", err)
+			}
+		}
+	}
+}
+
+// linkedUser returns the local user linked to the entry, linking a local account with the same
+// username and email or provisioning a new one on first login
+func (a *LDAPAuthenticator) linkedUser(ctx context.Context, entry *directory.Entry) (*models.User, error) {
+	subject := strings.ToLower(entry.Username)
+	identity, err := a.identityRepo.FindByProviderSubject(ctx, ldapProvider, subject)
+	if err == nil {
+		user, err := a.userRepo.FindByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, notFound(err, "user")
+		}
+		return user, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+
+	user, err := a.userRepo.FindByUsername(ctx, entry.Username)
+	switch {
+	case err == nil:
+		// A local account is only taken over when the directory agrees on its email
+		if entry.Email == "" || !strings.EqualFold(user.Email, entry.Email) {
+			return nil, service.NewConflictError("a local account named " + entry.Username + " already exists")
+		}
+	case gorm.IsRecordNotFoundError(err):
+		if user, err = a.provision(ctx, entry); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	err = a.identityRepo.Create(ctx, &models.Identity{
+		UserID:   user.ID,
+		Provider: ldapProvider,
+		Subject:  subject,
+		Email:    entry.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.InfoF(ctx, "Linked directory entry %s to user %s", entry.DN, user.Username)
+
+	return user, nil
+}
+
+// provision creates a local account for a directory user with the mapped or default role
+func (a *LDAPAuthenticator) provision(ctx context.Context, entry *directory.Entry) (*models.User, error) {
+	if entry.Email == "" {
+		return nil, service.NewForbiddenError("the directory entry has no email")
+	}
+	if _, err := a.userRepo.FindByEmail(ctx, entry.Email); err == nil {
+		return nil, service.NewConflictError("a local account with email " + entry.Email + " already exists")
+	}
+
+	roleName := a.mappedRole(entry)
+	if roleName == "" {
+		roleName = a.cfg.DefaultRole
+	}
+	role, err := a.roleRepo.FindByName(ctx, roleName)
+	if err != nil {
+		return nil, fmt.Errorf("role %q configured for the directory: %w", roleName, err)
+	}
+
+	// Passwords are checked by the directory, so the local one is never used
+	password, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Username:  entry.Username,
+		Email:     entry.Email,
+		Password:  password,
+		FirstName: entry.FirstName,
+		LastName:  entry.LastName,
+		RoleID:    role.ID,
+	}
+	if err := a.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	logger.InfoF(ctx, "Provisioned user %s from the directory with role %s", user.Username, role.Name)
+	a.audit.Record(ctx, service.AuditUserCreate, "user", user.ID, audit.Diff(nil, user))
+
+	return a.userRepo.FindByID(ctx, user.ID)
+}
+
+// apply copies the entry's profile attributes and mapped role to the user
+func (a *LDAPAuthenticator) apply(ctx context.Context, user *models.User, entry *directory.Entry) error {
+	email, firstName, lastName := user.Email, user.FirstName, user.LastName
+	if entry.Email != "" {
+		email = entry.Email
+	}
+	if entry.FirstName != "" {
+		firstName = entry.FirstName
+	}
+	if entry.LastName != "" {
+		lastName = entry.LastName
+	}
+
+	if email != user.Email || firstName != user.FirstName || lastName != user.LastName {
+		if err := a.userRepo.UpdateProfile(ctx, user.ID, email, firstName, lastName); err != nil {
+			return err
+		}
+		a.principals.Invalidate(ctx, user.ID)
+		before := *user
+		user.Email, user.FirstName, user.LastName = email, firstName, lastName
+		a.audit.Record(ctx, service.AuditUserUpdate, "user", user.ID, audit.Diff(&before, user))
+	}
+
+	// A user back in the directory can log in again
+	if user.DisabledAt != nil {
+		if err := a.userRepo.UpdateColumns(ctx, user.ID, map[string]interface{}{"disabled_at": nil}); err != nil {
+			return err
+		}
+		a.principals.Invalidate(ctx, user.ID)
+		logger.InfoF(ctx, "Enabled user %s, who is back in the directory", user.Username)
+		a.audit.Record(ctx, service.AuditUserEnable, "user", user.ID, map[string]audit.Change{
+			"disabled_at": {Before: *user.DisabledAt},
+		})
+		user.DisabledAt = nil
+	}
+
+	return assignRole(ctx, a.userRepo, a.roleRepo, a.principals, a.audit, user, a.mappedRole(entry), "directory groups")
+}
+
+// disable disables the account of a user who is no longer in the directory, which ends their
+// logins as their tokens are checked
+func (a *LDAPAuthenticator) disable(ctx context.Context, user *models.User) error {
+	if user.DisabledAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	if err := a.userRepo.UpdateColumns(ctx, user.ID, map[string]interface{}{"disabled_at": now}); err != nil {
+		return err
+	}
+	a.principals.Invalidate(ctx, user.ID)
+	logger.WarnF(ctx, "Disabled user %s, who is no longer in the directory", user.Username)
+	a.audit.Record(ctx, service.AuditUserDisable, "user", user.ID, map[string]audit.Change{
+		"disabled_at": {After: now},
+	})
+	return nil
+}
+
+// mappedRole returns the role of the first rule whose group the entry is a member of, or ""
+func (a *LDAPAuthenticator) mappedRole(entry *directory.Entry) string {
+	for _, rule := range a.cfg.RoleRules {
+		if entry.InGroup(rule.Group) {
+			return rule.Role
+		}
+	}
+	return ""
+}
@@ -0,0 +1,142 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	repoImpl "github.com/userblog/management/internal/repository/impl"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/cache"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/directory/ldaptest"
+)
+
+const (
+	ldapAliceDN   = "uid=alice,ou=people,dc=example,dc=com"
+	ldapBobDN     = "uid=bob,ou=people,dc=example,dc=com"
+	ldapEditorsDN = "cn=editors,ou=groups,dc=example,dc=com"
+)
+
+// ldapTest is a directory authenticator for a directory where alice is an editor and bob isn't.
+// Editors get the admin role and other staff the user role.
+type ldapTest struct {
+	db            *gorm.DB
+	server        *ldaptest.Server
+	authenticator *LDAPAuthenticator
+}
+
+func newLDAPTest(t *testing.T) *ldapTest {
+	t.Helper()
+
+	db := newTestDB(t)
+	server := ldaptest.NewServer(t)
+	server.Add(ldapAliceDN, "alice-secret", map[string][]string{
+		"objectClass": {"person"}, "uid": {"alice"}, "mail": {"alice@example.com"}, "memberOf": {"cn=staff,ou=groups,dc=example,dc=com"},
+	})
+	server.Add(ldapBobDN, "bob-secret", map[string][]string{
+		"objectClass": {"person"}, "uid": {"bob"}, "mail": {"bob@example.com"}, "memberOf": {"cn=staff,ou=groups,dc=example,dc=com"},
+	})
+	server.Add(ldapEditorsDN, "", map[string][]string{"objectClass": {"groupOfNames"}, "member": {ldapAliceDN}})
+
+	cfg := config.LDAPConfig{
+		URL:         server.URL,
+		BaseDN:      "dc=example,dc=com",
+		UserFilter:  "(&(objectClass=person)(uid={username}))",
+		GroupFilter: "(member={dn})",
+		Attributes:  config.LDAPAttributes{Username: "uid", Email: "mail", Groups: "memberOf"},
+		DefaultRole: "user",
+		RoleRules:   []config.LDAPRoleRule{{Group: "editors", Role: "admin"}, {Group: "staff", Role: "user"}},
+		Timeout:     5 * time.Second,
+	}
+
+	userRepo := repoImpl.NewUserRepository(db)
+	roleRepo := repoImpl.NewRoleRepository(db)
+	auditService := NewAuditService(repoImpl.NewAuditEventRepository(db))
+	cacheStore := cache.NewMemoryStore(100)
+	roleService := NewRoleService(roleRepo, auditService, cache.New("roles", cacheStore))
+	principals := NewPrincipalService(userRepo, repoImpl.NewOrganizationRepository(db), roleService, cache.New("principals", cacheStore))
+
+	return &ldapTest{
+		db:            db,
+		server:        server,
+		authenticator: NewLDAPAuthenticator(userRepo, roleRepo, repoImpl.NewIdentityRepository(db), principals, auditService, cfg).(*LDAPAuthenticator),
+	}
+}
+
+// user returns the stored user with their role
+func (l *ldapTest) user(t *testing.T, username string) *models.User {
+	t.Helper()
+
+	var user models.User
+	if err := l.db.Preload("Role").Where("username = ?", username).First(&user).Error; err != nil {
+		t.Fatalf("loading user %s: %v", username, err)
+	}
+	return &user
+}
+
+func TestLDAPAuthenticateMapsGroupsToRoles(t *testing.T) {
+	l := newLDAPTest(t)
+	ctx := context.Background()
+
+	for username, role := range map[string]string{"alice": "admin", "bob": "user"} {
+		user, err := l.authenticator.Authenticate(ctx, username, username+"-secret")
+		if err != nil {
+			t.Fatalf("Authenticate(%s): %v", username, err)
+		}
+		if user.Role.Name != role {
+			t.Errorf("%s has role %q, want %q", username, user.Role.Name, role)
+		}
+	}
+
+	if _, err := l.authenticator.Authenticate(ctx, "alice", ""); !errors.Is(err, service.ErrInvalidCredentials) {
+		t.Errorf("empty password: error = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := l.authenticator.Authenticate(ctx, "*", "bob-secret"); !errors.Is(err, service.ErrInvalidCredentials) {
+		t.Errorf("wildcard username: error = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestLDAPSyncFollowsDirectory(t *testing.T) {
+	l := newLDAPTest(t)
+	ctx := context.Background()
+
+	for _, username := range []string{"alice", "bob"} {
+		if _, err := l.authenticator.Authenticate(ctx, username, username+"-secret"); err != nil {
+			t.Fatalf("Authenticate(%s): %v", username, err)
+		}
+	}
+
+	// alice leaves the editors and bob leaves the directory
+	l.server.Add(ldapEditorsDN, "", map[string][]string{"objectClass": {"groupOfNames"}})
+	l.server.Remove(ldapBobDN)
+	if err := l.authenticator.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	alice, bob := l.user(t, "alice"), l.user(t, "bob")
+	if alice.Role.Name != "user" || alice.DisabledAt != nil {
+		t.Errorf("alice has role %q and disabled at %v, want an enabled user", alice.Role.Name, alice.DisabledAt)
+	}
+	if bob.DisabledAt == nil {
+		t.Error("bob is still enabled after leaving the directory")
+	}
+
+	// bob comes back
+	l.server.Add(ldapBobDN, "bob-secret", map[string][]string{"objectClass": {"person"}, "uid": {"bob"}, "mail": {"bob@example.com"}})
+	if err := l.authenticator.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if bob := l.user(t, "bob"); bob.DisabledAt != nil {
+		t.Errorf("bob is still disabled at %v after returning to the directory", bob.DisabledAt)
+	}
+
+	var actions []string
+	l.db.Model(&models.AuditEvent{}).Where("action IN (?)", []string{service.AuditUserDisable, service.AuditUserEnable}).Order("id").Pluck("action", &actions)
+	if len(actions) != 2 || actions[0] != service.AuditUserDisable || actions[1] != service.AuditUserEnable {
+		t.Errorf("audited %v, want the disable and enable", actions)
+	}
+}
@@ -0,0 +1,168 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/event"
+	"github.com/userblog/management/pkg/logger"
+	"github.com/userblog/management/pkg/mail"
+)
+
+// RegisterNotifications subscribes the user-facing email notifications to the event bus
+func RegisterNotifications(bus event.IBus, mailer mail.IMailer) {
+	bus.Subscribe(service.EventAccountLocked, func(ctx context.Context, e event.Event) {
+		email, _ := e.Data["email"].(string)
+		if email == "" {
+			return
+		}
+		username, _ := e.Data["username"].(string)
+		lockedUntil, _ := e.Data["locked_until"].(time.Time)
+		ip, _ := e.Data["ip"].(string)
+
+		err := mailer.Send(ctx, mail.Message{
+			To:      []string{email},
+			Subject: "Your account has been temporarily locked",
+			Body: fmt.Sprintf("Hello %s,\n\nYour account was locked after several failed login attempts from %s.\n"+
+				"You can sign in again after %s. If this wasn't you, consider changing your password "+
+				"and contact an administrator.\n", username, ip, lockedUntil.Format(time.RFC1123)),
+		})
+		if err != nil {
+			logger.ErrorF(ctx, "Failed to send account locked notification: %v", err)
+		}
+	})
+
+	bus.Subscribe(service.EventPasswordChanged, func(ctx context.Context, e event.Event) {
+		email, _ := e.Data["email"].(string)
+		username, _ := e.Data["username"].(string)
+
+		err := mailer.Send(ctx, mail.Message{
+			To:      []string{email},
+			Subject: "Your password has been changed",
+			Body: fmt.Sprintf("Hello %s,\n\nThe password of your account was changed and your other sessions were "+
+				"signed out. If this wasn't you, contact an administrator.\n", username),
+		})
+		if err != nil {
+			logger.ErrorF(ctx, "Failed to send password changed notification: %v", err)
+		}
+	})
+
+	bus.Subscribe(service.EventEmailChangeRequested, func(ctx context.Context, e event.Event) {
+		email, _ := e.Data["email"].(string)
+		newEmail, _ := e.Data["new_email"].(string)
+		username, _ := e.Data["username"].(string)
+		token, _ := e.Data["token"].(string)
+		expiresAt, _ := e.Data["expires_at"].(time.Time)
+
+		// The token only goes to the new address, which proves it belongs to the user
+		err := mailer.Send(ctx, mail.Message{
+			To:      []string{newEmail},
+			Subject: "Confirm your new email address",
+			Body: fmt.Sprintf("Hello %s,\n\nTo use this address for your account, sign in and send the following "+
+				"token to POST /api/me/email/verify:\n\n%s\n\nThe token expires on %s.\n",
+				username, token, expiresAt.Format(time.RFC1123)),
+		})
+		if err != nil {
+			logger.ErrorF(ctx, "Failed to send email verification: %v", err)
+		}
+
+		err = mailer.Send(ctx, mail.Message{
+			To:      []string{email},
+			Subject: "Your email address is being changed",
+			Body: fmt.Sprintf("Hello %s,\n\nSomeone asked to change the email address of your account to %s. "+
+				"If this wasn't you, change your password and contact an administrator.\n", username, newEmail),
+		})
+		if err != nil {
+			logger.ErrorF(ctx, "Failed to send email change notification: %v", err)
+		}
+	})
+
+	bus.Subscribe(service.EventDeletionScheduled, func(ctx context.Context, e event.Event) {
+		email, _ := e.Data["email"].(string)
+		username, _ := e.Data["username"].(string)
+		deleteAt, _ := e.Data["delete_at"].(time.Time)
+
+		err := mailer.Send(ctx, mail.Message{
+			To:      []string{email},
+			Subject: "Your account will be deleted",
+			Body: fmt.Sprintf("Hello %s,\n\nYour account will be deleted on %s. To keep it, sign in before then "+
+				"and send DELETE /api/me/deletion.\n", username, deleteAt.Format(time.RFC1123)),
+		})
+		if err != nil {
+			logger.ErrorF(ctx, "Failed to send account deletion notification: %v", err)
+		}
+	})
+
+	bus.Subscribe(service.EventDataExportReady, func(ctx context.Context, e event.Event) {
+		email, _ := e.Data["email"].(string)
+		username, _ := e.Data["username"].(string)
+		requestID, _ := e.Data["request_id"].(uint)
+		expiresAt, _ := e.Data["expires_at"].(time.Time)
+
+		err := mailer.Send(ctx, mail.Message{
+			To:      []string{email},
+			Subject: "Your data export is ready",
+			Body: fmt.Sprintf("Hello %s,\n\nThe copy of your data you asked for is ready. Sign in and download it from "+
+				"GET /api/me/data-requests/%d/download before %s.\n", username, requestID, expiresAt.Format(time.RFC1123)),
+		})
+		if err != nil {
+			logger.ErrorF(ctx, "Failed to send data export notification: %v", err)
+		}
+	})
+
+	bus.Subscribe(service.EventDataErased, func(ctx context.Context, e event.Event) {
+		email, _ := e.Data["email"].(string)
+		username, _ := e.Data["username"].(string)
+		certificateID, _ := e.Data["certificate_id"].(uint)
+
+		err := mailer.Send(ctx, mail.Message{
+			To:      []string{email},
+			Subject: "Your data has been erased",
+			Body: fmt.Sprintf("Hello %s,\n\nAs you asked, your account and personal data have been erased. "+
+				"The erasure is recorded in certificate %d. This is the last message you will receive from us.\n",
+				username, certificateID),
+		})
+		if err != nil {
+			logger.ErrorF(ctx, "Failed to send data erasure notification: %v", err)
+		}
+	})
+
+	bus.Subscribe(service.EventUserInvited, func(ctx context.Context, e event.Event) {
+		email, _ := e.Data["email"].(string)
+		inviter, _ := e.Data["inviter"].(string)
+		token, _ := e.Data["token"].(string)
+		expiresAt, _ := e.Data["expires_at"].(time.Time)
+
+		err := mailer.Send(ctx, mail.Message{
+			To:      []string{email},
+			Subject: "You have been invited to register",
+			Body: fmt.Sprintf("Hello,\n\n%s invited you to create an account. To accept, register with this email address "+
+				"at POST /api/auth/register, giving the following invitation_token:\n\n%s\n\n"+
+				"The invitation expires on %s.\n", inviter, token, expiresAt.Format(time.RFC1123)),
+		})
+		if err != nil {
+			logger.ErrorF(ctx, "Failed to send invitation: %v", err)
+		}
+	})
+
+	bus.Subscribe(service.EventMemberInvited, func(ctx context.Context, e event.Event) {
+		email, _ := e.Data["email"].(string)
+		organization, _ := e.Data["organization"].(string)
+		inviter, _ := e.Data["inviter"].(string)
+		token, _ := e.Data["token"].(string)
+		expiresAt, _ := e.Data["expires_at"].(time.Time)
+
+		err := mailer.Send(ctx, mail.Message{
+			To:      []string{email},
+			Subject: fmt.Sprintf("You have been invited to join %s", organization),
+			Body: fmt.Sprintf("Hello,\n\n%s invited you to join %s. To accept, sign in with this email address "+
+				"and send the following token to POST /api/orgs/invitations/accept:\n\n%s\n\n"+
+				"The invitation expires on %s.\n", inviter, organization, token, expiresAt.Format(time.RFC1123)),
+		})
+		if err != nil {
+			logger.ErrorF(ctx, "Failed to send invitation: %v", err)
+		}
+	})
+}
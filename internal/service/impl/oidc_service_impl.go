@@ -0,0 +1,298 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/api/validation"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/audit"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/logger"
+	"github.com/userblog/management/pkg/metrics"
+	"github.com/userblog/management/pkg/oidc"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// oidcLoginTimeout is how long a user has to complete a login at the provider
+const oidcLoginTimeout = 10 * time.Minute
+
+// usernameInvalidChars matches the characters not allowed in usernames
+var usernameInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// OIDCService implements the IOIDCService interface
+type OIDCService struct {
+	userRepo     repository.IUserRepository
+	roleRepo     repository.IRoleRepository
+	identityRepo repository.IIdentityRepository
+	principals   service.IPrincipalService
+	tokens       service.ITokenService
+	sessions     service.ISessionService
+	audit        service.IAuditService
+	states       oidc.StateStore
+	providers    map[string]*oidc.Provider
+}
+
+// NewOIDCService creates a new OIDC login service for the providers in the configuration
+func NewOIDCService(userRepo repository.IUserRepository, roleRepo repository.IRoleRepository, identityRepo repository.IIdentityRepository, principals service.IPrincipalService, tokens service.ITokenService, sessions service.ISessionService, auditService service.IAuditService, states oidc.StateStore) service.IOIDCService {
+	providers := make(map[string]*oidc.Provider)
+	for name, cfg := range config.Current().OIDC.Providers {
+		providers[name] = oidc.NewProvider(name, cfg)
+	}
+
+	return &OIDCService{
+		userRepo:     userRepo,
+		roleRepo:     roleRepo,
+		identityRepo: identityRepo,
+		principals:   principals,
+		tokens:       tokens,
+		sessions:     sessions,
+		audit:        auditService,
+		states:       states,
+		providers:    providers,
+	}
+}
+
+// AuthorizationURL starts a login, returning the provider URL to send the user to
+func (s *OIDCService) AuthorizationURL(ctx context.Context, providerName string) (string, error) {
+	ctx, span := tracing.Start(ctx, "OIDCService.AuthorizationURL")
+	defer span.End()
+
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", service.NewNotFoundError("identity provider")
+	}
+
+	state, err := oidc.RandomString(32)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		return "", err
+	}
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.states.Save(ctx, state, oidc.Pending{
+		Provider:  providerName,
+		Verifier:  verifier,
+		Nonce:     nonce,
+		ExpiresAt: time.Now().Add(oidcLoginTimeout),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return authURL, nil
+}
+
+// Callback completes a login: it redeems the code, verifies the ID token, finds or provisions
+// the linked user and returns one of our access tokens
+func (s *OIDCService) Callback(ctx context.Context, providerName, code, state string) (string, error) {
+	ctx, span := tracing.Start(ctx, "OIDCService.Callback")
+	defer span.End()
+
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", service.NewNotFoundError("identity provider")
+	}
+
+	// The state is single use and bound to the provider, so a callback can't be replayed or redirected
+	pending, err := s.states.Take(ctx, state)
+	if err != nil {
+		return "", err
+	}
+	if pending == nil || pending.Provider != providerName {
+		return "", service.NewUnauthorizedError("login has expired or is invalid, please start again")
+	}
+
+	rawIDToken, err := provider.Exchange(ctx, code, pending.Verifier)
+	if err != nil {
+		logger.WarnF(ctx, "OIDC code exchange with %s failed: %v", providerName, err)
+		return "", service.NewUnauthorizedError("the identity provider rejected the login")
+	}
+	claims, err := provider.Verify(ctx, rawIDToken, pending.Nonce)
+	if err != nil {
+		logger.WarnF(ctx, "OIDC login with %s failed: %v", providerName, err)
+		return "", service.NewUnauthorizedError("the identity provider returned an invalid ID token")
+	}
+
+	user, err := s.linkedUser(ctx, provider, claims)
+	if err != nil {
+		return "", err
+	}
+	if user.DisabledAt != nil {
+		return "", service.NewForbiddenError("account is disabled")
+	}
+	if err := s.syncRole(ctx, provider, claims, user); err != nil {
+		return "", err
+	}
+
+	session, err := s.sessions.Start(ctx, user)
+	if err != nil {
+		return "", err
+	}
+	token, err := s.tokens.Issue(ctx, user, session)
+	if err != nil {
+		return "", err
+	}
+
+	ctx = service.WithActor(ctx, service.Actor{UserID: user.ID, Username: user.Username})
+	s.audit.Record(ctx, service.AuditLogin, "session", session.ID, nil)
+	metrics.LoginAttempts.WithLabelValues("success").Inc()
+	return token, nil
+}
+
+// linkedUser returns the user linked to the identity, linking an account with the same verified
+// email or provisioning a new one on first login
+func (s *OIDCService) linkedUser(ctx context.Context, provider *oidc.Provider, claims oidc.Claims) (*models.User, error) {
+	identity, err := s.identityRepo.FindByProviderSubject(ctx, provider.Name(), claims.Subject())
+	if err == nil {
+		user, err := s.userRepo.FindByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, notFound(err, "user")
+		}
+		return user, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+
+	// An unverified email could belong to anyone, so it must never link to an existing account
+	email := claims.Email()
+	if email == "" || !claims.EmailVerified() {
+		return nil, service.NewForbiddenError("the identity provider did not return a verified email")
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+	if err != nil {
+		if !provider.Config().Provision {
+			return nil, service.NewForbiddenError("no account exists for " + email)
+		}
+		if user, err = s.provision(ctx, provider, claims); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.identityRepo.Create(ctx, &models.Identity{
+		UserID:   user.ID,
+		Provider: provider.Name(),
+		Subject:  claims.Subject(),
+		Email:    email,
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.InfoF(ctx, "Linked %s identity %s to user %s", provider.Name(), claims.Subject(), user.Username)
+
+	return user, nil
+}
+
+// provision creates an account for a new user with the mapped or default role
+func (s *OIDCService) provision(ctx context.Context, provider *oidc.Provider, claims oidc.Claims) (*models.User, error) {
+	roleName := mappedRole(provider.Config(), claims)
+	if roleName == "" {
+		roleName = provider.Config().DefaultRole
+	}
+	if roleName == "" {
+		roleName = "user"
+	}
+	role, err := s.roleRepo.FindByName(ctx, roleName)
+	if err != nil {
+		return nil, fmt.Errorf("role %q configured for %s: %w", roleName, provider.Name(), err)
+	}
+
+	username, err := s.availableUsername(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// The account can only log in through the provider until the user sets a password
+	password, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Username:  username,
+		Email:     claims.Email(),
+		Password:  password,
+		FirstName: claims.String("given_name"),
+		LastName:  claims.String("family_name"),
+		RoleID:    role.ID,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	logger.InfoF(ctx, "Provisioned user %s from %s with role %s", user.Username, provider.Name(), role.Name)
+	s.audit.Record(ctx, service.AuditUserCreate, "user", user.ID, audit.Diff(nil, user))
+
+	return s.userRepo.FindByID(ctx, user.ID)
+}
+
+// syncRole applies the first matching role rule on every login, so role changes at the provider
+// carry over; users no rule matches keep their role
+func (s *OIDCService) syncRole(ctx context.Context, provider *oidc.Provider, claims oidc.Claims, user *models.User) error {
+	return assignRole(ctx, s.userRepo, s.roleRepo, s.principals, s.audit, user, mappedRole(provider.Config(), claims), provider.Name()+" claims")
+}
+
+// mappedRole returns the role of the first rule the claims match, or ""
+func mappedRole(cfg config.OIDCProvider, claims oidc.Claims) string {
+	for _, rule := range cfg.RoleRules {
+		if claims.Has(rule.Claim, rule.Value) {
+			return rule.Role
+		}
+	}
+	return ""
+}
+
+// availableUsername derives a valid, unreserved and unused username from the preferred
+// username or the email, adding a number when it is taken
+func (s *OIDCService) availableUsername(ctx context.Context, claims oidc.Claims) (string, error) {
+	base := claims.String("preferred_username")
+	if base == "" || strings.Contains(base, "@") {
+		base, _, _ = strings.Cut(claims.Email(), "@")
+	}
+	base = strings.Trim(usernameInvalidChars.ReplaceAllString(base, ""), ".-")
+	if len(base) > 24 {
+		base = base[:24]
+	}
+	if len(base) < 3 {
+		base = "user"
+	}
+
+	for i := 1; i <= 100; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = fmt.Sprintf("%s%d", base, i)
+		}
+		if validation.ReservedUsername(candidate) {
+			continue
+		}
+		_, err := s.userRepo.FindByUsername(ctx, candidate)
+		if gorm.IsRecordNotFoundError(err) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", service.NewConflictError("could not find an available username")
+}
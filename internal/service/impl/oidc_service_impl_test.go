@@ -0,0 +1,206 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	repoImpl "github.com/userblog/management/internal/repository/impl"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/cache"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/oidc"
+	"github.com/userblog/management/pkg/oidc/oidctest"
+)
+
+// oidcTest is an OIDC service logging in through a fake provider, configured as "fake"
+type oidcTest struct {
+	db       *gorm.DB
+	provider *oidctest.Provider
+	service  service.IOIDCService
+}
+
+func newOIDCTest(t *testing.T) *oidcTest {
+	t.Helper()
+	ctx := context.Background()
+
+	db := newTestDB(t)
+	provider := oidctest.NewProvider(t, "blog-client")
+
+	cfg := config.Default()
+	cfg.JWT.Algorithm = "HS256"
+	cfg.JWT.Secret = strings.Repeat("s", 32)
+	cfg.OIDC.Providers = map[string]config.OIDCProvider{
+		"fake":  {Issuer: provider.Issuer, ClientID: "blog-client", RedirectURL: "http://localhost/callback", Provision: true},
+		"other": {Issuer: provider.Issuer, ClientID: "blog-client", RedirectURL: "http://localhost/callback", Provision: true},
+	}
+	setConfig(t, cfg)
+
+	userRepo := repoImpl.NewUserRepository(db)
+	roleRepo := repoImpl.NewRoleRepository(db)
+	auditService := NewAuditService(repoImpl.NewAuditEventRepository(db))
+	cacheStore := cache.NewMemoryStore(100)
+	roleService := NewRoleService(roleRepo, auditService, cache.New("roles", cacheStore))
+	principals := NewPrincipalService(userRepo, repoImpl.NewOrganizationRepository(db), roleService, cache.New("principals", cacheStore))
+	tokens := NewTokenService(repoImpl.NewSigningKeyRepository(db))
+	if err := tokens.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	sessions := NewSessionService(repoImpl.NewSessionRepository(db), auditService)
+
+	return &oidcTest{
+		db:       db,
+		provider: provider,
+		service:  NewOIDCService(userRepo, roleRepo, repoImpl.NewIdentityRepository(db), principals, tokens, sessions, auditService, oidc.NewMemoryStateStore()),
+	}
+}
+
+// start begins a login at the named provider and returns the authorization URL
+func (o *oidcTest) start(t *testing.T, providerName string) string {
+	t.Helper()
+
+	authURL, err := o.service.AuthorizationURL(context.Background(), providerName)
+	if err != nil {
+		t.Fatalf("AuthorizationURL: %v", err)
+	}
+	return authURL
+}
+
+// login completes a login at the fake provider with the ID token claims overridden by claims
+func (o *oidcTest) login(t *testing.T, claims jwt.MapClaims) (string, error) {
+	t.Helper()
+
+	code, state := o.provider.Authorize(t, o.start(t, "fake"), claims)
+	return o.service.Callback(context.Background(), "fake", code, state)
+}
+
+// identities returns how many identities are linked
+func (o *oidcTest) identities(t *testing.T) int {
+	t.Helper()
+
+	var count int
+	if err := o.db.Model(&models.Identity{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func verifiedEmail(email string) jwt.MapClaims {
+	return jwt.MapClaims{"email": email, "email_verified": true, "preferred_username": "alice"}
+}
+
+func TestOIDCCallbackProvisionsAndLinks(t *testing.T) {
+	o := newOIDCTest(t)
+
+	if _, err := o.login(t, verifiedEmail("alice@example.com")); err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	if _, err := o.login(t, verifiedEmail("alice@example.com")); err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+
+	var users int
+	o.db.Model(&models.User{}).Where("email = ?", "alice@example.com").Count(&users)
+	if users != 1 || o.identities(t) != 1 {
+		t.Errorf("got %d users and %d identities, want one of each", users, o.identities(t))
+	}
+}
+
+func TestOIDCCallbackChecksState(t *testing.T) {
+	o := newOIDCTest(t)
+	ctx := context.Background()
+
+	t.Run("unknown state", func(t *testing.T) {
+		code, _ := o.provider.Authorize(t, o.start(t, "fake"), verifiedEmail("alice@example.com"))
+		_, err := o.service.Callback(ctx, "fake", code, "forged")
+		assertErrorType[*service.UnauthorizedError](t, err)
+	})
+
+	t.Run("replayed state", func(t *testing.T) {
+		authURL := o.start(t, "fake")
+		code, state := o.provider.Authorize(t, authURL, verifiedEmail("alice@example.com"))
+		if _, err := o.service.Callback(ctx, "fake", code, state); err != nil {
+			t.Fatalf("Callback: %v", err)
+		}
+		code, _ = o.provider.Authorize(t, authURL, verifiedEmail("alice@example.com"))
+		_, err := o.service.Callback(ctx, "fake", code, state)
+		assertErrorType[*service.UnauthorizedError](t, err)
+	})
+
+	t.Run("state of another provider", func(t *testing.T) {
+		code, state := o.provider.Authorize(t, o.start(t, "other"), verifiedEmail("alice@example.com"))
+		_, err := o.service.Callback(ctx, "fake", code, state)
+		assertErrorType[*service.UnauthorizedError](t, err)
+	})
+}
+
+func TestOIDCCallbackChecksNonce(t *testing.T) {
+	o := newOIDCTest(t)
+
+	claims := verifiedEmail("alice@example.com")
+	claims["nonce"] = "replayed-nonce"
+	_, err := o.login(t, claims)
+	assertErrorType[*service.UnauthorizedError](t, err)
+}
+
+func TestOIDCCallbackChecksPKCE(t *testing.T) {
+	o := newOIDCTest(t)
+	ctx := context.Background()
+
+	// A code obtained with someone else's challenge can't be redeemed with this login's verifier
+	u, _ := url.Parse(o.start(t, "fake"))
+	query := u.Query()
+	attacker, _ := oidc.NewVerifier()
+	query.Set("code_challenge", oidc.Challenge(attacker))
+	u.RawQuery = query.Encode()
+
+	code, state := o.provider.Authorize(t, u.String(), verifiedEmail("alice@example.com"))
+	_, err := o.service.Callback(ctx, "fake", code, state)
+	assertErrorType[*service.UnauthorizedError](t, err)
+}
+
+func TestOIDCCallbackRejectsOtherAudience(t *testing.T) {
+	o := newOIDCTest(t)
+
+	claims := verifiedEmail("alice@example.com")
+	claims["aud"] = "another-client"
+	_, err := o.login(t, claims)
+	assertErrorType[*service.UnauthorizedError](t, err)
+}
+
+func TestOIDCCallbackDoesNotLinkUnverifiedEmail(t *testing.T) {
+	o := newOIDCTest(t)
+
+	existing := models.User{Username: "victim", Email: "victim@example.com", Password: "Password1", RoleID: 2}
+	if err := o.db.Create(&existing).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, verified := range []interface{}{false, "false", nil} {
+		claims := jwt.MapClaims{"email": "victim@example.com"}
+		if verified != nil {
+			claims["email_verified"] = verified
+		}
+		_, err := o.login(t, claims)
+		assertErrorType[*service.ForbiddenError](t, err)
+	}
+
+	if n := o.identities(t); n != 0 {
+		t.Errorf("%d identities were linked to the account", n)
+	}
+}
+
+// assertErrorType fails the test unless err is of type E
+func assertErrorType[E error](t *testing.T, err error) {
+	t.Helper()
+
+	var target E
+	if !errors.As(err, &target) {
+		t.Errorf("error = %v (%T), want %T", err, err, target)
+	}
+}
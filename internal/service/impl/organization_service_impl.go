@@ -0,0 +1,450 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/audit"
+	"github.com/userblog/management/pkg/cache"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/event"
+	"github.com/userblog/management/pkg/logger"
+	"github.com/userblog/management/pkg/tenant"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// OrganizationService implements the IOrganizationService interface
+type OrganizationService struct {
+	orgRepo        repository.IOrganizationRepository
+	invitationRepo repository.IInvitationRepository
+	roleRepo       repository.IRoleRepository
+	roles          service.IRoleService
+	principals     service.IPrincipalService
+	events         event.IBus
+	audit          service.IAuditService
+	cache          *cache.Cache // organizations by slug
+}
+
+// NewOrganizationService creates a new organization service
+func NewOrganizationService(orgRepo repository.IOrganizationRepository, invitationRepo repository.IInvitationRepository, roleRepo repository.IRoleRepository, roles service.IRoleService, principals service.IPrincipalService, events event.IBus, auditService service.IAuditService, orgCache *cache.Cache) service.IOrganizationService {
+	return &OrganizationService{
+		orgRepo:        orgRepo,
+		invitationRepo: invitationRepo,
+		roleRepo:       roleRepo,
+		roles:          roles,
+		principals:     principals,
+		events:         events,
+		audit:          auditService,
+		cache:          orgCache,
+	}
+}
+
+// Resolve returns the organization with the slug. Organizations are cached until they change.
+func (s *OrganizationService) Resolve(ctx context.Context, slug string) (*models.Organization, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.Resolve")
+	defer span.End()
+
+	org := &models.Organization{}
+	if s.cache.Get(ctx, slug, org) {
+		return org, nil
+	}
+
+	org, err := s.orgRepo.FindBySlug(ctx, slug)
+	if err != nil {
+		return nil, notFound(err, "organization")
+	}
+	s.cache.Set(ctx, slug, org)
+	return org, nil
+}
+
+// Current returns the organization the context acts in
+func (s *OrganizationService) Current(ctx context.Context) (*models.Organization, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.Current")
+	defer span.End()
+
+	return s.current(ctx)
+}
+
+// Create creates an organization whose owner becomes a member with the configured owner role
+func (s *OrganizationService) Create(ctx context.Context, org *models.Organization, ownerID uint) error {
+	ctx, span := tracing.Start(ctx, "OrganizationService.Create")
+	defer span.End()
+
+	existing, err := s.orgRepo.FindBySlug(ctx, org.Slug)
+	if err == nil && existing.ID != 0 {
+		return service.NewConflictError("organization slug already exists")
+	}
+	if err := s.checkRole(ctx, 0, org.DefaultRoleID); err != nil {
+		return err
+	}
+	ownerRole, err := s.roleRepo.FindByName(ctx, config.Current().Orgs.OwnerRole)
+	if err != nil {
+		return fmt.Errorf("role %q configured for organization owners: %w", config.Current().Orgs.OwnerRole, err)
+	}
+
+	if err := s.orgRepo.Create(ctx, org); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, service.AuditOrgCreate, "organization", org.ID, audit.Diff(nil, org))
+
+	return s.join(ctx, org.ID, ownerID, ownerRole.ID)
+}
+
+// Update changes the name, description and default role of the organization
+func (s *OrganizationService) Update(ctx context.Context, org *models.Organization) error {
+	ctx, span := tracing.Start(ctx, "OrganizationService.Update")
+	defer span.End()
+
+	existing, err := s.current(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.checkRole(ctx, existing.ID, org.DefaultRoleID); err != nil {
+		return err
+	}
+
+	before := *existing
+	existing.Name = org.Name
+	existing.Description = org.Description
+	existing.DefaultRoleID = org.DefaultRoleID
+	if err := s.orgRepo.Update(ctx, existing); err != nil {
+		return err
+	}
+	s.cache.Delete(ctx, existing.Slug)
+	*org = *existing
+
+	s.audit.Record(ctx, service.AuditOrgUpdate, "organization", org.ID, audit.Diff(&before, existing))
+	return nil
+}
+
+// ListMemberships returns the organizations a user is a member of, with their role in each
+func (s *OrganizationService) ListMemberships(ctx context.Context, userID uint) ([]models.Membership, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.ListMemberships")
+	defer span.End()
+
+	return s.orgRepo.ListMemberships(ctx, userID)
+}
+
+// ListMembers returns the members of the organization with pagination
+func (s *OrganizationService) ListMembers(ctx context.Context, page, perPage int) ([]models.Membership, int, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.ListMembers")
+	defer span.End()
+
+	org, err := s.current(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.orgRepo.ListMembers(ctx, org.ID, (page-1)*perPage, perPage)
+}
+
+// UpdateMember changes a member's role. The actor must hold every organization permission of
+// both the member's current role and the new one, and the last owner can't be demoted.
+func (s *OrganizationService) UpdateMember(ctx context.Context, actor *models.User, userID, roleID uint) error {
+	ctx, span := tracing.Start(ctx, "OrganizationService.UpdateMember")
+	defer span.End()
+
+	org, err := s.current(ctx)
+	if err != nil {
+		return err
+	}
+	membership, err := s.orgRepo.FindMembership(ctx, org.ID, userID)
+	if err != nil {
+		return notFound(err, "member")
+	}
+	if err := s.checkManageable(ctx, actor, membership); err != nil {
+		return err
+	}
+	if err := s.checkGrantable(ctx, actor, org.ID, roleID); err != nil {
+		return err
+	}
+	if roleID != membership.RoleID {
+		if err := s.checkLastOwner(ctx, membership); err != nil {
+			return err
+		}
+	}
+
+	if err := s.orgRepo.UpdateMembershipRole(ctx, org.ID, userID, roleID); err != nil {
+		return err
+	}
+	s.principals.Invalidate(ctx, userID)
+
+	s.audit.Record(ctx, service.AuditOrgMemberUpdate, "organization", org.ID, map[string]audit.Change{
+		"user_id": {After: userID},
+		"role_id": {Before: membership.RoleID, After: roleID},
+	})
+	return nil
+}
+
+// RemoveMember removes a user from the organization. The actor must hold every organization
+// permission of the member's role, and the last owner can't be removed.
+func (s *OrganizationService) RemoveMember(ctx context.Context, actor *models.User, userID uint) error {
+	ctx, span := tracing.Start(ctx, "OrganizationService.RemoveMember")
+	defer span.End()
+
+	org, err := s.current(ctx)
+	if err != nil {
+		return err
+	}
+	membership, err := s.orgRepo.FindMembership(ctx, org.ID, userID)
+	if err != nil {
+		return notFound(err, "member")
+	}
+	if err := s.checkManageable(ctx, actor, membership); err != nil {
+		return err
+	}
+	if err := s.checkLastOwner(ctx, membership); err != nil {
+		return err
+	}
+
+	if _, err := s.orgRepo.DeleteMembership(ctx, org.ID, userID); err != nil {
+		return err
+	}
+	s.principals.Invalidate(ctx, userID)
+
+	s.audit.Record(ctx, service.AuditOrgMemberRemove, "organization", org.ID, map[string]audit.Change{
+		"user_id": {Before: userID},
+		"role_id": {Before: membership.RoleID},
+	})
+	return nil
+}
+
+// Invite invites the owner of the email address to join the organization with the role, or
+// its default role. The single-use token is only sent to the invitee.
+func (s *OrganizationService) Invite(ctx context.Context, actor *models.User, email string, roleID uint) (*models.Invitation, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.Invite")
+	defer span.End()
+
+	org, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if roleID == 0 {
+		roleID = org.DefaultRoleID
+	}
+	if roleID == 0 {
+		return nil, service.NewValidationError("role_id is required when the organization has no default role")
+	}
+	if err := s.checkGrantable(ctx, actor, org.ID, roleID); err != nil {
+		return nil, err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	invitation := &models.Invitation{
+		OrganizationID: org.ID,
+		Email:          strings.ToLower(strings.TrimSpace(email)),
+		RoleID:         roleID,
+		TokenHash:      hashToken(token),
+		InvitedByID:    actor.ID,
+		ExpiresAt:      time.Now().Add(config.Current().Orgs.InvitationTTL),
+	}
+	if err := s.invitationRepo.Create(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, service.AuditOrgInvite, "organization", org.ID, map[string]audit.Change{
+		"email":   {After: invitation.Email},
+		"role_id": {After: roleID},
+	})
+	s.events.Publish(ctx, service.EventMemberInvited, map[string]interface{}{
+		"email":        invitation.Email,
+		"organization": org.Name,
+		"inviter":      actor.Username,
+		"token":        token,
+		"expires_at":   invitation.ExpiresAt,
+	})
+	return invitation, nil
+}
+
+// ListInvitations returns the organization's pending invitations
+func (s *OrganizationService) ListInvitations(ctx context.Context) ([]models.Invitation, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.ListInvitations")
+	defer span.End()
+
+	org, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.invitationRepo.ListPending(ctx, org.ID, time.Now())
+}
+
+// RevokeInvitation revokes one of the organization's invitations
+func (s *OrganizationService) RevokeInvitation(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "OrganizationService.RevokeInvitation")
+	defer span.End()
+
+	org, err := s.current(ctx)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.invitationRepo.Delete(ctx, org.ID, id)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return service.NewNotFoundError("invitation")
+	}
+
+	s.audit.Record(ctx, service.AuditOrgRevokeInvite, "organization", org.ID, map[string]audit.Change{
+		"invitation_id": {Before: id},
+	})
+	return nil
+}
+
+// AcceptInvitation makes the user a member of the organization they were invited to, with the
+// invited role. The invitation must have been sent to the user's email address.
+func (s *OrganizationService) AcceptInvitation(ctx context.Context, user *models.User, token string) (*models.Membership, error) {
+	ctx, span := tracing.Start(ctx, "OrganizationService.AcceptInvitation")
+	defer span.End()
+
+	invitation, err := s.invitationRepo.FindByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, notFound(err, "invitation")
+	}
+	if invitation.OrganizationID == platformInvitations {
+		return nil, service.NewNotFoundError("invitation")
+	}
+	if invitation.AcceptedAt != nil {
+		return nil, service.NewConflictError("invitation has already been used")
+	}
+	if !time.Now().Before(invitation.ExpiresAt) {
+		return nil, service.NewValidationError("invitation has expired")
+	}
+	if !strings.EqualFold(invitation.Email, user.Email) {
+		return nil, service.NewForbiddenError("invitation was sent to another email address")
+	}
+
+	// Marking it accepted first makes the token single-use even when accepted twice at once
+	accepted, err := s.invitationRepo.MarkAccepted(ctx, invitation.ID, user.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if accepted == 0 {
+		return nil, service.NewConflictError("invitation has already been used")
+	}
+
+	// Members accepting another invitation get its role instead of a second membership
+	if existing, err := s.orgRepo.FindMembership(ctx, invitation.OrganizationID, user.ID); err == nil {
+		if existing.RoleID != invitation.RoleID {
+			if err := s.checkLastOwner(ctx, existing); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.orgRepo.UpdateMembershipRole(ctx, invitation.OrganizationID, user.ID, invitation.RoleID); err != nil {
+			return nil, err
+		}
+		s.principals.Invalidate(ctx, user.ID)
+		s.audit.Record(ctx, service.AuditOrgMemberUpdate, "organization", invitation.OrganizationID, map[string]audit.Change{
+			"user_id": {After: user.ID},
+			"role_id": {Before: existing.RoleID, After: invitation.RoleID},
+		})
+	} else if err := s.join(ctx, invitation.OrganizationID, user.ID, invitation.RoleID); err != nil {
+		return nil, err
+	}
+
+	membership, err := s.orgRepo.FindMembership(ctx, invitation.OrganizationID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	org, err := s.orgRepo.FindByID(ctx, invitation.OrganizationID)
+	if err != nil {
+		return nil, notFound(err, "organization")
+	}
+	membership.Organization = *org
+	return membership, nil
+}
+
+// current loads the organization the context acts in
+func (s *OrganizationService) current(ctx context.Context) (*models.Organization, error) {
+	id := tenant.ID(ctx)
+	if id == 0 {
+		return nil, service.NewNotFoundError("organization")
+	}
+	org, err := s.orgRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, "organization")
+	}
+	return org, nil
+}
+
+// join adds a user to an organization with the role
+func (s *OrganizationService) join(ctx context.Context, orgID, userID, roleID uint) error {
+	if err := s.orgRepo.CreateMembership(ctx, &models.Membership{
+		OrganizationID: orgID,
+		UserID:         userID,
+		RoleID:         roleID,
+	}); err != nil {
+		return err
+	}
+	s.principals.Invalidate(ctx, userID)
+	logger.InfoF(ctx, "Added user %d to organization %d", userID, orgID)
+
+	s.audit.Record(ctx, service.AuditOrgJoin, "organization", orgID, map[string]audit.Change{
+		"user_id": {After: userID},
+		"role_id": {After: roleID},
+	})
+	return nil
+}
+
+// checkRole verifies that a role given by the organization exists and is shared by every
+// organization or belongs to this one, unless none is given
+func (s *OrganizationService) checkRole(ctx context.Context, orgID, roleID uint) error {
+	if roleID == 0 {
+		return nil
+	}
+	role, err := s.roleRepo.FindByID(ctx, roleID)
+	if err == nil && role.OrganizationID != 0 && role.OrganizationID != orgID {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		return notFound(err, "role")
+	}
+	return nil
+}
+
+// checkGrantable verifies that the organization can give the role and that the actor holds
+// every organization permission it grants, so members can't hand out more access than they have
+func (s *OrganizationService) checkGrantable(ctx context.Context, actor *models.User, orgID, roleID uint) error {
+	if err := s.checkRole(ctx, orgID, roleID); err != nil {
+		return err
+	}
+	return checkGrantable(ctx, s.roles, actor, roleID, organizationPermissions)
+}
+
+// checkManageable verifies that the actor holds every organization permission of the member's
+// role, so members can't demote or remove those with more access than they have
+func (s *OrganizationService) checkManageable(ctx context.Context, actor *models.User, membership *models.Membership) error {
+	missing, err := missingPermission(ctx, s.roles, actor, membership.RoleID, organizationPermissions)
+	if err != nil {
+		return err
+	}
+	if missing != "" {
+		return service.NewForbiddenError(fmt.Sprintf("you can't change a member with the %s permission you don't have", missing))
+	}
+	return nil
+}
+
+// checkLastOwner refuses to take the owner role from the member when nobody else in the
+// organization holds it
+func (s *OrganizationService) checkLastOwner(ctx context.Context, membership *models.Membership) error {
+	if membership.Role.Name != config.Current().Orgs.OwnerRole {
+		return nil
+	}
+	owners, err := s.orgRepo.CountMembersWithRole(ctx, membership.OrganizationID, membership.RoleID)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return service.NewConflictError("an organization must keep at least one owner")
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package impl
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	repoImpl "github.com/userblog/management/internal/repository/impl"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/cache"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/event"
+	"github.com/userblog/management/pkg/tenant"
+)
+
+// orgFixture is an organization owned by user 1, with the owner, manager and member roles
+type orgFixture struct {
+	db         *gorm.DB
+	orgs       service.IOrganizationService
+	roleRepo   repository.IRoleRepository
+	roleSvc    service.IRoleService
+	principals service.IPrincipalService
+	cacheStore cache.Store
+	ctx        context.Context
+	roles      map[string]*models.Role
+}
+
+// newOrgFixture creates acme, owned by user 1. Owners hold every organization permission,
+// managers can update the organization and read blogs, and members only read blogs.
+func newOrgFixture(t *testing.T) *orgFixture {
+	t.Helper()
+
+	db := newTestDB(t)
+	setConfig(t, config.Default())
+
+	for _, key := range []string{"organization:update", "blog:read", "blog:publish"} {
+		resource, action, _ := strings.Cut(key, ":")
+		if err := db.Create(&models.Permission{Name: resource + "_" + action, Resource: resource, Action: action}).Error; err != nil {
+			t.Fatalf("creating permission %s: %v", key, err)
+		}
+	}
+
+	orgRepo := repoImpl.NewOrganizationRepository(db)
+	roleRepo := repoImpl.NewRoleRepository(db)
+	auditService := NewAuditService(repoImpl.NewAuditEventRepository(db))
+	cacheStore := cache.NewMemoryStore(100)
+	roleService := NewRoleService(roleRepo, auditService, cache.New("roles", cacheStore))
+	principals := NewPrincipalService(repoImpl.NewUserRepository(db), orgRepo, roleService, cache.New("principals", cacheStore))
+
+	roles := map[string]*models.Role{}
+	for name, permissions := range map[string][]string{
+		config.Current().Orgs.OwnerRole: {"organization:update", "blog:read", "blog:publish"},
+		"manager":                       {"organization:update", "blog:read"},
+		"member":                        {"blog:read"},
+	} {
+		role := &models.Role{Name: name}
+		if err := roleService.Create(context.Background(), role, permissions); err != nil {
+			t.Fatalf("creating role %s: %v", name, err)
+		}
+		roles[name] = role
+	}
+
+	orgs := NewOrganizationService(orgRepo, repoImpl.NewInvitationRepository(db), roleRepo, roleService, principals, event.NewBus(), auditService, cache.New("organizations", cacheStore))
+	org := &models.Organization{Name: "Acme", Slug: "acme"}
+	if err := orgs.Create(context.Background(), org, 1); err != nil {
+		t.Fatalf("creating organization: %v", err)
+	}
+	ctx := tenant.WithOrganization(context.Background(), tenant.Organization{ID: org.ID, Slug: org.Slug, Source: tenant.SourceHeader})
+	return &orgFixture{db: db, orgs: orgs, roleRepo: roleRepo, roleSvc: roleService, principals: principals, cacheStore: cacheStore, ctx: ctx, roles: roles}
+}
+
+// join adds a user to the organization with the role
+func (f *orgFixture) join(t *testing.T, userID uint, role string) {
+	t.Helper()
+
+	membership := &models.Membership{OrganizationID: tenant.ID(f.ctx), UserID: userID, RoleID: f.roles[role].ID}
+	if err := f.db.Create(membership).Error; err != nil {
+		t.Fatalf("adding user %d: %v", userID, err)
+	}
+}
+
+// actor returns a user holding the role's permissions
+func (f *orgFixture) actor(t *testing.T, userID uint, role string) *models.User {
+	t.Helper()
+
+	var loaded models.Role
+	if err := f.db.Preload("Permissions").First(&loaded, f.roles[role].ID).Error; err != nil {
+		t.Fatalf("loading role %s: %v", role, err)
+	}
+	return &models.User{Model: gorm.Model{ID: userID}, RoleID: loaded.ID, Role: loaded}
+}
+
+// roleOf returns the name of the user's role in the organization, or "" when they aren't a member
+func (f *orgFixture) roleOf(userID uint) string {
+	var membership models.Membership
+	if err := f.db.Preload("Role").Where("organization_id = ? AND user_id = ?", tenant.ID(f.ctx), userID).First(&membership).Error; err != nil {
+		return ""
+	}
+	return membership.Role.Name
+}
+
+func TestMembersWithMorePermissionsAreProtected(t *testing.T) {
+	f := newOrgFixture(t)
+	owner := config.Current().Orgs.OwnerRole
+	f.join(t, 2, "manager")
+	f.join(t, 3, "member")
+	manager := f.actor(t, 2, "manager")
+
+	// The owner holds blog:publish, which the manager doesn't
+	err := f.orgs.UpdateMember(f.ctx, manager, 1, f.roles["member"].ID)
+	assertErrorType[*service.ForbiddenError](t, err)
+	err = f.orgs.RemoveMember(f.ctx, manager, 1)
+	assertErrorType[*service.ForbiddenError](t, err)
+	if got := f.roleOf(1); got != owner {
+		t.Errorf("the owner's role is %q after a manager changed it", got)
+	}
+
+	// Members with no more than the manager's permissions can be changed
+	if err := f.orgs.UpdateMember(f.ctx, manager, 3, f.roles["manager"].ID); err != nil {
+		t.Fatalf("promoting a member: %v", err)
+	}
+	if err := f.orgs.UpdateMember(f.ctx, manager, 3, f.roles["member"].ID); err != nil {
+		t.Fatalf("demoting a manager: %v", err)
+	}
+	if err := f.orgs.RemoveMember(f.ctx, manager, 3); err != nil {
+		t.Fatalf("removing a member: %v", err)
+	}
+	if got := f.roleOf(3); got != "" {
+		t.Errorf("the removed member still has the %q role", got)
+	}
+}
+
+func TestLastOwnerCannotBeDemotedOrRemoved(t *testing.T) {
+	f := newOrgFixture(t)
+	owner := config.Current().Orgs.OwnerRole
+	f.join(t, 2, "member")
+	actor := f.actor(t, 1, owner)
+
+	err := f.orgs.UpdateMember(f.ctx, actor, 1, f.roles["member"].ID)
+	assertErrorType[*service.ConflictError](t, err)
+	err = f.orgs.RemoveMember(f.ctx, actor, 1)
+	assertErrorType[*service.ConflictError](t, err)
+	if got := f.roleOf(1); got != owner {
+		t.Errorf("the last owner's role is %q", got)
+	}
+
+	// Keeping the owner role isn't a demotion
+	if err := f.orgs.UpdateMember(f.ctx, actor, 1, f.roles[owner].ID); err != nil {
+		t.Errorf("keeping the owner role: %v", err)
+	}
+
+	// With a second owner, either can go
+	if err := f.orgs.UpdateMember(f.ctx, actor, 2, f.roles[owner].ID); err != nil {
+		t.Fatalf("making a second owner: %v", err)
+	}
+	if err := f.orgs.UpdateMember(f.ctx, actor, 1, f.roles["member"].ID); err != nil {
+		t.Fatalf("demoting an owner: %v", err)
+	}
+	err = f.orgs.RemoveMember(f.ctx, f.actor(t, 2, owner), 2)
+	assertErrorType[*service.ConflictError](t, err)
+}
@@ -0,0 +1,105 @@
+package impl
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/cache"
+	"github.com/userblog/management/pkg/tenant"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// PrincipalService implements the IPrincipalService interface
+type PrincipalService struct {
+	userRepo repository.IUserRepository
+	orgRepo  repository.IOrganizationRepository
+	roles    service.IRoleService
+	cache    *cache.Cache // principals by user ID
+}
+
+// principal is the cached form of a user, without their password hash, and their memberships
+type principal struct {
+	User        models.User
+	Memberships []models.Membership
+}
+
+// NewPrincipalService creates a new principal service
+func NewPrincipalService(userRepo repository.IUserRepository, orgRepo repository.IOrganizationRepository, roles service.IRoleService, principalCache *cache.Cache) service.IPrincipalService {
+	return &PrincipalService{
+		userRepo: userRepo,
+		orgRepo:  orgRepo,
+		roles:    roles,
+		cache:    principalCache,
+	}
+}
+
+// Load returns a user whose role carries its effective permissions in place of the permissions
+// granted to it directly: those of their platform role and its ancestors, plus, when they are a
+// member of the organization ctx acts in, the organization permissions of their membership role.
+// The user is cached until Invalidate is called for it.
+func (s *PrincipalService) Load(ctx context.Context, id uint) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "PrincipalService.Load")
+	defer span.End()
+
+	key := principalKey(id)
+	var cached principal
+	if !s.cache.Get(ctx, key, &cached) {
+		user, err := s.userRepo.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		memberships, err := s.orgRepo.ListMemberships(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		user.Password = ""
+		cached = principal{User: *user, Memberships: memberships}
+		s.cache.Set(ctx, key, cached)
+	}
+
+	user := &cached.User
+	effective, err := s.roles.Effective(ctx, user.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	permissions := effective.Permissions
+
+	orgID := tenant.ID(ctx)
+	for i := range cached.Memberships {
+		if orgID == 0 || cached.Memberships[i].OrganizationID != orgID {
+			continue
+		}
+		membership := cached.Memberships[i]
+		user.Membership = &membership
+
+		granted, err := s.roles.Effective(ctx, membership.RoleID)
+		if err != nil {
+			return nil, err
+		}
+		permissions = mergePermissions(permissions, organizationPermissions(granted.Permissions))
+	}
+
+	user.Role.Permissions = permissions
+	return user, nil
+}
+
+// Invalidate drops the cached users, so changes to them or their memberships apply to their
+// next request
+func (s *PrincipalService) Invalidate(ctx context.Context, ids ...uint) {
+	ctx, span := tracing.Start(ctx, "PrincipalService.Invalidate")
+	defer span.End()
+
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, principalKey(id))
+	}
+	s.cache.Delete(ctx, keys...)
+}
+
+// principalKey returns the cache key of a user
+func principalKey(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
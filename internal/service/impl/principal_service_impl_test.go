@@ -0,0 +1,93 @@
+package impl
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/userblog/management/internal/models"
+	repoImpl "github.com/userblog/management/internal/repository/impl"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/lockout"
+)
+
+func TestCachedPrincipalHasNoPassword(t *testing.T) {
+	f := newOrgFixture(t)
+	alice := newPrincipalUsers(t, f)
+
+	user, err := f.principals.Load(f.ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if user.Password != "" {
+		t.Error("the loaded principal has a password hash")
+	}
+	cached, ok, _ := f.cacheStore.Get(context.Background(), "principals:"+principalKey(alice.ID))
+	if !ok {
+		t.Fatal("the principal wasn't cached")
+	}
+	if bytes.Contains(cached, []byte(alice.Password)) {
+		t.Error("the cached principal holds the password hash")
+	}
+}
+
+func TestPrincipalIsInvalidatedOnChanges(t *testing.T) {
+	f := newOrgFixture(t)
+	alice := newPrincipalUsers(t, f)
+	f.join(t, alice.ID, "member")
+	owner := f.actor(t, 1, config.Current().Orgs.OwnerRole)
+	users := NewUserService(repoImpl.NewUserRepository(f.db), f.roleRepo, lockout.NewMemoryStore(), f.roleSvc, f.principals, NewAuditService(repoImpl.NewAuditEventRepository(f.db)))
+
+	load := func() *models.User {
+		t.Helper()
+		user, err := f.principals.Load(f.ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		return user
+	}
+	if user := load(); user.Membership == nil || user.Membership.RoleID != f.roles["member"].ID {
+		t.Fatalf("membership = %+v, want member", user.Membership)
+	}
+
+	// Changes made behind the services' back aren't seen until the principal is invalidated
+	f.db.Model(&models.User{}).Where("id = ?", alice.ID).UpdateColumn("first_name", "Changed")
+	if user := load(); user.FirstName != "" {
+		t.Fatalf("first name = %q, want the cached principal", user.FirstName)
+	}
+
+	if err := f.orgs.UpdateMember(f.ctx, owner, alice.ID, f.roles["manager"].ID); err != nil {
+		t.Fatalf("UpdateMember: %v", err)
+	}
+	if user := load(); user.Membership == nil || user.Membership.RoleID != f.roles["manager"].ID || !hasPermission(user, "organization", "update") {
+		t.Errorf("membership = %+v, want manager after a membership change", user.Membership)
+	}
+
+	if err := users.Update(f.ctx, &models.User{Model: alice.Model, Username: "alice", Email: "alice@example.com", RoleID: 1}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if user := load(); user.RoleID != 1 {
+		t.Errorf("role = %d, want 1 after a role change", user.RoleID)
+	}
+
+	if err := f.orgs.RemoveMember(f.ctx, owner, alice.ID); err != nil {
+		t.Fatalf("RemoveMember: %v", err)
+	}
+	if user := load(); user.Membership != nil {
+		t.Errorf("membership = %+v, want none once removed", user.Membership)
+	}
+}
+
+// newPrincipalUsers creates the organization's owner, user 1, and alice, whom it returns
+func newPrincipalUsers(t *testing.T, f *orgFixture) *models.User {
+	t.Helper()
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "owner-password", RoleID: 1}
+	alice := &models.User{Username: "alice", Email: "alice@example.com", Password: "alice-password", RoleID: 2}
+	for _, user := range []*models.User{owner, alice} {
+		if err := f.db.Create(user).Error; err != nil {
+			t.Fatalf("creating %s: %v", user.Username, err)
+		}
+	}
+	return alice
+}
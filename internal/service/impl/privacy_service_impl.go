@@ -0,0 +1,550 @@
+package impl
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/audit"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/event"
+	"github.com/userblog/management/pkg/logger"
+	"github.com/userblog/management/pkg/storage"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// dataJobBatchSize is how many queued requests a job run processes at most
+const dataJobBatchSize = 10
+
+// PrivacyService implements the IPrivacyService interface
+type PrivacyService struct {
+	requestRepo  repository.IDataRequestRepository
+	personalRepo repository.IPersonalDataRepository
+	userRepo     repository.IUserRepository
+	principals   service.IPrincipalService
+	files        storage.Store
+	events       event.IBus
+	audit        service.IAuditService
+
+	// wake starts a job run early when a request is queued
+	wake chan struct{}
+}
+
+// NewPrivacyService creates a new data subject request service keeping exports in files
+func NewPrivacyService(requestRepo repository.IDataRequestRepository, personalRepo repository.IPersonalDataRepository, userRepo repository.IUserRepository, principals service.IPrincipalService, files storage.Store, events event.IBus, auditService service.IAuditService) service.IPrivacyService {
+	return &PrivacyService{
+		requestRepo:  requestRepo,
+		personalRepo: personalRepo,
+		userRepo:     userRepo,
+		principals:   principals,
+		files:        files,
+		events:       events,
+		audit:        auditService,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// RequestExport queues an export of everything stored about the user
+func (s *PrivacyService) RequestExport(ctx context.Context, userID uint) (*models.DataRequest, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.RequestExport")
+	defer span.End()
+
+	request, err := s.create(ctx, userID, models.DataExport, models.DataRequestQueued, "")
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, service.AuditPrivacyExportRequest, "data_request", request.ID, nil)
+	s.notify()
+	return request, nil
+}
+
+// RequestErasure asks for everything stored about the user to be erased, once an administrator
+// approves
+func (s *PrivacyService) RequestErasure(ctx context.Context, userID uint, currentPassword, reason string) (*models.DataRequest, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.RequestErasure")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, notFound(err, "user")
+	}
+	if err := user.ValidatePassword(currentPassword); err != nil {
+		return nil, service.NewValidationError("current password is incorrect")
+	}
+
+	request, err := s.create(ctx, userID, models.DataErasure, models.DataRequestPending, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, service.AuditPrivacyEraseRequest, "data_request", request.ID, map[string]audit.Change{
+		"reason": {After: reason},
+	})
+	return request, nil
+}
+
+// create stores a new request unless the user already has one of the kind in progress
+func (s *PrivacyService) create(ctx context.Context, userID uint, kind, status, reason string) (*models.DataRequest, error) {
+	open, err := s.requestRepo.CountOpen(ctx, userID, kind)
+	if err != nil {
+		return nil, err
+	}
+	if open > 0 {
+		return nil, service.NewConflictError(fmt.Sprintf("you already have an %s request in progress", kind))
+	}
+
+	request := &models.DataRequest{
+		UserID: userID,
+		Kind:   kind,
+		Status: status,
+		Reason: reason,
+	}
+	if err := s.requestRepo.Create(ctx, request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// ListOwn returns the user's requests, newest first
+func (s *PrivacyService) ListOwn(ctx context.Context, userID uint) ([]models.DataRequest, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.ListOwn")
+	defer span.End()
+
+	requests, _, err := s.requestRepo.List(ctx, repository.DataRequestFilter{UserID: userID}, 0, 100)
+	return requests, err
+}
+
+// Download returns the archive of one of the user's completed exports
+func (s *PrivacyService) Download(ctx context.Context, userID, id uint) ([]byte, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.Download")
+	defer span.End()
+
+	request, err := s.requestRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, "export")
+	}
+	if request.UserID != userID || request.Kind != models.DataExport {
+		return nil, service.NewNotFoundError("export")
+	}
+	if request.Status != models.DataRequestCompleted || request.FileKey == "" {
+		return nil, service.NewConflictError(fmt.Sprintf("export is %s", request.Status))
+	}
+
+	data, err := s.files.Get(ctx, request.FileKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, service.NewNotFoundError("export")
+	}
+	return data, err
+}
+
+// List returns a page of matching requests, newest first, and the number of matches
+func (s *PrivacyService) List(ctx context.Context, filter repository.DataRequestFilter, page, perPage int) ([]models.DataRequest, int, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.List")
+	defer span.End()
+
+	offset := (page - 1) * perPage
+	return s.requestRepo.List(ctx, filter, offset, perPage)
+}
+
+// Get returns a request by ID
+func (s *PrivacyService) Get(ctx context.Context, id uint) (*models.DataRequest, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.Get")
+	defer span.End()
+
+	request, err := s.requestRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, "data request")
+	}
+	return request, nil
+}
+
+// Approve queues a pending erasure, or one that failed, for processing. Administrators can't
+// approve their own.
+func (s *PrivacyService) Approve(ctx context.Context, reviewer *models.User, id uint, note string) (*models.DataRequest, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.Approve")
+	defer span.End()
+
+	request, err := s.review(ctx, reviewer, id, models.DataRequestQueued, note)
+	if err != nil {
+		return nil, err
+	}
+	s.notify()
+	return request, nil
+}
+
+// Reject declines a pending erasure
+func (s *PrivacyService) Reject(ctx context.Context, reviewer *models.User, id uint, note string) (*models.DataRequest, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.Reject")
+	defer span.End()
+
+	return s.review(ctx, reviewer, id, models.DataRequestRejected, note)
+}
+
+// review moves a pending, or failed, erasure to the status decided by the reviewer
+func (s *PrivacyService) review(ctx context.Context, reviewer *models.User, id uint, status, note string) (*models.DataRequest, error) {
+	request, err := s.requestRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, notFound(err, "data request")
+	}
+	if request.Kind != models.DataErasure {
+		return nil, service.NewConflictError("only erasure requests need a review")
+	}
+	if request.UserID == reviewer.ID {
+		return nil, service.NewForbiddenError("you can't review your own erasure request")
+	}
+	from := request.Status
+	if from != models.DataRequestPending && !(from == models.DataRequestFailed && status == models.DataRequestQueued) {
+		return nil, service.NewConflictError(fmt.Sprintf("erasure request is %s", from))
+	}
+
+	now := time.Now()
+	reviewed, err := s.requestRepo.Transition(ctx, id, from, map[string]interface{}{
+		"status":         status,
+		"reviewed_by_id": reviewer.ID,
+		"reviewed_at":    now,
+		"review_note":    note,
+		"error":          "",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reviewed == 0 {
+		return nil, service.NewConflictError("erasure request has already been reviewed")
+	}
+
+	action := service.AuditPrivacyApprove
+	if status == models.DataRequestRejected {
+		action = service.AuditPrivacyReject
+	}
+	s.audit.Record(ctx, action, "data_request", id, map[string]audit.Change{
+		"status":      {Before: from, After: status},
+		"review_note": {After: note},
+	})
+
+	request.Status, request.ReviewedByID, request.ReviewedAt, request.ReviewNote, request.Error = status, reviewer.ID, &now, note, ""
+	return request, nil
+}
+
+// Certificate returns the certificate of a completed erasure
+func (s *PrivacyService) Certificate(ctx context.Context, id uint) (*models.ErasureCertificate, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.Certificate")
+	defer span.End()
+
+	certificate, err := s.requestRepo.FindCertificate(ctx, id)
+	if err != nil {
+		return nil, notFound(err, "erasure certificate")
+	}
+	return certificate, nil
+}
+
+// ProcessQueued carries out queued requests and removes expired export archives. It returns how
+// many requests were processed; failed ones are marked as such and don't stop the others.
+func (s *PrivacyService) ProcessQueued(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.ProcessQueued")
+	defer span.End()
+
+	ctx = service.WithActor(ctx, systemActor)
+	if err := s.expireExports(ctx); err != nil {
+		return 0, err
+	}
+
+	requests, err := s.requestRepo.ListByStatus(ctx, models.DataRequestQueued, dataJobBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	processed := 0
+	for i := range requests {
+		request := &requests[i]
+
+		// Another instance may have claimed the request first
+		claimed, err := s.requestRepo.Transition(ctx, request.ID, models.DataRequestQueued, map[string]interface{}{"status": models.DataRequestProcessing})
+		if err != nil {
+			return processed, err
+		}
+		if claimed == 0 {
+			continue
+		}
+
+		if request.Kind == models.DataExport {
+			err = s.export(ctx, request)
+		} else {
+			err = s.erase(ctx, request)
+		}
+		if err != nil {
+			logger.ErrorF(ctx, "Failed to process %s request %d: %v", request.Kind, request.ID, err)
+			if _, err := s.requestRepo.Transition(ctx, request.ID, models.DataRequestProcessing, map[string]interface{}{
+				"status": models.DataRequestFailed,
+				"error":  err.Error(),
+			}); err != nil {
+				return processed, err
+			}
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// RunJobs processes queued requests every interval, and as soon as one is queued, until ctx is done
+func (s *PrivacyService) RunJobs(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+		if _, err := s.ProcessQueued(ctx); err != nil {
+			logger.ErrorF(ctx, "Failed to process data requests: %v", err)
+		}
+	}
+}
+
+// notify wakes the job without waiting for it
+func (s *PrivacyService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// export packages everything stored about the user into a ZIP of JSON files
+func (s *PrivacyService) export(ctx context.Context, request *models.DataRequest) error {
+	data, err := s.personalRepo.Collect(ctx, request.UserID)
+	if err != nil {
+		return err
+	}
+	archive, err := exportArchive(data)
+	if err != nil {
+		return err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("exports/%d-%s.zip", request.ID, token[:16])
+	if err := s.files.Put(ctx, key, archive); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(config.Current().Privacy.ExportTTL)
+	_, err = s.requestRepo.Transition(ctx, request.ID, models.DataRequestProcessing, map[string]interface{}{
+		"status":       models.DataRequestCompleted,
+		"file_key":     key,
+		"file_size":    len(archive),
+		"expires_at":   expiresAt,
+		"completed_at": now,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, service.AuditPrivacyExport, "data_request", request.ID, nil)
+	s.events.Publish(ctx, service.EventDataExportReady, map[string]interface{}{
+		"user_id":    data.User.ID,
+		"username":   data.User.Username,
+		"email":      data.User.Email,
+		"request_id": request.ID,
+		"expires_at": expiresAt,
+	})
+	return nil
+}
+
+// erase removes or anonymizes the user's data as configured per table, deletes their files and
+// records a certificate of completion
+func (s *PrivacyService) erase(ctx context.Context, request *models.DataRequest) error {
+	data, err := s.personalRepo.Collect(ctx, request.UserID)
+	if err != nil {
+		return err
+	}
+	user := data.User
+
+	results, err := s.personalRepo.Erase(ctx, &user, config.Current().Privacy.Erasure.Tables())
+	if err != nil {
+		return err
+	}
+	s.principals.Invalidate(ctx, user.ID)
+	results["files"] = models.ErasureResult{Action: config.ErasureDelete, Rows: s.removeFiles(ctx, data)}
+
+	tables, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	certificate := &models.ErasureCertificate{
+		CreatedAt:    time.Now().UTC().Truncate(time.Second),
+		RequestID:    request.ID,
+		UserID:       user.ID,
+		ApprovedByID: request.ReviewedByID,
+		Tables:       models.JSONText(tables),
+	}
+	certificate.Digest = certificate.ComputeDigest()
+	if err := s.requestRepo.CreateCertificate(ctx, certificate); err != nil {
+		return err
+	}
+
+	_, err = s.requestRepo.Transition(ctx, request.ID, models.DataRequestProcessing, map[string]interface{}{
+		"status":       models.DataRequestCompleted,
+		"completed_at": certificate.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, service.AuditPrivacyErase, "data_request", request.ID, map[string]audit.Change{
+		"certificate_id": {After: certificate.ID},
+	})
+	// The confirmation goes to the address the user had, which is no longer stored
+	s.events.Publish(ctx, service.EventDataErased, map[string]interface{}{
+		"user_id":        user.ID,
+		"username":       user.Username,
+		"email":          user.Email,
+		"request_id":     request.ID,
+		"certificate_id": certificate.ID,
+	})
+	return nil
+}
+
+// removeFiles deletes the user's avatar and export archives and returns how many files were
+// deleted. Failures only leave a file behind, so they are logged.
+func (s *PrivacyService) removeFiles(ctx context.Context, data *repository.PersonalData) int64 {
+	var keys []string
+	if strings.HasPrefix(data.User.AvatarURL, AvatarPath) {
+		keys = append(keys, "avatars/"+path.Base(data.User.AvatarURL))
+	}
+	for _, request := range data.DataRequests {
+		if request.FileKey != "" {
+			keys = append(keys, request.FileKey)
+			if _, err := s.requestRepo.Transition(ctx, request.ID, models.DataRequestCompleted, map[string]interface{}{
+				"status":   models.DataRequestExpired,
+				"file_key": "",
+			}); err != nil {
+				logger.WarnF(ctx, "Failed to expire export %d: %v", request.ID, err)
+			}
+		}
+	}
+
+	var removed int64
+	for _, key := range keys {
+		if err := s.files.Delete(ctx, key); err != nil {
+			logger.WarnF(ctx, "Failed to delete %s of erased user %d: %v", key, data.User.ID, err)
+			continue
+		}
+		removed++
+	}
+	return removed
+}
+
+// expireExports deletes the archives of exports past their expiry
+func (s *PrivacyService) expireExports(ctx context.Context) error {
+	requests, err := s.requestRepo.ListExpired(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, request := range requests {
+		if err := s.files.Delete(ctx, request.FileKey); err != nil {
+			logger.WarnF(ctx, "Failed to delete expired export %d: %v", request.ID, err)
+			continue
+		}
+		if _, err := s.requestRepo.Transition(ctx, request.ID, models.DataRequestCompleted, map[string]interface{}{
+			"status":   models.DataRequestExpired,
+			"file_key": "",
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportArchive encodes the user's data as a ZIP with one JSON file per kind of data
+func exportArchive(data *repository.PersonalData) ([]byte, error) {
+	user := data.User
+	user.Password = ""
+
+	blogs := make([]exportedBlog, 0, len(data.Blogs))
+	for _, blog := range data.Blogs {
+		blogs = append(blogs, exportedBlog{
+			ID:             blog.ID,
+			Title:          blog.Title,
+			Content:        blog.Content,
+			Published:      blog.Published,
+			OrganizationID: blog.OrganizationID,
+			CreatedAt:      blog.CreatedAt,
+			UpdatedAt:      blog.UpdatedAt,
+			DeletedAt:      blog.DeletedAt,
+		})
+	}
+	memberships := make([]exportedMembership, 0, len(data.Memberships))
+	for _, membership := range data.Memberships {
+		memberships = append(memberships, exportedMembership{
+			OrganizationID: membership.OrganizationID,
+			RoleID:         membership.RoleID,
+			JoinedAt:       membership.CreatedAt,
+		})
+	}
+
+	files := []struct {
+		name    string
+		content interface{}
+	}{
+		{"account.json", user},
+		{"blogs.json", blogs},
+		{"memberships.json", memberships},
+		{"sessions.json", data.Sessions},
+		{"identities.json", data.Identities},
+		{"invitations.json", data.Invitations},
+		{"impersonation_events.json", data.ImpersonationEvents},
+		{"audit_events.json", data.AuditEvents},
+		{"data_requests.json", data.DataRequests},
+	}
+
+	var out bytes.Buffer
+	archive := zip.NewWriter(&out)
+	for _, file := range files {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// exportedBlog is a blog as included in a data export, without its author, who is the user
+type exportedBlog struct {
+	ID             uint       `json:"id"`
+	Title          string     `json:"title"`
+	Content        string     `json:"content"`
+	Published      bool       `json:"published"`
+	OrganizationID uint       `json:"organization_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+}
+
+// exportedMembership is an organization membership as included in a data export
+type exportedMembership struct {
+	OrganizationID uint      `json:"organization_id"`
+	RoleID         uint      `json:"role_id"`
+	JoinedAt       time.Time `json:"joined_at"`
+}
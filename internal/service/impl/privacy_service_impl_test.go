@@ -0,0 +1,345 @@
+package impl
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	repoImpl "github.com/userblog/management/internal/repository/impl"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/audit"
+	"github.com/userblog/management/pkg/cache"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/event"
+	"github.com/userblog/management/pkg/logger"
+	"github.com/userblog/management/pkg/storage"
+)
+
+// privacyFixture holds a privacy service and the data it works on: the admin (user 1), alice
+// (user 2), whose data is exported and erased, and bob (user 3)
+type privacyFixture struct {
+	db                *gorm.DB
+	privacy           service.IPrivacyService
+	audits            service.IAuditService
+	files             storage.Store
+	admin, alice, bob *models.User
+}
+
+func newPrivacyFixture(t *testing.T) *privacyFixture {
+	t.Helper()
+
+	db := newTestDB(t)
+	setConfig(t, config.Default())
+
+	userRepo := repoImpl.NewUserRepository(db)
+	auditService := NewAuditService(repoImpl.NewAuditEventRepository(db))
+	cacheStore := cache.NewMemoryStore(100)
+	roleService := NewRoleService(repoImpl.NewRoleRepository(db), auditService, cache.New("roles", cacheStore))
+	principals := NewPrincipalService(userRepo, repoImpl.NewOrganizationRepository(db), roleService, cache.New("principals", cacheStore))
+	files := storage.NewDiskStore(t.TempDir())
+
+	f := &privacyFixture{
+		db:      db,
+		privacy: NewPrivacyService(repoImpl.NewDataRequestRepository(db), repoImpl.NewPersonalDataRepository(db), userRepo, principals, files, event.NewBus(), auditService),
+		audits:  auditService,
+		files:   files,
+		admin:   &models.User{Username: "admin", Email: "admin@example.com", Password: "admin-password", RoleID: 1},
+		alice:   &models.User{Username: "alice", Email: "alice@example.com", Password: "alice-password", RoleID: 2, FirstName: "Alice"},
+		bob:     &models.User{Username: "bob", Email: "bob@example.com", Password: "bob-password", RoleID: 2},
+	}
+	f.alice.AvatarURL = AvatarPath + "2.png"
+	ctx := context.Background()
+	for _, user := range []*models.User{f.admin, f.alice, f.bob} {
+		if err := userRepo.Create(ctx, user); err != nil {
+			t.Fatalf("creating %s: %v", user.Username, err)
+		}
+	}
+	if err := files.Put(ctx, "avatars/2.png", []byte("png")); err != nil {
+		t.Fatalf("storing avatar: %v", err)
+	}
+	rows := []interface{}{
+		&models.Blog{Title: "Alice's post", Content: "Hello", UserID: f.alice.ID},
+		&models.Blog{Title: "Bob's post", Content: "Hi", UserID: f.bob.ID},
+		&models.Session{UserID: f.alice.ID, ExpiresAt: time.Now().Add(time.Hour)},
+		&models.Session{UserID: f.bob.ID, ExpiresAt: time.Now().Add(time.Hour)},
+		&models.Membership{OrganizationID: 1, UserID: f.alice.ID, RoleID: 2},
+	}
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("creating %T: %v", row, err)
+		}
+	}
+
+	// An entry recorded before personal fields could be erased, then entries by alice, about
+	// her and about bob
+	legacy := &models.AuditEvent{CreatedAt: time.Now(), ActorID: f.alice.ID, ActorName: "alice", Action: service.AuditUserCreate, TargetType: "user", TargetID: "2", IP: "10.0.0.2"}
+	legacy.Hash = legacy.ComputeHash()
+	if err := db.Create(legacy).Error; err != nil {
+		t.Fatalf("creating legacy audit entry: %v", err)
+	}
+	asAlice := context.WithValue(service.WithActor(ctx, service.Actor{UserID: f.alice.ID, Username: "alice"}), logger.ClientIpKey, "10.0.0.2")
+	asAdmin := context.WithValue(service.WithActor(ctx, service.Actor{UserID: f.admin.ID, Username: "admin"}), logger.ClientIpKey, "10.0.0.1")
+	auditService.Record(asAlice, service.AuditUserUpdate, "user", f.alice.ID, map[string]audit.Change{"first_name": {Before: "", After: "Alice"}})
+	auditService.Record(asAdmin, service.AuditUserUpdate, "user", f.alice.ID, map[string]audit.Change{"email": {Before: "a@example.com", After: "alice@example.com"}})
+	auditService.Record(asAdmin, service.AuditUserUpdate, "user", f.bob.ID, map[string]audit.Change{"first_name": {Before: "", After: "Bob"}})
+	return f
+}
+
+// process runs the job once and returns the request as it left it
+func (f *privacyFixture) process(t *testing.T, id uint) *models.DataRequest {
+	t.Helper()
+
+	if _, err := f.privacy.ProcessQueued(context.Background()); err != nil {
+		t.Fatalf("ProcessQueued: %v", err)
+	}
+	request, err := f.privacy.Get(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	return request
+}
+
+func TestExportArchive(t *testing.T) {
+	f := newPrivacyFixture(t)
+	ctx := context.Background()
+
+	request, err := f.privacy.RequestExport(ctx, f.alice.ID)
+	if err != nil {
+		t.Fatalf("RequestExport: %v", err)
+	}
+	_, err = f.privacy.RequestExport(ctx, f.alice.ID)
+	assertErrorType[*service.ConflictError](t, err)
+
+	request = f.process(t, request.ID)
+	if request.Status != models.DataRequestCompleted || request.FileKey == "" || request.ExpiresAt == nil {
+		t.Fatalf("request = %+v, want completed with an archive", request)
+	}
+
+	_, err = f.privacy.Download(ctx, f.bob.ID, request.ID)
+	assertErrorType[*service.NotFoundError](t, err)
+	data, err := f.privacy.Download(ctx, f.alice.ID, request.ID)
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("reading archive: %v", err)
+	}
+	contents := map[string]string{}
+	for _, file := range archive.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatalf("opening %s: %v", file.Name, err)
+		}
+		content, _ := io.ReadAll(r)
+		r.Close()
+		contents[file.Name] = string(content)
+	}
+
+	var names []string
+	for name := range contents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	want := "account.json audit_events.json blogs.json data_requests.json identities.json impersonation_events.json invitations.json memberships.json sessions.json"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("archive holds %s, want %s", got, want)
+	}
+
+	var account models.User
+	if err := json.Unmarshal([]byte(contents["account.json"]), &account); err != nil {
+		t.Fatalf("decoding account.json: %v", err)
+	}
+	if account.Username != "alice" || account.FirstName != "Alice" || account.Password != "" {
+		t.Errorf("account = %+v, want alice without her password hash", account)
+	}
+	var blogs []exportedBlog
+	_ = json.Unmarshal([]byte(contents["blogs.json"]), &blogs)
+	if len(blogs) != 1 || blogs[0].Title != "Alice's post" {
+		t.Errorf("blogs = %+v, want only alice's", blogs)
+	}
+	var events []models.AuditEvent
+	_ = json.Unmarshal([]byte(contents["audit_events.json"]), &events)
+	if len(events) != 3 {
+		t.Errorf("%d audit events exported, want the 3 by or about alice", len(events))
+	}
+	var sessions []models.Session
+	_ = json.Unmarshal([]byte(contents["sessions.json"]), &sessions)
+	if len(sessions) != 1 || sessions[0].UserID != f.alice.ID {
+		t.Errorf("sessions = %+v, want only alice's", sessions)
+	}
+}
+
+func TestErasure(t *testing.T) {
+	f := newPrivacyFixture(t)
+	ctx := context.Background()
+
+	_, err := f.privacy.RequestErasure(ctx, f.alice.ID, "wrong-password", "")
+	assertErrorType[*service.ValidationError](t, err)
+	request, err := f.privacy.RequestErasure(ctx, f.alice.ID, "alice-password", "leaving")
+	if err != nil {
+		t.Fatalf("RequestErasure: %v", err)
+	}
+	if request = f.process(t, request.ID); request.Status != models.DataRequestPending {
+		t.Fatalf("an unapproved erasure is %s", request.Status)
+	}
+
+	_, err = f.privacy.Approve(ctx, f.alice, request.ID, "")
+	assertErrorType[*service.ForbiddenError](t, err)
+	if _, err := f.privacy.Approve(ctx, f.admin, request.ID, "verified"); err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	if request = f.process(t, request.ID); request.Status != models.DataRequestCompleted {
+		t.Fatalf("request = %+v, want completed", request)
+	}
+
+	certificate, err := f.privacy.Certificate(ctx, request.ID)
+	if err != nil {
+		t.Fatalf("Certificate: %v", err)
+	}
+	if certificate.UserID != f.alice.ID || certificate.ApprovedByID != f.admin.ID || certificate.Digest != certificate.ComputeDigest() {
+		t.Errorf("certificate = %+v", certificate)
+	}
+	var tables map[string]models.ErasureResult
+	if err := json.Unmarshal([]byte(certificate.Tables), &tables); err != nil {
+		t.Fatalf("decoding certificate tables: %v", err)
+	}
+	wantTables := map[string]models.ErasureResult{
+		"users":                {Action: config.ErasureAnonymize, Rows: 1},
+		"blogs":                {Action: config.ErasureDelete, Rows: 1},
+		"memberships":          {Action: config.ErasureDelete, Rows: 1},
+		"sessions":             {Action: config.ErasureDelete, Rows: 1},
+		"identities":           {Action: config.ErasureDelete},
+		"invitations":          {Action: config.ErasureDelete},
+		"impersonation_events": {Action: config.ErasureKeep},
+		"audit_events":         {Action: config.ErasureAnonymize, Rows: 2, Kept: 1},
+		"files":                {Action: config.ErasureDelete, Rows: 1},
+	}
+	for table, want := range wantTables {
+		if got := tables[table]; got != want {
+			t.Errorf("certificate %s = %+v, want %+v", table, got, want)
+		}
+	}
+
+	var user models.User
+	f.db.Unscoped().First(&user, f.alice.ID)
+	if user.Username != "erased-2" || user.FirstName != "" || user.Password != "" || user.DeletedAt == nil {
+		t.Errorf("user = %+v, want anonymized", user)
+	}
+	if _, err := f.files.Get(ctx, "avatars/2.png"); err == nil {
+		t.Error("the avatar was kept")
+	}
+	var blogs int
+	f.db.Model(&models.Blog{}).Count(&blogs)
+	if blogs != 1 {
+		t.Errorf("%d blogs left, want bob's", blogs)
+	}
+
+	var events []models.AuditEvent
+	f.db.Order("id").Find(&events)
+	byAlice, aboutAlice, aboutBob := events[1], events[2], events[3]
+	if byAlice.ActorName != "" || byAlice.IP != "" || byAlice.Changes != "" {
+		t.Errorf("entry by alice = %+v, want her name, IP and changes cleared", byAlice)
+	}
+	if aboutAlice.ActorName != "admin" || aboutAlice.IP != "10.0.0.1" || aboutAlice.Changes != "" {
+		t.Errorf("entry about alice = %+v, want only the changes cleared", aboutAlice)
+	}
+	if aboutBob.Changes == "" || events[0].ActorName != "alice" {
+		t.Error("entries the erasure doesn't apply to were changed")
+	}
+
+	// Anonymizing keeps the chain verifiable, including the erasure's own entries
+	result, err := f.audits.Verify(ctx)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !result.Valid {
+		t.Errorf("Verify = %+v, want the anonymized chain valid", result)
+	}
+
+	// A value written to an erased field still breaks it
+	f.db.Model(&models.AuditEvent{}).Where("id = ?", byAlice.ID).UpdateColumn("changes", `{"first_name":{"after":"Mallory"}}`)
+	if result, _ := f.audits.Verify(ctx); result.Valid || result.InvalidID != byAlice.ID {
+		t.Errorf("Verify = %+v, want entry %d invalid", result, byAlice.ID)
+	}
+}
+
+func TestAuditEventsCanBeKept(t *testing.T) {
+	f := newPrivacyFixture(t)
+	cfg := config.Default()
+	cfg.Privacy.Erasure.AuditEvents = config.ErasureKeep
+	setConfig(t, cfg)
+
+	request, _ := f.privacy.RequestErasure(context.Background(), f.alice.ID, "alice-password", "")
+	if _, err := f.privacy.Approve(context.Background(), f.admin, request.ID, ""); err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	f.process(t, request.ID)
+
+	var kept int
+	f.db.Model(&models.AuditEvent{}).Where("changes <> ''").Count(&kept)
+	if kept < 3 {
+		t.Errorf("%d entries keep their changes, want every entry about alice and bob", kept)
+	}
+}
+
+func TestPrivacyJob(t *testing.T) {
+	f := newPrivacyFixture(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// A request that can't be processed fails without stopping the others
+	failing := &models.DataRequest{UserID: 99, Kind: models.DataExport, Status: models.DataRequestQueued}
+	f.db.Create(failing)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.privacy.RunJobs(ctx, time.Hour)
+	}()
+
+	// Queuing a request wakes the job long before its interval
+	request, err := f.privacy.RequestExport(context.Background(), f.alice.ID)
+	if err != nil {
+		t.Fatalf("RequestExport: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		current, _ := f.privacy.Get(context.Background(), request.ID)
+		if current.Status == models.DataRequestCompleted {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("request is still %s", current.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if current, _ := f.privacy.Get(context.Background(), failing.ID); current.Status != models.DataRequestFailed || current.Error == "" {
+		t.Errorf("request for a missing user = %+v, want failed with its error", current)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunJobs didn't return once cancelled")
+	}
+
+	// Archives past their expiry are removed on the next run
+	current, _ := f.privacy.Get(context.Background(), request.ID)
+	f.db.Model(&models.DataRequest{}).Where("id = ?", request.ID).UpdateColumn("expires_at", time.Now().Add(-time.Minute))
+	if expired := f.process(t, request.ID); expired.Status != models.DataRequestExpired || expired.FileKey != "" {
+		t.Errorf("request = %+v, want expired", expired)
+	}
+	if _, err := f.files.Get(context.Background(), current.FileKey); err == nil {
+		t.Error("the expired archive was kept")
+	}
+}
@@ -0,0 +1,212 @@
+package impl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/audit"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/imaging"
+	"github.com/userblog/management/pkg/logger"
+	"github.com/userblog/management/pkg/storage"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// AvatarPath is the URL path avatars are served under
+const AvatarPath = "/api/avatars/"
+
+// avatarName matches the file names avatars are stored under: the user ID and a hash of the image
+var avatarName = regexp.MustCompile(`^[0-9]+-[0-9a-f]{16}\.(png|jpg)$`)
+
+// ProfileService implements the IProfileService interface
+type ProfileService struct {
+	userRepo   repository.IUserRepository
+	blogRepo   repository.IBlogRepository
+	principals service.IPrincipalService
+	files      storage.Store
+	audit      service.IAuditService
+}
+
+// NewProfileService creates a new profile service keeping avatars in files
+func NewProfileService(userRepo repository.IUserRepository, blogRepo repository.IBlogRepository, principals service.IPrincipalService, files storage.Store, auditService service.IAuditService) service.IProfileService {
+	return &ProfileService{
+		userRepo:   userRepo,
+		blogRepo:   blogRepo,
+		principals: principals,
+		files:      files,
+		audit:      auditService,
+	}
+}
+
+// Get returns a user's profile
+func (s *ProfileService) Get(ctx context.Context, userID uint) (*models.Profile, error) {
+	ctx, span := tracing.Start(ctx, "ProfileService.Get")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, notFound(err, "user")
+	}
+	return &user.Profile, nil
+}
+
+// Update replaces a user's profile fields, apart from the avatar, which is set by uploading one
+func (s *ProfileService) Update(ctx context.Context, userID uint, profile *models.Profile) error {
+	ctx, span := tracing.Start(ctx, "ProfileService.Update")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return notFound(err, "user")
+	}
+	profile.AvatarURL = user.AvatarURL
+
+	err = s.userRepo.UpdateColumns(ctx, userID, map[string]interface{}{
+		"display_name": profile.DisplayName,
+		"bio":          profile.Bio,
+		"website":      profile.Website,
+		"location":     profile.Location,
+		"social_links": profile.SocialLinks,
+	})
+	if err != nil {
+		return err
+	}
+	s.principals.Invalidate(ctx, userID)
+
+	s.audit.Record(ctx, service.AuditUserUpdate, "user", userID, audit.Diff(&user.Profile, profile))
+	return nil
+}
+
+// SetAvatar crops and scales the uploaded image to the configured avatar size and makes it the
+// user's avatar, replacing any previous one
+func (s *ProfileService) SetAvatar(ctx context.Context, userID uint, image io.Reader) (*models.Profile, error) {
+	ctx, span := tracing.Start(ctx, "ProfileService.SetAvatar")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, notFound(err, "user")
+	}
+
+	data, contentType, err := imaging.Thumbnail(image, config.Current().Avatars.Size)
+	if errors.Is(err, imaging.ErrUnsupported) || errors.Is(err, imaging.ErrTooLarge) {
+		return nil, service.NewValidationError(err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Names change with the content, so avatars can be cached by clients forever
+	ext := "png"
+	if contentType == "image/jpeg" {
+		ext = "jpg"
+	}
+	sum := sha256.Sum256(data)
+	name := fmt.Sprintf("%d-%s.%s", userID, hex.EncodeToString(sum[:8]), ext)
+	if err := s.files.Put(ctx, "avatars/"+name, data); err != nil {
+		return nil, err
+	}
+
+	before := user.Profile
+	if err := s.userRepo.UpdateColumns(ctx, userID, map[string]interface{}{"avatar_url": AvatarPath + name}); err != nil {
+		return nil, err
+	}
+	user.AvatarURL = AvatarPath + name
+	s.principals.Invalidate(ctx, userID)
+	s.removeAvatarFile(ctx, before.AvatarURL, user.AvatarURL)
+
+	s.audit.Record(ctx, service.AuditUserUpdate, "user", userID, audit.Diff(&before, &user.Profile))
+	return &user.Profile, nil
+}
+
+// RemoveAvatar removes a user's avatar
+func (s *ProfileService) RemoveAvatar(ctx context.Context, userID uint) error {
+	ctx, span := tracing.Start(ctx, "ProfileService.RemoveAvatar")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return notFound(err, "user")
+	}
+	if user.AvatarURL == "" {
+		return nil
+	}
+
+	if err := s.userRepo.UpdateColumns(ctx, userID, map[string]interface{}{"avatar_url": ""}); err != nil {
+		return err
+	}
+	s.principals.Invalidate(ctx, userID)
+	s.removeAvatarFile(ctx, user.AvatarURL, "")
+
+	s.audit.Record(ctx, service.AuditUserUpdate, "user", userID, map[string]audit.Change{
+		"avatar_url": {Before: user.AvatarURL},
+	})
+	return nil
+}
+
+// Author returns the public profile of the user with the username. Post counts only include
+// published blogs of the organization the context acts in.
+func (s *ProfileService) Author(ctx context.Context, username string) (*service.Author, error) {
+	ctx, span := tracing.Start(ctx, "ProfileService.Author")
+	defer span.End()
+
+	user, err := s.userRepo.FindByUsername(ctx, username)
+	if err != nil {
+		return nil, notFound(err, "author")
+	}
+	posts, err := s.blogRepo.CountPublishedByUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &service.Author{
+		Username:  user.Username,
+		Profile:   user.Profile,
+		PostCount: posts,
+		JoinedAt:  user.CreatedAt,
+	}, nil
+}
+
+// Avatar returns the avatar image stored under name with its content type
+func (s *ProfileService) Avatar(ctx context.Context, name string) ([]byte, string, error) {
+	ctx, span := tracing.Start(ctx, "ProfileService.Avatar")
+	defer span.End()
+
+	if !avatarName.MatchString(name) {
+		return nil, "", service.NewNotFoundError("avatar")
+	}
+	data, err := s.files.Get(ctx, "avatars/"+name)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, "", service.NewNotFoundError("avatar")
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	contentType := "image/png"
+	if strings.HasSuffix(name, ".jpg") {
+		contentType = "image/jpeg"
+	}
+	return data, contentType, nil
+}
+
+// removeAvatarFile deletes the file of a replaced avatar. Failures only leave an unused file
+// behind, so they are logged.
+func (s *ProfileService) removeAvatarFile(ctx context.Context, oldURL, newURL string) {
+	if oldURL == "" || oldURL == newURL || !strings.HasPrefix(oldURL, AvatarPath) {
+		return
+	}
+	if err := s.files.Delete(ctx, "avatars/"+path.Base(oldURL)); err != nil {
+		logger.WarnF(ctx, "Failed to delete replaced avatar %s: %v", oldURL, err)
+	}
+}
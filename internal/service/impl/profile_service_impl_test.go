@@ -0,0 +1,202 @@
+package impl
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/userblog/management/internal/models"
+	repoImpl "github.com/userblog/management/internal/repository/impl"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/storage"
+)
+
+// profileFixture holds a profile service keeping avatars in a temporary directory, and alice
+type profileFixture struct {
+	*authFixture
+	profiles service.IProfileService
+	files    storage.Store
+}
+
+func newProfileFixture(t *testing.T) *profileFixture {
+	t.Helper()
+
+	f := newAuthFixture(t)
+	files := storage.NewDiskStore(t.TempDir())
+	profiles := NewProfileService(repoImpl.NewUserRepository(f.db), repoImpl.NewBlogRepository(f.db), f.principals, files, NewAuditService(repoImpl.NewAuditEventRepository(f.db)))
+	return &profileFixture{authFixture: f, profiles: profiles, files: files}
+}
+
+// testImage returns a width by height image of one color, encoded as a PNG or, with asJPEG, as
+// a JPEG
+func testImage(t *testing.T, width, height int, asJPEG bool) []byte {
+	t.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+3] = 200, 255
+	}
+	var out bytes.Buffer
+	var err error
+	if asJPEG {
+		err = jpeg.Encode(&out, img, nil)
+	} else {
+		err = png.Encode(&out, img)
+	}
+	if err != nil {
+		t.Fatalf("encoding image: %v", err)
+	}
+	return out.Bytes()
+}
+
+// bombImage returns a PNG of a few bytes whose header declares 50000x50000 pixels
+func bombImage() []byte {
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], 50000)
+	binary.BigEndian.PutUint32(ihdr[4:], 50000)
+	ihdr[8], ihdr[9] = 8, 6
+	chunk := append([]byte("IHDR"), ihdr...)
+
+	var out bytes.Buffer
+	out.WriteString("\x89PNG\r\n\x1a\n")
+	_ = binary.Write(&out, binary.BigEndian, uint32(len(ihdr)))
+	out.Write(chunk)
+	_ = binary.Write(&out, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	return out.Bytes()
+}
+
+func TestSetAvatar(t *testing.T) {
+	f := newProfileFixture(t)
+	ctx := context.Background()
+
+	profile, err := f.profiles.SetAvatar(ctx, f.alice.ID, bytes.NewReader(testImage(t, 800, 400, false)))
+	if err != nil {
+		t.Fatalf("SetAvatar: %v", err)
+	}
+	if !strings.HasPrefix(profile.AvatarURL, AvatarPath) || !strings.HasSuffix(profile.AvatarURL, ".png") {
+		t.Fatalf("avatar URL = %q", profile.AvatarURL)
+	}
+
+	// The stored avatar is served, cropped and scaled to the configured size
+	first := path.Base(profile.AvatarURL)
+	data, contentType, err := f.profiles.Avatar(ctx, first)
+	if err != nil {
+		t.Fatalf("Avatar: %v", err)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || contentType != "image/png" || cfg.Width != 256 || cfg.Height != 256 {
+		t.Errorf("avatar is %s %dx%d (%v), want a 256x256 PNG", contentType, cfg.Width, cfg.Height, err)
+	}
+
+	// A new avatar replaces the previous one's file
+	profile, err = f.profiles.SetAvatar(ctx, f.alice.ID, bytes.NewReader(testImage(t, 64, 64, true)))
+	if err != nil {
+		t.Fatalf("SetAvatar: %v", err)
+	}
+	if !strings.HasSuffix(profile.AvatarURL, ".jpg") {
+		t.Errorf("avatar URL = %q, want a JPEG", profile.AvatarURL)
+	}
+	_, _, err = f.profiles.Avatar(ctx, first)
+	assertErrorType[*service.NotFoundError](t, err)
+
+	var user models.User
+	f.db.First(&user, f.alice.ID)
+	if user.AvatarURL != profile.AvatarURL {
+		t.Errorf("stored avatar URL = %q, want %q", user.AvatarURL, profile.AvatarURL)
+	}
+
+	if err := f.profiles.RemoveAvatar(ctx, f.alice.ID); err != nil {
+		t.Fatalf("RemoveAvatar: %v", err)
+	}
+	_, _, err = f.profiles.Avatar(ctx, path.Base(profile.AvatarURL))
+	assertErrorType[*service.NotFoundError](t, err)
+}
+
+func TestSetAvatarRejectsInvalidImages(t *testing.T) {
+	f := newProfileFixture(t)
+
+	uploads := map[string][]byte{
+		"text":               []byte("not an image"),
+		"svg":                []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`),
+		"decompression bomb": bombImage(),
+	}
+	for name, data := range uploads {
+		_, err := f.profiles.SetAvatar(context.Background(), f.alice.ID, bytes.NewReader(data))
+		var invalid *service.ValidationError
+		if !errors.As(err, &invalid) {
+			t.Errorf("%s: error = %v, want a validation error", name, err)
+		}
+	}
+
+	var user models.User
+	f.db.First(&user, f.alice.ID)
+	if user.AvatarURL != "" {
+		t.Errorf("avatar URL = %q after rejected uploads", user.AvatarURL)
+	}
+}
+
+func TestAvatarNamesAreChecked(t *testing.T) {
+	f := newProfileFixture(t)
+
+	for _, name := range []string{"../config.yml", "2-0123456789abcdef.svg", "x.png", ""} {
+		_, _, err := f.profiles.Avatar(context.Background(), name)
+		assertErrorType[*service.NotFoundError](t, err)
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	f := newProfileFixture(t)
+	ctx := context.Background()
+
+	avatar, err := f.profiles.SetAvatar(ctx, f.alice.ID, bytes.NewReader(testImage(t, 32, 32, false)))
+	if err != nil {
+		t.Fatalf("SetAvatar: %v", err)
+	}
+	// The principal is cached before the update, which must invalidate it
+	f.principal(t, f.alice.ID)
+
+	update := &models.Profile{
+		DisplayName: "Alice",
+		Bio:         "Writes things",
+		Website:     "https://alice.example.com",
+		Location:    "Lisbon",
+		SocialLinks: models.SocialLinks{"github": "https://github.com/alice"},
+		AvatarURL:   "https://evil.example.com/avatar.png",
+	}
+	if err := f.profiles.Update(ctx, f.alice.ID, update); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	profile, err := f.profiles.Get(ctx, f.alice.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if profile.DisplayName != "Alice" || profile.Bio != "Writes things" || profile.Location != "Lisbon" ||
+		profile.Website != "https://alice.example.com" || profile.SocialLinks["github"] != "https://github.com/alice" {
+		t.Errorf("profile = %+v", profile)
+	}
+	// The avatar is only set by uploading one
+	if profile.AvatarURL != avatar.AvatarURL || update.AvatarURL != avatar.AvatarURL {
+		t.Errorf("avatar URL = %q, want %q kept", profile.AvatarURL, avatar.AvatarURL)
+	}
+	if loaded := f.principal(t, f.alice.ID); loaded.DisplayName != "Alice" {
+		t.Errorf("the cached principal has display name %q", loaded.DisplayName)
+	}
+
+	// Every field is replaced, so omitted ones are cleared
+	if err := f.profiles.Update(ctx, f.alice.ID, &models.Profile{DisplayName: "A."}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	profile, _ = f.profiles.Get(ctx, f.alice.ID)
+	if profile.DisplayName != "A." || profile.Bio != "" || len(profile.SocialLinks) != 0 {
+		t.Errorf("profile = %+v, want only the display name", profile)
+	}
+}
@@ -0,0 +1,235 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/audit"
+	"github.com/userblog/management/pkg/cache"
+	"github.com/userblog/management/pkg/logger"
+	"github.com/userblog/management/pkg/tenant"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// RoleService implements the IRoleService interface
+type RoleService struct {
+	roleRepo repository.IRoleRepository
+	audit    service.IAuditService
+	cache    *cache.Cache // effective permission sets by role ID
+}
+
+// NewRoleService creates a new role service
+func NewRoleService(roleRepo repository.IRoleRepository, auditService service.IAuditService, permissionCache *cache.Cache) service.IRoleService {
+	return &RoleService{
+		roleRepo: roleRepo,
+		audit:    auditService,
+		cache:    permissionCache,
+	}
+}
+
+// List returns every role with the permissions granted to it directly
+func (s *RoleService) List(ctx context.Context) ([]models.Role, error) {
+	ctx, span := tracing.Start(ctx, "RoleService.List")
+	defer span.End()
+
+	return s.roleRepo.List(ctx)
+}
+
+// Create creates a role granted the permissions, given as resource:action. A role created in an
+// organization the request named belongs to it.
+func (s *RoleService) Create(ctx context.Context, role *models.Role, permissions []string) error {
+	ctx, span := tracing.Start(ctx, "RoleService.Create")
+	defer span.End()
+
+	role.OrganizationID = tenant.NamedID(ctx)
+	existing, err := s.roleRepo.FindByName(ctx, role.Name)
+	if err == nil && existing.ID != 0 {
+		return service.NewConflictError("role name already exists")
+	}
+	if err := s.checkParent(ctx, role); err != nil {
+		return err
+	}
+
+	role.Permissions, err = s.resolvePermissions(ctx, permissions)
+	if err != nil {
+		return err
+	}
+	if err := s.roleRepo.Create(ctx, role); err != nil {
+		return err
+	}
+
+	changes := audit.Diff(nil, role)
+	changes["permissions"] = audit.Change{After: permissionKeys(role.Permissions)}
+	s.audit.Record(ctx, service.AuditRoleCreate, "role", role.ID, changes)
+	return nil
+}
+
+// Update changes a role's description, parent and permissions. Its name can't change, since
+// configuration refers to roles by name.
+func (s *RoleService) Update(ctx context.Context, role *models.Role, permissions []string) error {
+	ctx, span := tracing.Start(ctx, "RoleService.Update")
+	defer span.End()
+
+	existing, err := s.roleRepo.FindByID(ctx, role.ID)
+	if err != nil {
+		return notFound(err, "role")
+	}
+	role.OrganizationID = existing.OrganizationID
+	if err := s.checkParent(ctx, role); err != nil {
+		return err
+	}
+	granted, err := s.resolvePermissions(ctx, permissions)
+	if err != nil {
+		return err
+	}
+
+	before := *existing
+	existing.Description = role.Description
+	existing.ParentID = role.ParentID
+	err = s.roleRepo.Update(ctx, existing)
+	if err == nil {
+		err = s.roleRepo.ReplacePermissions(ctx, existing, granted)
+	}
+	// Roles inheriting from this one changed too, so every cached set is dropped, even when
+	// only part of the change was written
+	s.cache.Clear(ctx)
+	if err != nil {
+		return err
+	}
+	existing.Permissions = granted
+	*role = *existing
+
+	changes := audit.Diff(&before, existing)
+	beforeKeys, afterKeys := permissionKeys(before.Permissions), permissionKeys(granted)
+	if fmt.Sprint(beforeKeys) != fmt.Sprint(afterKeys) {
+		changes["permissions"] = audit.Change{Before: beforeKeys, After: afterKeys}
+	}
+	s.audit.Record(ctx, service.AuditRoleUpdate, "role", role.ID, changes)
+	return nil
+}
+
+// Effective returns the permissions of the role and all its ancestors. A cycle, which updates
+// refuse to create, ends the walk instead of looping. Results are cached until a role changes.
+func (s *RoleService) Effective(ctx context.Context, roleID uint) (*service.RolePermissions, error) {
+	ctx, span := tracing.Start(ctx, "RoleService.Effective")
+	defer span.End()
+
+	key := strconv.FormatUint(uint64(roleID), 10)
+	effective := &service.RolePermissions{}
+	if s.cache.Get(ctx, key, effective) {
+		return effective, nil
+	}
+
+	granted := make(map[string]bool)
+	visited := make(map[uint]bool)
+	for id := roleID; ; {
+		if visited[id] {
+			logger.WarnF(ctx, "Role hierarchy of role %d has a cycle at role %d", roleID, id)
+			break
+		}
+		visited[id] = true
+
+		role, err := s.roleRepo.FindByID(ctx, id)
+		if err != nil {
+			return nil, notFound(err, "role")
+		}
+		effective.Roles = append(effective.Roles, role.Name)
+		for _, permission := range role.Permissions {
+			if !granted[permission.Key()] {
+				granted[permission.Key()] = true
+				effective.Permissions = append(effective.Permissions, permission)
+			}
+		}
+
+		if role.ParentID == nil {
+			break
+		}
+		id = *role.ParentID
+	}
+
+	s.cache.Set(ctx, key, effective)
+	return effective, nil
+}
+
+// ListPermissions returns every permission that can be granted
+func (s *RoleService) ListPermissions(ctx context.Context) ([]models.Permission, error) {
+	ctx, span := tracing.Start(ctx, "RoleService.ListPermissions")
+	defer span.End()
+
+	return s.roleRepo.ListPermissions(ctx)
+}
+
+// checkParent verifies that the role's parent exists, is shared by every organization or belongs
+// to the role's, and that inheriting from it wouldn't create a cycle
+func (s *RoleService) checkParent(ctx context.Context, role *models.Role) error {
+	if role.ParentID != nil && *role.ParentID == 0 {
+		role.ParentID = nil
+	}
+	if role.ParentID == nil {
+		return nil
+	}
+
+	visited := make(map[uint]bool)
+	for id := *role.ParentID; ; {
+		if id == role.ID || visited[id] {
+			return service.NewValidationError("parent role would create a cycle in the role hierarchy")
+		}
+		visited[id] = true
+
+		parent, err := s.roleRepo.FindByID(ctx, id)
+		if err != nil {
+			if id == *role.ParentID {
+				return notFound(err, "parent role")
+			}
+			return err
+		}
+		if id == *role.ParentID && parent.OrganizationID != 0 && parent.OrganizationID != role.OrganizationID {
+			return service.NewValidationError("a role can only inherit from roles shared by every organization or of its own organization")
+		}
+		if parent.ParentID == nil {
+			return nil
+		}
+		id = *parent.ParentID
+	}
+}
+
+// resolvePermissions looks up permissions given as resource:action, such as blog:* or *:*
+func (s *RoleService) resolvePermissions(ctx context.Context, keys []string) ([]models.Permission, error) {
+	all, err := s.roleRepo.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]models.Permission, len(all))
+	for _, permission := range all {
+		byKey[permission.Key()] = permission
+	}
+
+	permissions := []models.Permission{}
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		permission, ok := byKey[key]
+		if !ok {
+			return nil, service.NewValidationError(fmt.Sprintf("unknown permission %q", key))
+		}
+		if !seen[key] {
+			seen[key] = true
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions, nil
+}
+
+// permissionKeys returns the permissions as sorted resource:action strings
+func permissionKeys(permissions []models.Permission) []string {
+	keys := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		keys = append(keys, permission.Key())
+	}
+	sort.Strings(keys)
+	return keys
+}
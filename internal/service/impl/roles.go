@@ -0,0 +1,121 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/audit"
+	"github.com/userblog/management/pkg/logger"
+)
+
+// assignRole gives the user the named role from an external source such as a directory group,
+// unless they already have it, and reloads the user with the new role
+func assignRole(ctx context.Context, userRepo repository.IUserRepository, roleRepo repository.IRoleRepository, principals service.IPrincipalService, auditService service.IAuditService, user *models.User, roleName, source string) error {
+	if roleName == "" || roleName == user.Role.Name {
+		return nil
+	}
+
+	role, err := roleRepo.FindByName(ctx, roleName)
+	if err != nil {
+		return fmt.Errorf("role %q configured for %s: %w", roleName, source, err)
+	}
+	if err := userRepo.UpdateRole(ctx, user.ID, role.ID); err != nil {
+		return err
+	}
+	principals.Invalidate(ctx, user.ID)
+	logger.InfoF(ctx, "Changed role of user %s to %s from %s", user.Username, role.Name, source)
+	auditService.Record(ctx, service.AuditUserRoleChange, "user", user.ID, map[string]audit.Change{
+		"role_id": {Before: user.RoleID, After: role.ID},
+	})
+
+	updated, err := userRepo.FindByID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	*user = *updated
+	return nil
+}
+
+// checkPlatformRole verifies that a role given to a user directly, rather than through a
+// membership, exists and is shared by every organization, since it applies in all of them
+func checkPlatformRole(ctx context.Context, roleRepo repository.IRoleRepository, roleID uint) error {
+	role, err := roleRepo.FindByID(ctx, roleID)
+	if err != nil {
+		return notFound(err, "role")
+	}
+	if role.OrganizationID != 0 {
+		return service.NewValidationError("role belongs to an organization and can only be given by its memberships")
+	}
+	return nil
+}
+
+// checkGrantable verifies that the actor holds every permission the role grants, as narrowed by
+// filter, so users can't hand out more access than they have
+func checkGrantable(ctx context.Context, roles service.IRoleService, actor *models.User, roleID uint, filter func([]models.Permission) []models.Permission) error {
+	missing, err := missingPermission(ctx, roles, actor, roleID, filter)
+	if err != nil {
+		return err
+	}
+	if missing != "" {
+		return service.NewForbiddenError(fmt.Sprintf("you can't grant a role with the %s permission you don't have", missing))
+	}
+	return nil
+}
+
+// missingPermission returns the first permission of the role, as narrowed by filter, that the
+// actor doesn't hold, or "" when the actor holds them all. A nil filter checks every permission,
+// and organizationPermissions only those that apply within an organization.
+func missingPermission(ctx context.Context, roles service.IRoleService, actor *models.User, roleID uint, filter func([]models.Permission) []models.Permission) (string, error) {
+	effective, err := roles.Effective(ctx, roleID)
+	if err != nil {
+		return "", err
+	}
+	permissions := effective.Permissions
+	if filter != nil {
+		permissions = filter(permissions)
+	}
+	for _, permission := range permissions {
+		if !hasPermission(actor, permission.Resource, permission.Action) {
+			return permission.Key(), nil
+		}
+	}
+	return "", nil
+}
+
+// hasPermission reports whether the user's role grants the permission, directly or through a
+// wildcard such as blog:* or *:*. Load the user with IPrincipalService to include inherited ones.
+func hasPermission(user *models.User, resource, action string) bool {
+	return user != nil && user.Role.Grants(resource, action)
+}
+
+// organizationPermissions returns the permissions that apply within an organization: those on
+// organization resources, with a wildcard resource narrowed to each of them
+func organizationPermissions(permissions []models.Permission) []models.Permission {
+	var scoped []models.Permission
+	for _, permission := range permissions {
+		for _, resource := range models.OrganizationResources {
+			if permission.Resource == resource || permission.Resource == models.PermissionWildcard {
+				narrowed := permission
+				narrowed.Resource = resource
+				scoped = append(scoped, narrowed)
+			}
+		}
+	}
+	return scoped
+}
+
+// mergePermissions returns the permissions of both lists, without duplicates
+func mergePermissions(permissions, more []models.Permission) []models.Permission {
+	seen := make(map[string]bool, len(permissions)+len(more))
+	merged := make([]models.Permission, 0, len(permissions)+len(more))
+	for _, permission := range append(append([]models.Permission{}, permissions...), more...) {
+		if !seen[permission.Key()] {
+			seen[permission.Key()] = true
+			merged = append(merged, permission)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,152 @@
+package impl
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/logger"
+	"github.com/userblog/management/pkg/tenant"
+	"github.com/userblog/management/pkg/tracing"
+	"github.com/userblog/management/pkg/useragent"
+)
+
+// sessionTouchInterval limits how often a session's last seen time is written, so requests
+// don't each cost an update
+const sessionTouchInterval = time.Minute
+
+// SessionService implements the ISessionService interface
+type SessionService struct {
+	sessionRepo repository.ISessionRepository
+	audit       service.IAuditService
+}
+
+// NewSessionService creates a new session service
+func NewSessionService(sessionRepo repository.ISessionRepository, auditService service.IAuditService) service.ISessionService {
+	return &SessionService{
+		sessionRepo: sessionRepo,
+		audit:       auditService,
+	}
+}
+
+// Start records a new session for the user, described by the client IP and user agent of the
+// request, that lasts as long as the token issued for it
+func (s *SessionService) Start(ctx context.Context, user *models.User) (*models.Session, error) {
+	ctx, span := tracing.Start(ctx, "SessionService.Start")
+	defer span.End()
+
+	userAgent, _ := ctx.Value(logger.UserAgentKey).(string)
+	return s.start(ctx, &models.Session{
+		UserID: user.ID,
+		Device: useragent.DeviceName(userAgent),
+	}, config.Current().JWT.Expiry())
+}
+
+// StartImpersonation records a short session in which the actor acts as the user
+func (s *SessionService) StartImpersonation(ctx context.Context, actor, user *models.User) (*models.Session, error) {
+	ctx, span := tracing.Start(ctx, "SessionService.StartImpersonation")
+	defer span.End()
+
+	return s.start(ctx, &models.Session{
+		UserID:  user.ID,
+		ActorID: actor.ID,
+		Device:  "Support session by " + actor.Username,
+	}, config.Current().JWT.ImpersonationExpiry)
+}
+
+// start fills in the request details of a session and stores it
+func (s *SessionService) start(ctx context.Context, session *models.Session, lifetime time.Duration) (*models.Session, error) {
+	now := time.Now()
+	if err := s.sessionRepo.DeleteExpired(ctx, session.UserID, now); err != nil {
+		return nil, err
+	}
+
+	session.IP, _ = ctx.Value(logger.ClientIpKey).(string)
+	session.UserAgent, _ = ctx.Value(logger.UserAgentKey).(string)
+	if len(session.UserAgent) > 512 {
+		session.UserAgent = session.UserAgent[:512]
+	}
+	session.OrganizationID = tenant.NamedID(ctx)
+	session.LastSeenAt = now
+	session.ExpiresAt = now.Add(lifetime)
+
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Check returns the user's session named by a token, failing when it has been terminated,
+// and records that it was used
+func (s *SessionService) Check(ctx context.Context, userID uint, sessionID string) (*models.Session, error) {
+	ctx, span := tracing.Start(ctx, "SessionService.Check")
+	defer span.End()
+
+	id, err := strconv.ParseUint(sessionID, 10, 64)
+	if err != nil {
+		return nil, service.NewUnauthorizedError("invalid token session")
+	}
+
+	session, err := s.sessionRepo.FindByID(ctx, uint(id))
+	if gorm.IsRecordNotFoundError(err) || (err == nil && session.UserID != userID) {
+		return nil, service.NewUnauthorizedError("session has been terminated")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		if err := s.sessionRepo.Touch(ctx, session.ID, now); err != nil {
+			return nil, err
+		}
+		session.LastSeenAt = now
+	}
+	return session, nil
+}
+
+// List returns the user's active sessions, most recently used first
+func (s *SessionService) List(ctx context.Context, userID uint) ([]models.Session, error) {
+	ctx, span := tracing.Start(ctx, "SessionService.List")
+	defer span.End()
+
+	return s.sessionRepo.ListActiveByUser(ctx, userID, time.Now())
+}
+
+// Revoke terminates one of the user's sessions, logging out the device it belongs to
+func (s *SessionService) Revoke(ctx context.Context, userID, sessionID uint) error {
+	ctx, span := tracing.Start(ctx, "SessionService.Revoke")
+	defer span.End()
+
+	deleted, err := s.sessionRepo.Delete(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return service.NewNotFoundError("session")
+	}
+
+	logger.InfoF(ctx, "Session %d of user %d terminated", sessionID, userID)
+	s.audit.Record(ctx, service.AuditLogout, "session", sessionID, nil)
+	return nil
+}
+
+// RevokeOthers terminates all of the user's sessions except the current one and returns how many
+func (s *SessionService) RevokeOthers(ctx context.Context, userID, currentID uint) (int64, error) {
+	ctx, span := tracing.Start(ctx, "SessionService.RevokeOthers")
+	defer span.End()
+
+	deleted, err := s.sessionRepo.DeleteOthers(ctx, userID, currentID)
+	if err != nil {
+		return 0, err
+	}
+
+	logger.InfoF(ctx, "%d other sessions of user %d terminated", deleted, userID)
+	s.audit.Record(ctx, service.AuditLogoutOthers, "user", userID, nil)
+	return deleted, nil
+}
@@ -0,0 +1,88 @@
+package impl
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/userblog/management/internal/service"
+)
+
+func TestRevokedSessionLogsOutItsTokens(t *testing.T) {
+	f := newAuthFixture(t)
+	ctx := context.Background()
+
+	revoked, session := f.login(t, f.alice)
+	kept, _ := f.login(t, f.alice)
+	if _, _, err := f.auth.ValidateToken(ctx, revoked); err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+
+	if err := f.sessions.Revoke(ctx, f.alice.ID, session.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	_, _, err := f.auth.ValidateToken(ctx, revoked)
+	assertErrorType[*service.UnauthorizedError](t, err)
+	if _, _, err := f.auth.ValidateToken(ctx, kept); err != nil {
+		t.Errorf("a token for another session: %v", err)
+	}
+
+	err = f.sessions.Revoke(ctx, f.alice.ID, session.ID)
+	assertErrorType[*service.NotFoundError](t, err)
+}
+
+func TestRevokeOthersKeepsTheCurrentSession(t *testing.T) {
+	f := newAuthFixture(t)
+	ctx := context.Background()
+
+	current, session := f.login(t, f.alice)
+	others := []string{}
+	for i := 0; i < 2; i++ {
+		other, _ := f.login(t, f.alice)
+		others = append(others, other)
+	}
+	admin, _ := f.login(t, f.admin)
+
+	deleted, err := f.sessions.RevokeOthers(ctx, f.alice.ID, session.ID)
+	if err != nil {
+		t.Fatalf("RevokeOthers: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("RevokeOthers deleted %d sessions, want 2", deleted)
+	}
+
+	sessions, err := f.sessions.List(ctx, f.alice.ID)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != session.ID {
+		t.Errorf("sessions = %+v, want only the current one", sessions)
+	}
+	if _, _, err := f.auth.ValidateToken(ctx, current); err != nil {
+		t.Errorf("the current session's token: %v", err)
+	}
+	for _, other := range others {
+		_, _, err := f.auth.ValidateToken(ctx, other)
+		assertErrorType[*service.UnauthorizedError](t, err)
+	}
+	if _, _, err := f.auth.ValidateToken(ctx, admin); err != nil {
+		t.Errorf("another user's session was revoked: %v", err)
+	}
+}
+
+func TestSessionsBelongToTheirUser(t *testing.T) {
+	f := newAuthFixture(t)
+	ctx := context.Background()
+
+	admin, session := f.login(t, f.admin)
+
+	err := f.sessions.Revoke(ctx, f.alice.ID, session.ID)
+	assertErrorType[*service.NotFoundError](t, err)
+	if _, _, err := f.auth.ValidateToken(ctx, admin); err != nil {
+		t.Errorf("the session was revoked by another user: %v", err)
+	}
+
+	// A token can't name a session of another user either
+	_, err = f.sessions.Check(ctx, f.alice.ID, strconv.FormatUint(uint64(session.ID), 10))
+	assertErrorType[*service.UnauthorizedError](t, err)
+}
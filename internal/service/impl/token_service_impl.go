@@ -0,0 +1,287 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/logger"
+	"github.com/userblog/management/pkg/tenant"
+	"github.com/userblog/management/pkg/token"
+	"github.com/userblog/management/pkg/tracing"
+)
+
+// unknownKeyReloadInterval limits how often a token with an unknown kid reloads the keys,
+// which are created by other instances when they rotate first
+const unknownKeyReloadInterval = 10 * time.Second
+
+// TokenService implements the ITokenService interface. Keys are shared by all instances
+// through the database and cached in memory.
+type TokenService struct {
+	keyRepo repository.ISigningKeyRepository
+
+	mu       sync.RWMutex
+	keys     map[string]*token.Key
+	signing  *token.Key
+	loadedAt time.Time
+}
+
+// NewTokenService creates a new token service; call Refresh to load the keys before use
+func NewTokenService(keyRepo repository.ISigningKeyRepository) service.ITokenService {
+	return &TokenService{
+		keyRepo: keyRepo,
+		keys:    map[string]*token.Key{},
+	}
+}
+
+// Issue signs an access token for a user's session, expiring with the session
+func (s *TokenService) Issue(ctx context.Context, user *models.User, session *models.Session) (string, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.Issue")
+	defer span.End()
+
+	cfg := config.Current().JWT
+	id, err := randomID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := service.TokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        id,
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			Issuer:    cfg.Issuer,
+			Audience:  cfg.Audience,
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: session.ExpiresAt.Unix(),
+		},
+		SessionID: strconv.FormatUint(uint64(session.ID), 10),
+		Username:  user.Username,
+		RoleID:    user.RoleID,
+	}
+	if session.ActorID != 0 {
+		claims.Actor = &service.TokenActor{Subject: strconv.FormatUint(uint64(session.ActorID), 10)}
+	}
+	if org, ok := tenant.FromContext(ctx); ok && org.Explicit() {
+		claims.Organization = org.ID
+	}
+
+	if cfg.Algorithm == token.HS256 {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(cfg.Secret))
+	}
+
+	s.mu.RLock()
+	key := s.signing
+	s.mu.RUnlock()
+	if key == nil {
+		return "", errors.New("no token signing key is loaded")
+	}
+
+	signed := jwt.NewWithClaims(key.Method(), claims)
+	signed.Header["kid"] = key.ID
+	return signed.SignedString(key.PrivateKey)
+}
+
+// Verify checks the signature with the key named by the kid header, the time based claims,
+// the issuer and the audience, returning the claims of a valid token
+func (s *TokenService) Verify(ctx context.Context, tokenString string) (*service.TokenClaims, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.Verify")
+	defer span.End()
+
+	cfg := config.Current().JWT
+	claims := &service.TokenClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		if cfg.Algorithm == token.HS256 {
+			if t.Method != jwt.SigningMethodHS256 {
+				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+			}
+			return []byte(cfg.Secret), nil
+		}
+
+		kid, _ := t.Header["kid"].(string)
+		key := s.lookup(ctx, kid)
+		if key == nil {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		// The key decides the algorithm, so a token can't pick a weaker one
+		if t.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return key.Public(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !claims.VerifyIssuer(cfg.Issuer, true) {
+		return nil, jwt.NewValidationError("token has an unexpected issuer", jwt.ValidationErrorIssuer)
+	}
+	if !claims.VerifyAudience(cfg.Audience, true) {
+		return nil, jwt.NewValidationError("token has an unexpected audience", jwt.ValidationErrorAudience)
+	}
+	return claims, nil
+}
+
+// JWKS returns the public keys that can verify current tokens
+func (s *TokenService) JWKS(ctx context.Context) *token.JWKS {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set := &token.JWKS{Keys: []*token.JSONWebKey{}}
+	now := time.Now()
+	for _, key := range s.keys {
+		if key.ExpiresAt.After(now) {
+			set.Keys = append(set.Keys, key.JWK())
+		}
+	}
+	return set
+}
+
+// Refresh loads the keys, generates a new signing key when none exists for the configured
+// algorithm or the current one is due for rotation, and deletes expired keys
+func (s *TokenService) Refresh(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "TokenService.Refresh")
+	defer span.End()
+
+	cfg := config.Current().JWT
+	if cfg.Algorithm == token.HS256 {
+		return nil
+	}
+
+	if err := s.load(ctx); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	signing := s.signing
+	s.mu.RUnlock()
+
+	now := time.Now()
+	if signing == nil || !signing.CreatedAt.Add(cfg.RotationInterval).After(now) {
+		if err := s.rotate(ctx, cfg); err != nil {
+			return err
+		}
+	}
+
+	return s.keyRepo.DeleteExpired(ctx, now)
+}
+
+// RunRotation refreshes the keys every interval until ctx is done
+func (s *TokenService) RunRotation(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil {
+				logger.ErrorF(ctx, "Failed to refresh token signing keys: %v", err)
+			}
+		}
+	}
+}
+
+// rotate generates and stores a new signing key, which signs every token issued from now on
+func (s *TokenService) rotate(ctx context.Context, cfg config.JWTConfig) error {
+	key, err := token.Generate(cfg.Algorithm)
+	if err != nil {
+		return err
+	}
+	encoded, err := key.Encode()
+	if err != nil {
+		return err
+	}
+	if cfg.KeyEncryptionKey != "" {
+		if encoded, err = token.Seal(encoded, cfg.KeyEncryptionKey); err != nil {
+			return err
+		}
+	}
+
+	record := &models.SigningKey{
+		KID:        key.ID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: encoded,
+		ExpiresAt:  time.Now().Add(cfg.KeyLifetime()),
+	}
+	if err := s.keyRepo.Create(ctx, record); err != nil {
+		return err
+	}
+	logger.InfoF(ctx, "Generated %s token signing key %s", key.Algorithm, key.ID)
+
+	return s.load(ctx)
+}
+
+// load replaces the cached keys with the unexpired keys in the database, decrypting them with the
+// key encryption key. The newest key of the configured algorithm signs, so instances converge on
+// the key whichever of them rotated.
+func (s *TokenService) load(ctx context.Context) error {
+	records, err := s.keyRepo.ListUnexpired(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	cfg := config.Current().JWT
+	keys := make(map[string]*token.Key, len(records))
+	var signing *token.Key
+	for _, record := range records {
+		encoded, err := token.Open(record.PrivateKey, cfg.KeyEncryptionKey)
+		if err != nil {
+			logger.ErrorF(ctx, "Skipping unreadable token signing key %s: %v", record.KID, err)
+			continue
+		}
+		key, err := token.Parse(record.Algorithm, encoded)
+		if err != nil {
+			logger.ErrorF(ctx, "Skipping unreadable token signing key %s: %v", record.KID, err)
+			continue
+		}
+		key.CreatedAt = record.CreatedAt
+		key.ExpiresAt = record.ExpiresAt
+		keys[key.ID] = key
+
+		if key.Algorithm == cfg.Algorithm && (signing == nil || key.CreatedAt.After(signing.CreatedAt)) {
+			signing = key
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+	s.signing = signing
+	s.loadedAt = time.Now()
+	return nil
+}
+
+// lookup returns the unexpired key with the given kid, reloading the keys at most once per
+// unknownKeyReloadInterval when it isn't cached
+func (s *TokenService) lookup(ctx context.Context, kid string) *token.Key {
+	s.mu.RLock()
+	key, ok := s.keys[kid]
+	stale := time.Since(s.loadedAt) > unknownKeyReloadInterval
+	s.mu.RUnlock()
+
+	if !ok && kid != "" && stale {
+		if err := s.load(ctx); err != nil {
+			logger.ErrorF(ctx, "Failed to reload token signing keys: %v", err)
+			return nil
+		}
+		s.mu.RLock()
+		key, ok = s.keys[kid]
+		s.mu.RUnlock()
+	}
+
+	if !ok || !key.ExpiresAt.After(time.Now()) {
+		return nil
+	}
+	return key
+}
@@ -0,0 +1,268 @@
+package impl
+
+import (
+	"context"
+	"crypto/x509"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/jinzhu/gorm"
+	"github.com/userblog/management/internal/models"
+	repoImpl "github.com/userblog/management/internal/repository/impl"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/token"
+)
+
+// newTokenService returns a token service signing with algorithm and its loaded keys, stored
+// encrypted in the returned database
+func newTokenService(t *testing.T, algorithm string) (*gorm.DB, *TokenService) {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.JWT.Algorithm = algorithm
+	cfg.JWT.Secret = "a-test-secret-long-enough-for-hs256"
+	cfg.JWT.KeyEncryptionKey = "a-test-key-encryption-key"
+	setConfig(t, cfg)
+
+	db := newTestDB(t)
+	tokens := NewTokenService(repoImpl.NewSigningKeyRepository(db)).(*TokenService)
+	if err := tokens.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	return db, tokens
+}
+
+// issueToken signs a token for alice's session 1
+func issueToken(t *testing.T, tokens service.ITokenService) string {
+	t.Helper()
+
+	user := &models.User{Model: gorm.Model{ID: 2}, Username: "alice", RoleID: 2}
+	session := &models.Session{Model: gorm.Model{ID: 1}, UserID: 2, ExpiresAt: time.Now().Add(time.Hour)}
+	signed, err := tokens.Issue(context.Background(), user, session)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	return signed
+}
+
+// tokenKID returns the kid header of a signed token
+func tokenKID(t *testing.T, signed string) string {
+	t.Helper()
+
+	parsed, _, err := new(jwt.Parser).ParseUnverified(signed, &service.TokenClaims{})
+	if err != nil {
+		t.Fatalf("parsing token: %v", err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+// jwksKIDs returns the kids the JWKS publishes
+func jwksKIDs(tokens service.ITokenService) []string {
+	var kids []string
+	for _, key := range tokens.JWKS(context.Background()).Keys {
+		kids = append(kids, key.KeyID)
+	}
+	return kids
+}
+
+func TestRotatedKeysVerifyUntilTheGracePeriodEnds(t *testing.T) {
+	for _, algorithm := range []string{token.RS256, token.EdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			db, tokens := newTokenService(t, algorithm)
+			ctx := context.Background()
+
+			previous := issueToken(t, tokens)
+			previousKID := tokenKID(t, previous)
+
+			// The key is due for rotation once it is older than the rotation interval
+			db.Model(&models.SigningKey{}).UpdateColumn("created_at", time.Now().Add(-31*24*time.Hour))
+			if err := tokens.Refresh(ctx); err != nil {
+				t.Fatalf("Refresh: %v", err)
+			}
+			current := issueToken(t, tokens)
+			if tokenKID(t, current) == previousKID {
+				t.Fatal("the key wasn't rotated")
+			}
+
+			if _, err := tokens.Verify(ctx, previous); err != nil {
+				t.Errorf("a token signed by the previous key during its grace period: %v", err)
+			}
+			if kids := jwksKIDs(tokens); len(kids) != 2 {
+				t.Errorf("JWKS = %v, want the current and the previous key", kids)
+			}
+
+			// The grace period ends
+			db.Model(&models.SigningKey{}).Where(&models.SigningKey{KID: previousKID}).UpdateColumn("expires_at", time.Now().Add(-time.Second))
+			if err := tokens.Refresh(ctx); err != nil {
+				t.Fatalf("Refresh: %v", err)
+			}
+
+			if _, err := tokens.Verify(ctx, previous); err == nil {
+				t.Error("a token signed by an expired key verified")
+			}
+			if _, err := tokens.Verify(ctx, current); err != nil {
+				t.Errorf("a token signed by the current key: %v", err)
+			}
+			if kids := jwksKIDs(tokens); len(kids) != 1 || kids[0] != tokenKID(t, current) {
+				t.Errorf("JWKS = %v, want only the current key", kids)
+			}
+			var count int
+			db.Model(&models.SigningKey{}).Count(&count)
+			if count != 1 {
+				t.Errorf("%d keys stored, want the expired key deleted", count)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsTokens(t *testing.T) {
+	cfg := config.Default().JWT
+	claims := func() service.TokenClaims {
+		return service.TokenClaims{
+			StandardClaims: jwt.StandardClaims{
+				Subject:   "2",
+				Issuer:    cfg.Issuer,
+				Audience:  cfg.Audience,
+				IssuedAt:  time.Now().Unix(),
+				ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			},
+			SessionID: "1",
+		}
+	}
+	sign := func(t *testing.T, key *token.Key, kid string, claims service.TokenClaims) string {
+		signed := jwt.NewWithClaims(key.Method(), claims)
+		signed.Header["kid"] = kid
+		s, err := signed.SignedString(key.PrivateKey)
+		if err != nil {
+			t.Fatalf("signing: %v", err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name  string
+		token func(t *testing.T, key *token.Key) string
+	}{
+		{
+			name: "unknown kid",
+			token: func(t *testing.T, key *token.Key) string {
+				other, err := token.Generate(key.Algorithm)
+				if err != nil {
+					t.Fatalf("Generate: %v", err)
+				}
+				return sign(t, other, other.ID, claims())
+			},
+		},
+		{
+			name: "HS256 with an asymmetric kid",
+			token: func(t *testing.T, key *token.Key) string {
+				// The published public key used as an HMAC secret
+				public, err := x509.MarshalPKIXPublicKey(key.Public())
+				if err != nil {
+					t.Fatalf("marshalling public key: %v", err)
+				}
+				signed := jwt.NewWithClaims(jwt.SigningMethodHS256, claims())
+				signed.Header["kid"] = key.ID
+				s, err := signed.SignedString(public)
+				if err != nil {
+					t.Fatalf("signing: %v", err)
+				}
+				return s
+			},
+		},
+		{
+			name: "wrong issuer",
+			token: func(t *testing.T, key *token.Key) string {
+				c := claims()
+				c.Issuer = "someone-else"
+				return sign(t, key, key.ID, c)
+			},
+		},
+		{
+			name: "wrong audience",
+			token: func(t *testing.T, key *token.Key) string {
+				c := claims()
+				c.Audience = "another-service"
+				return sign(t, key, key.ID, c)
+			},
+		},
+		{
+			name: "expired",
+			token: func(t *testing.T, key *token.Key) string {
+				c := claims()
+				c.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+				return sign(t, key, key.ID, c)
+			},
+		},
+	}
+
+	for _, algorithm := range []string{token.RS256, token.EdDSA} {
+		for _, tt := range tests {
+			t.Run(algorithm+"/"+tt.name, func(t *testing.T) {
+				_, tokens := newTokenService(t, algorithm)
+
+				if _, err := tokens.Verify(context.Background(), sign(t, tokens.signing, tokens.signing.ID, claims())); err != nil {
+					t.Fatalf("a valid token: %v", err)
+				}
+				if _, err := tokens.Verify(context.Background(), tt.token(t, tokens.signing)); err == nil {
+					t.Error("the token verified")
+				}
+			})
+		}
+	}
+}
+
+func TestHS256RejectsAsymmetricTokens(t *testing.T) {
+	_, rsaTokens := newTokenService(t, token.RS256)
+	signed := issueToken(t, rsaTokens)
+
+	_, tokens := newTokenService(t, token.HS256)
+	if _, err := tokens.Verify(context.Background(), signed); err == nil {
+		t.Error("an RS256 token verified with HS256 configured")
+	}
+	if _, err := tokens.Verify(context.Background(), issueToken(t, tokens)); err != nil {
+		t.Errorf("an HS256 token: %v", err)
+	}
+	if kids := jwksKIDs(tokens); len(kids) != 0 {
+		t.Errorf("JWKS = %v, want no keys published for HS256", kids)
+	}
+}
+
+func TestSigningKeysAreStoredEncrypted(t *testing.T) {
+	db, tokens := newTokenService(t, token.RS256)
+	signed := issueToken(t, tokens)
+
+	var record models.SigningKey
+	db.First(&record)
+	if !strings.HasPrefix(record.PrivateKey, "sealed:v1:") || strings.Contains(record.PrivateKey, "PRIVATE KEY") {
+		t.Fatalf("stored key = %.40q..., want it encrypted", record.PrivateKey)
+	}
+
+	// Another instance with the same key encryption key reads the stored key
+	other := NewTokenService(repoImpl.NewSigningKeyRepository(db))
+	if err := other.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if _, err := other.Verify(context.Background(), signed); err != nil {
+		t.Errorf("verifying with the stored key: %v", err)
+	}
+
+	// With another key encryption key the stored key is unreadable, and a new one is generated
+	cfg := *config.Current()
+	cfg.JWT.KeyEncryptionKey = "another-key-encryption-key"
+	config.Set(&cfg)
+	other = NewTokenService(repoImpl.NewSigningKeyRepository(db))
+	if err := other.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if _, err := other.Verify(context.Background(), signed); err == nil {
+		t.Error("a key sealed with another key encryption key verified")
+	}
+	if tokenKID(t, issueToken(t, other)) == tokenKID(t, signed) {
+		t.Error("no new key was generated")
+	}
+}
@@ -1,72 +1,117 @@
 package impl
 
 import (
-	"errors"
-
+	"context"
+	"github.com/userblog/management/api/validation"
 	"github.com/userblog/management/internal/models"
 	"github.com/userblog/management/internal/repository"
 	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/audit"
+	"github.com/userblog/management/pkg/lockout"
+	"github.com/userblog/management/pkg/tracing"
 )
 
 // UserService implements the IUserService interface
 type UserService struct {
-	userRepo repository.IUserRepository
+	userRepo   repository.IUserRepository
+	roleRepo   repository.IRoleRepository
+	attempts   lockout.Store
+	roles      service.IRoleService
+	principals service.IPrincipalService
+	audit      service.IAuditService
 }
 
 // NewUserService creates a new user service
-func NewUserService(userRepo repository.IUserRepository) service.IUserService {
+func NewUserService(userRepo repository.IUserRepository, roleRepo repository.IRoleRepository, attempts lockout.Store, roles service.IRoleService, principals service.IPrincipalService, auditService service.IAuditService) service.IUserService {
 	return &UserService{
-		userRepo: userRepo,
+		userRepo:   userRepo,
+		roleRepo:   roleRepo,
+		attempts:   attempts,
+		roles:      roles,
+		principals: principals,
+		audit:      auditService,
 	}
 }
 
 // Create creates a new user
-func (s *UserService) Create(user *models.User) error {
+func (s *UserService) Create(ctx context.Context, user *models.User) error {
+	ctx, span := tracing.Start(ctx, "UserService.Create")
+	defer span.End()
+
 	// Check if username already exists
-	existingUser, err := s.userRepo.FindByUsername(user.Username)
+	existingUser, err := s.userRepo.FindByUsername(ctx, user.Username)
 	if err == nil && existingUser.ID != 0 {
-		return errors.New("username already exists")
+		return service.NewConflictError("username already exists")
 	}
 
 	// Check if email already exists
-	existingUser, err = s.userRepo.FindByEmail(user.Email)
+	existingUser, err = s.userRepo.FindByEmail(ctx, user.Email)
 	if err == nil && existingUser.ID != 0 {
-		return errors.New("email already exists")
+		return service.NewConflictError("email already exists")
+	}
+
+	if user.RoleID != 0 {
+		if err := checkPlatformRole(ctx, s.roleRepo, user.RoleID); err != nil {
+			return err
+		}
 	}
 
-	return s.userRepo.Create(user)
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, service.AuditUserCreate, "user", user.ID, audit.Diff(nil, user))
+	return nil
 }
 
 // GetByID returns a user by ID
-func (s *UserService) GetByID(id uint) (*models.User, error) {
-	return s.userRepo.FindByID(id)
+func (s *UserService) GetByID(ctx context.Context, id uint, includes []string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetByID")
+	defer span.End()
+
+	user, err := s.userRepo.FindByIDWithIncludes(ctx, id, includes)
+	if err != nil {
+		return nil, notFound(err, "user")
+	}
+	return user, nil
 }
 
 // Update updates a user
-func (s *UserService) Update(user *models.User) error {
-	// Get the existing user
-	existingUser, err := s.userRepo.FindByID(user.ID)
+func (s *UserService) Update(ctx context.Context, user *models.User) error {
+	ctx, span := tracing.Start(ctx, "UserService.Update")
+	defer span.End()
+
+	// Get the existing user, who must be visible in the organization the request acts in
+	existingUser, err := s.userRepo.FindByIDWithIncludes(ctx, user.ID, []string{"role"})
 	if err != nil {
-		return err
+		return notFound(err, "user")
 	}
 
-	// Check if username is being changed and if it already exists
+	// Check if username is being changed to a reserved one or one that already exists
 	if user.Username != existingUser.Username {
-		newUser, err := s.userRepo.FindByUsername(user.Username)
+		if validation.ReservedUsername(user.Username) {
+			return service.NewValidationError("username is reserved", service.FieldError{
+				Field:   "username",
+				Code:    "notreserved",
+				Message: "username is reserved and can't be used",
+			})
+		}
+		newUser, err := s.userRepo.FindByUsername(ctx, user.Username)
 		if err == nil && newUser.ID != 0 && newUser.ID != user.ID {
-			return errors.New("username already exists")
+			return service.NewConflictError("username already exists")
 		}
 	}
 
 	// Check if email is being changed and if it already exists
 	if user.Email != existingUser.Email {
-		newUser, err := s.userRepo.FindByEmail(user.Email)
+		newUser, err := s.userRepo.FindByEmail(ctx, user.Email)
 		if err == nil && newUser.ID != 0 && newUser.ID != user.ID {
-			return errors.New("email already exists")
+			return service.NewConflictError("email already exists")
 		}
 	}
 
 	// Update only allowed fields
+	before := *existingUser
 	existingUser.Username = user.Username
 	existingUser.Email = user.Email
 	existingUser.FirstName = user.FirstName
@@ -74,24 +119,83 @@ func (s *UserService) Update(user *models.User) error {
 
 	// Only update password if provided
 	if user.Password != "" {
-		existingUser.Password = user.Password
+		if err := existingUser.SetPassword(user.Password); err != nil {
+			return err
+		}
 	}
 
 	// Only admin can change roles
-	if user.RoleID != 0 {
+	if user.RoleID != 0 && user.RoleID != existingUser.RoleID {
+		if err := checkPlatformRole(ctx, s.roleRepo, user.RoleID); err != nil {
+			return err
+		}
 		existingUser.RoleID = user.RoleID
 	}
 
-	return s.userRepo.Update(existingUser)
+	changes := audit.Diff(&before, existingUser)
+	if err := s.userRepo.Update(ctx, existingUser); err != nil {
+		return err
+	}
+	s.principals.Invalidate(ctx, existingUser.ID)
+
+	s.audit.Record(ctx, service.AuditUserUpdate, "user", user.ID, changes)
+	return nil
 }
 
 // Delete deletes a user
-func (s *UserService) Delete(id uint) error {
-	return s.userRepo.Delete(id)
+func (s *UserService) Delete(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "UserService.Delete")
+	defer span.End()
+
+	existingUser, err := s.userRepo.FindByIDWithIncludes(ctx, id, []string{"role"})
+	if err != nil {
+		return notFound(err, "user")
+	}
+
+	if err := s.userRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.principals.Invalidate(ctx, id)
+
+	s.audit.Record(ctx, service.AuditUserDelete, "user", id, audit.Diff(existingUser, nil))
+	return nil
 }
 
 // List returns a list of users with pagination
-func (s *UserService) List(page, perPage int) ([]models.User, int, error) {
+func (s *UserService) List(ctx context.Context, page, perPage int, includes []string) ([]models.User, int, error) {
+	ctx, span := tracing.Start(ctx, "UserService.List")
+	defer span.End()
+
 	offset := (page - 1) * perPage
-	return s.userRepo.List(offset, perPage)
+	return s.userRepo.List(ctx, offset, perPage, includes)
+}
+
+// Unlock clears the failed login attempts and any lockout of a user's account
+func (s *UserService) Unlock(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "UserService.Unlock")
+	defer span.End()
+
+	user, err := s.userRepo.FindByIDWithIncludes(ctx, id, nil)
+	if err != nil {
+		return notFound(err, "user")
+	}
+
+	if err := s.attempts.Reset(ctx, accountLockoutKey(user.Username)); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, service.AuditUserUnlock, "user", id, nil)
+	return nil
+}
+
+// Permissions returns the user's effective permissions, including those their role inherits
+func (s *UserService) Permissions(ctx context.Context, id uint) (*service.RolePermissions, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Permissions")
+	defer span.End()
+
+	user, err := s.userRepo.FindByIDWithIncludes(ctx, id, nil)
+	if err != nil {
+		return nil, notFound(err, "user")
+	}
+	return s.roles.Effective(ctx, user.RoleID)
 }
@@ -0,0 +1,90 @@
+package impl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/userblog/management/internal/models"
+	repoImpl "github.com/userblog/management/internal/repository/impl"
+	"github.com/userblog/management/internal/service"
+	"github.com/userblog/management/pkg/cache"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/lockout"
+)
+
+// newUserService returns a user service on a database holding the seeded admin and alice
+func newUserService(t *testing.T) (service.IUserService, *models.User, *models.User) {
+	t.Helper()
+
+	db := newTestDB(t)
+	setConfig(t, config.Default())
+
+	userRepo := repoImpl.NewUserRepository(db)
+	roleRepo := repoImpl.NewRoleRepository(db)
+	auditService := NewAuditService(repoImpl.NewAuditEventRepository(db))
+	cacheStore := cache.NewMemoryStore(100)
+	roleService := NewRoleService(roleRepo, auditService, cache.New("roles", cacheStore))
+	principals := NewPrincipalService(userRepo, repoImpl.NewOrganizationRepository(db), roleService, cache.New("principals", cacheStore))
+
+	admin := &models.User{Username: "admin", Email: "admin@example.com", RoleID: 1}
+	alice := &models.User{Username: "alice", Email: "alice@example.com", RoleID: 2}
+	for _, user := range []*models.User{admin, alice} {
+		if err := userRepo.Create(context.Background(), user); err != nil {
+			t.Fatalf("creating %s: %v", user.Username, err)
+		}
+	}
+	return NewUserService(userRepo, roleRepo, lockout.NewMemoryStore(), roleService, principals, auditService), admin, alice
+}
+
+func TestUpdateReservedUsername(t *testing.T) {
+	users, admin, alice := newUserService(t)
+	ctx := context.Background()
+
+	// Keeping a reserved username is allowed
+	err := users.Update(ctx, &models.User{Model: admin.Model, Username: "admin", Email: "root@example.com", FirstName: "Site"})
+	if err != nil {
+		t.Fatalf("updating admin: %v", err)
+	}
+	updated, err := users.GetByID(ctx, admin.ID, nil)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if updated.Email != "root@example.com" || updated.FirstName != "Site" {
+		t.Errorf("admin = %s %s, want the update applied", updated.Email, updated.FirstName)
+	}
+
+	// Changing to one isn't
+	err = users.Update(ctx, &models.User{Model: alice.Model, Username: "Root", Email: alice.Email})
+	assertErrorType[*service.ValidationError](t, err)
+	if updated, _ := users.GetByID(ctx, alice.ID, nil); updated.Username != "alice" {
+		t.Errorf("alice was renamed to %s", updated.Username)
+	}
+}
+
+func TestUpdateHashesOnlyChangedPasswords(t *testing.T) {
+	users, admin, _ := newUserService(t)
+	ctx := context.Background()
+	stored := func(t *testing.T) *models.User {
+		t.Helper()
+		user, err := users.GetByID(ctx, admin.ID, nil)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		return user
+	}
+
+	if err := users.Update(ctx, &models.User{Model: admin.Model, Username: "admin", Email: admin.Email, Password: "first-pass1"}); err != nil {
+		t.Fatalf("setting the password: %v", err)
+	}
+	if err := stored(t).ValidatePassword("first-pass1"); err != nil {
+		t.Fatalf("the new password doesn't match: %v", err)
+	}
+
+	// Saving other changes keeps the stored hash rather than hashing it again
+	if err := users.Update(ctx, &models.User{Model: admin.Model, Username: "admin", Email: admin.Email, FirstName: "Site"}); err != nil {
+		t.Fatalf("updating the name: %v", err)
+	}
+	if err := stored(t).ValidatePassword("first-pass1"); err != nil {
+		t.Errorf("the password stopped matching after an update that didn't change it: %v", err)
+	}
+}
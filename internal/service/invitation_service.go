@@ -0,0 +1,16 @@
+package service
+
+import (
+	"context"
+
+	"github.com/userblog/management/internal/models"
+)
+
+// IInvitationService defines the interface for invitations to register. Registering with one is
+// done through IAuthService.Register.
+type IInvitationService interface {
+	Invite(ctx context.Context, actor *models.User, email string, roleID uint) (*models.Invitation, error)
+	List(ctx context.Context) ([]models.Invitation, error)
+	Revoke(ctx context.Context, id uint) error
+	Resend(ctx context.Context, actor *models.User, id uint) (*models.Invitation, error)
+}
@@ -0,0 +1,9 @@
+package service
+
+import "context"
+
+// IOIDCService defines the interface for logging in through OpenID Connect providers
+type IOIDCService interface {
+	AuthorizationURL(ctx context.Context, provider string) (string, error)
+	Callback(ctx context.Context, provider, code, state string) (string, error)
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"context"
+
+	"github.com/userblog/management/internal/models"
+)
+
+// IOrganizationService defines the interface for organizations, their members and invitations.
+// Apart from Resolve, Create, ListMemberships and AcceptInvitation, methods act on the
+// organization the context acts in.
+type IOrganizationService interface {
+	Resolve(ctx context.Context, slug string) (*models.Organization, error)
+	Current(ctx context.Context) (*models.Organization, error)
+	Create(ctx context.Context, org *models.Organization, ownerID uint) error
+	Update(ctx context.Context, org *models.Organization) error
+	ListMemberships(ctx context.Context, userID uint) ([]models.Membership, error)
+	ListMembers(ctx context.Context, page, perPage int) ([]models.Membership, int, error)
+	UpdateMember(ctx context.Context, actor *models.User, userID, roleID uint) error
+	RemoveMember(ctx context.Context, actor *models.User, userID uint) error
+	Invite(ctx context.Context, actor *models.User, email string, roleID uint) (*models.Invitation, error)
+	ListInvitations(ctx context.Context) ([]models.Invitation, error)
+	RevokeInvitation(ctx context.Context, id uint) error
+	AcceptInvitation(ctx context.Context, user *models.User, token string) (*models.Membership, error)
+}
@@ -0,0 +1,13 @@
+package service
+
+import (
+	"context"
+
+	"github.com/userblog/management/internal/models"
+)
+
+// IPrincipalService defines the interface for loading the users that requests act as
+type IPrincipalService interface {
+	Load(ctx context.Context, id uint) (*models.User, error)
+	Invalidate(ctx context.Context, ids ...uint)
+}
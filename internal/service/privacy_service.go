@@ -0,0 +1,25 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/userblog/management/internal/models"
+	"github.com/userblog/management/internal/repository"
+)
+
+// IPrivacyService defines the interface for data subject requests: users exporting a copy of
+// their data and asking for it to be erased, and administrators reviewing erasures
+type IPrivacyService interface {
+	RequestExport(ctx context.Context, userID uint) (*models.DataRequest, error)
+	RequestErasure(ctx context.Context, userID uint, currentPassword, reason string) (*models.DataRequest, error)
+	ListOwn(ctx context.Context, userID uint) ([]models.DataRequest, error)
+	Download(ctx context.Context, userID, id uint) ([]byte, error)
+	List(ctx context.Context, filter repository.DataRequestFilter, page, perPage int) ([]models.DataRequest, int, error)
+	Get(ctx context.Context, id uint) (*models.DataRequest, error)
+	Approve(ctx context.Context, reviewer *models.User, id uint, note string) (*models.DataRequest, error)
+	Reject(ctx context.Context, reviewer *models.User, id uint, note string) (*models.DataRequest, error)
+	Certificate(ctx context.Context, id uint) (*models.ErasureCertificate, error)
+	ProcessQueued(ctx context.Context) (int, error)
+	RunJobs(ctx context.Context, interval time.Duration)
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/userblog/management/internal/models"
+)
+
+// Author is the public profile of a user, without their email or role
+type Author struct {
+	Username string `json:"username"`
+	models.Profile
+	PostCount int       `json:"post_count"`
+	JoinedAt  time.Time `json:"joined_at"`
+}
+
+// IProfileService defines the interface for user profiles and avatars
+type IProfileService interface {
+	Get(ctx context.Context, userID uint) (*models.Profile, error)
+	Update(ctx context.Context, userID uint, profile *models.Profile) error
+	SetAvatar(ctx context.Context, userID uint, image io.Reader) (*models.Profile, error)
+	RemoveAvatar(ctx context.Context, userID uint) error
+	Author(ctx context.Context, username string) (*Author, error)
+	Avatar(ctx context.Context, name string) ([]byte, string, error)
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"context"
+
+	"github.com/userblog/management/internal/models"
+)
+
+// RolePermissions is a role's permission set after inheritance
+type RolePermissions struct {
+	// Roles is the role followed by its ancestors, nearest first
+	Roles       []string
+	Permissions []models.Permission
+}
+
+// IRoleService defines the interface for role operations
+type IRoleService interface {
+	List(ctx context.Context) ([]models.Role, error)
+	Create(ctx context.Context, role *models.Role, permissions []string) error
+	Update(ctx context.Context, role *models.Role, permissions []string) error
+	Effective(ctx context.Context, roleID uint) (*RolePermissions, error)
+	ListPermissions(ctx context.Context) ([]models.Permission, error)
+}
@@ -0,0 +1,17 @@
+package service
+
+import (
+	"context"
+
+	"github.com/userblog/management/internal/models"
+)
+
+// ISessionService defines the interface for managing the login sessions tokens belong to
+type ISessionService interface {
+	Start(ctx context.Context, user *models.User) (*models.Session, error)
+	StartImpersonation(ctx context.Context, actor, user *models.User) (*models.Session, error)
+	Check(ctx context.Context, userID uint, sessionID string) (*models.Session, error)
+	List(ctx context.Context, userID uint) ([]models.Session, error)
+	Revoke(ctx context.Context, userID, sessionID uint) error
+	RevokeOthers(ctx context.Context, userID, currentID uint) (int64, error)
+}
@@ -1,12 +1,18 @@
 package service
 
-import "github.com/userblog/management/internal/models"
+import (
+	"context"
+
+	"github.com/userblog/management/internal/models"
+)
 
 // IUserService defines the interface for user operations
 type IUserService interface {
-	Create(user *models.User) error
-	GetByID(id uint) (*models.User, error)
-	Update(user *models.User) error
-	Delete(id uint) error
-	List(page, perPage int) ([]models.User, int, error)
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id uint, includes []string) (*models.User, error)
+	Update(ctx context.Context, user *models.User) error
+	Delete(ctx context.Context, id uint) error
+	List(ctx context.Context, page, perPage int, includes []string) ([]models.User, int, error)
+	Unlock(ctx context.Context, id uint) error
+	Permissions(ctx context.Context, id uint) (*RolePermissions, error)
 }
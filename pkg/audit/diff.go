@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// Redacted replaces the values of secret fields in a diff
+const Redacted = "[redacted]"
+
+// Change is the value of a field before and after a change; a missing side is null
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// ignored are fields that change as a side effect or hold loaded relations, not the entity itself
+var ignored = map[string]bool{
+	"ID": true, "CreatedAt": true, "UpdatedAt": true, "DeletedAt": true,
+	"role": true, "user": true, "permissions": true,
+}
+
+// secret are fields whose values must never be written to the log
+var secret = map[string]bool{
+	"password": true,
+}
+
+// Diff returns the fields that differ between two versions of an entity, compared by their JSON
+// form. A nil before describes a creation, a nil after a deletion.
+func Diff(before, after interface{}) map[string]Change {
+	previous, current := fields(before), fields(after)
+
+	changes := map[string]Change{}
+	for name := range union(previous, current) {
+		if ignored[name] {
+			continue
+		}
+		oldValue, hadOld := previous[name]
+		newValue, hasNew := current[name]
+		if hadOld == hasNew && reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		if secret[name] {
+			oldValue, newValue = redact(oldValue, hadOld), redact(newValue, hasNew)
+		}
+		changes[name] = Change{Before: oldValue, After: newValue}
+	}
+	return changes
+}
+
+// fields returns an entity's JSON attributes
+func fields(entity interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	if entity == nil || (reflect.ValueOf(entity).Kind() == reflect.Ptr && reflect.ValueOf(entity).IsNil()) {
+		return result
+	}
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return result
+	}
+	_ = json.Unmarshal(data, &result)
+	return result
+}
+
+// union returns the keys of both maps
+func union(a, b map[string]interface{}) map[string]bool {
+	keys := make(map[string]bool, len(a)+len(b))
+	for key := range a {
+		keys[key] = true
+	}
+	for key := range b {
+		keys[key] = true
+	}
+	return keys
+}
+
+// redact hides a secret value, keeping only whether it was set
+func redact(value interface{}, present bool) interface{} {
+	if !present || value == nil || value == "" {
+		return nil
+	}
+	return Redacted
+}
@@ -0,0 +1,30 @@
+package buildinfo
+
+import "runtime"
+
+// Build metadata, injected at link time by the Makefile:
+//
+//	go build -ldflags "-X github.com/userblog/management/pkg/buildinfo.Version=1.0.0 ..."
+var (
+	Version = "dev"
+	Commit  = "unknown"
+	Date    = "unknown"
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build information of the running binary
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+	}
+}
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/logger"
+	"github.com/userblog/management/pkg/metrics"
+)
+
+// Store keeps cached values with an expiry. The in-memory store suits a single instance;
+// a shared implementation (for example backed by Redis) lets several instances use one cache,
+// so an invalidation made by any of them is seen by all.
+type Store interface {
+	// Get returns the value of key, reporting false when it is missing or expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value of key until ttl has passed
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the keys
+	Delete(ctx context.Context, keys ...string) error
+	// DeletePrefix removes every key starting with prefix
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// Cache keeps JSON encoded values in its own namespace of a store, counting hits and misses
+// under its name. Entries live for the configured cache.ttl, and a zero ttl disables caching.
+// Store errors are logged and treated as misses, so a failing backend only costs speed.
+type Cache struct {
+	name  string
+	store Store
+}
+
+// New creates a cache named name that keeps its values in store
+func New(name string, store Store) *Cache {
+	return &Cache{
+		name:  name,
+		store: store,
+	}
+}
+
+// Get decodes the value of key into v, reporting whether it was cached
+func (c *Cache) Get(ctx context.Context, key string, v interface{}) bool {
+	if !enabled() {
+		return false
+	}
+
+	data, ok, err := c.store.Get(ctx, c.key(key))
+	if err != nil {
+		logger.WarnF(ctx, "Failed to read %s cache: %v", c.name, err)
+	}
+	if ok && err == nil {
+		if err = json.Unmarshal(data, v); err == nil {
+			metrics.CacheRequests.WithLabelValues(c.name, "hit").Inc()
+			return true
+		}
+		logger.WarnF(ctx, "Failed to decode %s cache entry: %v", c.name, err)
+	}
+	metrics.CacheRequests.WithLabelValues(c.name, "miss").Inc()
+	return false
+}
+
+// Set caches v under key
+func (c *Cache) Set(ctx context.Context, key string, v interface{}) {
+	if !enabled() {
+		return
+	}
+
+	data, err := json.Marshal(v)
+	if err == nil {
+		err = c.store.Set(ctx, c.key(key), data, config.Current().Cache.TTL)
+	}
+	if err != nil {
+		logger.WarnF(ctx, "Failed to write %s cache: %v", c.name, err)
+	}
+}
+
+// Delete removes the keys from the cache
+func (c *Cache) Delete(ctx context.Context, keys ...string) {
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		names = append(names, c.key(key))
+	}
+	if err := c.store.Delete(ctx, names...); err != nil {
+		logger.ErrorF(ctx, "Failed to invalidate %s cache: %v", c.name, err)
+	}
+}
+
+// Clear removes every entry of the cache
+func (c *Cache) Clear(ctx context.Context) {
+	if err := c.store.DeletePrefix(ctx, c.key("")); err != nil {
+		logger.ErrorF(ctx, "Failed to clear %s cache: %v", c.name, err)
+	}
+}
+
+// key returns the store key of key, prefixed with the cache's namespace
+func (c *Cache) key(key string) string {
+	return c.name + ":" + key
+}
+
+// enabled reports whether the active configuration caches anything
+func enabled() bool {
+	return config.Current().Cache.TTL > 0
+}
@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/metrics"
+)
+
+// setTTL makes the active configuration cache entries for ttl
+func setTTL(t *testing.T, ttl time.Duration) {
+	t.Helper()
+
+	previous := config.Current()
+	cfg := config.Default()
+	cfg.Cache.TTL = ttl
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(previous) })
+}
+
+func TestMemoryStoreExpiresEntries(t *testing.T) {
+	store := NewMemoryStore(10)
+	ctx := context.Background()
+
+	_ = store.Set(ctx, "short", []byte("a"), 20*time.Millisecond)
+	_ = store.Set(ctx, "long", []byte("b"), time.Hour)
+	if _, ok, _ := store.Get(ctx, "short"); !ok {
+		t.Fatal("an entry is missing before its ttl")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, ok, _ := store.Get(ctx, "short"); ok {
+		t.Error("an entry is still returned after its ttl")
+	}
+	if value, ok, _ := store.Get(ctx, "long"); !ok || string(value) != "b" {
+		t.Errorf("Get(long) = %q, %v, want b", value, ok)
+	}
+}
+
+func TestMemoryStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	store := NewMemoryStore(2)
+	ctx := context.Background()
+
+	_ = store.Set(ctx, "a", []byte("a"), time.Hour)
+	_ = store.Set(ctx, "b", []byte("b"), time.Hour)
+	// Reading a makes b the least recently used
+	_, _, _ = store.Get(ctx, "a")
+	_ = store.Set(ctx, "c", []byte("c"), time.Hour)
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok, _ := store.Get(ctx, key); ok != want {
+			t.Errorf("Get(%s) found %v, want %v", key, ok, want)
+		}
+	}
+
+	// Replacing an entry doesn't count towards the limit
+	_ = store.Set(ctx, "c", []byte("c2"), time.Hour)
+	if value, ok, _ := store.Get(ctx, "a"); !ok || string(value) != "a" {
+		t.Errorf("Get(a) after replacing c = %q, %v", value, ok)
+	}
+}
+
+func TestCacheDeleteAndClear(t *testing.T) {
+	setTTL(t, time.Hour)
+	store := NewMemoryStore(10)
+	users, roles := New("test_users", store), New("test_roles", store)
+	ctx := context.Background()
+
+	for _, key := range []string{"1", "2"} {
+		users.Set(ctx, key, key)
+		roles.Set(ctx, key, key)
+	}
+
+	var v string
+	users.Delete(ctx, "1")
+	if users.Get(ctx, "1", &v) {
+		t.Error("a deleted entry is still cached")
+	}
+	if !users.Get(ctx, "2", &v) || v != "2" {
+		t.Errorf("Get(2) = %q, want the entry that wasn't deleted", v)
+	}
+
+	// Clearing a cache leaves the others sharing its store alone
+	users.Clear(ctx)
+	if users.Get(ctx, "2", &v) {
+		t.Error("an entry is still cached after Clear")
+	}
+	if !roles.Get(ctx, "1", &v) || !roles.Get(ctx, "2", &v) {
+		t.Error("Clear removed the entries of another cache")
+	}
+}
+
+func TestCacheCountsHitsAndMisses(t *testing.T) {
+	setTTL(t, time.Hour)
+	c := New("test_metrics", NewMemoryStore(10))
+	ctx := context.Background()
+	hits := metrics.CacheRequests.WithLabelValues("test_metrics", "hit")
+	misses := metrics.CacheRequests.WithLabelValues("test_metrics", "miss")
+
+	var v struct{ Name string }
+	c.Get(ctx, "alice", &v)
+	c.Set(ctx, "alice", struct{ Name string }{"alice"})
+	c.Get(ctx, "alice", &v)
+	c.Get(ctx, "alice", &v)
+
+	if v.Name != "alice" {
+		t.Errorf("cached value = %+v, want alice", v)
+	}
+	if got := testutil.ToFloat64(hits); got != 2 {
+		t.Errorf("hits = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(misses); got != 1 {
+		t.Errorf("misses = %v, want 1", got)
+	}
+}
+
+func TestZeroTTLDisablesCaching(t *testing.T) {
+	setTTL(t, 0)
+	store := NewMemoryStore(10)
+	c := New("test_disabled", store)
+	ctx := context.Background()
+
+	c.Set(ctx, "1", "one")
+	var v string
+	if c.Get(ctx, "1", &v) {
+		t.Error("a value was cached with caching disabled")
+	}
+	if _, ok, _ := store.Get(ctx, "test_disabled:1"); ok {
+		t.Error("the value was written to the store")
+	}
+}
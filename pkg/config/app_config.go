@@ -0,0 +1,509 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/userblog/management/pkg/policy"
+)
+
+// Config is the typed application configuration. Each field is read, in increasing precedence,
+// from its default, its yaml key in config.yml, its env variable and a -set flag override.
+// Fields tagged secret are redacted by Redacted.
+type Config struct {
+	App        AppConfig         `yaml:"app"`
+	Database   DatabaseConfig    `yaml:"database"`
+	JWT        JWTConfig         `yaml:"jwt"`
+	Metrics    MetricsConfig     `yaml:"metrics"`
+	Tracing    TracingConfig     `yaml:"tracing"`
+	Mail       MailConfig        `yaml:"mail"`
+	RateLimit  RateLimitConfig   `yaml:"rate_limit"`
+	Lockout    LockoutConfig     `yaml:"lockout"`
+	Cache      CacheConfig       `yaml:"cache"`
+	Storage    StorageConfig     `yaml:"storage"`
+	Avatars    AvatarsConfig     `yaml:"avatars"`
+	Validation ValidationConfig  `yaml:"validation"`
+	Secrets    SecretsConfig     `yaml:"secrets"`
+	Auth       AuthConfig        `yaml:"auth"`
+	Account    AccountConfig     `yaml:"account"`
+	Privacy    PrivacyConfig     `yaml:"privacy"`
+	LDAP       LDAPConfig        `yaml:"ldap"`
+	OIDC       OIDCConfig        `yaml:"oidc"`
+	Authz      AuthzConfig       `yaml:"authz"`
+	Orgs       OrgsConfig        `yaml:"organizations"`
+	Values     map[string]string `yaml:"values"`
+}
+
+// AppConfig holds the server settings
+type AppConfig struct {
+	Name             string        `yaml:"name" env:"APP_NAME"`
+	Env              string        `yaml:"env" env:"ENV"`
+	Port             string        `yaml:"port" env:"PORT"`
+	Debug            bool          `yaml:"debug" env:"DEBUG"`
+	LogLevel         string        `yaml:"log_level" env:"LOG_LEVEL" reload:"true"`
+	TrustedProxies   []string      `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	ReadinessTimeout time.Duration `yaml:"readiness_timeout" env:"READINESS_TIMEOUT"`
+	ShutdownDelay    time.Duration `yaml:"shutdown_delay" env:"SHUTDOWN_DELAY"`
+}
+
+// DatabaseConfig holds the database connection settings. Connections are reused for at most
+// ConnMaxLifetime, so rotated credentials are picked up by new connections.
+type DatabaseConfig struct {
+	Type            string        `yaml:"type" env:"DB_TYPE"`
+	Host            string        `yaml:"host" env:"DB_HOST"`
+	Port            string        `yaml:"port" env:"DB_PORT"`
+	User            string        `yaml:"user" env:"DB_USER" reload:"true"`
+	Password        string        `yaml:"password" env:"DB_PASSWORD" secret:"true" reload:"true"`
+	Name            string        `yaml:"name" env:"DB_NAME"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+}
+
+// JWTConfig holds the token signing settings. RS256 and EdDSA keys are generated and rotated
+// every RotationInterval; retired keys still verify tokens for GracePeriod, which defaults to
+// the token expiry. Secret is only used by HS256. Generated private keys are encrypted with
+// KeyEncryptionKey, which is best kept with the secret provider, before they are stored.
+type JWTConfig struct {
+	Algorithm           string        `yaml:"algorithm" env:"JWT_ALGORITHM"`
+	Secret              string        `yaml:"secret" env:"JWT_SECRET" secret:"true"`
+	Issuer              string        `yaml:"issuer" env:"JWT_ISSUER"`
+	Audience            string        `yaml:"audience" env:"JWT_AUDIENCE"`
+	ExpiryHours         int           `yaml:"expiry" env:"TOKEN_EXPIRY"`
+	RotationInterval    time.Duration `yaml:"rotation_interval" env:"JWT_ROTATION_INTERVAL"`
+	GracePeriod         time.Duration `yaml:"grace_period" env:"JWT_GRACE_PERIOD"`
+	ImpersonationExpiry time.Duration `yaml:"impersonation_expiry" env:"JWT_IMPERSONATION_EXPIRY"`
+	KeyEncryptionKey    string        `yaml:"key_encryption_key" env:"JWT_KEY_ENCRYPTION_KEY" secret:"true"`
+}
+
+// Expiry returns how long issued tokens are valid
+func (c JWTConfig) Expiry() time.Duration {
+	return time.Duration(c.ExpiryHours) * time.Hour
+}
+
+// KeyLifetime returns how long a generated key is kept: signing for a rotation interval,
+// then verifying for the grace period
+func (c JWTConfig) KeyLifetime() time.Duration {
+	grace := c.GracePeriod
+	if grace == 0 {
+		grace = c.Expiry()
+	}
+	return c.RotationInterval + grace
+}
+
+// MetricsConfig holds the admin metrics server settings; an empty port disables it
+type MetricsConfig struct {
+	Port  string `yaml:"port" env:"METRICS_PORT"`
+	Token string `yaml:"token" env:"METRICS_TOKEN" secret:"true"`
+}
+
+// TracingConfig holds the trace exporter settings
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" env:"TRACING_EXPORTER"`
+	File        string  `yaml:"file" env:"TRACING_FILE"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+}
+
+// MailConfig holds the SMTP settings; an empty host logs mail instead of sending it
+type MailConfig struct {
+	Host     string `yaml:"host" env:"MAIL_HOST"`
+	Port     string `yaml:"port" env:"MAIL_PORT"`
+	Username string `yaml:"username" env:"MAIL_USERNAME"`
+	Password string `yaml:"password" env:"MAIL_PASSWORD" secret:"true"`
+	From     string `yaml:"from" env:"MAIL_FROM"`
+}
+
+// RateLimitConfig holds the token bucket policies by route group
+type RateLimitConfig struct {
+	Enabled  bool                       `yaml:"enabled" env:"RATE_LIMIT_ENABLED" reload:"true"`
+	Policies map[string]RateLimitPolicy `yaml:"policies" reload:"true"`
+}
+
+// RateLimitPolicy is a token bucket; zero fields are inherited from the "default" policy
+type RateLimitPolicy struct {
+	Key      string        `yaml:"key"`
+	Limit    int           `yaml:"limit"`
+	Period   time.Duration `yaml:"period"`
+	Burst    int           `yaml:"burst"`
+	Disabled bool          `yaml:"disabled"`
+}
+
+// LockoutConfig holds the failed login policies per username and per client IP
+type LockoutConfig struct {
+	Account LockoutPolicy `yaml:"account" reload:"true"`
+	IP      LockoutPolicy `yaml:"ip" reload:"true"`
+}
+
+// LockoutPolicy controls how failed logins against one key are throttled
+type LockoutPolicy struct {
+	DelayAfter int           `yaml:"delay_after"`
+	BaseDelay  time.Duration `yaml:"base_delay"`
+	MaxDelay   time.Duration `yaml:"max_delay"`
+	Threshold  int           `yaml:"threshold"`
+	Duration   time.Duration `yaml:"duration"`
+	Window     time.Duration `yaml:"window"`
+}
+
+// CacheConfig holds the settings of the principal and role permission caches
+type CacheConfig struct {
+	TTL        time.Duration `yaml:"ttl" env:"CACHE_TTL" reload:"true"`
+	MaxEntries int           `yaml:"max_entries" env:"CACHE_MAX_ENTRIES"`
+}
+
+// StorageConfig holds where uploaded and generated files are kept
+type StorageConfig struct {
+	Dir string `yaml:"dir" env:"STORAGE_DIR"`
+}
+
+// AvatarsConfig holds the avatar upload settings. Uploads are cropped square and scaled down
+// to Size pixels.
+type AvatarsConfig struct {
+	Size     int `yaml:"size" env:"AVATAR_SIZE" reload:"true"`
+	MaxBytes int `yaml:"max_bytes" env:"AVATAR_MAX_BYTES" reload:"true"`
+}
+
+// ValidationConfig holds the input validation settings
+type ValidationConfig struct {
+	ReservedUsernames []string `yaml:"reserved_usernames" env:"RESERVED_USERNAMES"`
+}
+
+// SecretsConfig selects the provider that resolves ${secret:name} references
+type SecretsConfig struct {
+	Provider        string        `yaml:"provider" env:"SECRETS_PROVIDER"`
+	Dir             string        `yaml:"dir" env:"SECRETS_DIR"`
+	VaultAddress    string        `yaml:"vault_address" env:"VAULT_ADDR"`
+	VaultToken      string        `yaml:"vault_token" env:"VAULT_TOKEN" secret:"true"`
+	VaultMount      string        `yaml:"vault_mount" env:"VAULT_MOUNT"`
+	RefreshInterval time.Duration `yaml:"refresh_interval" env:"SECRETS_REFRESH_INTERVAL"`
+}
+
+// Registration modes
+const (
+	RegistrationOpen   = "open"   // anyone can register
+	RegistrationInvite = "invite" // registering needs an invitation
+	RegistrationClosed = "closed" // only administrators create users
+)
+
+// AuthConfig holds the password login and registration settings. Users registering get the role
+// of their invitation, if it names one, or DefaultRole.
+type AuthConfig struct {
+	// Authenticators are tried in order until one accepts the credentials: database, ldap
+	Authenticators []string      `yaml:"authenticators" env:"AUTH_AUTHENTICATORS"`
+	Registration   string        `yaml:"registration" env:"AUTH_REGISTRATION" reload:"true"`
+	DefaultRole    string        `yaml:"default_role" env:"AUTH_DEFAULT_ROLE" reload:"true"`
+	InvitationTTL  time.Duration `yaml:"invitation_ttl" env:"AUTH_INVITATION_TTL" reload:"true"`
+}
+
+// PrivacyConfig holds the settings of data subject requests. Exports can be downloaded for
+// ExportTTL; approved requests are processed by a job checking every JobInterval.
+type PrivacyConfig struct {
+	ExportTTL   time.Duration `yaml:"export_ttl" env:"PRIVACY_EXPORT_TTL" reload:"true"`
+	JobInterval time.Duration `yaml:"job_interval" env:"PRIVACY_JOB_INTERVAL"`
+	Erasure     ErasurePolicy `yaml:"erasure" reload:"true"`
+}
+
+// ErasurePolicy says what erasing a user does to each table holding their data. Sessions and
+// linked identities are always deleted. The audit log is append-only, so its entries are only
+// ever anonymized.
+type ErasurePolicy struct {
+	Users               string `yaml:"users" env:"ERASURE_USERS"`                               // anonymize or delete
+	Blogs               string `yaml:"blogs" env:"ERASURE_BLOGS"`                               // delete or keep
+	Memberships         string `yaml:"memberships" env:"ERASURE_MEMBERSHIPS"`                   // delete or keep
+	Invitations         string `yaml:"invitations" env:"ERASURE_INVITATIONS"`                   // delete or keep
+	ImpersonationEvents string `yaml:"impersonation_events" env:"ERASURE_IMPERSONATION_EVENTS"` // anonymize or keep
+	AuditEvents         string `yaml:"audit_events" env:"ERASURE_AUDIT_EVENTS"`                 // anonymize or keep
+}
+
+// Erasure actions
+const (
+	ErasureDelete    = "delete"
+	ErasureAnonymize = "anonymize"
+	ErasureKeep      = "keep"
+)
+
+// Tables returns the action of every configurable table by table name
+func (p ErasurePolicy) Tables() map[string]string {
+	return map[string]string{
+		"users":                p.Users,
+		"blogs":                p.Blogs,
+		"memberships":          p.Memberships,
+		"invitations":          p.Invitations,
+		"impersonation_events": p.ImpersonationEvents,
+		"audit_events":         p.AuditEvents,
+	}
+}
+
+// AccountConfig holds the settings of users managing their own account. Accounts users delete
+// are kept for DeletionGrace, during which they can cancel, and removed by a job running every
+// PurgeInterval.
+type AccountConfig struct {
+	EmailTokenTTL time.Duration `yaml:"email_token_ttl" env:"ACCOUNT_EMAIL_TOKEN_TTL" reload:"true"`
+	DeletionGrace time.Duration `yaml:"deletion_grace" env:"ACCOUNT_DELETION_GRACE" reload:"true"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env:"ACCOUNT_PURGE_INTERVAL"`
+}
+
+// LDAPConfig holds the directory used by the ldap authenticator. Users are found with UserFilter
+// under BaseDN using the service account, then authenticated by binding as their entry. Their
+// groups come from GroupAttribute and, when GroupFilter is set, a group search.
+type LDAPConfig struct {
+	URL                string         `yaml:"url" env:"LDAP_URL"`
+	StartTLS           bool           `yaml:"start_tls" env:"LDAP_START_TLS"`
+	InsecureSkipVerify bool           `yaml:"insecure_skip_verify" env:"LDAP_INSECURE_SKIP_VERIFY"`
+	BindDN             string         `yaml:"bind_dn" env:"LDAP_BIND_DN"`
+	BindPassword       string         `yaml:"bind_password" env:"LDAP_BIND_PASSWORD" secret:"true"`
+	BaseDN             string         `yaml:"base_dn" env:"LDAP_BASE_DN"`
+	UserFilter         string         `yaml:"user_filter" env:"LDAP_USER_FILTER"`
+	GroupBaseDN        string         `yaml:"group_base_dn" env:"LDAP_GROUP_BASE_DN"`
+	GroupFilter        string         `yaml:"group_filter" env:"LDAP_GROUP_FILTER"`
+	Attributes         LDAPAttributes `yaml:"attributes"`
+	DefaultRole        string         `yaml:"default_role"`
+	RoleRules          []LDAPRoleRule `yaml:"role_rules"`
+	Timeout            time.Duration  `yaml:"timeout" env:"LDAP_TIMEOUT"`
+	SyncInterval       time.Duration  `yaml:"sync_interval" env:"LDAP_SYNC_INTERVAL"`
+}
+
+// LDAPAttributes names the directory attributes copied to user profiles
+type LDAPAttributes struct {
+	Username  string `yaml:"username"`
+	Email     string `yaml:"email"`
+	FirstName string `yaml:"first_name"`
+	LastName  string `yaml:"last_name"`
+	Groups    string `yaml:"groups"`
+}
+
+// LDAPRoleRule grants Role to members of Group, given as a DN or just its common name
+type LDAPRoleRule struct {
+	Group string `yaml:"group"`
+	Role  string `yaml:"role"`
+}
+
+// OIDCConfig holds the OpenID Connect providers users can log in with, by name
+type OIDCConfig struct {
+	Providers map[string]OIDCProvider `yaml:"providers"`
+}
+
+// OIDCProvider is an OpenID Connect identity provider. Users are matched to accounts by verified
+// email; with Provision, unknown users get an account with the first matching role rule's role,
+// or DefaultRole.
+type OIDCProvider struct {
+	Issuer       string         `yaml:"issuer"`
+	ClientID     string         `yaml:"client_id"`
+	ClientSecret string         `yaml:"client_secret" secret:"true"`
+	RedirectURL  string         `yaml:"redirect_url"`
+	Scopes       []string       `yaml:"scopes"`
+	Provision    bool           `yaml:"provision"`
+	DefaultRole  string         `yaml:"default_role"`
+	RoleRules    []OIDCRoleRule `yaml:"role_rules"`
+}
+
+// OIDCRoleRule grants Role to users whose Claim equals Value or, for list claims such as groups,
+// contains it
+type OIDCRoleRule struct {
+	Claim string `yaml:"claim"`
+	Value string `yaml:"value"`
+	Role  string `yaml:"role"`
+}
+
+// AuthzConfig holds the access policy rules evaluated alongside role permissions
+type AuthzConfig struct {
+	Rules []AuthzRule `yaml:"rules" reload:"true"`
+}
+
+// AuthzRule allows or denies Actions, resource:action pairs that may use *, when every
+// condition in When holds. A matching deny rule overrides role permissions and allow rules.
+type AuthzRule struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Effect      string   `yaml:"effect"`
+	Actions     []string `yaml:"actions"`
+	When        []string `yaml:"when"`
+}
+
+// PolicyRules returns the rules in the form the policy engine compiles
+func (c AuthzConfig) PolicyRules() []policy.Rule {
+	rules := make([]policy.Rule, 0, len(c.Rules))
+	for _, rule := range c.Rules {
+		rules = append(rules, policy.Rule{
+			Name:        rule.Name,
+			Description: rule.Description,
+			Effect:      policy.Effect(rule.Effect),
+			Actions:     rule.Actions,
+			When:        rule.When,
+		})
+	}
+	return rules
+}
+
+// OrgsConfig holds the multi-tenancy settings
+type OrgsConfig struct {
+	Default       string        `yaml:"default" env:"ORG_DEFAULT"`
+	Domain        string        `yaml:"domain" env:"ORG_DOMAIN"`
+	OwnerRole     string        `yaml:"owner_role" env:"ORG_OWNER_ROLE" reload:"true"`
+	InvitationTTL time.Duration `yaml:"invitation_ttl" env:"ORG_INVITATION_TTL" reload:"true"`
+}
+
+// Default returns the configuration used for anything not set elsewhere
+func Default() *Config {
+	return &Config{
+		App: AppConfig{
+			Name:             "User Blog Management",
+			Env:              "development",
+			Port:             "8080",
+			LogLevel:         "debug",
+			ReadinessTimeout: 2 * time.Second,
+			ShutdownDelay:    5 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Type:            "sqlite",
+			Host:            "localhost",
+			Name:            "userblog",
+			ConnMaxLifetime: 5 * time.Minute,
+		},
+		JWT: JWTConfig{
+			Algorithm:           "RS256",
+			Issuer:              "user-blog-management",
+			Audience:            "user-blog-management",
+			ExpiryHours:         24,
+			RotationInterval:    30 * 24 * time.Hour,
+			ImpersonationExpiry: 15 * time.Minute,
+		},
+		Metrics: MetricsConfig{
+			Port: "9090",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			File:        "traces.json",
+			SampleRatio: 1,
+		},
+		Mail: MailConfig{
+			Port: "587",
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Policies: map[string]RateLimitPolicy{
+				"default": {Key: "ip", Limit: 300, Period: time.Minute, Burst: 60},
+			},
+		},
+		Lockout: LockoutConfig{
+			Account: LockoutPolicy{
+				DelayAfter: 3,
+				BaseDelay:  time.Second,
+				MaxDelay:   8 * time.Second,
+				Threshold:  5,
+				Duration:   15 * time.Minute,
+				Window:     15 * time.Minute,
+			},
+			IP: LockoutPolicy{
+				Threshold: 20,
+				Duration:  15 * time.Minute,
+				Window:    15 * time.Minute,
+			},
+		},
+		Cache: CacheConfig{
+			TTL:        time.Minute,
+			MaxEntries: 10000,
+		},
+		Storage: StorageConfig{
+			Dir: "data",
+		},
+		Avatars: AvatarsConfig{
+			Size:     256,
+			MaxBytes: 5 << 20,
+		},
+		Validation: ValidationConfig{
+			ReservedUsernames: []string{"admin", "administrator", "root", "system", "support", "api", "me", "null", "undefined"},
+		},
+		Auth: AuthConfig{
+			Authenticators: []string{"database"},
+			Registration:   RegistrationOpen,
+			DefaultRole:    "user",
+			InvitationTTL:  7 * 24 * time.Hour,
+		},
+		Account: AccountConfig{
+			EmailTokenTTL: 24 * time.Hour,
+			DeletionGrace: 30 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Privacy: PrivacyConfig{
+			ExportTTL:   7 * 24 * time.Hour,
+			JobInterval: 30 * time.Second,
+			Erasure: ErasurePolicy{
+				Users:               ErasureAnonymize,
+				Blogs:               ErasureDelete,
+				Memberships:         ErasureDelete,
+				Invitations:         ErasureDelete,
+				ImpersonationEvents: ErasureKeep,
+				AuditEvents:         ErasureAnonymize,
+			},
+		},
+		LDAP: LDAPConfig{
+			UserFilter: "(uid={username})",
+			Attributes: LDAPAttributes{
+				Username:  "uid",
+				Email:     "mail",
+				FirstName: "givenName",
+				LastName:  "sn",
+				Groups:    "memberOf",
+			},
+			DefaultRole:  "user",
+			Timeout:      5 * time.Second,
+			SyncInterval: time.Hour,
+		},
+		Orgs: OrgsConfig{
+			Default:       "default",
+			OwnerRole:     "org_admin",
+			InvitationTTL: 7 * 24 * time.Hour,
+		},
+		Authz: AuthzConfig{
+			Rules: []AuthzRule{
+				{
+					Name:        "authors-only",
+					Description: "only the author of a blog can change or delete it",
+					Effect:      "deny",
+					Actions:     []string{"blog:update", "blog:delete"},
+					When:        []string{"resource.user_id != subject.id"},
+				},
+			},
+		},
+		Secrets: SecretsConfig{
+			Provider:        "file",
+			Dir:             "/run/secrets",
+			VaultMount:      "secret",
+			RefreshInterval: time.Minute,
+		},
+	}
+}
+
+var (
+	current      atomic.Pointer[Config]
+	reloadMu     sync.Mutex
+	reloadHooks  []func(*Config)
+	defaultsOnce sync.Once
+)
+
+// Current returns the active configuration, falling back to the defaults before Set is called
+func Current() *Config {
+	if cfg := current.Load(); cfg != nil {
+		return cfg
+	}
+	defaultsOnce.Do(func() {
+		current.CompareAndSwap(nil, Default())
+	})
+	return current.Load()
+}
+
+// Set makes cfg the active configuration
+func Set(cfg *Config) {
+	current.Store(cfg)
+}
+
+// OnReload registers a hook that runs after a hot reload has changed the active configuration
+func OnReload(hook func(*Config)) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	reloadHooks = append(reloadHooks, hook)
+}
+
+// IsProduction reports whether the configuration is for a production deployment
+func (c *Config) IsProduction() bool {
+	return c.App.Env == "production"
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/userblog/management/pkg/secrets"
+	"gopkg.in/yaml.v3"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load builds the configuration from the defaults, the yaml file at path, the environment
+// (including a .env file and *_FILE variables) and overrides of the form "app.port=9000",
+// in that order of precedence, then resolves ${secret:name} references through the
+// configured secret provider. A missing file is not an error; the result is not validated.
+func Load(path string, overrides []string) (*Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	// .env only fills variables that aren't already set in the environment
+	_ = godotenv.Load()
+	if err := applyEnv(reflect.ValueOf(cfg).Elem()); err != nil {
+		return nil, err
+	}
+
+	for _, override := range overrides {
+		key, value, ok := strings.Cut(override, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid override %q, expected key=value", override)
+		}
+		if err := setPath(reflect.ValueOf(cfg).Elem(), strings.Split(key, "."), value); err != nil {
+			return nil, fmt.Errorf("invalid override %q: %w", override, err)
+		}
+	}
+
+	if err := resolveSecrets(cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// applyEnv sets every field that has an env tag whose variable is present, even if empty.
+// NAME_FILE, as used by Docker and Kubernetes secret mounts, names a file holding the value.
+func applyEnv(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			if err := applyEnv(v.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := field.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		value, ok := os.LookupEnv(name)
+		if file, fileOk := os.LookupEnv(name + "_FILE"); fileOk {
+			if ok {
+				return fmt.Errorf("both %s and %s_FILE are set", name, name)
+			}
+			content, err := secrets.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read %s_FILE: %w", name, err)
+			}
+			value, ok = content, true
+		}
+		if ok {
+			if err := setValue(v.Field(i), value); err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// setPath sets the field at the yaml key path, descending into structs and maps of structs
+func setPath(v reflect.Value, path []string, value string) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if yamlName(v.Type().Field(i)) != path[0] {
+				continue
+			}
+			if len(path) == 1 {
+				return setValue(v.Field(i), value)
+			}
+			return setPath(v.Field(i), path[1:], value)
+		}
+		return fmt.Errorf("unknown key %s", path[0])
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		key := reflect.ValueOf(path[0])
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if existing := v.MapIndex(key); existing.IsValid() {
+			elem.Set(existing)
+		}
+		var err error
+		if len(path) == 1 {
+			err = setValue(elem, value)
+		} else {
+			err = setPath(elem, path[1:], value)
+		}
+		if err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+		return nil
+	}
+	return fmt.Errorf("key %s does not have nested values", path[0])
+}
+
+// setValue parses a string into a field of a supported kind
+func setValue(v reflect.Value, value string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// yamlName returns the yaml key of a struct field
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
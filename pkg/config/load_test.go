@@ -0,0 +1,88 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFile writes content to name in a temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("writing %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "config.yml", `
+app:
+  name: From YAML
+  port: "7000"
+  log_level: info
+database:
+  name: yaml_db
+jwt:
+  issuer: yaml-issuer
+  audience: yaml-audience
+`)
+	t.Setenv("PORT", "7100")
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("JWT_AUDIENCE", "env-audience")
+	t.Setenv("DB_NAME_FILE", writeFile(t, "db_name", "file_db\n"))
+
+	cfg, err := Load(path, []string{"app.log_level=error", "rate_limit.policies.auth.limit=20"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		key, got, want string
+	}{
+		{key: "app.readiness_timeout (default)", got: cfg.App.ReadinessTimeout.String(), want: "2s"},
+		{key: "app.name (yaml over default)", got: cfg.App.Name, want: "From YAML"},
+		{key: "jwt.issuer (yaml over default)", got: cfg.JWT.Issuer, want: "yaml-issuer"},
+		{key: "app.port (env over yaml)", got: cfg.App.Port, want: "7100"},
+		{key: "jwt.audience (env over yaml)", got: cfg.JWT.Audience, want: "env-audience"},
+		{key: "database.name (_FILE over yaml)", got: cfg.Database.Name, want: "file_db"},
+		{key: "app.log_level (override over env)", got: cfg.App.LogLevel, want: "error"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.key, tt.got, tt.want)
+		}
+	}
+	if policy := cfg.RateLimit.Policies["auth"]; policy.Limit != 20 {
+		t.Errorf("rate_limit.policies.auth.limit = %d, want 20 from the override", policy.Limit)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		overrides []string
+		want      string
+	}{
+		{name: "variable and file both set", env: map[string]string{"DB_NAME": "a", "DB_NAME_FILE": "/dev/null"}, want: "both DB_NAME and DB_NAME_FILE"},
+		{name: "missing file", env: map[string]string{"DB_NAME_FILE": "/nonexistent/db_name"}, want: "DB_NAME_FILE"},
+		{name: "invalid env value", env: map[string]string{"TOKEN_EXPIRY": "soon"}, want: "TOKEN_EXPIRY"},
+		{name: "override without value", overrides: []string{"app.port"}, want: "expected key=value"},
+		{name: "unknown override key", overrides: []string{"app.nope=1"}, want: "unknown key nope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			_, err := Load(filepath.Join(t.TempDir(), "missing.yml"), tt.overrides)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue replaces secrets in printed configuration
+const redactedValue = "******"
+
+// Redacted returns a copy of the configuration with every secret field that is set masked,
+// including those in maps, which are copied so the active configuration is left untouched
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redactSecrets(reflect.ValueOf(&redacted).Elem())
+	return &redacted
+}
+
+// YAML renders the configuration as yaml
+func (c *Config) YAML() ([]byte, error) {
+	return yaml.Marshal(c)
+}
+
+// redactSecrets masks the non-empty string fields tagged secret
+func redactSecrets(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		switch {
+		case field.Kind() == reflect.Struct:
+			redactSecrets(field)
+		case field.Kind() == reflect.Map && field.Type().Elem().Kind() == reflect.Struct && !field.IsNil():
+			copied := reflect.MakeMapWithSize(field.Type(), field.Len())
+			for _, key := range field.MapKeys() {
+				elem := reflect.New(field.Type().Elem()).Elem()
+				elem.Set(field.MapIndex(key))
+				redactSecrets(elem)
+				copied.SetMapIndex(key, elem)
+			}
+			field.Set(copied)
+		case t.Field(i).Tag.Get("secret") == "true" && field.Kind() == reflect.String && field.String() != "":
+			field.SetString(redactedValue)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Reload loads and validates the configuration again and applies only the fields tagged
+// reload, such as the log level and rate limits. It returns the keys that were applied and the
+// changed keys that were ignored because they need a restart.
+func Reload(path string, overrides []string) (applied, ignored []string, err error) {
+	next, err := Load(path, overrides)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := next.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	merged := *Current()
+	mergeReloadable(reflect.ValueOf(&merged).Elem(), reflect.ValueOf(next).Elem(), "", &applied, &ignored)
+	if len(applied) == 0 {
+		return nil, ignored, nil
+	}
+
+	Set(&merged)
+
+	reloadMu.Lock()
+	hooks := append([]func(*Config){}, reloadHooks...)
+	reloadMu.Unlock()
+	for _, hook := range hooks {
+		hook(&merged)
+	}
+
+	return applied, ignored, nil
+}
+
+// Watch polls the config file every interval and reloads it when it changes, until ctx is done.
+// Every refresh interval, if positive, it also reloads so rotated secrets from files or the
+// secret provider are picked up. report is called after every reload of a changed file and
+// after refreshes that changed or failed.
+func Watch(ctx context.Context, path string, overrides []string, interval, refresh time.Duration, report func(applied, ignored []string, err error)) {
+	last := fileVersion(path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var refreshes <-chan time.Time
+	if refresh > 0 {
+		refreshTicker := time.NewTicker(refresh)
+		defer refreshTicker.Stop()
+		refreshes = refreshTicker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			version := fileVersion(path)
+			if version == last {
+				continue
+			}
+			last = version
+			report(Reload(path, overrides))
+		case <-refreshes:
+			if applied, _, err := Reload(path, overrides); len(applied) > 0 || err != nil {
+				report(applied, nil, err)
+			}
+		}
+	}
+}
+
+// fileVersion identifies the current contents of a file by modification time and size
+func fileVersion(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size())
+}
+
+// mergeReloadable copies the changed reloadable fields from next into dst and records
+// every other changed field as ignored
+func mergeReloadable(dst, next reflect.Value, prefix string, applied, ignored *[]string) {
+	t := dst.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := prefix + yamlName(field)
+
+		if reflect.DeepEqual(dst.Field(i).Interface(), next.Field(i).Interface()) {
+			continue
+		}
+
+		switch {
+		case field.Tag.Get("reload") == "true":
+			dst.Field(i).Set(next.Field(i))
+			*applied = append(*applied, key)
+		case field.Type.Kind() == reflect.Struct:
+			mergeReloadable(dst.Field(i), next.Field(i), key+".", applied, ignored)
+		default:
+			*ignored = append(*ignored, key)
+		}
+	}
+}
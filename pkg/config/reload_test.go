@@ -0,0 +1,96 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+// loadCurrent makes the configuration loaded from path the current one until the test ends
+func loadCurrent(t *testing.T, path string) {
+	t.Helper()
+
+	cfg, err := Load(path, nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	previous := Current()
+	Set(cfg)
+	t.Cleanup(func() { Set(previous) })
+}
+
+func TestReloadAppliesOnlyReloadableFields(t *testing.T) {
+	path := writeFile(t, "config.yml", `
+app:
+  port: "7000"
+  log_level: info
+jwt:
+  algorithm: HS256
+  secret: the-first-secret-of-32-or-more-characters
+`)
+	loadCurrent(t, path)
+
+	if err := os.WriteFile(path, []byte(`
+app:
+  port: "9000"
+  log_level: warn
+jwt:
+  algorithm: HS256
+  secret: a-rotated-secret-of-32-or-more-characters
+cache:
+  ttl: 5m
+`), 0o600); err != nil {
+		t.Fatalf("rewriting config: %v", err)
+	}
+
+	applied, ignored, err := Reload(path, nil)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	slices.Sort(applied)
+	slices.Sort(ignored)
+	if want := []string{"app.log_level", "cache.ttl"}; !slices.Equal(applied, want) {
+		t.Errorf("applied = %v, want %v", applied, want)
+	}
+	// A rotated HS256 secret would log everyone out, so it waits for a restart
+	if want := []string{"app.port", "jwt.secret"}; !slices.Equal(ignored, want) {
+		t.Errorf("ignored = %v, want %v", ignored, want)
+	}
+
+	cfg := Current()
+	if cfg.App.LogLevel != "warn" || cfg.Cache.TTL.String() != "5m0s" {
+		t.Errorf("log level %s and cache ttl %s, want the reloaded values", cfg.App.LogLevel, cfg.Cache.TTL)
+	}
+	if cfg.App.Port != "7000" || cfg.JWT.Secret != "the-first-secret-of-32-or-more-characters" {
+		t.Errorf("port %s and secret %s changed without a restart", cfg.App.Port, cfg.JWT.Secret)
+	}
+}
+
+func TestReloadKeepsTheConfigurationWhenInvalid(t *testing.T) {
+	path := writeFile(t, "config.yml", "app:\n  log_level: info\n")
+	loadCurrent(t, path)
+	before := Current()
+
+	for name, content := range map[string]string{
+		"invalid value": "app:\n  log_level: loud\n",
+		"invalid yaml":  "app: [\n",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("rewriting config: %v", err)
+		}
+		if _, _, err := Reload(path, nil); err == nil {
+			t.Errorf("%s: Reload succeeded", name)
+		}
+		if Current() != before {
+			t.Errorf("%s: the configuration was replaced", name)
+		}
+	}
+
+	// An unchanged file applies nothing
+	if err := os.WriteFile(path, []byte("app:\n  log_level: info\n"), 0o600); err != nil {
+		t.Fatalf("rewriting config: %v", err)
+	}
+	if applied, ignored, err := Reload(path, nil); err != nil || len(applied) != 0 || len(ignored) != 0 {
+		t.Errorf("Reload = %v, %v, %v, want nothing applied or ignored", applied, ignored, err)
+	}
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/userblog/management/pkg/secrets"
+)
+
+// secretResolveTimeout bounds the time spent resolving all secret references
+const secretResolveTimeout = 30 * time.Second
+
+// SecretProvider returns the provider selected by the secrets section
+func (c SecretsConfig) SecretProvider() (secrets.SecretProvider, error) {
+	switch c.Provider {
+	case "file":
+		return secrets.NewFileProvider(c.Dir), nil
+	case "vault":
+		if c.VaultAddress == "" {
+			return nil, fmt.Errorf("secrets.vault_address is required for the vault provider")
+		}
+		return secrets.NewVaultProvider(c.VaultAddress, c.VaultToken, c.VaultMount), nil
+	default:
+		return nil, fmt.Errorf("secrets.provider must be file or vault, got %q", c.Provider)
+	}
+}
+
+// resolveSecrets replaces ${secret:name} references in every string setting outside the secrets section
+func resolveSecrets(cfg *Config) error {
+	v := reflect.ValueOf(cfg).Elem()
+	if !hasSecretReferences(v) {
+		return nil
+	}
+
+	provider, err := cfg.Secrets.SecretProvider()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretResolveTimeout)
+	defer cancel()
+
+	return walkStrings(v, func(s string) (string, error) {
+		return secrets.ResolveReferences(ctx, provider, s)
+	})
+}
+
+// hasSecretReferences reports whether any string setting contains a reference
+func hasSecretReferences(v reflect.Value) bool {
+	found := false
+	_ = walkStrings(v, func(s string) (string, error) {
+		found = found || secrets.HasReferences(s)
+		return s, nil
+	})
+	return found
+}
+
+// walkStrings replaces every string, string slice element and map value string with fn's result,
+// skipping the secrets section so the provider settings are never resolved through themselves
+func walkStrings(v reflect.Value, fn func(string) (string, error)) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).Type() == reflect.TypeOf(SecretsConfig{}) {
+				continue
+			}
+			if err := walkStrings(v.Field(i), fn); err != nil {
+				return err
+			}
+		}
+	case reflect.String:
+		s, err := fn(v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := walkStrings(v.Index(i), fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			// Map values aren't addressable, so walk a copy and store it back
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			if err := walkStrings(elem, fn); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+		}
+	}
+	return nil
+}
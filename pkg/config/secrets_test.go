@@ -0,0 +1,117 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newVault starts a Vault KV v2 server holding the database credentials at app/db
+func newVault(t *testing.T) *httptest.Server {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/app/db" || r.Header.Get("X-Vault-Token") != "root-token" {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string][]string{"errors": {}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"data": map[string]string{"user": "app", "password": "hunter2"}},
+		})
+	}))
+	t.Cleanup(vault.Close)
+	return vault
+}
+
+func TestResolveSecrets(t *testing.T) {
+	vault := newVault(t)
+
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		token    string
+		want     string
+		wantErr  bool
+	}{
+		{name: "resolved", user: "${secret:app/db#user}", password: "${secret:app/db#password}", token: "root-token", want: "hunter2"},
+		{name: "no references", user: "app", password: "plain", token: "", want: "plain"},
+		{name: "missing secret", user: "${secret:app/db#user}", password: "${secret:app/cache#password}", token: "root-token", wantErr: true},
+		{name: "rejected token", user: "${secret:app/db#user}", password: "${secret:app/db#password}", token: "other", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Secrets = SecretsConfig{Provider: "vault", VaultAddress: vault.URL, VaultToken: tt.token, VaultMount: "secret"}
+			cfg.Database.User = tt.user
+			cfg.Database.Password = tt.password
+
+			err := resolveSecrets(cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("resolveSecrets succeeded")
+				}
+				if strings.Contains(err.Error(), "hunter2") {
+					t.Errorf("error %v contains the secret", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveSecrets: %v", err)
+			}
+			if cfg.Database.User != "app" || cfg.Database.Password != tt.want {
+				t.Errorf("database credentials = %q/%q, want app/%q", cfg.Database.User, cfg.Database.Password, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Database.User = "app"
+	cfg.Database.Password = "hunter2"
+	cfg.JWT.Secret = "signing-key"
+	cfg.Secrets.VaultToken = "root-token"
+	cfg.LDAP.BindPassword = "bind-secret"
+	cfg.Mail.Password = ""
+	cfg.OIDC.Providers = map[string]OIDCProvider{"google": {ClientID: "client", ClientSecret: "client-secret"}}
+
+	redacted := cfg.Redacted()
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "database password", got: redacted.Database.Password, want: redactedValue},
+		{name: "jwt secret", got: redacted.JWT.Secret, want: redactedValue},
+		{name: "vault token", got: redacted.Secrets.VaultToken, want: redactedValue},
+		{name: "ldap bind password", got: redacted.LDAP.BindPassword, want: redactedValue},
+		{name: "oidc client secret", got: redacted.OIDC.Providers["google"].ClientSecret, want: redactedValue},
+		{name: "unset secret", got: redacted.Mail.Password, want: ""},
+		{name: "database user", got: redacted.Database.User, want: "app"},
+		{name: "oidc client id", got: redacted.OIDC.Providers["google"].ClientID, want: "client"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// The active configuration keeps its secrets
+	if cfg.Database.Password != "hunter2" || cfg.OIDC.Providers["google"].ClientSecret != "client-secret" {
+		t.Error("Redacted changed the configuration it copied")
+	}
+
+	out, err := redacted.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"hunter2", "signing-key", "root-token", "bind-secret", "client-secret"} {
+		if strings.Contains(string(out), secret) {
+			t.Errorf("redacted YAML contains %q", secret)
+		}
+	}
+}
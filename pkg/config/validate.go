@@ -0,0 +1,247 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/userblog/management/pkg/policy"
+)
+
+// insecureSecrets are the placeholder JWT secrets shipped in examples
+var insecureSecrets = map[string]bool{
+	"your-secret-key":                           true,
+	"your-secret-key-here":                      true,
+	"your-secret-key-change-this-in-production": true,
+}
+
+// minProductionSecretLength is the shortest JWT secret accepted in production
+const minProductionSecretLength = 32
+
+// Validate reports every invalid setting. In production it also refuses insecure
+// settings: a placeholder or short HS256 JWT secret, signing keys stored unencrypted and debug
+// mode.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	switch c.App.Env {
+	case "development", "staging", "production", "test":
+	default:
+		add("app.env must be development, staging, production or test, got %q", c.App.Env)
+	}
+	if c.App.Port == "" {
+		add("app.port is required")
+	}
+	if !validLogLevel(c.App.LogLevel) {
+		add("app.log_level must be debug, info, warn or error, got %q", c.App.LogLevel)
+	}
+	if c.App.ReadinessTimeout <= 0 {
+		add("app.readiness_timeout must be positive")
+	}
+	if c.App.ShutdownDelay < 0 {
+		add("app.shutdown_delay must not be negative")
+	}
+
+	switch c.Database.Type {
+	case "sqlite", "postgres", "mysql":
+	default:
+		add("database.type must be sqlite, postgres or mysql, got %q", c.Database.Type)
+	}
+
+	switch c.JWT.Algorithm {
+	case "HS256":
+		if c.JWT.Secret == "" {
+			add("jwt.secret is required for HS256")
+		}
+	case "RS256", "EdDSA":
+		if c.JWT.RotationInterval <= 0 {
+			add("jwt.rotation_interval must be positive")
+		}
+	default:
+		add("jwt.algorithm must be HS256, RS256 or EdDSA, got %q", c.JWT.Algorithm)
+	}
+	if c.JWT.Issuer == "" || c.JWT.Audience == "" {
+		add("jwt.issuer and jwt.audience are required")
+	}
+	if c.JWT.ExpiryHours <= 0 {
+		add("jwt.expiry must be a positive number of hours")
+	}
+	if c.JWT.GracePeriod != 0 && c.JWT.GracePeriod < c.JWT.Expiry() {
+		add("jwt.grace_period must be at least jwt.expiry so retired keys outlive the tokens they signed")
+	}
+	if c.JWT.ImpersonationExpiry <= 0 || c.JWT.ImpersonationExpiry > c.JWT.Expiry() {
+		add("jwt.impersonation_expiry must be positive and at most jwt.expiry")
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "otlp", "stdout", "file":
+	default:
+		add("tracing.exporter must be none, otlp, stdout or file, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		add("tracing.sample_ratio must be between 0 and 1")
+	}
+
+	for name, policy := range c.RateLimit.Policies {
+		switch policy.Key {
+		case "", "ip", "user", "token":
+		default:
+			add("rate_limit.policies.%s.key must be ip, user or token, got %q", name, policy.Key)
+		}
+		if policy.Limit < 0 || policy.Burst < 0 || policy.Period < 0 {
+			add("rate_limit.policies.%s must not have negative values", name)
+		}
+	}
+
+	for name, policy := range map[string]LockoutPolicy{"account": c.Lockout.Account, "ip": c.Lockout.IP} {
+		if policy.Threshold > 0 && policy.Duration <= 0 {
+			add("lockout.%s.duration must be positive when a threshold is set", name)
+		}
+	}
+
+	if c.Orgs.Default == "" {
+		add("organizations.default must name the default organization")
+	}
+	if c.Orgs.OwnerRole == "" {
+		add("organizations.owner_role is required")
+	}
+	if c.Orgs.InvitationTTL <= 0 {
+		add("organizations.invitation_ttl must be positive")
+	}
+
+	if c.Cache.TTL < 0 {
+		add("cache.ttl must not be negative")
+	}
+	if c.Cache.MaxEntries <= 0 {
+		add("cache.max_entries must be positive")
+	}
+
+	if c.Storage.Dir == "" {
+		add("storage.dir is required")
+	}
+	if c.Avatars.Size < 16 || c.Avatars.Size > 1024 {
+		add("avatars.size must be between 16 and 1024")
+	}
+	if c.Avatars.MaxBytes <= 0 {
+		add("avatars.max_bytes must be positive")
+	}
+
+	switch c.Auth.Registration {
+	case RegistrationOpen, RegistrationInvite, RegistrationClosed:
+	default:
+		add("auth.registration must be open, invite or closed, got %q", c.Auth.Registration)
+	}
+	if c.Auth.DefaultRole == "" {
+		add("auth.default_role is required")
+	}
+	if c.Auth.InvitationTTL <= 0 {
+		add("auth.invitation_ttl must be positive")
+	}
+
+	if c.Account.EmailTokenTTL <= 0 {
+		add("account.email_token_ttl must be positive")
+	}
+	if c.Account.DeletionGrace < 0 {
+		add("account.deletion_grace must not be negative")
+	}
+	if c.Account.PurgeInterval <= 0 {
+		add("account.purge_interval must be positive")
+	}
+
+	if c.Privacy.ExportTTL <= 0 {
+		add("privacy.export_ttl must be positive")
+	}
+	if c.Privacy.JobInterval <= 0 {
+		add("privacy.job_interval must be positive")
+	}
+	erasure := c.Privacy.Erasure
+	for _, rule := range []struct {
+		table, action string
+		allowed       []string
+	}{
+		{"users", erasure.Users, []string{ErasureAnonymize, ErasureDelete}},
+		{"blogs", erasure.Blogs, []string{ErasureDelete, ErasureKeep}},
+		{"memberships", erasure.Memberships, []string{ErasureDelete, ErasureKeep}},
+		{"invitations", erasure.Invitations, []string{ErasureDelete, ErasureKeep}},
+		{"impersonation_events", erasure.ImpersonationEvents, []string{ErasureAnonymize, ErasureKeep}},
+		{"audit_events", erasure.AuditEvents, []string{ErasureAnonymize, ErasureKeep}},
+	} {
+		if !slices.Contains(rule.allowed, rule.action) {
+			add("privacy.erasure.%s must be %s, got %q", rule.table, strings.Join(rule.allowed, " or "), rule.action)
+		}
+	}
+	// Blogs kept after their author is deleted would point at a missing user
+	if erasure.Users == ErasureDelete && erasure.Blogs == ErasureKeep {
+		add("privacy.erasure.blogs must be delete when privacy.erasure.users is delete")
+	}
+
+	if len(c.Auth.Authenticators) == 0 {
+		add("auth.authenticators must name at least one authenticator")
+	}
+	for _, name := range c.Auth.Authenticators {
+		switch name {
+		case "database":
+		case "ldap":
+			if c.LDAP.URL == "" || c.LDAP.BaseDN == "" {
+				add("ldap.url and ldap.base_dn are required for the ldap authenticator")
+			}
+			if !strings.Contains(c.LDAP.UserFilter, "{username}") {
+				add("ldap.user_filter must contain {username}")
+			}
+			if c.LDAP.Timeout <= 0 {
+				add("ldap.timeout must be positive")
+			}
+		default:
+			add("auth.authenticators must only contain database and ldap, got %q", name)
+		}
+	}
+
+	for name, provider := range c.OIDC.Providers {
+		if provider.Issuer == "" || provider.ClientID == "" || provider.RedirectURL == "" {
+			add("oidc.providers.%s needs issuer, client_id and redirect_url", name)
+		}
+		for i, rule := range provider.RoleRules {
+			if rule.Claim == "" || rule.Role == "" {
+				add("oidc.providers.%s.role_rules[%d] needs a claim and a role", name, i)
+			}
+		}
+	}
+
+	if _, err := policy.Compile(c.Authz.PolicyRules()); err != nil {
+		add("authz.rules: %v", err)
+	}
+
+	if _, err := c.Secrets.SecretProvider(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Database.ConnMaxLifetime < 0 {
+		add("database.conn_max_lifetime must not be negative")
+	}
+
+	if c.IsProduction() {
+		if c.JWT.Algorithm == "HS256" && (insecureSecrets[c.JWT.Secret] || len(c.JWT.Secret) < minProductionSecretLength) {
+			add("jwt.secret must be a unique value of at least %d characters in production", minProductionSecretLength)
+		}
+		if c.JWT.Algorithm != "HS256" && len(c.JWT.KeyEncryptionKey) < minProductionSecretLength {
+			add("jwt.key_encryption_key must be at least %d characters in production, so signing keys aren't stored in plain text", minProductionSecretLength)
+		}
+		if c.App.Debug {
+			add("app.debug must be off in production")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validLogLevel reports whether level is one the logger understands
+func validLogLevel(level string) bool {
+	switch strings.ToLower(level) {
+	case "debug", "info", "warn", "error":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	const secret = "a-unique-secret-of-32-or-more-characters"
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string // part of the error, or "" when valid
+	}{
+		{name: "defaults", modify: func(c *Config) {}},
+		{name: "unknown environment", modify: func(c *Config) { c.App.Env = "prod" }, want: "app.env"},
+		{name: "unknown algorithm", modify: func(c *Config) { c.JWT.Algorithm = "none" }, want: "jwt.algorithm"},
+		{name: "HS256 without a secret", modify: func(c *Config) { c.JWT.Algorithm = "HS256"; c.JWT.Secret = "" }, want: "jwt.secret is required"},
+		{name: "grace period shorter than tokens", modify: func(c *Config) { c.JWT.GracePeriod = 1 }, want: "jwt.grace_period"},
+		{name: "unknown erasure action", modify: func(c *Config) { c.Privacy.Erasure.AuditEvents = ErasureDelete }, want: "privacy.erasure.audit_events"},
+		{name: "placeholder secret outside production", modify: func(c *Config) { c.JWT.Algorithm = "HS256"; c.JWT.Secret = "your-secret-key" }},
+		{name: "debug outside production", modify: func(c *Config) { c.App.Debug = true }},
+		{
+			name:   "placeholder secret in production",
+			modify: production("HS256", "your-secret-key-change-this-in-production", ""),
+			want:   "jwt.secret must be a unique value",
+		},
+		{name: "short secret in production", modify: production("HS256", "short-secret", ""), want: "jwt.secret must be a unique value"},
+		{name: "HS256 in production", modify: production("HS256", secret, "")},
+		{name: "unencrypted keys in production", modify: production("RS256", "", ""), want: "jwt.key_encryption_key"},
+		{name: "RS256 in production", modify: production("RS256", "", secret)},
+		{
+			name: "debug in production",
+			modify: func(c *Config) {
+				production("EdDSA", "", secret)(c)
+				c.App.Debug = true
+			},
+			want: "app.debug must be off in production",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.modify(cfg)
+			err := cfg.Validate()
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("Validate: %v", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryError(t *testing.T) {
+	cfg := Default()
+	cfg.App.Port = ""
+	cfg.Cache.MaxEntries = 0
+	cfg.Auth.Registration = "sometimes"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate succeeded")
+	}
+	for _, key := range []string{"app.port", "cache.max_entries", "auth.registration"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error %q doesn't mention %s", err, key)
+		}
+	}
+}
+
+// production returns a modification running the configuration in production with the signing
+// algorithm, HS256 secret and key encryption key
+func production(algorithm, secret, keyEncryptionKey string) func(*Config) {
+	return func(c *Config) {
+		c.App.Env = "production"
+		c.JWT.Algorithm = algorithm
+		c.JWT.Secret = secret
+		c.JWT.KeyEncryptionKey = keyEncryptionKey
+	}
+}
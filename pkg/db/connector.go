@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/userblog/management/pkg/config"
+)
+
+// connector opens every new connection with the credentials in the active configuration,
+// so rotated database passwords are used without reopening the pool
+type connector struct {
+	driver driver.Driver
+	dsn    func(cfg config.DatabaseConfig) string
+}
+
+// Connect opens a connection using the current data source name
+func (c *connector) Connect(_ context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn(config.Current().Database))
+}
+
+// Driver returns the underlying driver
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// openPool creates a connection pool for a registered driver whose DSN is rebuilt for each connection
+func openPool(driverName string, dsn func(cfg config.DatabaseConfig) string) (*sql.DB, error) {
+	// sql.Open doesn't connect, it only looks up the registered driver
+	lookup, err := sql.Open(driverName, "")
+	if err != nil {
+		return nil, fmt.Errorf("unknown database driver %s: %w", driverName, err)
+	}
+	drv := lookup.Driver()
+	_ = lookup.Close()
+
+	return sql.OpenDB(&connector{driver: drv, dsn: dsn}), nil
+}
@@ -6,47 +6,56 @@ import (
 	_ "github.com/jinzhu/gorm/dialects/mysql"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
 	_ "github.com/jinzhu/gorm/dialects/sqlite"
-	"github.com/joho/godotenv"
 	"github.com/userblog/management/pkg/config"
 )
 
-// connect initializes the database connection (private function)
-func Connect() *gorm.DB {
-	err := godotenv.Load()
-	if err != nil {
-		fmt.Println("Error loading .env file, using default values")
-	}
+// Connect opens the database described by cfg. For postgres and mysql the credentials are
+// read from the active configuration for every new connection, and connections are recycled
+// after cfg.ConnMaxLifetime, so rotated credentials are picked up without a restart.
+func Connect(cfg config.DatabaseConfig) *gorm.DB {
+	var db *gorm.DB
+	var err error
 
-	dbType := config.GetOrDefaultString("DB_TYPE", "sqlite")
-	var connectionString string
-	var dialect string
-
-	switch dbType {
+	switch cfg.Type {
 	case "postgres":
-		host := config.GetOrDefaultString("DB_HOST", "localhost")
-		port := config.GetOrDefaultString("DB_PORT", "5432")
-		user := config.GetOrDefaultString("DB_USER", "postgres")
-		password := config.GetOrDefaultString("DB_PASSWORD", "postgres")
-		dbname := config.GetOrDefaultString("DB_NAME", "userblog")
-		dialect = "postgres"
-		connectionString = fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
+		db, err = openWithConnector("postgres", func(cfg config.DatabaseConfig) string {
+			return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+				cfg.Host, portOrDefault(cfg.Port, "5432"), cfg.User, cfg.Password, cfg.Name)
+		}, cfg)
 	case "mysql":
-		host := config.GetOrDefaultString("DB_HOST", "localhost")
-		port := config.GetOrDefaultString("DB_PORT", "3306")
-		user := config.GetOrDefaultString("DB_USER", "santosh")
-		password := config.GetOrDefaultString("DB_PASSWORD", "password")
-		dbname := config.GetOrDefaultString("DB_NAME", "userblog")
-		dialect = "mysql"
-		connectionString = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8&parseTime=True&loc=Local", user, password, host, port, dbname)
+		db, err = openWithConnector("mysql", func(cfg config.DatabaseConfig) string {
+			return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8&parseTime=True&loc=Local",
+				cfg.User, cfg.Password, cfg.Host, portOrDefault(cfg.Port, "3306"), cfg.Name)
+		}, cfg)
 	default:
-		dialect = "sqlite3"
-		connectionString = "./userblog.db"
+		db, err = gorm.Open("sqlite3", "./userblog.db")
 	}
-
-	db, err := gorm.Open(dialect, connectionString)
 	if err != nil {
 		panic(fmt.Sprintf("Failed to connect to the database: %v", err))
 	}
 
 	return db
 }
+
+// openWithConnector opens a gorm database on a pool whose DSN is rebuilt for each connection
+func openWithConnector(dialect string, dsn func(cfg config.DatabaseConfig) string, cfg config.DatabaseConfig) (*gorm.DB, error) {
+	pool, err := openPool(dialect, dsn)
+	if err != nil {
+		return nil, err
+	}
+	pool.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	if err := pool.Ping(); err != nil {
+		_ = pool.Close()
+		return nil, err
+	}
+	return gorm.Open(dialect, pool)
+}
+
+// portOrDefault returns the dialect's standard port when none is configured
+func portOrDefault(port, defaultPort string) string {
+	if port == "" {
+		return defaultPort
+	}
+	return port
+}
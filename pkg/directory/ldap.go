@@ -0,0 +1,221 @@
+package directory
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/userblog/management/pkg/config"
+)
+
+var (
+	// ErrInvalidCredentials is returned when the directory rejects a user's password
+	ErrInvalidCredentials = errors.New("invalid directory credentials")
+	// ErrNotFound is returned when no directory entry matches a username
+	ErrNotFound = errors.New("directory entry not found")
+)
+
+// Entry is a user's directory entry
+type Entry struct {
+	DN        string
+	Username  string
+	Email     string
+	FirstName string
+	LastName  string
+	Groups    []string
+}
+
+// LDAP looks up and authenticates users in an LDAP or Active Directory server. Each call uses
+// its own connection, bound as the service account for searches.
+type LDAP struct {
+	cfg config.LDAPConfig
+}
+
+// NewLDAP creates a directory client from its configuration
+func NewLDAP(cfg config.LDAPConfig) *LDAP {
+	return &LDAP{cfg: cfg}
+}
+
+// Authenticate finds the user's entry and verifies the password by binding as it
+func (d *LDAP) Authenticate(username, password string) (*Entry, error) {
+	// An empty password would be an unauthenticated bind, which many servers accept
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := d.find(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap bind as %s failed: %w", entry.DN, err)
+	}
+
+	// Search groups as the service account, which may see more than the user
+	if err := d.bindServiceAccount(conn); err != nil {
+		return nil, err
+	}
+	if err := d.addGroups(conn, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Lookup returns the user's current entry, for profile sync
+func (d *LDAP) Lookup(username string) (*Entry, error) {
+	conn, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := d.find(conn, username)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.addGroups(conn, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// connect dials the server, upgrades the connection with StartTLS when configured and binds
+// as the service account
+func (d *LDAP) connect() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: d.cfg.InsecureSkipVerify}
+	if u, err := url.Parse(d.cfg.URL); err == nil {
+		tlsConfig.ServerName = u.Hostname()
+	}
+
+	conn, err := ldap.DialURL(d.cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: d.cfg.Timeout}),
+		ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("ldap connection failed: %w", err)
+	}
+	conn.SetTimeout(d.cfg.Timeout)
+
+	if d.cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap StartTLS failed: %w", err)
+		}
+	}
+
+	if err := d.bindServiceAccount(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// bindServiceAccount binds as the configured service account; without one, searches are anonymous
+func (d *LDAP) bindServiceAccount(conn *ldap.Conn) error {
+	if d.cfg.BindDN == "" {
+		return nil
+	}
+	if err := conn.Bind(d.cfg.BindDN, d.cfg.BindPassword); err != nil {
+		return fmt.Errorf("ldap service account bind failed: %w", err)
+	}
+	return nil
+}
+
+// find searches for the single entry matching the username
+func (d *LDAP) find(conn *ldap.Conn, username string) (*Entry, error) {
+	attrs := d.cfg.Attributes
+	filter := strings.ReplaceAll(d.cfg.UserFilter, "{username}", ldap.EscapeFilter(username))
+	request := ldap.NewSearchRequest(d.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(d.cfg.Timeout.Seconds()), false,
+		filter, nonEmpty(attrs.Username, attrs.Email, attrs.FirstName, attrs.LastName, attrs.Groups), nil)
+
+	result, err := conn.Search(request)
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("ldap user search failed: %w", err)
+	}
+	switch {
+	case result == nil || len(result.Entries) == 0:
+		return nil, ErrNotFound
+	case len(result.Entries) > 1:
+		return nil, fmt.Errorf("ldap user filter matches more than one entry for %q", username)
+	}
+
+	found := result.Entries[0]
+	entry := &Entry{
+		DN:        found.DN,
+		Username:  found.GetAttributeValue(attrs.Username),
+		Email:     found.GetAttributeValue(attrs.Email),
+		FirstName: found.GetAttributeValue(attrs.FirstName),
+		LastName:  found.GetAttributeValue(attrs.LastName),
+		Groups:    found.GetAttributeValues(attrs.Groups),
+	}
+	if entry.Username == "" {
+		entry.Username = username
+	}
+	return entry, nil
+}
+
+// addGroups adds the DNs of the groups found by the group filter, if one is configured
+func (d *LDAP) addGroups(conn *ldap.Conn, entry *Entry) error {
+	if d.cfg.GroupFilter == "" {
+		return nil
+	}
+
+	base := d.cfg.GroupBaseDN
+	if base == "" {
+		base = d.cfg.BaseDN
+	}
+	filter := strings.NewReplacer(
+		"{dn}", ldap.EscapeFilter(entry.DN),
+		"{username}", ldap.EscapeFilter(entry.Username),
+	).Replace(d.cfg.GroupFilter)
+	request := ldap.NewSearchRequest(base, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, int(d.cfg.Timeout.Seconds()), false,
+		filter, []string{"1.1"}, nil)
+
+	result, err := conn.Search(request)
+	if err != nil {
+		return fmt.Errorf("ldap group search failed: %w", err)
+	}
+	for _, group := range result.Entries {
+		entry.Groups = append(entry.Groups, group.DN)
+	}
+	return nil
+}
+
+// InGroup reports whether the entry is a member of group, given as a DN or just its common name
+func (e *Entry) InGroup(group string) bool {
+	for _, dn := range e.Groups {
+		if strings.EqualFold(dn, group) {
+			return true
+		}
+		if parsed, err := ldap.ParseDN(dn); err == nil && len(parsed.RDNs) > 0 && len(parsed.RDNs[0].Attributes) > 0 {
+			if strings.EqualFold(parsed.RDNs[0].Attributes[0].Value, group) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nonEmpty returns the attribute names that are set
+func nonEmpty(names ...string) []string {
+	var result []string
+	for _, name := range names {
+		if name != "" {
+			result = append(result, name)
+		}
+	}
+	return result
+}
@@ -0,0 +1,129 @@
+package directory_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/directory"
+	"github.com/userblog/management/pkg/directory/ldaptest"
+)
+
+const (
+	serviceDN = "cn=service,dc=example,dc=com"
+	aliceDN   = "uid=alice,ou=people,dc=example,dc=com"
+	bobDN     = "uid=bob,ou=people,dc=example,dc=com"
+	editorsDN = "cn=editors,ou=groups,dc=example,dc=com"
+)
+
+// newDirectory starts a directory with alice, bob and an editors group alice belongs to, and a
+// client configured for it
+func newDirectory(t *testing.T) (*ldaptest.Server, *directory.LDAP) {
+	server := ldaptest.NewServer(t)
+	server.Add(serviceDN, "service-secret", nil)
+	server.Add(aliceDN, "alice-secret", map[string][]string{
+		"objectClass": {"person"}, "uid": {"alice"}, "mail": {"alice@example.com"}, "givenName": {"Alice"},
+		"memberOf": {"cn=staff,ou=groups,dc=example,dc=com"},
+	})
+	server.Add(bobDN, "bob-secret", map[string][]string{
+		"objectClass": {"person"}, "uid": {"bob"}, "mail": {"bob@example.com"},
+	})
+	server.Add(editorsDN, "", map[string][]string{"objectClass": {"groupOfNames"}, "member": {aliceDN}})
+
+	client := directory.NewLDAP(config.LDAPConfig{
+		URL:          server.URL,
+		BindDN:       serviceDN,
+		BindPassword: "service-secret",
+		BaseDN:       "dc=example,dc=com",
+		UserFilter:   "(&(objectClass=person)(uid={username}))",
+		GroupBaseDN:  "ou=groups,dc=example,dc=com",
+		GroupFilter:  "(member={dn})",
+		Attributes:   config.LDAPAttributes{Username: "uid", Email: "mail", FirstName: "givenName", Groups: "memberOf"},
+		Timeout:      5 * time.Second,
+	})
+	return server, client
+}
+
+func TestAuthenticate(t *testing.T) {
+	_, client := newDirectory(t)
+
+	entry, err := client.Authenticate("alice", "alice-secret")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if entry.DN != aliceDN || entry.Email != "alice@example.com" || entry.FirstName != "Alice" {
+		t.Errorf("entry = %+v", entry)
+	}
+
+	if _, err := client.Authenticate("alice", "wrong"); !errors.Is(err, directory.ErrInvalidCredentials) {
+		t.Errorf("wrong password: error = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := client.Authenticate("carol", "carol-secret"); !errors.Is(err, directory.ErrNotFound) {
+		t.Errorf("unknown user: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestAuthenticateRejectsEmptyPassword(t *testing.T) {
+	server, client := newDirectory(t)
+
+	// The server would accept the bind as unauthenticated, so the client must not attempt it
+	if _, err := client.Authenticate("alice", ""); !errors.Is(err, directory.ErrInvalidCredentials) {
+		t.Errorf("error = %v, want ErrInvalidCredentials", err)
+	}
+	for _, dn := range server.Binds() {
+		if dn == aliceDN {
+			t.Errorf("client bound as %s with an empty password", dn)
+		}
+	}
+}
+
+func TestAuthenticateEscapesUsername(t *testing.T) {
+	server, client := newDirectory(t)
+
+	for _, username := range []string{"*", "alice)(uid=*", "*)(|(uid=*", "bob)(objectClass=*"} {
+		t.Run(username, func(t *testing.T) {
+			if _, err := client.Authenticate(username, "bob-secret"); !errors.Is(err, directory.ErrNotFound) {
+				t.Errorf("error = %v, want ErrNotFound", err)
+			}
+			filters := server.Filters()
+			last := filters[len(filters)-1]
+			if want := "(uid=" + escaped(username) + ")"; !strings.Contains(last, want) {
+				t.Errorf("filter %s doesn't match %s literally", last, want)
+			}
+		})
+	}
+}
+
+func TestGroups(t *testing.T) {
+	_, client := newDirectory(t)
+
+	entry, err := client.Lookup("alice")
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+
+	tests := []struct {
+		group string
+		want  bool
+	}{
+		{group: "staff", want: true},
+		{group: "cn=staff,ou=groups,dc=example,dc=com", want: true},
+		{group: "EDITORS", want: true},
+		{group: editorsDN, want: true},
+		{group: "admins", want: false},
+		{group: "example", want: false},
+	}
+	for _, tt := range tests {
+		if got := entry.InGroup(tt.group); got != tt.want {
+			t.Errorf("InGroup(%q) = %v, want %v (groups %v)", tt.group, got, tt.want, entry.Groups)
+		}
+	}
+}
+
+// escaped is the username as a filter value, with the characters that have a meaning in filters
+// written as hex escapes
+func escaped(username string) string {
+	return strings.NewReplacer("*", `\2a`, "(", `\28`, ")", `\29`, `\`, `\5c`).Replace(username)
+}
@@ -0,0 +1,273 @@
+// Package ldaptest runs an in-process LDAP server for tests, in the manner of net/http/httptest.
+// It speaks just enough of the protocol for the directory client: simple binds, and searches
+// with and, or, not, equality and presence filters.
+package ldaptest
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Protocol operations, by their application tag
+const (
+	opBindRequest      = 0
+	opBindResponse     = 1
+	opUnbindRequest    = 2
+	opSearchRequest    = 3
+	opSearchEntry      = 4
+	opSearchResultDone = 5
+)
+
+// Server is an LDAP server holding entries in memory. Like many real servers it accepts a bind
+// with an empty password as unauthenticated, so clients must refuse such binds themselves.
+type Server struct {
+	URL string
+
+	listener net.Listener
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	binds   []string
+	filters []string
+}
+
+// entry is a directory entry with the password that binds as it
+type entry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+// NewServer starts a server on a local port, stopped when the test ends
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ldaptest: listening: %v", err)
+	}
+
+	s := &Server{URL: "ldap://" + listener.Addr().String(), listener: listener, entries: map[string]*entry{}}
+	go s.serve()
+	t.Cleanup(func() { _ = listener.Close() })
+	return s
+}
+
+// Add adds or replaces the entry named dn. An empty password means the entry can't be bound as.
+func (s *Server) Add(dn, password string, attributes map[string][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[strings.ToLower(dn)] = &entry{dn: dn, password: password, attributes: attributes}
+}
+
+// Remove deletes the entry named dn
+func (s *Server) Remove(dn string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, strings.ToLower(dn))
+}
+
+// Binds returns the DNs of the binds the server received, in order
+func (s *Server) Binds() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.binds...)
+}
+
+// Filters returns the filters of the searches the server received, in order
+func (s *Server) Filters() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.filters...)
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle answers the requests on a connection until the client unbinds or hangs up
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID := packet.Children[0].Value
+		request := packet.Children[1]
+
+		var responses []*ber.Packet
+		switch request.Tag {
+		case opBindRequest:
+			responses = []*ber.Packet{s.bind(request)}
+		case opSearchRequest:
+			responses = s.search(request)
+		case opUnbindRequest:
+			return
+		default:
+			return
+		}
+
+		for _, response := range responses {
+			message := ber.NewSequence("LDAP Response")
+			message.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "Message ID"))
+			message.AppendChild(response)
+			if _, err := conn.Write(message.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// bind checks a simple bind's password against the entry's
+func (s *Server) bind(request *ber.Packet) *ber.Packet {
+	dn := stringValue(request.Children[1])
+	password := stringValue(request.Children[2])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.binds = append(s.binds, dn)
+
+	code := uint16(ldap.LDAPResultInvalidCredentials)
+	if e, ok := s.entries[strings.ToLower(dn)]; password == "" || (ok && e.password != "" && e.password == password) {
+		code = ldap.LDAPResultSuccess
+	}
+	return result(opBindResponse, code)
+}
+
+// search returns the entries under the base DN matching the filter, then the result
+func (s *Server) search(request *ber.Packet) []*ber.Packet {
+	base := strings.ToLower(stringValue(request.Children[0]))
+	sizeLimit, _ := request.Children[3].Value.(int64)
+	filter := request.Children[6]
+	var requested []string
+	for _, attribute := range request.Children[7].Children {
+		requested = append(requested, stringValue(attribute))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	decompiled, _ := ldap.DecompileFilter(filter)
+	s.filters = append(s.filters, decompiled)
+
+	var responses []*ber.Packet
+	for key, e := range s.entries {
+		if (key != base && !strings.HasSuffix(key, ","+base)) || !matches(filter, e) {
+			continue
+		}
+		if sizeLimit > 0 && int64(len(responses)) == sizeLimit {
+			return append(responses, result(opSearchResultDone, ldap.LDAPResultSizeLimitExceeded))
+		}
+		responses = append(responses, searchEntry(e, requested))
+	}
+	return append(responses, result(opSearchResultDone, ldap.LDAPResultSuccess))
+}
+
+// matches evaluates a filter against an entry; filter types the server doesn't know match nothing
+func matches(filter *ber.Packet, e *entry) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matches(child, e) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if matches(child, e) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return len(filter.Children) == 1 && !matches(filter.Children[0], e)
+	case ldap.FilterEqualityMatch:
+		want := stringValue(filter.Children[1])
+		for _, value := range e.values(stringValue(filter.Children[0])) {
+			if strings.EqualFold(value, want) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		return len(e.values(ber.DecodeString(filter.Data.Bytes()))) > 0
+	default:
+		return false
+	}
+}
+
+// values returns the values of the attribute, whose name is case insensitive
+func (e *entry) values(name string) []string {
+	for attribute, values := range e.attributes {
+		if strings.EqualFold(attribute, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+// searchEntry encodes the entry with the requested attributes, all of them if none are named
+// and none for the special "1.1"
+func searchEntry(e *entry, requested []string) *ber.Packet {
+	packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, opSearchEntry, nil, "Search Result Entry")
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn, "Object Name"))
+
+	attributes := ber.NewSequence("Attributes")
+	for name, values := range e.attributes {
+		if !wanted(name, requested) {
+			continue
+		}
+		attribute := ber.NewSequence("Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+	packet.AppendChild(attributes)
+	return packet
+}
+
+func wanted(name string, requested []string) bool {
+	if len(requested) == 0 {
+		return true
+	}
+	for _, r := range requested {
+		if r == "*" || strings.EqualFold(r, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// result encodes an operation's result with the code
+func result(op ber.Tag, code uint16) *ber.Packet {
+	packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, op, nil, "Result")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, ldap.LDAPResultCodeMap[code], "Diagnostic Message"))
+	return packet
+}
+
+func stringValue(packet *ber.Packet) string {
+	return ber.DecodeString(packet.Data.Bytes())
+}
@@ -0,0 +1,66 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/userblog/management/pkg/logger"
+)
+
+// Event is something that happened in the application that other parts may react to
+type Event struct {
+	Name string
+	Time time.Time
+	Data map[string]interface{}
+}
+
+// Handler reacts to a published event
+type Handler func(ctx context.Context, e Event)
+
+// IBus delivers published events to the handlers subscribed to their name
+type IBus interface {
+	Subscribe(name string, handler Handler)
+	Publish(ctx context.Context, name string, data map[string]interface{})
+}
+
+// Bus is an in-process IBus that runs handlers asynchronously
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// NewBus creates an event bus with no subscribers
+func NewBus() IBus {
+	return &Bus{
+		handlers: make(map[string][]Handler),
+	}
+}
+
+// Subscribe registers a handler for events with the given name
+func (b *Bus) Subscribe(name string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[name] = append(b.handlers[name], handler)
+}
+
+// Publish delivers the event to every subscriber in its own goroutine. Handlers get a context
+// that keeps the request's values but not its cancellation, so they outlive the request.
+func (b *Bus) Publish(ctx context.Context, name string, data map[string]interface{}) {
+	b.mu.RLock()
+	handlers := append([]Handler(nil), b.handlers[name]...)
+	b.mu.RUnlock()
+
+	e := Event{Name: name, Time: time.Now(), Data: data}
+	ctx = context.WithoutCancel(ctx)
+	for _, handler := range handlers {
+		go func(handler Handler) {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.ErrorF(ctx, "Event handler for %s panicked: %v", name, r)
+				}
+			}()
+			handler(ctx, e)
+		}(handler)
+	}
+}
@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunReportsEachCheck(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Register("database", func(ctx context.Context) error { return nil })
+	checker.Register("migrations", func(ctx context.Context) error { return errors.New("2 pending") })
+
+	report := checker.Run(context.Background())
+	if report.Status != StatusFail {
+		t.Errorf("status = %s, want %s", report.Status, StatusFail)
+	}
+	if got := report.Checks["database"]; got.Status != StatusOK || got.Error != "" {
+		t.Errorf("database = %+v", got)
+	}
+	if got := report.Checks["migrations"]; got.Status != StatusFail || got.Error != "2 pending" {
+		t.Errorf("migrations = %+v", got)
+	}
+}
+
+func TestRunTimesOutEachCheck(t *testing.T) {
+	checker := NewChecker(20 * time.Millisecond)
+	release := make(chan struct{})
+	defer close(release)
+
+	checker.Register("respects ctx", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	// A check ignoring its context doesn't hold up the report either
+	checker.Register("ignores ctx", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	checker.Register("fast", func(ctx context.Context) error { return nil })
+
+	start := time.Now()
+	report := checker.Run(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Run took %s, want about the 20ms timeout", elapsed)
+	}
+
+	if report.Status != StatusFail {
+		t.Errorf("status = %s, want %s", report.Status, StatusFail)
+	}
+	if got := report.Checks["ignores ctx"]; got.Status != StatusFail || got.Error != "check timed out" {
+		t.Errorf("ignores ctx = %+v, want timed out", got)
+	}
+	if got := report.Checks["respects ctx"]; got.Status != StatusFail {
+		t.Errorf("respects ctx = %+v, want failed", got)
+	}
+	if got := report.Checks["fast"]; got.Status != StatusOK {
+		t.Errorf("fast = %+v, want ok", got)
+	}
+}
+
+func TestRunFailsOnceShuttingDown(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Register("database", func(ctx context.Context) error { return nil })
+
+	if report := checker.Run(context.Background()); report.Status != StatusOK {
+		t.Fatalf("status = %s before shutdown, want %s", report.Status, StatusOK)
+	}
+
+	checker.SetShuttingDown()
+	report := checker.Run(context.Background())
+	if report.Status != StatusShuttingDown {
+		t.Errorf("status = %s, want %s", report.Status, StatusShuttingDown)
+	}
+	// The checks still run, so the report shows what is healthy during the drain
+	if got := report.Checks["database"]; got.Status != StatusOK {
+		t.Errorf("database = %+v", got)
+	}
+}
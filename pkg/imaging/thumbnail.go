@@ -0,0 +1,109 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	// Registered so image.Decode accepts GIFs
+	_ "image/gif"
+)
+
+// MaxPixels bounds the size of images accepted for decoding, so a small compressed file can't
+// make the server allocate gigabytes
+const MaxPixels = 25_000_000
+
+// ErrUnsupported is returned for data that isn't a JPEG, PNG or GIF image
+var ErrUnsupported = errors.New("image must be a JPEG, PNG or GIF")
+
+// ErrTooLarge is returned for images with more than MaxPixels pixels
+var ErrTooLarge = errors.New("image dimensions are too large")
+
+// Thumbnail crops the center square of the image read from r and scales it down to size pixels
+// square, or leaves it at its own size when that is smaller. JPEGs stay JPEGs; other formats
+// become PNGs to keep transparency. It returns the encoded image with its content type.
+func Thumbnail(r io.Reader, size int) ([]byte, string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupported
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, "", ErrTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupported
+	}
+
+	dst := scale(crop(src), size)
+
+	var out bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&out, dst, &jpeg.Options{Quality: 85})
+		return out.Bytes(), "image/jpeg", err
+	}
+	err = png.Encode(&out, dst)
+	return out.Bytes(), "image/png", err
+}
+
+// crop returns the largest square at the center of img as an NRGBA image
+func crop(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	origin := image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2)
+
+	square := image.NewNRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), img, origin, draw.Src)
+	return square
+}
+
+// scale shrinks a square image to size pixels square by averaging the source pixels covered by
+// each target pixel
+func scale(src *image.NRGBA, size int) *image.NRGBA {
+	side := src.Bounds().Dx()
+	if size <= 0 || size >= side {
+		return src
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		y0, y1 := y*side/size, (y+1)*side/size
+		for x := 0; x < size; x++ {
+			x0, x1 := x*side/size, (x+1)*side/size
+
+			// Colors are weighted by alpha so transparent pixels don't darken the edges
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					alpha := uint64(p[3])
+					r += uint64(p[0]) * alpha
+					g += uint64(p[1]) * alpha
+					b += uint64(p[2]) * alpha
+					a += alpha
+					n++
+				}
+			}
+
+			o := dst.Pix[y*dst.Stride+x*4:]
+			if a > 0 {
+				o[0], o[1], o[2] = uint8(r/a), uint8(g/a), uint8(b/a)
+			}
+			o[3] = uint8(a / n)
+		}
+	}
+	return dst
+}
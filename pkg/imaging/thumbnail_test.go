@@ -0,0 +1,135 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// encode returns a width by height image in the format, red on its left half and blue on its
+// right
+func encode(t *testing.T, format string, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBA{R: 255, A: 255}
+			if x >= width/2 {
+				c = color.NRGBA{B: 255, A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	var out bytes.Buffer
+	var err error
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&out, img, nil)
+	case "gif":
+		err = gif.Encode(&out, img, nil)
+	default:
+		err = png.Encode(&out, img)
+	}
+	if err != nil {
+		t.Fatalf("encoding %s: %v", format, err)
+	}
+	return out.Bytes()
+}
+
+// pngHeader returns a PNG holding only a header declaring the dimensions, a few bytes that would
+// decode to width*height pixels
+func pngHeader(width, height uint32) []byte {
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	ihdr[8], ihdr[9] = 8, 6 // 8 bit RGBA
+
+	var out bytes.Buffer
+	out.WriteString("\x89PNG\r\n\x1a\n")
+	_ = binary.Write(&out, binary.BigEndian, uint32(len(ihdr)))
+	chunk := append([]byte("IHDR"), ihdr...)
+	out.Write(chunk)
+	_ = binary.Write(&out, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	return out.Bytes()
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		size     int
+		wantType string
+		wantSide int
+	}{
+		{name: "png scaled down", data: encode(t, "png", 600, 300), size: 256, wantType: "image/png", wantSide: 256},
+		{name: "tall png cropped", data: encode(t, "png", 100, 400), size: 256, wantType: "image/png", wantSide: 100},
+		{name: "jpeg stays jpeg", data: encode(t, "jpeg", 512, 512), size: 128, wantType: "image/jpeg", wantSide: 128},
+		{name: "gif becomes png", data: encode(t, "gif", 64, 32), size: 256, wantType: "image/png", wantSide: 32},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, contentType, err := Thumbnail(bytes.NewReader(tt.data), tt.size)
+			if err != nil {
+				t.Fatalf("Thumbnail: %v", err)
+			}
+			if contentType != tt.wantType {
+				t.Errorf("content type = %s, want %s", contentType, tt.wantType)
+			}
+			img, _, err := image.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("decoding thumbnail: %v", err)
+			}
+			if b := img.Bounds(); b.Dx() != tt.wantSide || b.Dy() != tt.wantSide {
+				t.Errorf("thumbnail is %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.wantSide, tt.wantSide)
+			}
+		})
+	}
+}
+
+func TestThumbnailCropsTheCenter(t *testing.T) {
+	// The center square of a 300x100 image, red then blue, is half of each
+	data, _, err := Thumbnail(bytes.NewReader(encode(t, "png", 300, 100)), 10)
+	if err != nil {
+		t.Fatalf("Thumbnail: %v", err)
+	}
+	img, _ := png.Decode(bytes.NewReader(data))
+	if r, _, b, _ := img.At(1, 5).RGBA(); r == 0 || b != 0 {
+		t.Error("left of the thumbnail isn't red")
+	}
+	if r, _, b, _ := img.At(8, 5).RGBA(); r != 0 || b == 0 {
+		t.Error("right of the thumbnail isn't blue")
+	}
+}
+
+func TestThumbnailRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{name: "text", data: []byte("not an image"), want: ErrUnsupported},
+		{name: "empty", data: nil, want: ErrUnsupported},
+		{name: "svg", data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`), want: ErrUnsupported},
+		{name: "truncated png", data: encode(t, "png", 64, 64)[:60], want: ErrUnsupported},
+		// A header declaring 100000x100000 pixels is refused before anything is allocated
+		{name: "decompression bomb", data: pngHeader(100000, 100000), want: ErrTooLarge},
+		{name: "zero width", data: pngHeader(0, 10), want: ErrUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Thumbnail(bytes.NewReader(tt.data), 256)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package lockout
+
+import (
+	"context"
+	"time"
+
+	"github.com/userblog/management/pkg/config"
+)
+
+// Policy controls how failed attempts against one key are throttled
+type Policy struct {
+	DelayAfter int           // failures before progressive delays start, 0 disables delays
+	BaseDelay  time.Duration // delay after the first failure past DelayAfter, doubling each time
+	MaxDelay   time.Duration // upper bound for the progressive delay
+	Threshold  int           // failures that lock the key, 0 disables lockout
+	Duration   time.Duration // how long a lockout lasts
+	Window     time.Duration // failures older than this are forgotten
+}
+
+// Status is the failure count and lockout state of a key
+type Status struct {
+	Failures    int
+	LockedUntil time.Time
+}
+
+// Locked reports whether the key is locked at the given time
+func (s Status) Locked(now time.Time) bool {
+	return now.Before(s.LockedUntil)
+}
+
+// Store keeps failed attempt counters. The in-memory store suits a single instance;
+// a shared implementation lets several instances see the same counters.
+type Store interface {
+	// Get returns the current status of key
+	Get(ctx context.Context, key string) (Status, error)
+	// Fail records a failed attempt, locking key once the policy threshold is reached
+	Fail(ctx context.Context, key string, policy Policy) (Status, error)
+	// Reset clears the failures and any lockout of key
+	Reset(ctx context.Context, key string) error
+}
+
+// Delay returns the progressive delay to apply after the given number of failures
+func (p Policy) Delay(failures int) time.Duration {
+	if p.DelayAfter <= 0 || failures <= p.DelayAfter {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.DelayAfter + 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// AccountPolicy returns the per-username policy from the active configuration
+func AccountPolicy() Policy {
+	return Policy(config.Current().Lockout.Account)
+}
+
+// IPPolicy returns the per-client-IP policy from the active configuration
+func IPPolicy() Policy {
+	return Policy(config.Current().Lockout.IP)
+}
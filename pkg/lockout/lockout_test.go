@@ -0,0 +1,98 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	policy := Policy{DelayAfter: 3, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 0, want: 0},
+		{failures: 3, want: 0},
+		{failures: 4, want: time.Second},
+		{failures: 5, want: 2 * time.Second},
+		{failures: 6, want: 4 * time.Second},
+		{failures: 7, want: 5 * time.Second},
+		{failures: 50, want: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := policy.Delay(tt.failures); got != tt.want {
+			t.Errorf("Delay(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+
+	if got := (Policy{BaseDelay: time.Second, MaxDelay: time.Minute}).Delay(10); got != 0 {
+		t.Errorf("Delay with delays disabled = %s, want 0", got)
+	}
+}
+
+func TestMemoryStoreLocksAtThreshold(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	policy := Policy{Threshold: 3, Duration: time.Hour, Window: time.Hour}
+
+	for i := 1; i <= 3; i++ {
+		status, err := store.Fail(ctx, "alice", policy)
+		if err != nil {
+			t.Fatalf("Fail: %v", err)
+		}
+		if status.Failures != i || status.Locked(time.Now()) != (i == 3) {
+			t.Errorf("after %d failures: %+v", i, status)
+		}
+	}
+
+	status, _ := store.Get(ctx, "alice")
+	if !status.Locked(time.Now()) || status.Locked(time.Now().Add(2*time.Hour)) {
+		t.Errorf("status = %+v, want locked for an hour", status)
+	}
+
+	// Failing while locked doesn't extend the lockout
+	lockedUntil := status.LockedUntil
+	if status, _ := store.Fail(ctx, "alice", policy); !status.LockedUntil.Equal(lockedUntil) {
+		t.Errorf("lockout moved from %s to %s", lockedUntil, status.LockedUntil)
+	}
+
+	if status, _ := store.Get(ctx, "bob"); status.Failures != 0 || status.Locked(time.Now()) {
+		t.Errorf("another key has %+v", status)
+	}
+
+	if err := store.Reset(ctx, "alice"); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if status, _ := store.Get(ctx, "alice"); status.Failures != 0 || status.Locked(time.Now()) {
+		t.Errorf("after Reset: %+v", status)
+	}
+}
+
+func TestMemoryStoreForgetsOldFailures(t *testing.T) {
+	store := NewMemoryStore().(*MemoryStore)
+	ctx := context.Background()
+	policy := Policy{Threshold: 2, Duration: time.Hour, Window: 20 * time.Millisecond}
+
+	_, _ = store.Fail(ctx, "alice", policy)
+	time.Sleep(30 * time.Millisecond)
+
+	if status, _ := store.Get(ctx, "alice"); status.Failures != 0 {
+		t.Errorf("a failure outside the window still counts: %+v", status)
+	}
+	if status, _ := store.Fail(ctx, "alice", policy); status.Failures != 1 || status.Locked(time.Now()) {
+		t.Errorf("a failure after the window = %+v, want the first of a new window", status)
+	}
+
+	// Locked keys are kept past the window, and the rest are swept
+	_, _ = store.Fail(ctx, "alice", policy)
+	_, _ = store.Fail(ctx, "bob", policy)
+	store.sweep(time.Now().Add(time.Minute))
+	if _, ok := store.entries["alice"]; !ok {
+		t.Error("a locked key was swept")
+	}
+	if _, ok := store.entries["bob"]; ok {
+		t.Error("an expired key was kept")
+	}
+}
@@ -0,0 +1,91 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often expired entries are dropped from memory
+const sweepInterval = time.Minute
+
+type entry struct {
+	status Status
+	last   time.Time
+	window time.Duration
+}
+
+// MemoryStore keeps failed attempt counters in process memory
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() Store {
+	return &MemoryStore{
+		entries:   make(map[string]*entry),
+		lastSweep: time.Now(),
+	}
+}
+
+// Get returns the current status of key
+func (s *MemoryStore) Get(_ context.Context, key string) (Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || s.expired(e, time.Now()) {
+		return Status{}, nil
+	}
+	return e.status, nil
+}
+
+// Fail records a failed attempt, locking key once the policy threshold is reached
+func (s *MemoryStore) Fail(_ context.Context, key string, policy Policy) (Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	e, ok := s.entries[key]
+	if !ok || s.expired(e, now) {
+		e = &entry{}
+		s.entries[key] = e
+	}
+
+	e.status.Failures++
+	e.last = now
+	e.window = policy.Window
+	if policy.Threshold > 0 && e.status.Failures >= policy.Threshold && !e.status.Locked(now) {
+		e.status.LockedUntil = now.Add(policy.Duration)
+	}
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+	return e.status, nil
+}
+
+// Reset clears the failures and any lockout of key
+func (s *MemoryStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// expired reports whether an entry's failures have aged out and it is no longer locked
+func (s *MemoryStore) expired(e *entry, now time.Time) bool {
+	return !e.status.Locked(now) && e.window > 0 && now.Sub(e.last) > e.window
+}
+
+// sweep drops expired entries so unknown usernames don't accumulate
+func (s *MemoryStore) sweep(now time.Time) {
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if s.expired(e, now) {
+			delete(s.entries, key)
+		}
+	}
+}
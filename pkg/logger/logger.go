@@ -8,9 +8,9 @@ import (
 	"strings"
 	"time"
 
-	"github.com/userblog/management/pkg/config"
 	"github.com/userblog/management/pkg/helper"
 
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -28,6 +28,7 @@ const (
 
 var (
 	log         *zap.Logger
+	level       = zap.NewAtomicLevelAt(zap.DebugLevel)
 	ProjectRoot string
 )
 
@@ -36,11 +37,31 @@ func GetLogger() *zap.Logger {
 	return log
 }
 
-// init automatically initializes the global logger when the package is imported
+// init automatically initializes the global logger when the package is imported.
+// It logs to the console at debug level until Configure is called with the loaded configuration.
 func init() {
 	// Initialize the project root to the working directory
 	ProjectRoot, _ = os.Getwd()
 
+	log = newLogger(false)
+}
+
+// Configure switches to JSON output for production and sets the minimum level
+func Configure(production bool, levelName string) error {
+	if err := SetLevel(levelName); err != nil {
+		return err
+	}
+	log = newLogger(production)
+	return nil
+}
+
+// SetLevel changes the minimum level that is logged; it can be called at any time
+func SetLevel(name string) error {
+	return level.UnmarshalText([]byte(name))
+}
+
+// newLogger builds a logger writing to stdout, as JSON when asked to
+func newLogger(json bool) *zap.Logger {
 	// Define the encoder configuration
 	encoderConfig := zapcore.EncoderConfig{
 		TimeKey:        "timestamp",
@@ -59,21 +80,19 @@ func init() {
 	}
 
 	encoder := zapcore.NewConsoleEncoder(encoderConfig)
-	level := zap.DebugLevel
-	if config.GetOrDefaultString("ENV", "") == "production" {
+	if json {
 		encoder = zapcore.NewJSONEncoder(encoderConfig)
-		level = zap.InfoLevel
 	}
 
 	// Create a new core that writes to stdout
 	core := zapcore.NewCore(
 		encoder,
 		zapcore.AddSync(os.Stdout),
-		zap.NewAtomicLevelAt(level),
+		level,
 	)
 
 	// Create the logger with stack traces for errors
-	log = zap.New(core,
+	return zap.New(core,
 		zap.AddCaller(),
 		zap.AddCallerSkip(1),
 		zap.AddStacktrace(zapcore.ErrorLevel), // Add stack traces for Error level and above
@@ -157,7 +176,7 @@ func formatContextInfo(ctx context.Context) string {
 	}
 
 	// Get debug ID if exists
-	if debugID, ok := ctx.Value(UserAgentKey).(string); ok && debugID != "" {
+	if debugID, ok := ctx.Value(DebugIDKey).(string); ok && debugID != "" {
 		if hasItems {
 			sb.WriteString(" ")
 		}
@@ -166,13 +185,23 @@ func formatContextInfo(ctx context.Context) string {
 		hasItems = true
 	}
 
-	// Get trace ID if exists
-	if traceID, ok := ctx.Value(TraceIDKey).(string); ok && traceID != "" {
+	// Get trace and span IDs from the active span, falling back to a trace ID stored in the context
+	spanContext := trace.SpanContextFromContext(ctx)
+	traceID, _ := ctx.Value(TraceIDKey).(string)
+	if spanContext.HasTraceID() {
+		traceID = spanContext.TraceID().String()
+	}
+	if traceID != "" {
 		if hasItems {
 			sb.WriteString(" ")
 		}
 		sb.WriteString("trace_id ")
 		sb.WriteString(traceID)
+		hasItems = true
+	}
+	if spanContext.HasSpanID() {
+		sb.WriteString(" span_id ")
+		sb.WriteString(spanContext.SpanID().String())
 	}
 
 	if ip, ok := ctx.Value(ClientIpKey).(string); ok && ip != "" {
@@ -0,0 +1,78 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/logger"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// IMailer sends email
+type IMailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewMailer returns an SMTP mailer when a host is configured, otherwise a mailer that only logs messages
+func NewMailer(cfg config.MailConfig) IMailer {
+	if cfg.Host == "" {
+		return &LogMailer{}
+	}
+
+	from := cfg.From
+	if from == "" {
+		from = "no-reply@" + cfg.Host
+	}
+
+	return &SMTPMailer{
+		addr:     net.JoinHostPort(cfg.Host, cfg.Port),
+		host:     cfg.Host,
+		username: cfg.Username,
+		password: cfg.Password,
+		from:     from,
+	}
+}
+
+// SMTPMailer sends email through an SMTP server
+type SMTPMailer struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+// Send delivers the message, authenticating when a username is configured
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		m.from, strings.Join(msg.To, ", "), msg.Subject, msg.Body)
+
+	if err := smtp.SendMail(m.addr, auth, m.from, msg.To, []byte(body)); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
+
+// LogMailer writes messages to the log instead of sending them, for development
+type LogMailer struct{}
+
+// Send logs the message
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	logger.InfoF(ctx, "Mail to %s: %s\n%s", strings.Join(msg.To, ", "), msg.Subject, msg.Body)
+	return nil
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const startTimeKey = "metrics:start_time"
+
+// InstrumentDB registers GORM callbacks that time every query and a collector for the connection pool stats
+func InstrumentDB(db *gorm.DB, name string) {
+	callbacks := db.Callback()
+
+	callbacks.Create().Before("gorm:begin_transaction").Register("metrics:before_create", before)
+	callbacks.Create().After("gorm:commit_or_rollback_transaction").Register("metrics:after_create", after("create"))
+	callbacks.Query().Before("gorm:query").Register("metrics:before_query", before)
+	callbacks.Query().After("gorm:after_query").Register("metrics:after_query", after("query"))
+	callbacks.Update().Before("gorm:begin_transaction").Register("metrics:before_update", before)
+	callbacks.Update().After("gorm:commit_or_rollback_transaction").Register("metrics:after_update", after("update"))
+	callbacks.Delete().Before("gorm:begin_transaction").Register("metrics:before_delete", before)
+	callbacks.Delete().After("gorm:commit_or_rollback_transaction").Register("metrics:after_delete", after("delete"))
+	callbacks.RowQuery().Before("gorm:row_query").Register("metrics:before_row_query", before)
+	callbacks.RowQuery().After("gorm:row_query").Register("metrics:after_row_query", after("row_query"))
+
+	Registry.MustRegister(collectors.NewDBStatsCollector(db.DB(), name))
+}
+
+// before records when the query started
+func before(scope *gorm.Scope) {
+	scope.Set(startTimeKey, time.Now())
+}
+
+// after observes the query duration and counts errors for the given operation
+func after(operation string) func(scope *gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		value, ok := scope.Get(startTimeKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := scope.TableName()
+		DBQueryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+
+		if scope.HasError() && !gorm.IsRecordNotFoundError(scope.DB().Error) {
+			DBQueryErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
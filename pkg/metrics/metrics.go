@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every metric exposed by the application
+var Registry = prometheus.NewRegistry()
+
+// HTTP metrics, labelled by route template rather than raw path to keep cardinality bounded
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total number of HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method and route template.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	HTTPRequestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Number of HTTP requests currently being served.",
+	})
+)
+
+// Database metrics
+var (
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Database query latency by operation and table.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation", "table"})
+
+	DBQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Database queries that returned an error, excluding record not found.",
+	}, []string{"operation", "table"})
+)
+
+// Authentication metrics
+var (
+	LoginAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_login_attempts_total",
+		Help: "Login attempts by result (success or failure).",
+	}, []string{"result"})
+
+	TokenValidationFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_token_validation_failures_total",
+		Help: "Rejected bearer tokens by reason.",
+	}, []string{"reason"})
+)
+
+// Audit metrics
+var (
+	AuditWriteFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "audit_write_failures_total",
+		Help: "Audit events that could not be recorded.",
+	})
+)
+
+// Cache metrics
+var CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "cache_requests_total",
+	Help: "Cache lookups by cache and result (hit or miss).",
+}, []string{"cache", "result"})
+
+// Rate limiting metrics
+var RateLimitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "rate_limit_rejections_total",
+	Help: "Requests rejected with 429 by rate limit policy.",
+}, []string{"policy"})
+
+// init registers the application metrics along with the Go runtime and process collectors
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		HTTPRequestsInFlight,
+		DBQueryDuration,
+		DBQueryErrors,
+		LoginAttempts,
+		TokenValidationFailures,
+		RateLimitRejections,
+		AuditWriteFailures,
+		CacheRequests,
+	)
+}
+
+// Handler returns the Prometheus scrape handler. When token is set, scrapes must send it as a bearer token.
+func Handler(token string) http.Handler {
+	handler := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+	if token == "" {
+		return handler
+	}
+
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
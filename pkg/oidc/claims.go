@@ -0,0 +1,62 @@
+package oidc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Claims are the verified claims of an ID token
+type Claims map[string]interface{}
+
+// Subject returns the provider's identifier for the user
+func (c Claims) Subject() string {
+	return c.String("sub")
+}
+
+// Email returns the email claim
+func (c Claims) Email() string {
+	return strings.TrimSpace(c.String("email"))
+}
+
+// EmailVerified reports whether the provider has verified the email; some providers send the
+// claim as a string
+func (c Claims) EmailVerified() bool {
+	switch v := c["email_verified"].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// String returns a string claim, or "" when it is missing or not a string
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Strings returns a claim as a list: a list claim's scalar elements or a scalar claim on its own
+func (c Claims) Strings(name string) []string {
+	switch v := c[name].(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if _, nested := item.(map[string]interface{}); !nested {
+				values = append(values, fmt.Sprint(item))
+			}
+		}
+		return values
+	case map[string]interface{}:
+		return nil
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+// Has reports whether the claim equals value or, for a list claim, contains it
+func (c Claims) Has(name, value string) bool {
+	return contains(c.Strings(name), value)
+}
@@ -0,0 +1,157 @@
+// Package oidctest runs a fake OpenID Connect provider for tests, in the manner of net/http/httptest
+package oidctest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/userblog/management/pkg/oidc"
+	"github.com/userblog/management/pkg/token"
+)
+
+// Provider is an OpenID Connect provider serving discovery, a key set and a token endpoint that
+// checks PKCE. Its issuer is the URL of the test server.
+type Provider struct {
+	Issuer   string
+	ClientID string
+
+	server *httptest.Server
+	key    *token.Key
+
+	mu     sync.Mutex
+	grants map[string]grant
+}
+
+// grant is an authorization code waiting to be redeemed
+type grant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+// NewProvider starts a provider issuing ID tokens to clientID, stopped when the test ends
+func NewProvider(t testing.TB, clientID string) *Provider {
+	t.Helper()
+
+	key, err := token.Generate(token.EdDSA)
+	if err != nil {
+		t.Fatalf("oidctest: generating key: %v", err)
+	}
+
+	p := &Provider{ClientID: clientID, key: key, grants: map[string]grant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/keys", p.keys)
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+	p.Issuer = p.server.URL
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// Authorize plays the user logging in at the authorization URL a client sent them to, and
+// returns the code and state the provider redirects back with. The ID token has the standard
+// claims for the client, with the login's nonce, overridden by claims.
+func (p *Provider) Authorize(t testing.TB, authURL string, claims jwt.MapClaims) (code, state string) {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("oidctest: invalid authorization URL: %v", err)
+	}
+	query := u.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("oidctest: authorization URL has no S256 code challenge: %s", authURL)
+	}
+
+	idClaims := p.Claims(query.Get("nonce"))
+	for name, value := range claims {
+		idClaims[name] = value
+	}
+
+	code, err = oidc.RandomString(16)
+	if err != nil {
+		t.Fatalf("oidctest: %v", err)
+	}
+	p.mu.Lock()
+	p.grants[code] = grant{challenge: query.Get("code_challenge"), claims: idClaims}
+	p.mu.Unlock()
+	return code, query.Get("state")
+}
+
+// Claims returns the standard claims of an ID token for the client with the nonce
+func (p *Provider) Claims(nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   p.Issuer,
+		"aud":   p.ClientID,
+		"sub":   "subject-1",
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": nonce,
+	}
+}
+
+// Sign returns an ID token with the claims, signed with the provider's key
+func (p *Provider) Sign(t testing.TB, claims jwt.MapClaims) string {
+	t.Helper()
+
+	tok := jwt.NewWithClaims(p.key.Method(), claims)
+	tok.Header["kid"] = p.key.ID
+	signed, err := tok.SignedString(p.key.PrivateKey)
+	if err != nil {
+		t.Fatalf("oidctest: signing ID token: %v", err)
+	}
+	return signed
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                 p.Issuer,
+		"authorization_endpoint": p.Issuer + "/authorize",
+		"token_endpoint":         p.Issuer + "/token",
+		"jwks_uri":               p.Issuer + "/keys",
+	})
+}
+
+func (p *Provider) keys(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, token.JWKS{Keys: []*token.JSONWebKey{p.key.JWK()}})
+}
+
+// token redeems a code once, and only with the verifier whose challenge started the login
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	p.mu.Lock()
+	g, ok := p.grants[r.PostForm.Get("code")]
+	delete(p.grants, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	if !ok || oidc.Challenge(r.PostForm.Get("code_verifier")) != g.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	tok := jwt.NewWithClaims(p.key.Method(), g.claims)
+	tok.Header["kid"] = p.key.ID
+	signed, err := tok.SignedString(p.key.PrivateKey)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id_token": signed, "token_type": "Bearer"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
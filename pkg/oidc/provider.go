@@ -0,0 +1,299 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/token"
+)
+
+// keysReloadInterval limits how often an ID token with an unknown kid refetches the provider's keys
+const keysReloadInterval = time.Minute
+
+// signingAlgorithms are the ID token algorithms accepted from providers
+var signingAlgorithms = map[string]bool{
+	"RS256": true, "RS384": true, "RS512": true,
+	"PS256": true, "PS384": true, "PS512": true,
+	"ES256": true, "ES384": true, "ES512": true,
+	token.EdDSA: true,
+}
+
+// Provider is an OpenID Connect provider used with the authorization code flow and PKCE.
+// Its endpoints are discovered from the issuer on first use.
+type Provider struct {
+	name   string
+	cfg    config.OIDCProvider
+	client *http.Client
+
+	mu       sync.Mutex
+	meta     *metadata
+	keys     map[string]*token.JSONWebKey
+	keysTime time.Time
+}
+
+// metadata is the part of the discovery document we use
+type metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// NewProvider creates a provider from its configuration
+func NewProvider(name string, cfg config.OIDCProvider) *Provider {
+	return &Provider{
+		name:   name,
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name returns the name the provider is configured under
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// Config returns the provider's configuration
+func (p *Provider) Config() config.OIDCProvider {
+	return p.cfg
+}
+
+// AuthCodeURL returns the authorization endpoint URL that starts a login
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	scopes := p.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {Challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return meta.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// tokenResponse is the body of a successful token request
+type tokenResponse struct {
+	IDToken string `json:"id_token"`
+}
+
+// tokenError is the body of a failed token request
+type tokenError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange redeems an authorization code with its PKCE verifier and returns the raw ID token
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	// client_secret_basic is the default; fall back to client_secret_post when it isn't supported
+	basic := p.cfg.ClientSecret != "" && (len(meta.TokenAuthMethods) == 0 || contains(meta.TokenAuthMethods, "client_secret_basic"))
+	if !basic {
+		form.Set("client_id", p.cfg.ClientID)
+		if p.cfg.ClientSecret != "" {
+			form.Set("client_secret", p.cfg.ClientSecret)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if basic {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body tokenError
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return "", fmt.Errorf("token request returned %s: %s %s", resp.Status, body.Error, body.ErrorDescription)
+	}
+
+	var body tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid token response: %w", err)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("token response has no id_token")
+	}
+	return body.IDToken, nil
+}
+
+// Verify checks an ID token's signature against the provider's keys, its issuer, audience,
+// time based claims and nonce, and returns its claims
+func (p *Provider) Verify(ctx context.Context, rawIDToken, nonce string) (Claims, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, func(t *jwt.Token) (interface{}, error) {
+		if !signingAlgorithms[t.Method.Alg()] {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		key, err := p.key(ctx, meta, kid)
+		if err != nil {
+			return nil, err
+		}
+		if key.Algorithm != "" && key.Algorithm != t.Method.Alg() {
+			return nil, fmt.Errorf("key %q is not for %v", kid, t.Header["alg"])
+		}
+		return key.PublicKey()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	idClaims := Claims(claims)
+	if idClaims.String("iss") != meta.Issuer {
+		return nil, errors.New("invalid ID token: unexpected issuer")
+	}
+	audiences := idClaims.Strings("aud")
+	if !contains(audiences, p.cfg.ClientID) {
+		return nil, errors.New("invalid ID token: unexpected audience")
+	}
+	if azp := idClaims.String("azp"); len(audiences) > 1 && azp != p.cfg.ClientID {
+		return nil, errors.New("invalid ID token: unexpected authorized party")
+	}
+	if _, ok := claims["exp"]; !ok || idClaims.Subject() == "" {
+		return nil, errors.New("invalid ID token: missing exp or sub")
+	}
+	if idClaims.String("nonce") != nonce {
+		return nil, errors.New("invalid ID token: nonce mismatch")
+	}
+	return idClaims, nil
+}
+
+// discover fetches and caches the provider's discovery document
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+
+	var meta metadata
+	endpoint := strings.TrimRight(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, endpoint, &meta); err != nil {
+		return nil, fmt.Errorf("discovery for %s failed: %w", p.name, err)
+	}
+	// The issuer must match exactly, or a compromised document could vouch for another issuer's tokens
+	if meta.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("discovery for %s returned issuer %q, expected %q", p.name, meta.Issuer, p.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery for %s is missing endpoints", p.name)
+	}
+
+	p.meta = &meta
+	return p.meta, nil
+}
+
+// key returns the provider key with the given kid, refetching the key set at most once per
+// keysReloadInterval when the kid is unknown, as after a rotation
+func (p *Provider) key(ctx context.Context, meta *metadata, kid string) (*token.JSONWebKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.keysTime) < keysReloadInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var set struct {
+		Keys []*token.JSONWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, meta.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("fetching keys for %s failed: %w", p.name, err)
+	}
+	p.keys = make(map[string]*token.JSONWebKey, len(set.Keys))
+	for _, key := range set.Keys {
+		if key.Use == "" || key.Use == "sig" {
+			p.keys[key.KeyID] = key
+		}
+	}
+	p.keysTime = time.Now()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// getJSON decodes the JSON body of a GET request
+func (p *Provider) getJSON(ctx context.Context, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// contains reports whether list has value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package oidc_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/userblog/management/pkg/config"
+	"github.com/userblog/management/pkg/oidc"
+	"github.com/userblog/management/pkg/oidc/oidctest"
+)
+
+// newProvider starts a fake provider and a client configured for it
+func newProvider(t *testing.T) (*oidctest.Provider, *oidc.Provider) {
+	fake := oidctest.NewProvider(t, "blog-client")
+	client := oidc.NewProvider("fake", config.OIDCProvider{
+		Issuer:      fake.Issuer,
+		ClientID:    "blog-client",
+		RedirectURL: "http://localhost/api/auth/oidc/fake/callback",
+	})
+	return fake, client
+}
+
+func TestExchangeChecksPKCE(t *testing.T) {
+	ctx := context.Background()
+	fake, client := newProvider(t)
+
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := client.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	u, _ := url.Parse(authURL)
+	if got := u.Query().Get("code_challenge"); got != oidc.Challenge(verifier) {
+		t.Errorf("code_challenge = %q, want the S256 challenge of the verifier", got)
+	}
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		code, _ := fake.Authorize(t, authURL, nil)
+		other, _ := oidc.NewVerifier()
+		if _, err := client.Exchange(ctx, code, other); err == nil {
+			t.Error("Exchange succeeded with another verifier")
+		}
+	})
+
+	t.Run("right verifier", func(t *testing.T) {
+		code, _ := fake.Authorize(t, authURL, nil)
+		rawIDToken, err := client.Exchange(ctx, code, verifier)
+		if err != nil {
+			t.Fatalf("Exchange: %v", err)
+		}
+		claims, err := client.Verify(ctx, rawIDToken, "nonce-1")
+		if err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+		if claims.Subject() != "subject-1" {
+			t.Errorf("Subject = %q, want subject-1", claims.Subject())
+		}
+
+		// Codes are single use
+		if _, err := client.Exchange(ctx, code, verifier); err == nil {
+			t.Error("Exchange redeemed a code twice")
+		}
+	})
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	ctx := context.Background()
+	fake, client := newProvider(t)
+
+	tests := []struct {
+		name   string
+		claims func(jwt.MapClaims)
+		nonce  string
+	}{
+		{name: "nonce mismatch", claims: func(jwt.MapClaims) {}, nonce: "other"},
+		{name: "other audience", claims: func(c jwt.MapClaims) { c["aud"] = "another-client" }},
+		{name: "other audiences without ours", claims: func(c jwt.MapClaims) { c["aud"] = []string{"a", "b"} }},
+		{name: "another authorized party", claims: func(c jwt.MapClaims) { c["aud"] = []string{"blog-client", "b"}; c["azp"] = "b" }},
+		{name: "other issuer", claims: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{name: "expired", claims: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{name: "no subject", claims: func(c jwt.MapClaims) { delete(c, "sub") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := fake.Claims("nonce-1")
+			tt.claims(claims)
+			nonce := tt.nonce
+			if nonce == "" {
+				nonce = "nonce-1"
+			}
+
+			if _, err := client.Verify(ctx, fake.Sign(t, claims), nonce); err == nil {
+				t.Error("Verify accepted the token")
+			}
+		})
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		if _, err := client.Verify(ctx, fake.Sign(t, fake.Claims("nonce-1")), "nonce-1"); err != nil {
+			t.Errorf("Verify: %v", err)
+		}
+	})
+}
+
+func TestStateStore(t *testing.T) {
+	ctx := context.Background()
+	store := oidc.NewMemoryStateStore()
+
+	if err := store.Save(ctx, "live", oidc.Pending{Provider: "fake", ExpiresAt: time.Now().Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(ctx, "expired", oidc.Pending{Provider: "fake", ExpiresAt: time.Now().Add(-time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if pending, _ := store.Take(ctx, "live"); pending == nil || pending.Provider != "fake" {
+		t.Errorf("Take(live) = %v, want the pending login", pending)
+	}
+	if pending, _ := store.Take(ctx, "live"); pending != nil {
+		t.Error("Take returned a state twice")
+	}
+	if pending, _ := store.Take(ctx, "expired"); pending != nil {
+		t.Error("Take returned an expired state")
+	}
+	if pending, _ := store.Take(ctx, "unknown"); pending != nil {
+		t.Error("Take returned an unknown state")
+	}
+}
@@ -0,0 +1,70 @@
+package oidc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Pending is a login that was sent to a provider and awaits its callback
+type Pending struct {
+	Provider  string
+	Verifier  string
+	Nonce     string
+	ExpiresAt time.Time
+}
+
+// StateStore keeps pending logins by their state parameter. Take returns a pending login at
+// most once, or nil when the state is unknown or expired.
+type StateStore interface {
+	Save(ctx context.Context, state string, pending Pending) error
+	Take(ctx context.Context, state string) (*Pending, error)
+}
+
+// memoryStateStore keeps pending logins in process memory; all callbacks must reach the
+// instance that started the login
+type memoryStateStore struct {
+	mu        sync.Mutex
+	pending   map[string]Pending
+	lastSweep time.Time
+}
+
+// NewMemoryStateStore creates a StateStore backed by process memory
+func NewMemoryStateStore() StateStore {
+	return &memoryStateStore{pending: map[string]Pending{}}
+}
+
+// Save stores a pending login, dropping expired ones at most once a minute
+func (s *memoryStateStore) Save(ctx context.Context, state string, pending Pending) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for key, p := range s.pending {
+			if !p.ExpiresAt.After(now) {
+				delete(s.pending, key)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	s.pending[state] = pending
+	return nil
+}
+
+// Take removes and returns the pending login for state
+func (s *memoryStateStore) Take(ctx context.Context, state string) (*Pending, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending, ok := s.pending[state]
+	if !ok {
+		return nil, nil
+	}
+	delete(s.pending, state)
+	if !pending.ExpiresAt.After(time.Now()) {
+		return nil, nil
+	}
+	return &pending, nil
+}
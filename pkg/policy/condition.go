@@ -0,0 +1,342 @@
+package policy
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Conditions compare two operands, such as resource.user_id == subject.id, or test a single
+// one for truth, such as resource.published. Operands are attributes named subject.<name>,
+// resource.<name> or env.<name>, or literals: numbers, true, false, quoted or bare strings and
+// lists like [editor, 'chief editor']. The operators are ==, !=, <, <=, >, >=, in (the left
+// value is in the list on the right) and contains (the list or string on the left contains the
+// right value). A condition on an attribute the request doesn't have is false.
+
+// operators lists the comparison operators, longest first so <= isn't read as <
+var operators = []string{"==", "!=", "<=", ">=", "<", ">", "in", "contains"}
+
+// scopes are the attribute sets operands can refer to
+var scopes = []string{"subject", "resource", "env"}
+
+// condition is a parsed condition
+type condition struct {
+	expr  string
+	left  operand
+	op    string
+	right operand
+}
+
+// operand is an attribute reference or a literal value
+type operand struct {
+	scope string
+	name  string
+	value interface{}
+}
+
+// parseCondition parses a condition expression
+func parseCondition(expr string) (*condition, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, fmt.Errorf("condition %q: %w", expr, err)
+	}
+
+	cond := &condition{expr: expr}
+	switch len(tokens) {
+	case 1:
+		cond.left = tokens[0].operand
+	case 3:
+		if !tokens[1].isOperator {
+			return nil, fmt.Errorf("condition %q: expected an operator, got %q", expr, tokens[1].text)
+		}
+		cond.left, cond.op, cond.right = tokens[0].operand, tokens[1].text, tokens[2].operand
+	default:
+		return nil, fmt.Errorf("condition %q: expected a value or a comparison like a == b", expr)
+	}
+	for _, token := range []token{tokens[0], tokens[len(tokens)-1]} {
+		if token.isOperator {
+			return nil, fmt.Errorf("condition %q: operator %q needs a value on each side", expr, token.text)
+		}
+	}
+	return cond, nil
+}
+
+// eval evaluates the condition against the request, explaining a false result caused by a
+// missing attribute
+func (c *condition) eval(req Request) (bool, string) {
+	left, ok := c.left.resolve(req)
+	if !ok {
+		return false, c.left.String() + " is not set"
+	}
+	if c.op == "" {
+		return truthy(left), ""
+	}
+	right, ok := c.right.resolve(req)
+	if !ok {
+		return false, c.right.String() + " is not set"
+	}
+
+	switch c.op {
+	case "==":
+		return equal(left, right), ""
+	case "!=":
+		return !equal(left, right), ""
+	case "<", "<=", ">", ">=":
+		cmp, ok := compare(left, right)
+		if !ok {
+			return false, "values are not both numbers or both strings"
+		}
+		switch c.op {
+		case "<":
+			return cmp < 0, ""
+		case "<=":
+			return cmp <= 0, ""
+		case ">":
+			return cmp > 0, ""
+		default:
+			return cmp >= 0, ""
+		}
+	case "in":
+		list, ok := right.([]interface{})
+		if !ok {
+			return false, c.right.String() + " is not a list"
+		}
+		return contains(list, left), ""
+	default:
+		if s, ok := left.(string); ok {
+			sub, ok := right.(string)
+			return ok && strings.Contains(s, sub), ""
+		}
+		list, ok := left.([]interface{})
+		if !ok {
+			return false, c.left.String() + " is not a list or string"
+		}
+		return contains(list, right), ""
+	}
+}
+
+// resolve returns the operand's value, and false for an attribute the request doesn't have
+func (o operand) resolve(req Request) (interface{}, bool) {
+	if o.scope == "" {
+		return o.value, true
+	}
+
+	var attrs Attributes
+	switch o.scope {
+	case "subject":
+		attrs = req.Subject
+	case "resource":
+		attrs = req.Resource
+	default:
+		attrs = req.Environment
+	}
+	value, ok := attrs[o.name]
+	if !ok || value == nil {
+		return nil, false
+	}
+	return normalize(value), true
+}
+
+// String returns the operand as written
+func (o operand) String() string {
+	if o.scope != "" {
+		return o.scope + "." + o.name
+	}
+	return fmt.Sprint(o.value)
+}
+
+// token is a lexical element of a condition
+type token struct {
+	text       string
+	isOperator bool
+	operand    operand
+}
+
+// tokenize splits a condition into operands and operators
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	rest := strings.TrimSpace(expr)
+	for rest != "" {
+		var tok token
+		var err error
+		switch {
+		case rest[0] == '\'' || rest[0] == '"':
+			var s string
+			s, rest, err = readQuoted(rest)
+			tok = token{text: s, operand: operand{value: s}}
+		case rest[0] == '[':
+			var list []interface{}
+			list, rest, err = readList(rest)
+			tok = token{text: "[...]", operand: operand{value: list}}
+		default:
+			if op := readOperator(rest); op != "" {
+				tok = token{text: op, isOperator: true}
+				rest = rest[len(op):]
+				break
+			}
+			end := strings.IndexAny(rest, " \t=!<>")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("unexpected %q", rest[:1])
+			}
+			word := rest[:end]
+			rest = rest[end:]
+			tok = token{text: word, operand: parseWord(word)}
+		}
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, tok)
+		rest = strings.TrimSpace(rest)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty condition")
+	}
+	return tokens, nil
+}
+
+// readOperator returns the operator at the start of s, if any. Word operators must be
+// followed by a space.
+func readOperator(s string) string {
+	for _, op := range operators {
+		if !strings.HasPrefix(s, op) {
+			continue
+		}
+		if op == "in" || op == "contains" {
+			if len(s) == len(op) || (s[len(op)] != ' ' && s[len(op)] != '\t') {
+				continue
+			}
+		}
+		return op
+	}
+	return ""
+}
+
+// readQuoted reads a quoted string from the start of s
+func readQuoted(s string) (string, string, error) {
+	end := strings.IndexByte(s[1:], s[0])
+	if end < 0 {
+		return "", "", fmt.Errorf("unterminated string %s", s)
+	}
+	return s[1 : end+1], s[end+2:], nil
+}
+
+// readList reads a list literal of quoted or bare values from the start of s
+func readList(s string) ([]interface{}, string, error) {
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return nil, "", fmt.Errorf("unterminated list %s", s)
+	}
+	list := []interface{}{}
+	for _, item := range strings.Split(s[1:end], ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if len(item) >= 2 && (item[0] == '\'' || item[0] == '"') && item[len(item)-1] == item[0] {
+			list = append(list, item[1:len(item)-1])
+			continue
+		}
+		list = append(list, parseWord(item).value)
+	}
+	return list, s[end+1:], nil
+}
+
+// parseWord reads an unquoted word as an attribute reference, a boolean, a number or a string
+func parseWord(word string) operand {
+	for _, scope := range scopes {
+		if name, ok := strings.CutPrefix(word, scope+"."); ok && name != "" {
+			return operand{scope: scope, name: name}
+		}
+	}
+	switch word {
+	case "true":
+		return operand{value: true}
+	case "false":
+		return operand{value: false}
+	}
+	if n, err := strconv.ParseFloat(word, 64); err == nil {
+		return operand{value: n}
+	}
+	return operand{value: word}
+}
+
+// normalize converts numbers to float64 and slices to []interface{} so values compare by kind
+func normalize(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Slice, reflect.Array:
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = normalize(v.Index(i).Interface())
+		}
+		return list
+	}
+	return value
+}
+
+// equal reports whether two normalized scalar values are the same
+func equal(a, b interface{}) bool {
+	a, b = normalize(a), normalize(b)
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// compare orders two numbers or two strings
+func compare(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		switch {
+		case !ok:
+			return 0, false
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		y, ok := b.(string)
+		return strings.Compare(x, y), ok
+	}
+	return 0, false
+}
+
+// contains reports whether the list has the value
+func contains(list []interface{}, value interface{}) bool {
+	for _, item := range list {
+		if equal(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// truthy reports whether a value counts as true on its own
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	}
+	return value != nil
+}
@@ -0,0 +1,185 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Effect is what a matching rule does to a decision
+type Effect string
+
+const (
+	Allow Effect = "allow"
+	Deny  Effect = "deny"
+)
+
+// Attributes describe the subject, resource or environment of a request by name
+type Attributes map[string]interface{}
+
+// Rule allows or denies its actions when every condition holds. Actions are resource:action
+// pairs such as blog:update, and may use * for any resource or action.
+type Rule struct {
+	Name        string
+	Description string
+	Effect      Effect
+	Actions     []string
+	When        []string
+}
+
+// Request is a decision to make: whether the subject may perform the action on the resource
+type Request struct {
+	Action      string
+	Subject     Attributes
+	Resource    Attributes
+	Environment Attributes
+	// Role names the subject's role when it grants the action by itself, empty otherwise
+	Role string
+}
+
+// Decision is the outcome of a request, with every rule that applied to its action
+type Decision struct {
+	Allowed bool         `json:"allowed"`
+	Action  string       `json:"action"`
+	Rule    string       `json:"rule,omitempty"`
+	Reason  string       `json:"reason"`
+	Rules   []RuleResult `json:"rules"`
+}
+
+// RuleResult shows how a rule's conditions evaluated
+type RuleResult struct {
+	Name       string            `json:"name"`
+	Effect     Effect            `json:"effect"`
+	Matched    bool              `json:"matched"`
+	Conditions []ConditionResult `json:"conditions,omitempty"`
+}
+
+// ConditionResult is the value of one condition, with why it was false when an attribute is missing
+type ConditionResult struct {
+	Condition string `json:"condition"`
+	Result    bool   `json:"result"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// compiledRule is a rule with its conditions parsed
+type compiledRule struct {
+	Rule
+	conditions []*condition
+}
+
+// Engine evaluates requests against a set of rules
+type Engine struct {
+	rules []compiledRule
+}
+
+// Compile parses the rules' conditions and returns an engine that evaluates them
+func Compile(rules []Rule) (*Engine, error) {
+	engine := &Engine{}
+	for i, rule := range rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i+1)
+		}
+		if rule.Effect != Allow && rule.Effect != Deny {
+			return nil, fmt.Errorf("%s: effect must be allow or deny, got %q", name, rule.Effect)
+		}
+		if len(rule.Actions) == 0 {
+			return nil, fmt.Errorf("%s: needs at least one action", name)
+		}
+		for _, action := range rule.Actions {
+			if action != "*" && !strings.Contains(action, ":") {
+				return nil, fmt.Errorf("%s: action %q must be resource:action or *", name, action)
+			}
+		}
+
+		compiled := compiledRule{Rule: rule}
+		compiled.Name = name
+		for _, expr := range rule.When {
+			cond, err := parseCondition(expr)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			compiled.conditions = append(compiled.conditions, cond)
+		}
+		engine.rules = append(engine.rules, compiled)
+	}
+	return engine, nil
+}
+
+// Evaluate decides the request. A matching deny rule always wins; otherwise the action is
+// allowed by the subject's role or by a matching allow rule, and denied when neither applies.
+func (e *Engine) Evaluate(req Request) *Decision {
+	decision := &Decision{Action: req.Action, Rules: []RuleResult{}}
+
+	var allowedBy, deniedBy *compiledRule
+	for i := range e.rules {
+		rule := &e.rules[i]
+		if !rule.appliesTo(req.Action) {
+			continue
+		}
+
+		result := rule.evaluate(req)
+		decision.Rules = append(decision.Rules, result)
+		if !result.Matched {
+			continue
+		}
+		if rule.Effect == Deny && deniedBy == nil {
+			deniedBy = rule
+		}
+		if rule.Effect == Allow && allowedBy == nil {
+			allowedBy = rule
+		}
+	}
+
+	switch {
+	case deniedBy != nil:
+		decision.Rule = deniedBy.Name
+		decision.Reason = describe("denied by rule "+deniedBy.Name, deniedBy.Description)
+	case req.Role != "":
+		decision.Allowed = true
+		decision.Rule = "role:" + req.Role
+		decision.Reason = fmt.Sprintf("granted by the %s role", req.Role)
+	case allowedBy != nil:
+		decision.Allowed = true
+		decision.Rule = allowedBy.Name
+		decision.Reason = describe("allowed by rule "+allowedBy.Name, allowedBy.Description)
+	default:
+		decision.Reason = "no role permission or rule allows " + req.Action
+	}
+	return decision
+}
+
+// appliesTo reports whether one of the rule's actions matches the action
+func (r *compiledRule) appliesTo(action string) bool {
+	resource, verb, _ := strings.Cut(action, ":")
+	for _, pattern := range r.Actions {
+		if pattern == "*" {
+			return true
+		}
+		patternResource, patternVerb, _ := strings.Cut(pattern, ":")
+		if (patternResource == "*" || patternResource == resource) && (patternVerb == "*" || patternVerb == verb) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluate checks every condition, so explanations show them all, and matches when all hold
+func (r *compiledRule) evaluate(req Request) RuleResult {
+	result := RuleResult{Name: r.Name, Effect: r.Effect, Matched: true}
+	for _, cond := range r.conditions {
+		ok, detail := cond.eval(req)
+		result.Conditions = append(result.Conditions, ConditionResult{Condition: cond.expr, Result: ok, Detail: detail})
+		if !ok {
+			result.Matched = false
+		}
+	}
+	return result
+}
+
+// describe appends a rule's description to a reason
+func describe(reason, description string) string {
+	if description == "" {
+		return reason
+	}
+	return reason + ": " + description
+}
@@ -0,0 +1,109 @@
+package policy
+
+import "testing"
+
+func TestEvaluate(t *testing.T) {
+	engine, err := Compile([]Rule{
+		{
+			Name:    "authors-only",
+			Effect:  Deny,
+			Actions: []string{"blog:update", "blog:delete"},
+			When:    []string{"resource.user_id != subject.id"},
+		},
+		{
+			Name:    "editors-publish",
+			Effect:  Allow,
+			Actions: []string{"blog:publish"},
+			When:    []string{"subject.role == editor", "resource.published == false"},
+		},
+		{
+			Name:    "no-impersonated-deletes",
+			Effect:  Deny,
+			Actions: []string{"*:delete"},
+			When:    []string{"env.impersonating"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		req     Request
+		allowed bool
+		rule    string
+	}{
+		{
+			name:    "role allows its own blog",
+			req:     Request{Action: "blog:update", Subject: Attributes{"id": uint(1)}, Resource: Attributes{"user_id": uint(1)}, Role: "author"},
+			allowed: true,
+			rule:    "role:author",
+		},
+		{
+			name:    "deny wins over role",
+			req:     Request{Action: "blog:update", Subject: Attributes{"id": uint(1)}, Resource: Attributes{"user_id": uint(2)}, Role: "admin"},
+			allowed: false,
+			rule:    "authors-only",
+		},
+		{
+			name:    "deny on missing attribute does not match",
+			req:     Request{Action: "blog:delete", Subject: Attributes{"id": uint(1)}, Resource: Attributes{}, Role: "author"},
+			allowed: true,
+			rule:    "role:author",
+		},
+		{
+			name:    "wildcard deny wins over role",
+			req:     Request{Action: "user:delete", Subject: Attributes{"id": uint(1)}, Environment: Attributes{"impersonating": true}, Role: "admin"},
+			allowed: false,
+			rule:    "no-impersonated-deletes",
+		},
+		{
+			name:    "attribute rule allows without role",
+			req:     Request{Action: "blog:publish", Subject: Attributes{"role": "editor"}, Resource: Attributes{"published": false}},
+			allowed: true,
+			rule:    "editors-publish",
+		},
+		{
+			name:    "attribute rule needs every condition",
+			req:     Request{Action: "blog:publish", Subject: Attributes{"role": "editor"}, Resource: Attributes{"published": true}},
+			allowed: false,
+		},
+		{
+			name:    "nothing allows",
+			req:     Request{Action: "blog:create", Subject: Attributes{"role": "reader"}},
+			allowed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := engine.Evaluate(tt.req)
+			if decision.Allowed != tt.allowed {
+				t.Fatalf("Allowed = %v, want %v (%s)", decision.Allowed, tt.allowed, decision.Reason)
+			}
+			if decision.Rule != tt.rule {
+				t.Errorf("Rule = %q, want %q", decision.Rule, tt.rule)
+			}
+		})
+	}
+}
+
+func TestCompileRejectsInvalidRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{name: "unknown effect", rule: Rule{Effect: "maybe", Actions: []string{"blog:update"}}},
+		{name: "no actions", rule: Rule{Effect: Allow}},
+		{name: "action without resource", rule: Rule{Effect: Allow, Actions: []string{"update"}}},
+		{name: "bad condition", rule: Rule{Effect: Deny, Actions: []string{"blog:update"}, When: []string{"resource.user_id !="}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compile([]Rule{tt.rule}); err == nil {
+				t.Error("Compile succeeded, want an error")
+			}
+		})
+	}
+}
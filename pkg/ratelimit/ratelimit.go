@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/userblog/management/pkg/config"
+)
+
+// Key selects what a policy counts requests against
+type Key string
+
+const (
+	KeyIP    Key = "ip"    // client IP address
+	KeyUser  Key = "user"  // authenticated user ID, falling back to the client IP
+	KeyToken Key = "token" // bearer token, falling back to the client IP
+)
+
+// Policy is a token bucket: Limit requests are allowed per Period, with up to Burst at once
+type Policy struct {
+	Name   string
+	Key    Key
+	Limit  int
+	Period time.Duration
+	Burst  int
+}
+
+// Enabled reports whether the policy limits anything
+func (p Policy) Enabled() bool {
+	return p.Limit > 0 && p.Period > 0
+}
+
+// Capacity returns the bucket size, which defaults to Limit when Burst is unset
+func (p Policy) Capacity() int {
+	if p.Burst > 0 {
+		return p.Burst
+	}
+	return p.Limit
+}
+
+// Interval returns how long it takes to refill a single token
+func (p Policy) Interval() time.Duration {
+	return p.Period / time.Duration(p.Limit)
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed    bool
+	Limit      int           // bucket capacity
+	Remaining  int           // tokens left after this request
+	Reset      time.Duration // time until the bucket is full again
+	RetryAfter time.Duration // time until the next token is available, zero when allowed
+}
+
+// Store keeps token buckets. The in-memory store suits a single instance; a shared
+// implementation (for example backed by Redis) lets several instances enforce one limit.
+type Store interface {
+	// Take removes one token from the bucket identified by key, creating it full if needed
+	Take(ctx context.Context, key string, policy Policy) (Result, error)
+}
+
+// LoadPolicy returns the named policy from the active configuration. Fields the policy
+// leaves unset are taken from the default policy, and a disabled policy has no limit.
+func LoadPolicy(name string) Policy {
+	policies := config.Current().RateLimit.Policies
+	fallback := policies["default"]
+	configured, ok := policies[name]
+	if !ok {
+		configured = fallback
+	}
+
+	policy := Policy{
+		Name:   name,
+		Key:    Key(configured.Key),
+		Limit:  configured.Limit,
+		Period: configured.Period,
+		Burst:  configured.Burst,
+	}
+	if policy.Key == "" {
+		policy.Key = Key(fallback.Key)
+	}
+	if policy.Key == "" {
+		policy.Key = KeyIP
+	}
+	if policy.Limit == 0 {
+		policy.Limit = fallback.Limit
+	}
+	if policy.Period == 0 {
+		policy.Period = fallback.Period
+	}
+	if policy.Burst == 0 {
+		policy.Burst = fallback.Burst
+	}
+	if configured.Disabled {
+		policy.Limit = 0
+	}
+	return policy
+}
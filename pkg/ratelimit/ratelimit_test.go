@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/userblog/management/pkg/config"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	policy := Policy{Name: "login", Key: KeyIP, Limit: 2, Period: time.Hour, Burst: 3}
+
+	for i := 1; i <= 3; i++ {
+		result, err := store.Take(ctx, "10.0.0.1", policy)
+		if err != nil {
+			t.Fatalf("Take: %v", err)
+		}
+		if !result.Allowed || result.Remaining != 3-i || result.Limit != 3 || result.RetryAfter != 0 {
+			t.Errorf("request %d = %+v, want allowed with %d remaining of 3", i, result, 3-i)
+		}
+	}
+
+	result, _ := store.Take(ctx, "10.0.0.1", policy)
+	if result.Allowed || result.Remaining != 0 {
+		t.Errorf("request past the burst = %+v, want denied", result)
+	}
+	// Two tokens an hour is one every 30 minutes
+	if result.RetryAfter <= 29*time.Minute || result.RetryAfter > 30*time.Minute {
+		t.Errorf("RetryAfter = %s, want about 30m", result.RetryAfter)
+	}
+	if result.Reset <= 89*time.Minute || result.Reset > 90*time.Minute {
+		t.Errorf("Reset = %s, want about 90m to refill 3 tokens", result.Reset)
+	}
+
+	// Other keys have their own bucket
+	if result, _ := store.Take(ctx, "10.0.0.2", policy); !result.Allowed {
+		t.Errorf("another client was limited: %+v", result)
+	}
+}
+
+func TestMemoryStoreRefills(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	policy := Policy{Name: "api", Key: KeyUser, Limit: 50, Period: time.Second, Burst: 1}
+
+	if result, _ := store.Take(ctx, "user:1", policy); !result.Allowed {
+		t.Fatalf("first request = %+v, want allowed", result)
+	}
+	result, _ := store.Take(ctx, "user:1", policy)
+	if result.Allowed {
+		t.Fatalf("second request = %+v, want denied until a token refills", result)
+	}
+
+	time.Sleep(result.RetryAfter + 5*time.Millisecond)
+	if result, _ := store.Take(ctx, "user:1", policy); !result.Allowed {
+		t.Errorf("request after RetryAfter = %+v, want allowed", result)
+	}
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	store := NewMemoryStore().(*MemoryStore)
+	ctx := context.Background()
+
+	_, _ = store.Take(ctx, "idle", Policy{Limit: 1000, Period: time.Second})
+	_, _ = store.Take(ctx, "busy", Policy{Limit: 1, Period: time.Hour})
+
+	store.sweep(time.Now().Add(time.Second))
+	if _, ok := store.buckets["idle"]; ok {
+		t.Error("a refilled bucket was kept")
+	}
+	if _, ok := store.buckets["busy"]; !ok {
+		t.Error("a bucket still refilling was dropped")
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	previous := config.Current()
+	cfg := config.Default()
+	cfg.RateLimit.Policies = map[string]config.RateLimitPolicy{
+		"default": {Key: "ip", Limit: 100, Period: time.Minute, Burst: 20},
+		"login":   {Limit: 5},
+		"token":   {Key: "token", Period: time.Hour},
+		"uploads": {Disabled: true},
+	}
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(previous) })
+
+	tests := []struct {
+		name    string
+		want    Policy
+		enabled bool
+	}{
+		{name: "login", want: Policy{Name: "login", Key: KeyIP, Limit: 5, Period: time.Minute, Burst: 20}, enabled: true},
+		{name: "token", want: Policy{Name: "token", Key: KeyToken, Limit: 100, Period: time.Hour, Burst: 20}, enabled: true},
+		{name: "unknown", want: Policy{Name: "unknown", Key: KeyIP, Limit: 100, Period: time.Minute, Burst: 20}, enabled: true},
+		{name: "uploads", want: Policy{Name: "uploads", Key: KeyIP, Limit: 0, Period: time.Minute, Burst: 20}},
+	}
+	for _, tt := range tests {
+		got := LoadPolicy(tt.name)
+		if got != tt.want {
+			t.Errorf("LoadPolicy(%q) = %+v, want %+v", tt.name, got, tt.want)
+		}
+		if got.Enabled() != tt.enabled {
+			t.Errorf("LoadPolicy(%q).Enabled() = %v, want %v", tt.name, got.Enabled(), tt.enabled)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileProvider reads secrets from files in a directory, such as Docker or Kubernetes secret
+// mounts. "name" reads dir/name and "name#key" reads dir/name/key.
+type FileProvider struct {
+	dir string
+}
+
+// NewFileProvider creates a provider reading from dir
+func NewFileProvider(dir string) SecretProvider {
+	return &FileProvider{dir: dir}
+}
+
+// Resolve returns the contents of the secret's file without the trailing newline
+func (p *FileProvider) Resolve(_ context.Context, name string) (string, error) {
+	path, key := splitName(name)
+
+	file := filepath.Join(p.dir, path)
+	if key != "" {
+		file = filepath.Join(file, key)
+	}
+
+	// Keep lookups inside the secrets directory
+	if rel, err := filepath.Rel(p.dir, file); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("secret name %q escapes the secrets directory", name)
+	}
+
+	return ReadFile(file)
+}
+
+// ReadFile reads a secret file, trimming the trailing newline most editors and tools add
+func ReadFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
@@ -0,0 +1,46 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// referencePattern matches ${secret:name} references in configuration values
+var referencePattern = regexp.MustCompile(`\$\{secret:([^}]+)\}`)
+
+// SecretProvider resolves secrets by name. A name is either "path" or "path#key",
+// for secrets that hold several values, such as database credentials.
+type SecretProvider interface {
+	Resolve(ctx context.Context, name string) (string, error)
+}
+
+// ResolveReferences replaces every ${secret:name} reference in value with the secret from provider
+func ResolveReferences(ctx context.Context, provider SecretProvider, value string) (string, error) {
+	var resolveErr error
+	resolved := referencePattern.ReplaceAllStringFunc(value, func(reference string) string {
+		if resolveErr != nil {
+			return reference
+		}
+		name := referencePattern.FindStringSubmatch(reference)[1]
+		secret, err := provider.Resolve(ctx, name)
+		if err != nil {
+			resolveErr = fmt.Errorf("failed to resolve secret %q: %w", name, err)
+			return reference
+		}
+		return secret
+	})
+	return resolved, resolveErr
+}
+
+// HasReferences reports whether value contains a ${secret:name} reference
+func HasReferences(value string) bool {
+	return referencePattern.MatchString(value)
+}
+
+// splitName splits a secret name into its path and optional key
+func splitName(name string) (path, key string) {
+	path, key, _ = strings.Cut(name, "#")
+	return path, key
+}
@@ -0,0 +1,180 @@
+package secrets_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/userblog/management/pkg/secrets"
+)
+
+// fakeProvider resolves the names in its map and fails for any other
+type fakeProvider map[string]string
+
+func (p fakeProvider) Resolve(_ context.Context, name string) (string, error) {
+	if value, ok := p[name]; ok {
+		return value, nil
+	}
+	return "", errors.New("no such secret")
+}
+
+func TestResolveReferences(t *testing.T) {
+	provider := fakeProvider{"db#password": "hunter2", "db#user": "app", "jwt": "signing-key"}
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr string
+	}{
+		{name: "no reference", value: "plain", want: "plain"},
+		{name: "whole value", value: "${secret:jwt}", want: "signing-key"},
+		{name: "keyed", value: "${secret:db#password}", want: "hunter2"},
+		{name: "several", value: "${secret:db#user}:${secret:db#password}@db", want: "app:hunter2@db"},
+		{name: "not a reference", value: "${env:HOME} and $secret:jwt", want: "${env:HOME} and $secret:jwt"},
+		{name: "missing", value: "${secret:db#user}:${secret:missing}", wantErr: `failed to resolve secret "missing"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := secrets.ResolveReferences(context.Background(), provider, tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				if strings.Contains(got, "hunter2") || strings.Contains(err.Error(), "app") {
+					t.Errorf("failed resolution leaked a resolved secret: %q, %v", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveReferences: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if secrets.HasReferences(tt.value) != (tt.value != tt.want) {
+				t.Errorf("HasReferences(%q) = %v", tt.value, secrets.HasReferences(tt.value))
+			}
+		})
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "jwt"), "signing-key\n")
+	writeFile(t, filepath.Join(dir, "db", "password"), "hunter2\r\n")
+	writeFile(t, filepath.Join(filepath.Dir(dir), "outside"), "not for us")
+	provider := secrets.NewFileProvider(dir)
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "jwt", want: "signing-key"},
+		{name: "db#password", want: "hunter2"},
+		{name: "missing", wantErr: true},
+		{name: "../outside", wantErr: true},
+		{name: "db#../../outside", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := provider.Resolve(context.Background(), tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVaultProvider(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root-token" {
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(map[string][]string{"errors": {"permission denied"}})
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/app/db":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"data": map[string]interface{}{"password": "hunter2", "port": 5432}},
+			})
+		case "/v1/secret/data/app/jwt":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"data": map[string]interface{}{"value": "signing-key"}},
+			})
+		case "/v1/secret/data/app/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("<html>upstream error</html>"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string][]string{"errors": {}})
+		}
+	}))
+	defer vault.Close()
+
+	tests := []struct {
+		name    string
+		token   string
+		secret  string
+		want    string
+		wantErr string
+	}{
+		{name: "default field", token: "root-token", secret: "app/jwt", want: "signing-key"},
+		{name: "named field", token: "root-token", secret: "app/db#password", want: "hunter2"},
+		{name: "number field", token: "root-token", secret: "app/db#port", want: "5432"},
+		{name: "missing field", token: "root-token", secret: "app/db#user", wantErr: `has no field "user"`},
+		{name: "missing secret", token: "root-token", secret: "app/none", wantErr: "404"},
+		{name: "server error", token: "root-token", secret: "app/broken", wantErr: "500"},
+		{name: "wrong token", token: "other", secret: "app/jwt", wantErr: "permission denied"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := secrets.NewVaultProvider(vault.URL+"/", tt.token, "/secret/")
+			got, err := provider.Resolve(context.Background(), tt.secret)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("unreachable", func(t *testing.T) {
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closed.Close()
+		if _, err := secrets.NewVaultProvider(closed.URL, "root-token", "secret").Resolve(context.Background(), "app/jwt"); err == nil {
+			t.Error("Resolve succeeded against a closed server")
+		}
+	})
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
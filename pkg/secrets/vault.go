@@ -0,0 +1,76 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultVaultKey is the field read when a secret name has no #key
+const defaultVaultKey = "value"
+
+// VaultProvider reads secrets from a HashiCorp Vault compatible KV version 2 engine over HTTP.
+// "name#key" reads field key of the secret at name; "name" reads its "value" field.
+type VaultProvider struct {
+	address string
+	token   string
+	mount   string
+	client  *http.Client
+}
+
+// NewVaultProvider creates a provider for the KV v2 engine mounted at mount on the server at address
+func NewVaultProvider(address, token, mount string) SecretProvider {
+	return &VaultProvider{
+		address: strings.TrimRight(address, "/"),
+		token:   token,
+		mount:   strings.Trim(mount, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// vaultResponse is the body of a KV v2 read
+type vaultResponse struct {
+	Data struct {
+		Data map[string]interface{} `json:"data"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+// Resolve reads the secret's field from Vault
+func (p *VaultProvider) Resolve(ctx context.Context, name string) (string, error) {
+	path, key := splitName(name)
+	if key == "" {
+		key = defaultVaultKey
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/%s/data/%s", p.address, url.PathEscape(p.mount), strings.TrimLeft(path, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body vaultResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return "", fmt.Errorf("invalid vault response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s: %s", resp.Status, strings.Join(body.Errors, "; "))
+	}
+
+	value, ok := body.Data.Data[key]
+	if !ok {
+		return "", fmt.Errorf("vault secret %q has no field %q", path, key)
+	}
+	return fmt.Sprint(value), nil
+}
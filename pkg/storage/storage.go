@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+// ErrNotFound is returned when no file is stored under a key
+var ErrNotFound = errors.New("file not found")
+
+// ErrInvalidKey is returned for keys that aren't made of path segments of letters, digits,
+// '-', '_' and '.'
+var ErrInvalidKey = errors.New("invalid file key")
+
+// keyPattern matches keys of one or more '/'-separated segments, none of them starting with '.'
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*(/[A-Za-z0-9_-][A-Za-z0-9._-]*)*$`)
+
+// Store keeps uploaded and generated files by key, such as avatars/12-1a2b3c.png. The disk store
+// suits a single instance; a shared implementation (for example backed by S3) lets several
+// instances serve the same files.
+type Store interface {
+	// Put stores data under key, replacing any file already there
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns the file stored under key, or ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes the file stored under key, if any
+	Delete(ctx context.Context, key string) error
+}
+
+// ValidKey reports whether key can name a file
+func ValidKey(key string) bool {
+	return keyPattern.MatchString(key)
+}
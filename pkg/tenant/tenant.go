@@ -0,0 +1,76 @@
+package tenant
+
+import "context"
+
+// Source is where the active organization of a request was taken from
+type Source string
+
+const (
+	SourceHeader    Source = "header"    // the X-Organization header
+	SourceSubdomain Source = "subdomain" // the request host below the configured domain
+	SourceToken     Source = "token"     // the org claim of the access token
+	SourceDefault   Source = "default"   // the configured default organization
+)
+
+// Header names the organization a request acts in, by slug
+const Header = "X-Organization"
+
+// Organization is the organization a request acts in
+type Organization struct {
+	ID     uint
+	Slug   string
+	Source Source
+}
+
+type contextKey struct{}
+
+// WithOrganization returns a context acting in the organization
+func WithOrganization(ctx context.Context, org Organization) context.Context {
+	return context.WithValue(ctx, contextKey{}, org)
+}
+
+// FromContext returns the organization the context acts in, if any
+func FromContext(ctx context.Context) (Organization, bool) {
+	org, ok := ctx.Value(contextKey{}).(Organization)
+	return org, ok
+}
+
+// ID returns the ID of the organization the context acts in, or 0 outside of any
+func ID(ctx context.Context) uint {
+	org, _ := FromContext(ctx)
+	return org.ID
+}
+
+// Explicit reports whether the organization was named by the request rather than defaulted,
+// so that tokens issued for it can carry it
+func (o Organization) Explicit() bool {
+	return o.Source == SourceHeader || o.Source == SourceSubdomain
+}
+
+// WithTokenOrganization returns a context acting in the organization named by an access token's
+// org claim, unless the request named one itself
+func WithTokenOrganization(ctx context.Context, id uint) context.Context {
+	if id == 0 {
+		return ctx
+	}
+	if org, ok := FromContext(ctx); ok && org.Source != SourceDefault {
+		return ctx
+	}
+	return WithOrganization(ctx, Organization{ID: id, Source: SourceToken})
+}
+
+// NamedID returns the ID of the organization ctx acts in when the request named it, by header,
+// subdomain or token, or 0 in the default organization or outside of any
+func NamedID(ctx context.Context) uint {
+	org, ok := FromContext(ctx)
+	if !ok || org.Source == SourceDefault {
+		return 0
+	}
+	return org.ID
+}
+
+// WithoutOrganization returns a context acting in no organization, for work on the whole
+// platform such as verifying the audit log
+func WithoutOrganization(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, Organization{})
+}
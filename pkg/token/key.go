@@ -0,0 +1,236 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Supported signing algorithms. HS256 uses the shared jwt.secret and has no published keys.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+// rsaKeyBits is the size of generated RSA keys
+const rsaKeyBits = 2048
+
+// Key is an asymmetric signing key identified by its kid
+type Key struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.Signer
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+}
+
+// Generate creates a new key for an asymmetric algorithm, using its JWK thumbprint as the kid
+func Generate(algorithm string) (*Key, error) {
+	var private crypto.Signer
+	var err error
+	switch algorithm {
+	case RS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case EdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("cannot generate keys for algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newKey(algorithm, private)
+}
+
+// Parse decodes a PEM encoded PKCS #8 private key
+func Parse(algorithm, encoded string) (*Key, error) {
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil {
+		return nil, errors.New("no PEM encoded key found")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	private, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", parsed)
+	}
+	return newKey(algorithm, private)
+}
+
+// newKey checks that the private key fits the algorithm and derives its kid
+func newKey(algorithm string, private crypto.Signer) (*Key, error) {
+	key := &Key{Algorithm: algorithm, PrivateKey: private}
+	if key.JWK() == nil {
+		return nil, fmt.Errorf("a %T cannot be used for %s", private, algorithm)
+	}
+	id, err := key.thumbprint()
+	if err != nil {
+		return nil, err
+	}
+	key.ID = id
+	return key, nil
+}
+
+// Encode returns the private key in PEM encoded PKCS #8 form
+func (k *Key) Encode() (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// Public returns the verification key
+func (k *Key) Public() crypto.PublicKey {
+	return k.PrivateKey.Public()
+}
+
+// Method returns the jwt-go signing method for the key's algorithm
+func (k *Key) Method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// JWKS is a JSON Web Key Set (RFC 7517) of public keys
+type JWKS struct {
+	Keys []*JSONWebKey `json:"keys"`
+}
+
+// JSONWebKey holds the public members of a JSON Web Key
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	KeyID     string `json:"kid"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWK returns the public key as a JSON Web Key, or nil if its type doesn't match the algorithm
+func (k *Key) JWK() *JSONWebKey {
+	switch public := k.Public().(type) {
+	case *rsa.PublicKey:
+		if k.Algorithm != RS256 {
+			return nil
+		}
+		return &JSONWebKey{
+			KeyType:   "RSA",
+			Use:       "sig",
+			Algorithm: k.Algorithm,
+			KeyID:     k.ID,
+			N:         encode(public.N.Bytes()),
+			E:         encode(big.NewInt(int64(public.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		if k.Algorithm != EdDSA {
+			return nil
+		}
+		return &JSONWebKey{
+			KeyType:   "OKP",
+			Use:       "sig",
+			Algorithm: k.Algorithm,
+			KeyID:     k.ID,
+			Curve:     "Ed25519",
+			X:         encode(public),
+		}
+	}
+	return nil
+}
+
+// PublicKey decodes an RSA, EC or Ed25519 JSON Web Key, such as one published by another issuer
+func (j *JSONWebKey) PublicKey() (crypto.PublicKey, error) {
+	switch j.KeyType {
+	case "RSA":
+		n, errN := decode(j.N)
+		e, errE := decode(j.E)
+		if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid RSA key %q", j.KeyID)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q for key %q", j.Curve, j.KeyID)
+		}
+		x, errX := decode(j.X)
+		y, errY := decode(j.Y)
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("invalid EC key %q", j.KeyID)
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("invalid EC key %q", j.KeyID)
+		}
+		return key, nil
+	case "OKP":
+		x, err := decode(j.X)
+		if j.Curve != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key %q", j.KeyID)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q for key %q", j.KeyType, j.KeyID)
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint from the required members in lexical order
+func (k *Key) thumbprint() (string, error) {
+	key := k.JWK()
+	var members interface{}
+	if key.KeyType == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{key.E, key.KeyType, key.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{key.Curve, key.KeyType, key.X}
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return encode(sum[:]), nil
+}
+
+// encode returns unpadded base64url, as JOSE uses
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decode reads unpadded base64url
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
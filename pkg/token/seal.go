@@ -0,0 +1,68 @@
+package token
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// sealedPrefix marks a private key encrypted by Seal, telling it apart from a plain PEM key
+const sealedPrefix = "sealed:v1:"
+
+// Seal encrypts an encoded private key with AES-256-GCM under a key derived from passphrase,
+// for storing it where the database alone mustn't reveal it
+func Seal(encoded, passphrase string) (string, error) {
+	aead, err := newAEAD(passphrase)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(encoded), []byte(sealedPrefix))
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a private key sealed with passphrase. Keys stored before encryption was
+// configured are plain PEM and returned as they are.
+func Open(stored, passphrase string) (string, error) {
+	if !strings.HasPrefix(stored, sealedPrefix) {
+		return stored, nil
+	}
+	if passphrase == "" {
+		return "", errors.New("the key is encrypted but no key encryption key is configured")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, sealedPrefix))
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(passphrase)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("the encrypted key is truncated")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	encoded, err := aead.Open(nil, nonce, ciphertext, []byte(sealedPrefix))
+	if err != nil {
+		return "", errors.New("the key can't be decrypted with the configured key encryption key")
+	}
+	return string(encoded), nil
+}
+
+// newAEAD returns AES-256-GCM keyed with the SHA-256 of passphrase
+func newAEAD(passphrase string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(passphrase))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
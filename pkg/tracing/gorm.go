@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	gormContextKey = "tracing:context"
+	gormSpanKey    = "tracing:span"
+)
+
+// DB returns a handle on db that carries ctx, so queries run through it become children of the span in ctx
+func DB(ctx context.Context, db *gorm.DB) *gorm.DB {
+	return db.Set(gormContextKey, ctx)
+}
+
+// InstrumentDB registers GORM callbacks that create a span for every query run through DB
+func InstrumentDB(db *gorm.DB, system string) {
+	callbacks := db.Callback()
+
+	callbacks.Create().Before("gorm:begin_transaction").Register("tracing:before_create", before("create", system))
+	callbacks.Create().After("gorm:commit_or_rollback_transaction").Register("tracing:after_create", after)
+	callbacks.Query().Before("gorm:query").Register("tracing:before_query", before("query", system))
+	callbacks.Query().After("gorm:after_query").Register("tracing:after_query", after)
+	callbacks.Update().Before("gorm:begin_transaction").Register("tracing:before_update", before("update", system))
+	callbacks.Update().After("gorm:commit_or_rollback_transaction").Register("tracing:after_update", after)
+	callbacks.Delete().Before("gorm:begin_transaction").Register("tracing:before_delete", before("delete", system))
+	callbacks.Delete().After("gorm:commit_or_rollback_transaction").Register("tracing:after_delete", after)
+	callbacks.RowQuery().Before("gorm:row_query").Register("tracing:before_row_query", before("row_query", system))
+	callbacks.RowQuery().After("gorm:row_query").Register("tracing:after_row_query", after)
+}
+
+// before starts a span for the query when the scope carries a context
+func before(operation, system string) func(scope *gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		value, ok := scope.Get(gormContextKey)
+		if !ok {
+			return
+		}
+		ctx, ok := value.(context.Context)
+		if !ok {
+			return
+		}
+
+		_, span := Tracer().Start(ctx, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemKey.String(system),
+				semconv.DBOperation(operation),
+				semconv.DBSQLTable(scope.TableName()),
+			),
+		)
+		scope.Set(gormSpanKey, span)
+	}
+}
+
+// after ends the span started by before, recording the statement and any error
+func after(scope *gorm.Scope) {
+	value, ok := scope.Get(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+
+	span.SetAttributes(semconv.DBStatement(scope.SQL), attribute.Int64("db.rows_affected", scope.DB().RowsAffected))
+	if scope.HasError() && !gorm.IsRecordNotFoundError(scope.DB().Error) {
+		span.RecordError(scope.DB().Error)
+		span.SetStatus(codes.Error, scope.DB().Error.Error())
+	}
+	span.End()
+}
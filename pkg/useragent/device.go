@@ -0,0 +1,54 @@
+package useragent
+
+import "strings"
+
+// browsers are matched in order, since most user agents also name the engines they are compatible with
+var browsers = []struct{ token, name string }{
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"Firefox/", "Firefox"},
+	{"Chrome/", "Chrome"},
+	{"CriOS/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"curl/", "curl"},
+	{"PostmanRuntime/", "Postman"},
+	{"okhttp/", "OkHttp"},
+	{"python-requests/", "Python"},
+	{"Go-http-client/", "Go"},
+}
+
+// systems are matched in order, so phones and tablets win over the desktop systems they resemble
+var systems = []struct{ token, name string }{
+	{"iPhone", "iPhone"},
+	{"iPad", "iPad"},
+	{"Android", "Android"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "macOS"},
+	{"CrOS", "ChromeOS"},
+	{"Linux", "Linux"},
+}
+
+// DeviceName returns an approximate, human readable device name such as "Firefox on Windows"
+func DeviceName(userAgent string) string {
+	browser := match(userAgent, browsers)
+	system := match(userAgent, systems)
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+	return "Unknown device"
+}
+
+// match returns the name of the first entry whose token is in the user agent
+func match(userAgent string, entries []struct{ token, name string }) string {
+	for _, entry := range entries {
+		if strings.Contains(userAgent, entry.token) {
+			return entry.name
+		}
+	}
+	return ""
+}